@@ -0,0 +1,29 @@
+package main
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	"com.github/m-horky/playbook-verifier/internal/ansibleplugin"
+)
+
+// installAnsiblePlugin installs the Ansible callback plugin calling this executable into the directory.
+func installAnsiblePlugin(directory string) int {
+	verifier, err := os.Executable()
+	if err == nil {
+		// the plugin is run from any directory
+		verifier, err = filepath.Abs(verifier)
+	}
+	if err != nil {
+		slog.Error("could not find the path to the verifier", slog.Any("error", err))
+		return ExitIOError
+	}
+	path, err := ansibleplugin.Install(directory, verifier, ExitWarnOnlyKey)
+	if err != nil {
+		slog.Error("could not install Ansible plugin", slog.String("directory", directory), slog.Any("error", err))
+		return ExitIOError
+	}
+	slog.Info("Ansible plugin installed", slog.String("path", path), slog.String("verifier", verifier))
+	return ExitOK
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+
+	"com.github/m-horky/playbook-verifier/internal/audit"
+	"com.github/m-horky/playbook-verifier/pkg/verifier"
+)
+
+// openAuditLog opens the audit log selected by the arguments, or returns nil if it is disabled
+// or cannot be opened; a missing audit log does not prevent the verification.
+//
+// The log is opened before the verification, so it stays writable in the sandbox.
+func openAuditLog(arguments *Arguments) audit.Log {
+	var log audit.Log
+	var err error
+	switch arguments.AuditLog {
+	case "":
+		return nil
+	case AuditJournald:
+		log, err = audit.OpenJournald()
+	default:
+		log, err = audit.OpenFile(arguments.AuditLog, arguments.AuditLogMaxSize, arguments.AuditLogKeep)
+	}
+	if err != nil {
+		// The directory of the default log only exists where insights-client is installed
+		level := slog.LevelWarn
+		if arguments.AuditLog == DefaultAuditLog && errors.Is(err, fs.ErrNotExist) {
+			level = slog.LevelDebug
+		}
+		slog.Log(context.Background(), level, "audit log is disabled", slog.String("path", arguments.AuditLog), slog.Any("error", err))
+		return nil
+	}
+	return log
+}
+
+// processCaller describes the process that runs the verifier.
+func processCaller() string {
+	return fmt.Sprintf("parent process %d, user %d", os.Getppid(), os.Getuid())
+}
+
+// recordVerification appends the decision about the payload to the audit log, if it is enabled.
+func recordVerification(log audit.Log, contentType string, payload []byte, report verifier.Report, err error) {
+	if log == nil {
+		return
+	}
+	if auditErr := log.Write(audit.NewRecord(processCaller(), contentType, payload, report, err)); auditErr != nil {
+		slog.Error("could not record verification", slog.Any("error", auditErr))
+	}
+}
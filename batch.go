@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+
+	"com.github/m-horky/playbook-verifier/internal/audit"
+	"com.github/m-horky/playbook-verifier/pkg/verifier"
+)
+
+// FileReport is the verification result of a single file in a directory.
+type FileReport struct {
+	Path   string          `json:"path"`
+	Report verifier.Report `json:"report"`
+	// exitCode is the exit code the verification of the file alone would have.
+	exitCode int
+}
+
+// verifyDirectory verifies every playbook in the directory tree and prints the results.
+//
+// The playbooks are verified concurrently. The exit code is the one of the first
+// failed file in the lexical order, so it does not depend on scheduling.
+func verifyDirectory(ctx context.Context, arguments *Arguments, keyring *verifier.Keyring) int {
+	paths, err := findPlaybooks(arguments.PayloadDir)
+	if err != nil {
+		slog.Error("could not list playbooks", slog.Any("error", err))
+		return ExitIOError
+	}
+	slog.Debug("verifying directory", slog.String("directory", arguments.PayloadDir), slog.Int("playbooks", len(paths)))
+
+	reports := verifyFiles(ctx, paths, arguments, keyring)
+	if err = printFileReports(os.Stdout, reports, arguments); err != nil {
+		slog.Error("could not print report", slog.Any("error", err))
+		return ExitIOError
+	}
+	return fileReportsExitCode(reports)
+}
+
+// verifyFiles verifies the playbooks concurrently and returns their reports in the order of the paths.
+func verifyFiles(ctx context.Context, paths []string, arguments *Arguments, keyring *verifier.Keyring) []FileReport {
+	policy := arguments.Policy()
+	auditLog := openAuditLog(arguments)
+	reports := make([]FileReport, len(paths))
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for range min(runtime.NumCPU(), len(paths)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				reports[i] = verifyFile(ctx, paths[i], arguments, keyring, policy, auditLog)
+			}
+		}()
+	}
+	for i := range paths {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+	return reports
+}
+
+// fileReportsExitCode returns the exit code of the first failed file, ExitWarnOnlyKey if a file
+// is verified by a warn-only key, or ExitOK.
+func fileReportsExitCode(reports []FileReport) int {
+	code := ExitOK
+	for _, report := range reports {
+		switch report.exitCode {
+		case ExitOK:
+		case ExitWarnOnlyKey:
+			code = ExitWarnOnlyKey
+		default:
+			return report.exitCode
+		}
+	}
+	return code
+}
+
+// verifyFile verifies a single playbook of the directory.
+func verifyFile(ctx context.Context, path string, arguments *Arguments, keyring *verifier.Keyring, policy verifier.Policy, auditLog audit.Log) FileReport {
+	logger := slog.With(slog.String("path", path))
+
+	payload, err := readPlaybook(PlaybookSource{path: path}, arguments.MaxSize, 0)
+	if err != nil {
+		report := verifier.Report{Plays: []verifier.PlayReport{}, SkippedDocuments: []int{}, Error: err.Error()}
+		return FileReport{Path: path, Report: report, exitCode: ExitIOError}
+	}
+
+	report, err := verifier.VerifyContext(ctx, arguments.ContentType, payload, keyring, policy)
+	recordVerification(auditLog, arguments.ContentType, payload, report, err)
+	if err != nil {
+		logger.Error("could not verify playbook", slog.Any("error", err))
+		return FileReport{Path: path, Report: report, exitCode: exitCode(err)}
+	}
+	logger.Info("playbook verified")
+	return FileReport{Path: path, Report: report, exitCode: verdictCode(report, err)}
+}
+
+// findPlaybooks returns the paths of the YAML files in the directory tree, in lexical order.
+func findPlaybooks(root string) ([]string, error) {
+	var paths []string
+	err := filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.Type().IsRegular() && (filepath.Ext(path) == ".yml" || filepath.Ext(path) == ".yaml") {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	return paths, err
+}
+
+// printFileReports writes the results of the directory verification in the output format.
+func printFileReports(w io.Writer, reports []FileReport, arguments *Arguments) error {
+	if arguments.Format == FormatJSON {
+		return json.NewEncoder(w).Encode(reports)
+	}
+
+	for _, report := range reports {
+		if report.Report.Verified {
+			fmt.Fprintf(w, "%s: verified\n", report.Path)
+			printWarnings(os.Stderr, report.Path+": ", report.Report, arguments.Unsigned)
+		} else {
+			fmt.Fprintf(w, "%s: %s\n", report.Path, report.Report.Error)
+		}
+		for _, play := range report.Report.Plays {
+			fmt.Fprint(os.Stderr, play.Diff)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// writePlaybooks writes the files into a temporary directory, creating the subdirectories of their names.
+func writePlaybooks(t *testing.T, files map[string][]byte) string {
+	t.Helper()
+	directory := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(directory, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, content, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return directory
+}
+
+func TestVerifyFiles(t *testing.T) {
+	signed := signedPlaybook(t, testPlaybook)
+	directory := writePlaybooks(t, map[string][]byte{
+		"1-signed.yml":         signed,
+		"2-tampered.yml":       tamperedPlaybook(t),
+		"3-unsigned.yml":       []byte(testPlaybook),
+		"notes.txt":            []byte("not a playbook"),
+		"nested/4-signed.yaml": signed,
+	})
+	arguments := parseTestArguments(t, "--payload-dir", directory)
+	keyring := mustLoadKeyring(arguments)
+
+	paths, err := findPlaybooks(directory)
+	if err != nil {
+		t.Fatalf("findPlaybooks() error = %v", err)
+	}
+	reports := verifyFiles(context.Background(), paths, arguments, keyring)
+
+	want := []struct {
+		name     string
+		verified bool
+		exitCode int
+	}{
+		{"1-signed.yml", true, ExitOK},
+		{"2-tampered.yml", false, ExitSignatureMismatch},
+		{"3-unsigned.yml", false, ExitMissingSignature},
+		{"nested/4-signed.yaml", true, ExitOK},
+	}
+	if len(reports) != len(want) {
+		t.Fatalf("verifyFiles() = %d reports, want %d", len(reports), len(want))
+	}
+	for i, report := range reports {
+		if report.Path != filepath.Join(directory, want[i].name) {
+			t.Errorf("report %d is of %s, want %s", i, report.Path, want[i].name)
+		}
+		if report.Report.Verified != want[i].verified || report.exitCode != want[i].exitCode {
+			t.Errorf("%s: verified %t with exit code %d, want %t with %d", want[i].name, report.Report.Verified, report.exitCode, want[i].verified, want[i].exitCode)
+		}
+	}
+	if code := fileReportsExitCode(reports); code != ExitSignatureMismatch {
+		t.Errorf("fileReportsExitCode() = %d, want %d of the first failed file", code, ExitSignatureMismatch)
+	}
+
+	var output bytes.Buffer
+	if err = printFileReports(&output, reports, arguments); err != nil {
+		t.Fatalf("printFileReports() error = %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(output.String()), "\n")
+	if len(lines) != len(want) {
+		t.Fatalf("printFileReports() = %q, want a line per file", output.String())
+	}
+	for i, line := range lines {
+		if verified := strings.HasSuffix(line, ": verified"); verified != want[i].verified {
+			t.Errorf("printFileReports() line %q, want verified %t", line, want[i].verified)
+		}
+	}
+}
+
+func TestFileReportsExitCode(t *testing.T) {
+	tests := []struct {
+		name  string
+		codes []int
+		want  int
+	}{
+		{name: "none", want: ExitOK},
+		{name: "verified", codes: []int{ExitOK, ExitOK}, want: ExitOK},
+		{name: "warn-only key", codes: []int{ExitOK, ExitWarnOnlyKey, ExitOK}, want: ExitWarnOnlyKey},
+		{name: "first failure", codes: []int{ExitOK, ExitMissingSignature, ExitSignatureMismatch}, want: ExitMissingSignature},
+		{name: "failure over warn-only key", codes: []int{ExitWarnOnlyKey, ExitParseError}, want: ExitParseError},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var reports []FileReport
+			for _, code := range test.codes {
+				reports = append(reports, FileReport{exitCode: code})
+			}
+			if got := fileReportsExitCode(reports); got != test.want {
+				t.Errorf("fileReportsExitCode() = %d, want %d", got, test.want)
+			}
+		})
+	}
+}
+
+func TestPayloadDir(t *testing.T) {
+	signed := signedPlaybook(t, testPlaybook)
+	tests := []struct {
+		name  string
+		files map[string][]byte
+		want  int
+	}{
+		{name: "verified", files: map[string][]byte{"a.yml": signed, "b.yml": signed}, want: ExitOK},
+		{name: "mixed", files: map[string][]byte{"a.yml": signed, "b.yml": []byte(testPlaybook), "c.yml": tamperedPlaybook(t)}, want: ExitMissingSignature},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			directory := writePlaybooks(t, test.files)
+			stdout, stderr, code := runMain(t, nil, nil, append(testArguments(t), "--payload-dir", directory)...)
+			if code != test.want {
+				t.Errorf("exit code = %d, want %d; stderr:\n%s", code, test.want, stderr)
+			}
+			var names []string
+			for _, line := range strings.Split(strings.TrimSpace(stdout), "\n") {
+				path, _, _ := strings.Cut(line, ": ")
+				names = append(names, filepath.Base(path))
+			}
+			var want []string
+			for name := range test.files {
+				want = append(want, name)
+			}
+			slices.Sort(want)
+			if !slices.Equal(names, want) {
+				t.Errorf("reported files = %v, want %v", names, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"strings"
+
+	"gopkg.in/ini.v1"
+)
+
+// configSection is the section of the configuration file the options are read from.
+const configSection = "playbook-verifier"
+
+// environmentPrefix is the prefix of the environment variables the options are read from.
+const environmentPrefix = "PLAYBOOK_VERIFIER_"
+
+// environmentAliases are the historical environment variables of options, used if the variable
+// with the prefix is not set.
+var environmentAliases = map[string]string{
+	"payload": "PLAYBOOK_SOURCE",
+}
+
+// applyConfigFile sets the options from the configuration file that have not been
+// set on the command line. The environment variables override both, see applyEnvironment.
+//
+// The options in the file are named after the command-line flags:
+//
+//	[playbook-verifier]
+//	log-level = debug
+//	gpg-key-dir = /etc/insights-client/keys
+//	require-all-signed = true
+//
+// A missing file is not an error unless it has been requested explicitly.
+func applyConfigFile(flags *flag.FlagSet, path string, explicit bool) error {
+	content, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) && !explicit {
+		slog.Debug("no configuration file", slog.String("path", path))
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("could not read configuration file: %w", err)
+	}
+
+	config, err := ini.Load(content)
+	if err != nil {
+		return fmt.Errorf("could not parse configuration file %s: %w", path, err)
+	}
+
+	set := map[string]bool{}
+	flags.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+
+	for _, key := range config.Section(configSection).Keys() {
+		name := key.Name()
+		if name == "config" || flags.Lookup(name) == nil {
+			return fmt.Errorf("unknown option in configuration file %s: %s", path, name)
+		}
+		if set[name] {
+			continue
+		}
+		if err := flags.Set(name, key.Value()); err != nil {
+			return fmt.Errorf("invalid value of option %s in configuration file %s: %w", name, path, err)
+		}
+	}
+	return nil
+}
+
+// environmentVariable returns the name of the environment variable of the option,
+// e.g. PLAYBOOK_VERIFIER_LOG_LEVEL for log-level.
+func environmentVariable(name string) string {
+	return environmentPrefix + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
+// applyEnvironment sets the options from their environment variables, overriding the values
+// from the command line and the configuration file. Empty variables are ignored.
+//
+// The values of repeatable options are replaced, rather than appended to, by calling their reset
+// function first; a variable holds a single value, or a comma-separated list if the option accepts one.
+// The configuration file is selected by PLAYBOOK_VERIFIER_CONFIG before it is read, and --version
+// is an action rather than an option, so neither is set here.
+func applyEnvironment(flags *flag.FlagSet, resets map[string]func()) error {
+	var err error
+	flags.VisitAll(func(f *flag.Flag) {
+		if err != nil || f.Name == "config" || f.Name == "version" {
+			return
+		}
+		variable := environmentVariable(f.Name)
+		value, ok := os.LookupEnv(variable)
+		if alias, hasAlias := environmentAliases[f.Name]; hasAlias && (!ok || value == "") {
+			variable = alias
+			value, ok = os.LookupEnv(alias)
+		}
+		if !ok || value == "" {
+			return
+		}
+		slog.Debug("option set by environment", slog.String("option", f.Name), slog.String("variable", variable))
+		if reset, ok := resets[f.Name]; ok {
+			reset()
+		}
+		if setErr := flags.Set(f.Name, value); setErr != nil {
+			err = fmt.Errorf("invalid value of option %s in environment variable %s: %w", f.Name, variable, setErr)
+		}
+	})
+	return err
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// writeConfig writes the [playbook-verifier] section with the lines into a temporary configuration file.
+func writeConfig(t *testing.T, lines ...string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "playbook-verifier.conf")
+	content := "[" + configSection + "]\n" + strings.Join(lines, "\n") + "\n"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// clearEnvironment unsets the variables of the options for the duration of the test.
+func clearEnvironment(t *testing.T, variables ...string) {
+	t.Helper()
+	for _, variable := range variables {
+		t.Setenv(variable, "")
+	}
+}
+
+func TestParseArgumentsPrecedence(t *testing.T) {
+	config := writeConfig(t, "log-level = warn", "payload = /config/playbook.yml")
+	tests := []struct {
+		name        string
+		args        []string
+		environment map[string]string
+		logLevel    string
+		payload     string
+	}{
+		{name: "defaults", args: []string{"--config", writeConfig(t)}, logLevel: "info", payload: ""},
+		{name: "config over defaults", args: []string{"--config", config}, logLevel: "warn", payload: "/config/playbook.yml"},
+		{name: "flags over config", args: []string{"--config", config, "--log-level", "error", "--payload", "/flag/playbook.yml"}, logLevel: "error", payload: "/flag/playbook.yml"},
+		{
+			name:        "environment over flags",
+			args:        []string{"--config", config, "--log-level", "error", "--payload", "/flag/playbook.yml"},
+			environment: map[string]string{"PLAYBOOK_VERIFIER_LOG_LEVEL": "debug", "PLAYBOOK_SOURCE": "/source/playbook.yml"},
+			logLevel:    "debug",
+			payload:     "/source/playbook.yml",
+		},
+		{
+			name:        "PLAYBOOK_SOURCE over config",
+			args:        []string{"--config", config},
+			environment: map[string]string{"PLAYBOOK_SOURCE": "/source/playbook.yml"},
+			logLevel:    "warn",
+			payload:     "/source/playbook.yml",
+		},
+		{
+			name:        "prefixed variable over PLAYBOOK_SOURCE",
+			args:        []string{"--config", writeConfig(t), "--payload", "/flag/playbook.yml"},
+			environment: map[string]string{"PLAYBOOK_VERIFIER_PAYLOAD": "/env/playbook.yml", "PLAYBOOK_SOURCE": "/source/playbook.yml"},
+			logLevel:    "info",
+			payload:     "/env/playbook.yml",
+		},
+		{
+			name:        "empty variables are ignored",
+			args:        []string{"--config", config, "--payload", "/flag/playbook.yml"},
+			environment: map[string]string{"PLAYBOOK_VERIFIER_LOG_LEVEL": "", "PLAYBOOK_SOURCE": ""},
+			logLevel:    "warn",
+			payload:     "/flag/playbook.yml",
+		},
+		{
+			name:        "configuration file from the environment",
+			environment: map[string]string{"PLAYBOOK_VERIFIER_CONFIG": config},
+			logLevel:    "warn",
+			payload:     "/config/playbook.yml",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			clearEnvironment(t, "PLAYBOOK_VERIFIER_CONFIG", "PLAYBOOK_VERIFIER_LOG_LEVEL", "PLAYBOOK_VERIFIER_PAYLOAD", "PLAYBOOK_SOURCE")
+			for variable, value := range test.environment {
+				t.Setenv(variable, value)
+			}
+			arguments, err := parseArguments(test.args)
+			if err != nil {
+				t.Fatalf("parseArguments() error = %v", err)
+			}
+			if arguments.LogLevel != test.logLevel {
+				t.Errorf("LogLevel = %q, want %q", arguments.LogLevel, test.logLevel)
+			}
+			if arguments.Payload != test.payload {
+				t.Errorf("Payload = %q, want %q", arguments.Payload, test.payload)
+			}
+		})
+	}
+}
+
+func TestParseArgumentsEnvironmentReplacesRepeatable(t *testing.T) {
+	clearEnvironment(t, "PLAYBOOK_VERIFIER_CONFIG")
+	config := writeConfig(t, "trust-store = dir:/config")
+	t.Setenv("PLAYBOOK_VERIFIER_TRUST_STORE", "dir:/env")
+
+	arguments, err := parseArguments([]string{"--config", config, "--trust-store", "dir:/flag", "--trust-store", "dir:/other"})
+	if err != nil {
+		t.Fatalf("parseArguments() error = %v", err)
+	}
+	if want := []string{"dir:/env"}; !slices.Equal(arguments.TrustStores, want) {
+		t.Errorf("TrustStores = %v, want %v", arguments.TrustStores, want)
+	}
+}
+
+func TestParseArgumentsEnvironmentInvalid(t *testing.T) {
+	clearEnvironment(t, "PLAYBOOK_VERIFIER_CONFIG")
+	t.Setenv("PLAYBOOK_VERIFIER_MAX_SIZE", "large")
+
+	_, err := parseArguments([]string{"--config", writeConfig(t)})
+	if err == nil || !strings.Contains(err.Error(), "PLAYBOOK_VERIFIER_MAX_SIZE") {
+		t.Errorf("parseArguments() error = %v, want the invalid variable", err)
+	}
+}
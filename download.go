@@ -0,0 +1,39 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"com.github/m-horky/playbook-verifier/internal/httpclient"
+)
+
+// downloadPlaybook downloads the playbook from --payload-url. It reaches the server the way
+// the other online features do, see httpConfig, and is limited like a playbook read from a file:
+// by --max-size and, for the whole request, by --read-timeout.
+//
+// Nothing is written to the disk; the playbook is verified from memory.
+func downloadPlaybook(ctx context.Context, arguments *Arguments) ([]byte, error) {
+	config := httpConfig(arguments)
+	config.Timeout = arguments.ReadTimeout
+	client, err := httpclient.New(config)
+	if err != nil {
+		return nil, err
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, arguments.PayloadURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	slog.Debug("downloading playbook", slog.String("url", arguments.PayloadURL))
+	response, err := client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("could not download %s: %s", arguments.PayloadURL, response.Status)
+	}
+	// the client's timeout already covers reading the body
+	return readLimited(response.Body, arguments.MaxSize, 0)
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newPlaybookServer returns an HTTPS server of the handler and the --tls-ca-dir that trusts it.
+func newPlaybookServer(t *testing.T, handler http.HandlerFunc) (*httptest.Server, string) {
+	t.Helper()
+	server := httptest.NewTLSServer(handler)
+	t.Cleanup(server.Close)
+	caDir := t.TempDir()
+	certificate := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := os.WriteFile(filepath.Join(caDir, "server.pem"), certificate, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return server, caDir
+}
+
+func TestDownloadPlaybook(t *testing.T) {
+	content := []byte("- hosts: localhost\n")
+	server, caDir := newPlaybookServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/playbook.yml":
+			w.Write(content)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+
+	tests := []struct {
+		name    string
+		path    string
+		maxSize int64
+		wantErr string
+	}{
+		{name: "found", path: "/playbook.yml"},
+		{name: "exactly the limit", path: "/playbook.yml", maxSize: int64(len(content))},
+		{name: "over the limit", path: "/playbook.yml", maxSize: int64(len(content)) - 1, wantErr: ErrPayloadTooLarge.Error()},
+		{name: "not found", path: "/missing.yml", wantErr: "404 Not Found"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			arguments := &Arguments{PayloadURL: server.URL + test.path, MaxSize: test.maxSize, ReadTimeout: 10 * time.Second, TLSCADirectories: []string{caDir}}
+			got, err := downloadPlaybook(context.Background(), arguments)
+			if test.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+					t.Fatalf("downloadPlaybook() error = %v, want %q", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("downloadPlaybook() error = %v", err)
+			}
+			if !bytes.Equal(got, content) {
+				t.Errorf("downloadPlaybook() = %q, want %q", got, content)
+			}
+		})
+	}
+}
+
+func TestDownloadPlaybookReadTimeout(t *testing.T) {
+	stalled := make(chan struct{})
+	server, caDir := newPlaybookServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("- hosts: localhost\n"))
+		w.(http.Flusher).Flush()
+		select {
+		case <-stalled:
+		case <-r.Context().Done():
+		}
+	})
+	// unblock the handler before the server waits for it
+	t.Cleanup(func() { close(stalled) })
+
+	arguments := &Arguments{PayloadURL: server.URL, ReadTimeout: 100 * time.Millisecond, TLSCADirectories: []string{caDir}}
+	start := time.Now()
+	_, err := downloadPlaybook(context.Background(), arguments)
+	if err == nil {
+		t.Fatal("downloadPlaybook() error = nil, want the timeout of the body")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("downloadPlaybook() returned after %s, want about %s", elapsed, arguments.ReadTimeout)
+	}
+}
+
+func TestPayloadURLOutput(t *testing.T) {
+	playbook := signedPlaybook(t, testPlaybook)
+	server, caDir := newPlaybookServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/playbook.yml" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(playbook)
+	})
+	environment := []string{"NO_PROXY=*"}
+
+	t.Run("verified", func(t *testing.T) {
+		output := filepath.Join(t.TempDir(), "playbook.yml")
+		stdout, stderr, code := runMain(t, nil, environment, append(testArguments(t), "--payload-url", server.URL+"/playbook.yml", "--tls-ca-dir", caDir, "--output", output)...)
+		if code != ExitOK {
+			t.Fatalf("exit code = %d, want %d; stderr:\n%s", code, ExitOK, stderr)
+		}
+		if stdout != "" {
+			t.Errorf("stdout = %q, want the playbook in --output only", stdout)
+		}
+		written, err := os.ReadFile(output)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(written, playbook) {
+			t.Errorf("--output = %q, want %q", written, playbook)
+		}
+	})
+
+	t.Run("not downloaded", func(t *testing.T) {
+		output := filepath.Join(t.TempDir(), "playbook.yml")
+		previous := []byte("previous playbook\n")
+		if err := os.WriteFile(output, previous, 0o600); err != nil {
+			t.Fatal(err)
+		}
+		_, stderr, code := runMain(t, nil, environment, append(testArguments(t), "--payload-url", server.URL+"/missing.yml", "--tls-ca-dir", caDir, "--output", output)...)
+		if code != ExitIOError {
+			t.Errorf("exit code = %d, want %d; stderr:\n%s", code, ExitIOError, stderr)
+		}
+		written, err := os.ReadFile(output)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(written, previous) {
+			t.Errorf("--output = %q, want the previous playbook kept", written)
+		}
+	})
+}
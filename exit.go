@@ -0,0 +1,63 @@
+package main
+
+import (
+	"errors"
+
+	"com.github/m-horky/playbook-verifier/internal/archive"
+	"com.github/m-horky/playbook-verifier/internal/manifest"
+	"com.github/m-horky/playbook-verifier/pkg/verifier"
+)
+
+// Exit codes of the verifier.
+//
+// Callers such as insights-client and rhc branch on these values, so existing
+// codes must never be renumbered.
+const (
+	// ExitOK means every play has been verified and the playbook was printed.
+	ExitOK = 0
+	// ExitParseError means the playbook could not be parsed or processed.
+	ExitParseError = 1
+	// ExitMissingSignature means a play is not signed or does not declare its exclusions.
+	ExitMissingSignature = 2
+	// ExitSignatureMismatch means a signature is malformed or does not match the play.
+	ExitSignatureMismatch = 3
+	// ExitIOError means the playbook could not be read or written.
+	ExitIOError = 4
+	// ExitUsageError means the command-line arguments are invalid.
+	ExitUsageError = 5
+	// ExitPolicyViolation means the playbook is verified, but the rules of --policy-file refuse it.
+	ExitPolicyViolation = 6
+	// ExitWarnOnlyKey means the playbook is verified and was printed, but a signature was created
+	// by a key of a warn-only trust store. It is not a failure.
+	ExitWarnOnlyKey = 10
+)
+
+// verdictCode maps the result of a verification to the exit code: the one of the error,
+// or ExitWarnOnlyKey if the payload is verified by a warn-only key.
+func verdictCode(report verifier.Report, err error) int {
+	if err == nil && len(report.WarnOnlyKeyIDs()) > 0 {
+		return ExitWarnOnlyKey
+	}
+	return exitCode(err)
+}
+
+// exitCode maps an error returned by the verification pipeline to the exit code.
+func exitCode(err error) int {
+	var verificationError verifier.VerificationError
+	switch {
+	case err == nil:
+		return ExitOK
+	case errors.Is(err, verifier.ErrPolicyViolation):
+		return ExitPolicyViolation
+	case errors.Is(err, verifier.ErrNoSignature):
+		return ExitMissingSignature
+	case errors.Is(err, archive.ErrManifestMismatch), errors.Is(err, manifest.ErrMismatch):
+		return ExitSignatureMismatch
+	case errors.Is(err, archive.ErrArchiveTooLarge):
+		return ExitIOError
+	case errors.As(err, &verificationError):
+		return ExitSignatureMismatch
+	default:
+		return ExitParseError
+	}
+}
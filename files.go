@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"path/filepath"
+
+	"com.github/m-horky/playbook-verifier/internal/hygiene"
+	"com.github/m-horky/playbook-verifier/internal/manifest"
+	"com.github/m-horky/playbook-verifier/pkg/verifier"
+)
+
+// checkFilesManifest verifies the signature of the files manifest, and checks that the files
+// of its directory tree, e.g. the roles and templates of the playbook, are the ones it lists.
+//
+// The manifest is signed like any detached signature of a payload, so the keys, the maximal
+// age of the signature and the revoked keys apply to it as well.
+func checkFilesManifest(arguments *Arguments, keyring *verifier.Keyring) error {
+	content, err := readSignature(arguments.FilesManifest, arguments.MaxSize)
+	if err != nil {
+		return fmt.Errorf("could not read files manifest: %w", err)
+	}
+	signature, err := readSignature(arguments.FilesManifestSignature, arguments.MaxSize)
+	if err != nil {
+		return fmt.Errorf("could not read signature of files manifest: %w", err)
+	}
+	defer hygiene.Wipe(signature)
+
+	// the rules of the policy are about plays, which the manifest has none of
+	policy := verifier.Policy{MaxSignatureAge: arguments.MaxSignatureAge, ClockSkew: arguments.ClockSkew}
+	report, err := verifier.VerifyDetachedPolicy(content, signature, keyring, policy)
+	if err != nil {
+		return fmt.Errorf("%w: %w", manifest.ErrUntrusted, err)
+	}
+	entries, err := manifest.Parse(content)
+	if err != nil {
+		return err
+	}
+
+	directory := filepath.Dir(arguments.FilesManifest)
+	ignored := []string{filepath.Base(arguments.FilesManifest)}
+	if relative, err := filepath.Rel(directory, arguments.FilesManifestSignature); err == nil && filepath.IsLocal(relative) {
+		ignored = append(ignored, relative)
+	}
+	if err = manifest.CheckDirectory(directory, entries, ignored...); err != nil {
+		return err
+	}
+	slog.Info("files match their manifest", slog.String("directory", directory), slog.Int("files", len(entries)), slog.String("key", report.KeyID))
+	return nil
+}
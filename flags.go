@@ -0,0 +1,749 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"com.github/m-horky/playbook-verifier/internal/ansibleplugin"
+	"com.github/m-horky/playbook-verifier/internal/audit"
+	"com.github/m-horky/playbook-verifier/internal/httpclient"
+	"com.github/m-horky/playbook-verifier/internal/keystore"
+	"com.github/m-horky/playbook-verifier/internal/rekor"
+	"com.github/m-horky/playbook-verifier/internal/summary"
+	"com.github/m-horky/playbook-verifier/internal/yggdrasil"
+	"com.github/m-horky/playbook-verifier/pkg/verifier"
+)
+
+// Output formats.
+const (
+	// FormatText prints the verified playbook.
+	FormatText = "text"
+	// FormatJSON prints the verification report.
+	FormatJSON = "json"
+)
+
+// DefaultCacheTTL is the default time cached verification reports are valid for.
+const DefaultCacheTTL = 24 * time.Hour
+
+// OpenPGP backends.
+const (
+	// GPGBackendNative verifies OpenPGP signatures in-process.
+	GPGBackendNative = "native"
+	// GPGBackendExec runs gpgv, like the Python verifier.
+	GPGBackendExec = "exec"
+)
+
+// Sandbox modes.
+const (
+	// SandboxOff leaves the process unrestricted.
+	SandboxOff = "off"
+	// SandboxStrict takes away the file system and the network once the keys and the payload are loaded.
+	SandboxStrict = "strict"
+)
+
+// AuditJournald sends the audit records to journald instead of a file.
+const AuditJournald = "journald"
+
+// DefaultClockSkew is the tolerated difference of the clocks of the signer and the verifier.
+const DefaultClockSkew = 5 * time.Minute
+
+// Subcommands.
+const (
+	// CommandServe runs the verification service.
+	CommandServe = "serve"
+	// CommandStripSignature prints the serialized plays of the payload the signer has to sign.
+	CommandStripSignature = "strip-signature"
+	// CommandSign signs the plays of the payload with a private key, for test environments.
+	CommandSign = "sign"
+	// CommandSelfTest verifies the embedded sample playbook, to tell failures of the environment from bad payloads.
+	CommandSelfTest = "self-test"
+	// CommandRunnerHook verifies the playbooks of an ansible-runner private data directory before they are run.
+	CommandRunnerHook = "runner-hook"
+	// CommandWebhook runs the Kubernetes admission webhook refusing objects with playbooks that are not verified.
+	CommandWebhook = "webhook"
+)
+
+// DefaultWebhookListen is the address the admission webhook listens on.
+const DefaultWebhookListen = ":8443"
+
+// Arguments holds the parsed command-line arguments.
+type Arguments struct {
+	// Command is the subcommand to run. Empty string means verifying a single payload.
+	Command string
+	// Socket is a path to the unix socket the service listens on.
+	Socket string
+	// Listen is the TCP address the HTTP API listens on. Setting it implies the 'serve' command.
+	Listen string
+	// DBus makes the service listen on the system bus instead of the socket.
+	DBus bool
+	// YggdrasilDirective makes the service a yggdrasil worker of the directive on the system bus
+	// instead of listening on the socket.
+	YggdrasilDirective string
+	// YggdrasilForward is the directive of the worker the verified payloads are forwarded to.
+	YggdrasilForward string
+	// WebhookListen is the TCP address the admission webhook listens on.
+	WebhookListen string
+	// TLSCert and TLSKey are paths to the PEM certificate and private key of the admission webhook.
+	TLSCert string
+	TLSKey  string
+	// WebhookFields are the fields of the kinds whose embedded playbooks the admission webhook verifies.
+	// Nil means webhook.DefaultFields.
+	WebhookFields map[string][]string
+	// MetricsListen is the TCP address the metrics are served on, in the gRPC and D-Bus modes.
+	MetricsListen string
+	// Payload is a path to the playbook. Empty string or '-' means standard input.
+	Payload string
+	// PayloadFD is a file descriptor inherited from the parent process the playbook is read from.
+	// Negative value means the playbook is read from Payload.
+	PayloadFD int
+	// PayloadURL is the HTTPS URL the playbook is downloaded from, see downloadPlaybook.
+	PayloadURL string
+	// Output is the path the verified payload is written to instead of standard output, see writeOutput.
+	// The file is only replaced when the payload is verified.
+	Output string
+	// OutputMode is the permissions of the Output file.
+	OutputMode os.FileMode
+	// OutputOwner is the 'USER[:GROUP]' the Output file is owned by, as names or numeric IDs.
+	// Empty string means the user running the verifier.
+	OutputOwner string
+	// OutputUID and OutputGID are the IDs of OutputOwner, -1 for the ones of the process.
+	OutputUID, OutputGID int
+	// PayloadDir is a directory whose playbooks are all verified.
+	PayloadDir string
+	// BatchStdin verifies the payloads framed on standard input, see verifyStream.
+	BatchStdin bool
+	// ArchivePlaybook is the member of an archive payload that is the playbook.
+	// Empty string means the only YAML file of the archive.
+	ArchivePlaybook string
+	// ArchiveManifest is the member of an archive payload listing the digests of all its members.
+	// Empty string means the manifest is not checked.
+	ArchiveManifest string
+	// FilesManifest is a path to a manifest of the digests of the files the playbook uses, e.g. its roles.
+	// Empty string means the files are not checked.
+	FilesManifest string
+	// FilesManifestSignature is a path to the detached signature of FilesManifest.
+	FilesManifestSignature string
+	// PrivateDataDir is the ansible-runner private data directory of the 'runner-hook' command.
+	PrivateDataDir string
+	// RunnerPlaybooks are the playbooks the 'runner-hook' command verifies, relative to the project directory.
+	// Empty means the YAML files at the top of the project directory.
+	RunnerPlaybooks []string
+	// RunnerIdent is the identifier of the ansible-runner job whose artifacts directory gets the verdict.
+	RunnerIdent string
+	// ContentType is the content type of the payload.
+	ContentType string
+	// Format is the format of the output.
+	Format string
+	// Report is the format of the summary printed instead of the verified payload, see summary.Render.
+	// Empty string prints the payload.
+	Report string
+	// LogLevel is the minimal level of logged messages.
+	LogLevel string
+	// LogFile is a path logs are appended to. Empty string means standard error.
+	LogFile string
+	// AuditLog is a path the verification decisions are appended to, or AuditJournald.
+	// Empty string disables the audit log.
+	AuditLog string
+	// AuditLogMaxSize is the size the audit log is rotated at, in bytes. Zero disables the rotation.
+	AuditLogMaxSize int64
+	// AuditLogKeep is the number of rotated audit logs that are kept.
+	AuditLogKeep int
+	// LogFormat is the format of the logs.
+	LogFormat string
+	// MaxSize is the maximal size of the payload in bytes. Zero means no limit.
+	MaxSize int64
+	// ReferenceSerializationFiles are paths to the serialized plays the signer hashed, by the index of the play.
+	ReferenceSerializationFiles []string
+	// ReferenceSerializations are the contents of ReferenceSerializationFiles.
+	ReferenceSerializations [][]byte
+	// PolicyFile is a path to the YAML rules evaluated once the signatures are verified.
+	PolicyFile string
+	// Rules are the rules of PolicyFile.
+	Rules []verifier.Rule
+	// ContentSource names where the payload comes from, selecting the rules that apply to it.
+	ContentSource string
+	// MemoryBudget is the memory the parsed payload may take, in bytes. Zero means no limit.
+	MemoryBudget int64
+	// ReadTimeout is the time standard input has to be closed in. Zero means no limit.
+	ReadTimeout time.Duration
+	// PrivateKey is a path to the ASCII-armored OpenPGP private key of the 'sign' command.
+	PrivateKey string
+	// Signature is a path to a detached signature of the payload. Empty string means
+	// the signatures are embedded in the plays.
+	Signature string
+	// KeyDirectories are directories of additional trusted public keys.
+	KeyDirectories []string
+	// TrustStores are the specifications of the stores of trusted public keys, see keystore.ParseStore.
+	// Empty means the embedded keys.
+	TrustStores []string
+	// Stores are the parsed TrustStores.
+	Stores []keystore.Store
+	// TPMAuth is the authorization of the keys sealed by the TPM, e.g. 'pcr:sha256:0,7'.
+	TPMAuth string
+	// KeyURL is the HTTPS endpoint of a signed bundle of additional trusted public keys.
+	// Empty string means no keys are fetched.
+	KeyURL string
+	// KeyURLPins are the pins of the public keys the certificate chain of KeyURL has to contain.
+	KeyURLPins []string
+	// KeyURLRefresh is the age of the cached key bundle after which it is fetched again.
+	KeyURLRefresh time.Duration
+	// InsightsClientConfig is the configuration file of insights-client whose proxy the online features use.
+	InsightsClientConfig string
+	// TLSCADirectories are directories of PEM certificate authorities the online features trust
+	// in addition to the system ones.
+	TLSCADirectories []string
+	// GPGBackend is the implementation OpenPGP signatures are verified with.
+	GPGBackend string
+	// TmpDir is the directory of temporary files. Empty string means the system temporary directory.
+	TmpDir string
+	// Sandbox restricts the process after the keys and the payload are loaded.
+	Sandbox string
+	// NoTmpFiles keeps all data in memory; it rules out the features that need files.
+	NoTmpFiles bool
+	// RevokedKeys is a path to a list of fingerprints of revoked keys.
+	RevokedKeys string
+	// CABundle is a path to the PEM certificates of the certificate authorities whose
+	// PKCS#7 signatures are trusted. Empty string means PKCS#7 signatures are not trusted.
+	CABundle string
+	// SigstoreRoots is a path to the PEM certificates of the Sigstore certificate authority.
+	// Empty string means Sigstore bundles are not trusted.
+	SigstoreRoots string
+	// SigstoreRekorKeys is a path to the PEM public keys of the Rekor transparency logs.
+	SigstoreRekorKeys string
+	// SigstoreIdentities are the signers whose Sigstore bundles are accepted.
+	SigstoreIdentities []verifier.SigstoreIdentity
+	// SigstoreRekorURL is the Rekor instance inclusion proofs are fetched from.
+	// Empty string means the proofs in the bundles are trusted without going online.
+	SigstoreRekorURL string
+	// Inspect prints what would be hashed instead of verifying the payload.
+	Inspect bool
+	// EmitCanonical is a path the serialized plays are written to instead of verifying the payload,
+	// '-' meaning the standard output. Empty string means the payload is verified.
+	EmitCanonical string
+	// Version prints the version of the verifier, what it supports and the embedded keys.
+	Version bool
+	// AnsiblePluginDir is the directory the Ansible callback plugin is installed into, see ansibleplugin.
+	AnsiblePluginDir string
+	// Jobs is the number of plays verified concurrently.
+	Jobs int
+	// NoCache disables the cache of verification reports.
+	NoCache bool
+	// CacheDir is the directory of cached verification reports.
+	CacheDir string
+	// CacheTTL is the time cached verification reports are valid for.
+	CacheTTL time.Duration
+	// ShowDiff prints what the exclusions removed from each play.
+	ShowDiff bool
+	// Strict rejects plays with top-level keys unknown to Ansible.
+	Strict bool
+	// AllowDuplicateKeys accepts playbooks whose mappings contain duplicate keys.
+	AllowDuplicateKeys bool
+	// NormalizePayload removes a byte order mark, CRLF line endings and trailing whitespace from the payload.
+	NormalizePayload bool
+	// RequireAllSigned rejects playbooks containing YAML documents without a signature.
+	RequireAllSigned bool
+	// Unsigned is what happens to plays and documents without a signature, see verifier.Policy.Unsigned.
+	Unsigned string
+	// HashAlgorithm is the digest algorithm of plays that do not declare one.
+	HashAlgorithm string
+	// MinimumHashAlgorithm is the weakest digest algorithm that is accepted.
+	MinimumHashAlgorithm string
+	// SerializationProfiles are the serialization profiles of insights-core releases a signature may match.
+	SerializationProfiles []string
+	// MaxSignatureAge is the longest time since the creation of a signature it is accepted for.
+	// Zero means signatures do not expire.
+	MaxSignatureAge time.Duration
+	// ClockSkew is how far in the future a signature may have been created.
+	ClockSkew time.Duration
+}
+
+// parseArguments parses the command-line arguments.
+//
+// The interface mirrors the Python verifier, so the binary can be used as its drop-in replacement.
+// Options are taken from the environment, the command line, the configuration file and
+// the defaults, in this order of precedence.
+func parseArguments(args []string) (*Arguments, error) {
+	arguments := &Arguments{OutputMode: DefaultOutputMode}
+	if len(args) > 0 && slices.Contains([]string{CommandServe, CommandStripSignature, CommandSign, CommandSelfTest, CommandRunnerHook, CommandWebhook}, args[0]) {
+		arguments.Command, args = args[0], args[1:]
+	}
+
+	flags := flag.NewFlagSet("playbook-verifier", flag.ContinueOnError)
+	flags.StringVar(&arguments.Payload, "payload", "", "path to the payload, '-' for standard input ($PLAYBOOK_SOURCE)")
+	flags.IntVar(&arguments.PayloadFD, "payload-fd", -1, "read the payload from the file descriptor inherited from the parent process")
+	flags.StringVar(&arguments.PayloadURL, "payload-url", "", "HTTPS URL to download the payload from, through the proxy and certificate authorities of --insights-client-config and --tls-ca-dir")
+	flags.StringVar(&arguments.Output, "output", "", "write the verified payload to the path instead of standard output; the file is replaced atomically, and only if the payload is verified")
+	flags.Func("output-mode", fmt.Sprintf("octal permissions of the --output file (default %04o)", DefaultOutputMode), func(value string) error {
+		mode, err := strconv.ParseUint(value, 8, 32)
+		if err != nil || mode > 0o777 {
+			return fmt.Errorf("invalid mode, octal permissions are required: %s", value)
+		}
+		arguments.OutputMode = os.FileMode(mode)
+		return nil
+	})
+	flags.StringVar(&arguments.OutputOwner, "output-owner", "", "'USER[:GROUP]' the --output file is owned by, names or numeric IDs (default the user running the verifier)")
+	flags.StringVar(&arguments.ArchivePlaybook, "archive-playbook", "", "name of the playbook in a tar, tar.gz or zip payload, if it contains more than one YAML file")
+	flags.StringVar(&arguments.FilesManifest, "files-manifest", "", "path to a signed manifest, in the format of sha256sum, of every file in its directory tree, e.g. the roles and templates of the playbook")
+	flags.StringVar(&arguments.FilesManifestSignature, "files-manifest-signature", "", "path to the detached signature of --files-manifest")
+	flags.StringVar(&arguments.ArchiveManifest, "archive-manifest", "", "name of the manifest in a tar, tar.gz or zip payload, in the format of sha256sum, that all its files must match")
+	flags.StringVar(&arguments.Socket, "socket", DefaultSocket, "path to the unix socket the 'serve' command listens on")
+	flags.StringVar(&arguments.Listen, "listen", "", "serve the HTTP API on the address (e.g. 127.0.0.1:8700) instead of verifying a single payload")
+	flags.BoolVar(&arguments.DBus, "dbus", false, "make the 'serve' command export the verifier on the D-Bus system bus instead")
+	flags.StringVar(&arguments.YggdrasilDirective, "yggdrasil-directive", "", "make the 'serve' command a yggdrasil (rhc) worker of the directive on the D-Bus system bus instead")
+	flags.StringVar(&arguments.YggdrasilForward, "yggdrasil-forward", yggdrasil.DefaultForward, "directive of the yggdrasil worker the verified payloads are forwarded to")
+	flags.StringVar(&arguments.WebhookListen, "webhook-listen", DefaultWebhookListen, "address the 'webhook' command serves the admission webhook on")
+	flags.StringVar(&arguments.TLSCert, "tls-cert", "", "path to the PEM certificate of the 'webhook' command")
+	flags.StringVar(&arguments.TLSKey, "tls-key", "", "path to the PEM private key of the 'webhook' command")
+	flags.Func("webhook-field", "'KIND=FIELD' dot-separated field of objects of the kind the 'webhook' command verifies the embedded playbook of, can be repeated (default JobTemplate=spec.playbook; ConfigMap entries ending with .yml or .yaml are always verified)", func(value string) error {
+		kind, field, ok := strings.Cut(value, "=")
+		if !ok || kind == "" || field == "" {
+			return fmt.Errorf("field must be KIND=FIELD")
+		}
+		if arguments.WebhookFields == nil {
+			arguments.WebhookFields = map[string][]string{}
+		}
+		arguments.WebhookFields[kind] = append(arguments.WebhookFields[kind], field)
+		return nil
+	})
+	flags.StringVar(&arguments.MetricsListen, "metrics-listen", "", "serve the Prometheus metrics of the 'serve' command on the address (the HTTP API serves them on /metrics)")
+	flags.BoolVar(&arguments.BatchStdin, "batch-stdin", false, "verify many payloads, each prefixed by its length as a 4-byte big-endian integer, from standard input, and write a verdict and the verified payload for each of them to standard output in the same framing")
+	flags.StringVar(&arguments.PayloadDir, "payload-dir", "", "verify every *.yml and *.yaml file in the directory tree instead of a single payload")
+	flags.StringVar(&arguments.PrivateDataDir, "private-data-dir", "", "ansible-runner private data directory whose project the 'runner-hook' command verifies")
+	flags.Func("playbook", "playbook of the 'runner-hook' command, relative to the project directory, can be repeated (default the YAML files at the top of the project directory)", func(value string) error {
+		arguments.RunnerPlaybooks = append(arguments.RunnerPlaybooks, value)
+		return nil
+	})
+	flags.StringVar(&arguments.RunnerIdent, "ident", "", "identifier of the ansible-runner job, the 'runner-hook' command writes the verdict into its artifacts directory")
+	flags.StringVar(&arguments.ContentType, "content-type", verifier.PlaybookContentType, "content type of the payload: "+strings.Join(verifier.ContentTypes(), ", "))
+	flags.StringVar(&arguments.Format, "format", FormatText, "output format: 'text' prints the verified payload, 'json' the verification report")
+	flags.StringVar(&arguments.Report, "report", "", "print a summary of the verification for people instead of the verified payload: "+strings.Join(summary.Formats(), ", "))
+	flags.StringVar(&arguments.LogLevel, "log-level", "info", "minimal level of logged messages: debug, info, warn, error")
+	flags.StringVar(&arguments.LogFile, "log-file", "", "path to append logs to instead of standard error")
+	flags.StringVar(&arguments.AuditLog, "audit-log", DefaultAuditLog, "path to append the chained records of verification decisions to, 'journald' to send them to journald, or empty to disable them")
+	flags.Int64Var(&arguments.AuditLogMaxSize, "audit-log-max-size", audit.DefaultMaxSize, "size in bytes the audit log is rotated at, 0 for no rotation")
+	flags.IntVar(&arguments.AuditLogKeep, "audit-log-keep", audit.DefaultKeep, "number of rotated audit logs that are kept")
+	flags.StringVar(&arguments.LogFormat, "log-format", LogFormatAuto, "format of the logs: auto, text, json, journald")
+	flags.Int64Var(&arguments.MaxSize, "max-size", DefaultMaxSize, "maximal size of the payload in bytes, 0 for no limit")
+	flags.Func("reference-serialization", "path to the serialized play the signer hashed, compared with the serialization of a play whose signature does not match; the Nth path is the reference of the Nth play, can be repeated", func(value string) error {
+		arguments.ReferenceSerializationFiles = append(arguments.ReferenceSerializationFiles, value)
+		return nil
+	})
+	flags.StringVar(&arguments.PolicyFile, "policy-file", "", "path to the YAML allow and deny rules evaluated once the signatures are verified (e.g. required keys, denied hosts, maximal number of tasks)")
+	flags.StringVar(&arguments.ContentSource, "content-source", "", "where the payload comes from (e.g. insights, satellite), selecting the rules of --policy-file that apply to it")
+	flags.Int64Var(&arguments.MemoryBudget, "memory-budget", DefaultMemoryBudget, "estimated memory the parsed payload may take in bytes, 0 for no limit")
+	flags.DurationVar(&arguments.ReadTimeout, "read-timeout", DefaultReadTimeout, "time to read the payload from standard input in, 0 for no limit")
+	flags.StringVar(&arguments.PrivateKey, "private-key", "", "path to the unencrypted ASCII-armored OpenPGP private key the 'sign' command signs with")
+	flags.StringVar(&arguments.Signature, "signature", "", "path to a detached signature of the whole payload, instead of the signatures embedded in the plays")
+	flags.Func("gpg-key-dir", "directory of additional trusted ASCII-armored public keys, can be repeated or comma-separated", func(value string) error {
+		for _, directory := range strings.Split(value, ",") {
+			if directory = strings.TrimSpace(directory); directory != "" {
+				arguments.KeyDirectories = append(arguments.KeyDirectories, directory)
+			}
+		}
+		return nil
+	})
+	flags.Func("trust-store", "store of trusted public keys: '"+keystore.DefaultStore+"', 'dir:PATH', 'keyring:KEYRING' (Linux kernel keyring, e.g. @u or a name) or 'tpm:OBJECT' (persistent handles or a directory of objects sealed by the TPM), prefixed by 'warn-only:' if signatures of its keys are accepted with a warning and exit code 10, can be repeated (default "+keystore.DefaultStore+")", func(value string) error {
+		arguments.TrustStores = append(arguments.TrustStores, value)
+		return nil
+	})
+	flags.StringVar(&arguments.TPMAuth, "tpm-auth", "", "authorization of the keys of the 'tpm:' trust stores passed to tpm2_unseal, e.g. pcr:sha256:0,7")
+	flags.StringVar(&arguments.KeyURL, "key-url", "", "HTTPS URL of a bundle of additional trusted public keys, detached-signed by a trusted key at URL"+keystore.RemoteSignatureSuffix+"; cached in --cache-dir and used from there when offline")
+	flags.Func("key-url-pin", "base64 SHA-256 digest of a public key (SubjectPublicKeyInfo) the certificate chain of --key-url has to contain, can be repeated", func(value string) error {
+		if digest, err := base64.StdEncoding.DecodeString(value); err != nil || len(digest) != sha256.Size {
+			return fmt.Errorf("pin must be a base64 SHA-256 digest")
+		}
+		arguments.KeyURLPins = append(arguments.KeyURLPins, value)
+		return nil
+	})
+	flags.DurationVar(&arguments.KeyURLRefresh, "key-url-refresh", keystore.DefaultRemoteRefresh, "age of the cached --key-url bundle after which it is fetched again")
+	flags.StringVar(&arguments.InsightsClientConfig, "insights-client-config", httpclient.DefaultInsightsConfig, "configuration file of insights-client whose 'proxy' is used by --payload-url, --key-url and --sigstore-rekor-url before HTTPS_PROXY; NO_PROXY applies to both")
+	flags.Func("tls-ca-dir", "directory of PEM certificate authorities (*.pem) trusted by --payload-url, --key-url and --sigstore-rekor-url in addition to the system ones, can be repeated (default "+httpclient.DefaultCADirectory+")", func(value string) error {
+		arguments.TLSCADirectories = append(arguments.TLSCADirectories, value)
+		return nil
+	})
+	flags.StringVar(&arguments.GPGBackend, "gpg-backend", GPGBackendNative, "implementation of OpenPGP verification: 'native' is built in, 'exec' runs gpgv in a temporary GNUPGHOME")
+	flags.StringVar(&arguments.TmpDir, "tmp-dir", "", "directory of temporary files (e.g. the GNUPGHOME of --gpg-backend exec), whose SELinux context they get (default $TMPDIR or /tmp)")
+	flags.BoolVar(&arguments.NoTmpFiles, "no-tmpfiles", false, "never write temporary files, verify in memory only; disables the cache and --gpg-backend exec")
+	flags.StringVar(&arguments.Sandbox, "sandbox", SandboxOff, "'strict' drops access to the file system and the network (Landlock, seccomp) after loading the keys and the payload, before parsing it; disables the cache")
+	flags.StringVar(&arguments.RevokedKeys, "revoked-keys", "", "path to a list of fingerprints of revoked keys, in addition to the embedded one")
+	flags.StringVar(&arguments.CABundle, "ca-bundle", "", "path to the PEM certificates of the certificate authorities (e.g. of Satellite) whose CMS/PKCS#7 signatures are trusted")
+	flags.StringVar(&arguments.SigstoreRoots, "sigstore-roots", "", "path to the PEM certificates of the Sigstore certificate authority, enables Sigstore bundles")
+	flags.StringVar(&arguments.SigstoreRekorKeys, "sigstore-rekor-keys", "", "path to the PEM public keys of the Rekor transparency logs")
+	flags.Func("sigstore-identity", "signer accepted from Sigstore as 'ISSUER=SUBJECT' (e.g. https://accounts.google.com=signer@example.com), can be repeated", func(value string) error {
+		issuer, subject, ok := strings.Cut(value, "=")
+		if !ok || issuer == "" || subject == "" {
+			return fmt.Errorf("identity must be ISSUER=SUBJECT")
+		}
+		arguments.SigstoreIdentities = append(arguments.SigstoreIdentities, verifier.SigstoreIdentity{Issuer: issuer, SubjectAlternativeName: subject})
+		return nil
+	})
+	flags.StringVar(&arguments.SigstoreRekorURL, "sigstore-rekor-url", "", "fetch the inclusion proofs of Sigstore bundles from the Rekor instance (e.g. "+rekor.DefaultURL+")")
+	flags.BoolVar(&arguments.RequireAllSigned, "require-all-signed", false, "fail if any YAML document is not signed instead of skipping it")
+	flags.StringVar(&arguments.Unsigned, "unsigned", verifier.UnsignedReject, "what happens to plays and documents without a signature: 'reject' refuses unsigned plays and skips unsigned documents, 'warn' passes them on with a warning, 'allow' passes them on silently; they are never verified")
+	flags.BoolVar(&arguments.Strict, "strict", false, "fail if a play contains a top-level key unknown to Ansible")
+	flags.BoolVar(&arguments.AllowDuplicateKeys, "allow-duplicate-keys", false, "accept duplicate keys in the playbook, the last value of a key wins")
+	flags.BoolVar(&arguments.NormalizePayload, "normalize-payload", false, "remove a UTF-8 byte order mark, CRLF line endings and trailing whitespace from the payload before verifying and printing it")
+	flags.BoolVar(&arguments.Version, "version", false, "print the version, the supported serializations and the fingerprints of the embedded keys")
+	flags.StringVar(&arguments.AnsiblePluginDir, "ansible-plugin-dir", "", "install the Ansible callback plugin that runs this verifier on playbooks of Insights before ansible-playbook runs them into the directory (e.g. "+ansibleplugin.DefaultDirectory+") and exit")
+	flags.BoolVar(&arguments.Inspect, "inspect", false, "print the serialized plays, their digests, exclusions and signatures without verifying them")
+	flags.StringVar(&arguments.EmitCanonical, "emit-canonical", "", "write the exact bytes the signatures of the plays are computed over to the path ('-' for standard output) without verifying them, separated by NUL bytes if there are several plays; uses the first of --serialization-profiles")
+	flags.IntVar(&arguments.Jobs, "jobs", 1, "number of plays verified concurrently")
+	flags.StringVar(&arguments.HashAlgorithm, "hash-algorithm", verifier.DefaultHashAlgorithm, "digest algorithm of plays that do not declare one in 'insights_signature_hash'")
+	flags.StringVar(&arguments.MinimumHashAlgorithm, "min-hash-algorithm", verifier.DefaultHashAlgorithm, "weakest digest algorithm that is accepted")
+	flags.Func("serialization-profiles", "comma-separated serialization profiles of insights-core releases a signature may match, tried in order: "+strings.Join(verifier.SerializationProfiles(), ", ")+" (default "+verifier.DefaultSerializationProfile+")", func(value string) error {
+		arguments.SerializationProfiles = nil
+		for _, profile := range strings.Split(value, ",") {
+			profile = strings.TrimSpace(profile)
+			if !slices.Contains(verifier.SerializationProfiles(), profile) {
+				return fmt.Errorf("unsupported serialization profile: %s", profile)
+			}
+			arguments.SerializationProfiles = append(arguments.SerializationProfiles, profile)
+		}
+		return nil
+	})
+	flags.Func("max-signature-age", "refuse signatures older than the age in days (e.g. 30d) or as a duration (e.g. 12h), and those created in the future", func(value string) error {
+		age, err := parseAge(value)
+		arguments.MaxSignatureAge = age
+		return err
+	})
+	flags.DurationVar(&arguments.ClockSkew, "clock-skew", DefaultClockSkew, "how far in the future signatures may have been created, with --max-signature-age")
+	flags.BoolVar(&arguments.NoCache, "no-cache", false, "always verify the payload, even if the same payload has been verified before")
+	flags.StringVar(&arguments.CacheDir, "cache-dir", DefaultCacheDir, "directory of cached verification reports")
+	flags.DurationVar(&arguments.CacheTTL, "cache-ttl", DefaultCacheTTL, "time cached verification reports are valid for")
+	flags.BoolVar(&arguments.ShowDiff, "show-diff", false, "print a diff of each play and its cleaned form to standard error, or add it to the JSON report")
+	configFile := flags.String("config", DefaultConfigFile, "path to the configuration file ($PLAYBOOK_VERIFIER_CONFIG)")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: %s [OPTIONS]\n       %s serve [OPTIONS]\n       %s strip-signature [OPTIONS]\n       %s sign --private-key PATH [OPTIONS]\n       %s self-test [OPTIONS]\n       %s runner-hook --private-data-dir PATH [OPTIONS]\n       %s webhook --tls-cert PATH --tls-key PATH [OPTIONS]\n\n", flags.Name(), flags.Name(), flags.Name(), flags.Name(), flags.Name(), flags.Name(), flags.Name())
+		fmt.Fprintf(flags.Output(), "Verifies the signature of the payload and prints it if it is valid.\n")
+		fmt.Fprintf(flags.Output(), "The 'serve' command verifies payloads sent over the gRPC, D-Bus or HTTP API, or dispatched by yggdrasil, instead.\n")
+		fmt.Fprintf(flags.Output(), "The 'strip-signature' command prints the serialized plays of the payload the signer has to sign.\n")
+		fmt.Fprintf(flags.Output(), "The 'sign' command prints the payload with its plays signed by the private key, for test environments.\n")
+		fmt.Fprintf(flags.Output(), "The 'self-test' command verifies an embedded sample playbook with the embedded keys.\n")
+		fmt.Fprintf(flags.Output(), "The 'runner-hook' command verifies the project of an ansible-runner private data directory and writes\n")
+		fmt.Fprintf(flags.Output(), "the verdict to artifacts/[IDENT/]%s, before ansible-runner runs it.\n", RunnerVerdictFile)
+		fmt.Fprintf(flags.Output(), "The 'webhook' command serves a Kubernetes validating admission webhook on /validate, refusing\n")
+		fmt.Fprintf(flags.Output(), "ConfigMaps and custom resources that embed playbooks which are not verified.\n\n")
+		fmt.Fprintf(flags.Output(), "Every option can be set in the configuration file, and by the environment variable %sOPTION\n", environmentPrefix)
+		fmt.Fprintf(flags.Output(), "(e.g. %s), which takes precedence over the command line and the configuration file.\n\n", environmentVariable("log-level"))
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+	explicitConfig := *configFile != DefaultConfigFile
+	if env, ok := os.LookupEnv("PLAYBOOK_VERIFIER_CONFIG"); ok && env != "" {
+		*configFile, explicitConfig = env, true
+	}
+	if err := applyConfigFile(flags, *configFile, explicitConfig); err != nil {
+		return nil, err
+	}
+	// the repeatable options set by environment variables replace the values of the other sources
+	resets := map[string]func(){
+		"webhook-field":           func() { arguments.WebhookFields = nil },
+		"playbook":                func() { arguments.RunnerPlaybooks = nil },
+		"reference-serialization": func() { arguments.ReferenceSerializationFiles = nil },
+		"gpg-key-dir":             func() { arguments.KeyDirectories = nil },
+		"trust-store":             func() { arguments.TrustStores = nil },
+		"key-url-pin":             func() { arguments.KeyURLPins = nil },
+		"tls-ca-dir":              func() { arguments.TLSCADirectories = nil },
+		"sigstore-identity":       func() { arguments.SigstoreIdentities = nil },
+	}
+	if err := applyEnvironment(flags, resets); err != nil {
+		return nil, err
+	}
+
+	if flags.NArg() > 0 {
+		flags.Usage()
+		return nil, fmt.Errorf("unexpected arguments: %v", flags.Args())
+	}
+	if arguments.Listen != "" {
+		arguments.Command = CommandServe
+	}
+	if arguments.YggdrasilDirective != "" && (arguments.Command != CommandServe || arguments.DBus || arguments.Listen != "") {
+		return nil, fmt.Errorf("--yggdrasil-directive can only be used with the %s command, without --dbus or --listen", CommandServe)
+	}
+	if arguments.PayloadDir != "" && (arguments.Payload != "" || arguments.PayloadFD >= 0 || arguments.Signature != "" || arguments.Inspect) {
+		return nil, fmt.Errorf("--payload-dir cannot be combined with --payload, --payload-fd, --signature or --inspect")
+	}
+	if arguments.EmitCanonical != "" && (arguments.Command != "" || arguments.PayloadDir != "" || arguments.Signature != "" || arguments.Inspect || arguments.ContentType != verifier.PlaybookContentType) {
+		return nil, fmt.Errorf("--emit-canonical cannot be combined with a command, --payload-dir, --signature, --inspect or --content-type")
+	}
+	if (arguments.ArchivePlaybook != "" || arguments.ArchiveManifest != "") && (arguments.PayloadDir != "" || slices.Contains([]string{CommandServe, CommandSelfTest, CommandRunnerHook, CommandWebhook}, arguments.Command)) {
+		return nil, fmt.Errorf("--archive-playbook and --archive-manifest only apply to a single payload")
+	}
+	if (arguments.FilesManifest == "") != (arguments.FilesManifestSignature == "") {
+		return nil, fmt.Errorf("--files-manifest and --files-manifest-signature have to be used together")
+	}
+	if arguments.FilesManifest != "" && (arguments.PayloadDir != "" || arguments.Inspect || arguments.EmitCanonical != "" || (arguments.Command != "" && arguments.Command != CommandRunnerHook)) {
+		return nil, fmt.Errorf("--files-manifest only applies to the verification of a single payload and to the %s command", CommandRunnerHook)
+	}
+	if arguments.BatchStdin && (arguments.Command != "" || arguments.Payload != "" || arguments.PayloadFD >= 0 || arguments.PayloadDir != "" || arguments.Signature != "" || arguments.Inspect || arguments.EmitCanonical != "" || arguments.FilesManifest != "") {
+		return nil, fmt.Errorf("--batch-stdin cannot be combined with a command, --payload, --payload-fd, --payload-dir, --signature, --inspect, --emit-canonical or --files-manifest")
+	}
+	if arguments.PayloadFD >= 0 && arguments.Payload != "" {
+		return nil, fmt.Errorf("--payload-fd cannot be combined with --payload")
+	}
+	if arguments.AnsiblePluginDir != "" && (arguments.Command != "" || arguments.Payload != "" || arguments.PayloadFD >= 0 || arguments.PayloadURL != "" || arguments.PayloadDir != "" || arguments.BatchStdin || arguments.Version) {
+		return nil, fmt.Errorf("--ansible-plugin-dir cannot be combined with a command, --payload, --payload-fd, --payload-url, --payload-dir, --batch-stdin or --version")
+	}
+	if arguments.PayloadURL != "" {
+		if arguments.Payload != "" || arguments.PayloadFD >= 0 || arguments.PayloadDir != "" || arguments.BatchStdin || slices.Contains([]string{CommandServe, CommandSelfTest, CommandRunnerHook, CommandWebhook}, arguments.Command) {
+			return nil, fmt.Errorf("--payload-url cannot be combined with --payload, --payload-fd, --payload-dir, --batch-stdin or the %s, %s, %s and %s commands", CommandServe, CommandSelfTest, CommandRunnerHook, CommandWebhook)
+		}
+		if u, err := url.Parse(arguments.PayloadURL); err != nil || u.Scheme != "https" || u.Host == "" {
+			return nil, fmt.Errorf("invalid payload URL, an https URL is required: %s", arguments.PayloadURL)
+		}
+	}
+	if arguments.Output != "" {
+		if arguments.Command != "" || arguments.PayloadDir != "" || arguments.BatchStdin || arguments.Inspect || arguments.EmitCanonical != "" || arguments.Format != FormatText || arguments.Report != "" {
+			return nil, fmt.Errorf("--output only applies to the verified payload, it cannot be combined with a command, --payload-dir, --batch-stdin, --inspect, --emit-canonical, --format json or --report")
+		}
+		// the file is renamed into place after the verification, when the sandbox denies it
+		if arguments.Sandbox == SandboxStrict {
+			return nil, fmt.Errorf("--output cannot be combined with --sandbox %s", SandboxStrict)
+		}
+	}
+	if arguments.Output == "" && (arguments.OutputMode != DefaultOutputMode || arguments.OutputOwner != "") {
+		return nil, fmt.Errorf("--output-mode and --output-owner require --output")
+	}
+	arguments.OutputUID, arguments.OutputGID = -1, -1
+	if arguments.OutputOwner != "" {
+		uid, gid, err := parseOwner(arguments.OutputOwner)
+		if err != nil {
+			return nil, err
+		}
+		arguments.OutputUID, arguments.OutputGID = uid, gid
+	}
+	if (arguments.Command == CommandStripSignature || arguments.Command == CommandSign) && (arguments.PayloadDir != "" || arguments.Signature != "" || arguments.Inspect || arguments.ContentType != verifier.PlaybookContentType) {
+		return nil, fmt.Errorf("%s cannot be combined with --payload-dir, --signature, --inspect or --content-type", arguments.Command)
+	}
+	if arguments.Command == CommandSelfTest && (arguments.Payload != "" || arguments.PayloadFD >= 0 || arguments.PayloadDir != "" || arguments.Signature != "" || arguments.Inspect || arguments.ContentType != verifier.PlaybookContentType) {
+		return nil, fmt.Errorf("%s cannot be combined with --payload, --payload-fd, --payload-dir, --signature, --inspect or --content-type", arguments.Command)
+	}
+	if (arguments.Command == CommandRunnerHook) != (arguments.PrivateDataDir != "") {
+		return nil, fmt.Errorf("--private-data-dir is required by and only used by the %s command", CommandRunnerHook)
+	}
+	if arguments.Command != CommandRunnerHook && (len(arguments.RunnerPlaybooks) > 0 || arguments.RunnerIdent != "") {
+		return nil, fmt.Errorf("--playbook and --ident are only used by the %s command", CommandRunnerHook)
+	}
+	if arguments.Command == CommandRunnerHook && (arguments.Payload != "" || arguments.PayloadFD >= 0 || arguments.PayloadDir != "" || arguments.Signature != "" || arguments.Inspect) {
+		return nil, fmt.Errorf("%s cannot be combined with --payload, --payload-fd, --payload-dir, --signature or --inspect", arguments.Command)
+	}
+	if arguments.RunnerIdent != "" && (!filepath.IsLocal(arguments.RunnerIdent) || filepath.Base(arguments.RunnerIdent) != arguments.RunnerIdent) {
+		return nil, fmt.Errorf("invalid ansible-runner ident: %s", arguments.RunnerIdent)
+	}
+	if (arguments.Command == CommandWebhook) != (arguments.TLSCert != "" || arguments.TLSKey != "") || (arguments.TLSCert == "") != (arguments.TLSKey == "") {
+		return nil, fmt.Errorf("--tls-cert and --tls-key are required by and only used by the %s command", CommandWebhook)
+	}
+	if arguments.Command == CommandWebhook && (arguments.Payload != "" || arguments.PayloadFD >= 0 || arguments.PayloadDir != "" || arguments.Signature != "" || arguments.Inspect || arguments.ContentType != verifier.PlaybookContentType) {
+		return nil, fmt.Errorf("%s cannot be combined with --payload, --payload-fd, --payload-dir, --signature, --inspect or --content-type", arguments.Command)
+	}
+	if (arguments.Command == CommandSign) != (arguments.PrivateKey != "") {
+		return nil, fmt.Errorf("--private-key is required by and only used by the %s command", CommandSign)
+	}
+	if !slices.Contains(verifier.ContentTypes(), arguments.ContentType) {
+		return nil, fmt.Errorf("unsupported content type: %s", arguments.ContentType)
+	}
+	if arguments.Format != FormatText && arguments.Format != FormatJSON {
+		return nil, fmt.Errorf("unsupported format: %s", arguments.Format)
+	}
+	if arguments.Report != "" {
+		if !slices.Contains(summary.Formats(), arguments.Report) {
+			return nil, fmt.Errorf("unsupported report format: %s", arguments.Report)
+		}
+		if arguments.Format != FormatText || arguments.Command != "" || arguments.PayloadDir != "" || arguments.BatchStdin || arguments.Inspect || arguments.EmitCanonical != "" {
+			return nil, fmt.Errorf("--report cannot be combined with --format json, --payload-dir, --batch-stdin, --inspect, --emit-canonical or a command")
+		}
+	}
+	if !slices.Contains(verifier.UnsignedPolicies(), arguments.Unsigned) {
+		return nil, fmt.Errorf("unsupported handling of unsigned content: %s", arguments.Unsigned)
+	}
+	if arguments.RequireAllSigned && arguments.Unsigned != verifier.UnsignedReject {
+		return nil, fmt.Errorf("--require-all-signed cannot be combined with --unsigned %s", arguments.Unsigned)
+	}
+	if arguments.MaxSize < 0 {
+		return nil, fmt.Errorf("invalid maximal size: %d", arguments.MaxSize)
+	}
+	if arguments.AuditLog == AuditJournald && !journaldSupported {
+		return nil, fmt.Errorf("audit log %s is only supported on Linux", arguments.AuditLog)
+	}
+	if arguments.AuditLogMaxSize < 0 || arguments.AuditLogKeep < 0 {
+		return nil, fmt.Errorf("invalid audit log rotation: %d bytes, %d files", arguments.AuditLogMaxSize, arguments.AuditLogKeep)
+	}
+	if arguments.PolicyFile != "" {
+		content, err := os.ReadFile(arguments.PolicyFile)
+		if err != nil {
+			return nil, fmt.Errorf("could not read policy file: %w", err)
+		}
+		if arguments.Rules, err = verifier.ParseRules(content); err != nil {
+			return nil, fmt.Errorf("invalid policy file %s: %w", arguments.PolicyFile, err)
+		}
+	}
+	for _, path := range arguments.ReferenceSerializationFiles {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("could not read reference serialization: %w", err)
+		}
+		// Serializations never end with a newline, files usually do.
+		arguments.ReferenceSerializations = append(arguments.ReferenceSerializations, bytes.TrimSuffix(content, []byte("\n")))
+	}
+	if arguments.MemoryBudget < 0 {
+		return nil, fmt.Errorf("invalid memory budget: %d", arguments.MemoryBudget)
+	}
+	if arguments.ReadTimeout < 0 {
+		return nil, fmt.Errorf("invalid read timeout: %s", arguments.ReadTimeout)
+	}
+	if arguments.ClockSkew < 0 {
+		return nil, fmt.Errorf("invalid clock skew: %s", arguments.ClockSkew)
+	}
+	if arguments.GPGBackend != GPGBackendNative && arguments.GPGBackend != GPGBackendExec {
+		return nil, fmt.Errorf("unsupported GPG backend: %s", arguments.GPGBackend)
+	}
+	if arguments.NoTmpFiles && arguments.GPGBackend == GPGBackendExec {
+		return nil, fmt.Errorf("--no-tmpfiles cannot be used with --gpg-backend %s, gpgv reads the keys from files", GPGBackendExec)
+	}
+	switch arguments.Sandbox {
+	case SandboxOff:
+	case SandboxStrict:
+		if !sandboxSupported {
+			return nil, fmt.Errorf("sandbox %s is only supported on Linux", arguments.Sandbox)
+		}
+		if arguments.Command == CommandServe || arguments.Command == CommandSign || arguments.Command == CommandSelfTest || arguments.Command == CommandRunnerHook || arguments.Command == CommandWebhook || arguments.PayloadDir != "" {
+			return nil, fmt.Errorf("--sandbox %s can only be used when verifying, inspecting or stripping a single payload", arguments.Sandbox)
+		}
+		if arguments.GPGBackend == GPGBackendExec || arguments.SigstoreRekorURL != "" {
+			return nil, fmt.Errorf("--sandbox %s cannot be used with --gpg-backend %s or --sigstore-rekor-url", arguments.Sandbox, GPGBackendExec)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported sandbox: %s", arguments.Sandbox)
+	}
+	if len(arguments.TrustStores) == 0 {
+		arguments.TrustStores = []string{keystore.DefaultStore}
+	}
+	for _, spec := range arguments.TrustStores {
+		store, err := keystore.ParseStore(spec)
+		if err != nil {
+			return nil, err
+		}
+		if tpm, ok := keystore.Unwrap(store).(*keystore.TPMStore); ok {
+			if arguments.NoTmpFiles && !tpm.Persistent() {
+				return nil, fmt.Errorf("--no-tmpfiles cannot be used with trust store %s, only with persistent handles", tpm)
+			}
+			tpm.Auth, tpm.TmpDir = arguments.TPMAuth, arguments.TmpDir
+		}
+		arguments.Stores = append(arguments.Stores, store)
+	}
+	if arguments.TPMAuth != "" && !slices.ContainsFunc(arguments.Stores, func(store keystore.Store) bool {
+		_, ok := keystore.Unwrap(store).(*keystore.TPMStore)
+		return ok
+	}) {
+		return nil, fmt.Errorf("--tpm-auth requires a tpm: trust store")
+	}
+	if arguments.KeyURL != "" {
+		if keyURL, err := url.Parse(arguments.KeyURL); err != nil || keyURL.Scheme != "https" || keyURL.Host == "" {
+			return nil, fmt.Errorf("--key-url must be an HTTPS URL")
+		}
+	} else if len(arguments.KeyURLPins) > 0 {
+		return nil, fmt.Errorf("--key-url-pin requires --key-url")
+	}
+	if arguments.TLSCADirectories == nil {
+		arguments.TLSCADirectories = []string{httpclient.DefaultCADirectory}
+	}
+	if arguments.KeyURLRefresh < 0 {
+		return nil, fmt.Errorf("invalid key bundle refresh: %s", arguments.KeyURLRefresh)
+	}
+	if arguments.NoTmpFiles && arguments.TmpDir != "" {
+		return nil, fmt.Errorf("--no-tmpfiles and --tmp-dir are mutually exclusive")
+	}
+	if arguments.Jobs < 1 {
+		return nil, fmt.Errorf("invalid number of jobs: %d", arguments.Jobs)
+	}
+	if (arguments.SigstoreRoots != "") != (arguments.SigstoreRekorKeys != "") || (arguments.SigstoreRoots != "") != (len(arguments.SigstoreIdentities) > 0) {
+		return nil, fmt.Errorf("--sigstore-roots, --sigstore-rekor-keys and --sigstore-identity must be set together")
+	}
+	if arguments.SigstoreRekorURL != "" && arguments.SigstoreRoots == "" {
+		return nil, fmt.Errorf("--sigstore-rekor-url requires --sigstore-roots")
+	}
+	for _, name := range []string{arguments.HashAlgorithm, arguments.MinimumHashAlgorithm} {
+		if _, err := verifier.LookupHashAlgorithm(name); err != nil {
+			return nil, fmt.Errorf("unsupported hash algorithm: %s", name)
+		}
+	}
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(arguments.LogLevel)); err != nil {
+		return nil, fmt.Errorf("unsupported log level: %s", arguments.LogLevel)
+	}
+	switch arguments.LogFormat {
+	case LogFormatAuto, LogFormatText, LogFormatJSON:
+	case LogFormatJournald:
+		if !journaldSupported {
+			return nil, fmt.Errorf("log format %s is only supported on Linux", arguments.LogFormat)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported log format: %s", arguments.LogFormat)
+	}
+	return arguments, nil
+}
+
+// Policy returns the verification policy selected by the arguments.
+func (a *Arguments) Policy() verifier.Policy {
+	return verifier.Policy{
+		RequireAllSigned:        a.RequireAllSigned,
+		Unsigned:                a.Unsigned,
+		AllowDuplicateKeys:      a.AllowDuplicateKeys,
+		NormalizePayload:        a.NormalizePayload,
+		Strict:                  a.Strict,
+		ReportDiff:              a.ShowDiff,
+		Jobs:                    a.Jobs,
+		HashAlgorithm:           a.HashAlgorithm,
+		MinimumHashAlgorithm:    a.MinimumHashAlgorithm,
+		SerializationProfiles:   a.SerializationProfiles,
+		MaxSignatureAge:         a.MaxSignatureAge,
+		ClockSkew:               a.ClockSkew,
+		MemoryBudget:            a.MemoryBudget,
+		Rules:                   a.Rules,
+		ContentSource:           a.ContentSource,
+		ReferenceSerializations: a.ReferenceSerializations,
+	}
+}
+
+// parseAge parses an age given either in days, e.g. '30d', or as a duration, e.g. '12h'.
+func parseAge(value string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid number of days: %s", value)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	age, err := time.ParseDuration(value)
+	if err != nil || age < 0 {
+		return 0, fmt.Errorf("invalid age: %s", value)
+	}
+	return age, nil
+}
+
+// mustParseArguments parses the arguments of the process or exits.
+func mustParseArguments() *Arguments {
+	arguments, err := parseArguments(os.Args[1:])
+	if err == flag.ErrHelp {
+		os.Exit(ExitOK)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %s\n", err)
+		os.Exit(ExitUsageError)
+	}
+	return arguments
+}
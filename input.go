@@ -0,0 +1,100 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"time"
+
+	"com.github/m-horky/playbook-verifier/internal/archive"
+)
+
+// DefaultMaxSize is the default maximal size of the payload, in bytes.
+const DefaultMaxSize = 4 << 20
+
+// DefaultMemoryBudget is the default memory the parsed payload may take, in bytes.
+//
+// It is a multiple of DefaultMaxSize, since parsed YAML takes more memory than its text.
+const DefaultMemoryBudget = 64 * DefaultMaxSize
+
+// DefaultReadTimeout is the default time the payload has to be read in.
+const DefaultReadTimeout = 30 * time.Second
+
+var (
+	// ErrPayloadTooLarge is returned when the payload exceeds the maximal size.
+	ErrPayloadTooLarge = errors.New("payload is too large")
+	// ErrReadTimeout is returned when the payload could not be read in time.
+	ErrReadTimeout = errors.New("timed out reading payload")
+)
+
+// readLimited reads the whole reader, failing if it contains more than maxSize bytes
+// or if it is not closed before the timeout passes.
+//
+// Non-positive maxSize or timeout disable the respective limit.
+func readLimited(r io.Reader, maxSize int64, timeout time.Duration) ([]byte, error) {
+	if maxSize > 0 {
+		r = io.LimitReader(r, maxSize+1)
+	}
+
+	type result struct {
+		content []byte
+		err     error
+	}
+	done := make(chan result, 1)
+	go func() {
+		content, err := io.ReadAll(r)
+		done <- result{content, err}
+	}()
+
+	var deadline <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		deadline = timer.C
+	}
+
+	select {
+	case res := <-done:
+		if res.err != nil {
+			return nil, res.err
+		}
+		if maxSize > 0 && int64(len(res.content)) > maxSize {
+			return nil, fmt.Errorf("%w: limit is %d bytes", ErrPayloadTooLarge, maxSize)
+		}
+		return res.content, nil
+	case <-deadline:
+		return nil, fmt.Errorf("%w: no end of input after %s", ErrReadTimeout, timeout)
+	}
+}
+
+// unpackPlaybook returns the playbook of a tar, tar.gz or zip payload, after checking the members
+// of the archive against its manifest if the arguments name one. Other payloads are returned as they are.
+//
+// The signatures of the playbook are verified as usual; the manifest only ensures that the other
+// files of the archive, e.g. its metadata, are the ones it was created with.
+func unpackPlaybook(payload []byte, arguments *Arguments) ([]byte, error) {
+	if archive.Detect(payload) == "" {
+		if arguments.ArchivePlaybook != "" || arguments.ArchiveManifest != "" {
+			return nil, fmt.Errorf("%w: payload is not a tar, tar.gz or zip archive", archive.ErrMalformedArchive)
+		}
+		return payload, nil
+	}
+
+	unpacked, err := archive.Open(payload, arguments.MaxSize)
+	if err != nil {
+		return nil, err
+	}
+	if arguments.ArchiveManifest != "" {
+		if err = unpacked.CheckManifest(arguments.ArchiveManifest); err != nil {
+			return nil, err
+		}
+		slog.Debug("archive matches its manifest", slog.String("manifest", arguments.ArchiveManifest))
+	}
+	name, playbook, err := unpacked.Playbook(arguments.ArchivePlaybook)
+	if err != nil {
+		return nil, err
+	}
+	slog.Debug("playbook unpacked", slog.String("format", unpacked.Format), slog.String("member", name), slog.Int("size", len(playbook)))
+	return playbook, nil
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadLimited(t *testing.T) {
+	const maxSize = 16
+	tests := []struct {
+		name    string
+		size    int
+		maxSize int64
+		wantErr error
+	}{
+		{name: "below the limit", size: maxSize - 1, maxSize: maxSize},
+		{name: "exactly the limit", size: maxSize, maxSize: maxSize},
+		{name: "one byte over the limit", size: maxSize + 1, maxSize: maxSize, wantErr: ErrPayloadTooLarge},
+		{name: "no limit", size: 10 * maxSize, maxSize: 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			content := strings.Repeat("x", test.size)
+			got, err := readLimited(strings.NewReader(content), test.maxSize, time.Second)
+			if !errors.Is(err, test.wantErr) {
+				t.Fatalf("readLimited() error = %v, want %v", err, test.wantErr)
+			}
+			if err == nil && string(got) != content {
+				t.Errorf("readLimited() = %d bytes, want %d", len(got), test.size)
+			}
+		})
+	}
+}
+
+func TestReadLimitedTimeout(t *testing.T) {
+	// the writer sends part of the payload and never closes the pipe
+	reader, writer := io.Pipe()
+	t.Cleanup(func() { writer.Close() })
+	go writer.Write([]byte("- hosts: localhost\n"))
+
+	const timeout = 50 * time.Millisecond
+	start := time.Now()
+	_, err := readLimited(reader, DefaultMaxSize, timeout)
+	if !errors.Is(err, ErrReadTimeout) {
+		t.Fatalf("readLimited() error = %v, want %v", err, ErrReadTimeout)
+	}
+	if elapsed := time.Since(start); elapsed < timeout || elapsed > 5*time.Second {
+		t.Errorf("readLimited() returned after %s, want about %s", elapsed, timeout)
+	}
+}
+
+func TestReadLimitedError(t *testing.T) {
+	readErr := errors.New("read failed")
+	reader := io.MultiReader(bytes.NewReader([]byte("- hosts")), &failingReader{readErr})
+	if _, err := readLimited(reader, DefaultMaxSize, time.Second); !errors.Is(err, readErr) {
+		t.Errorf("readLimited() error = %v, want %v", err, readErr)
+	}
+}
+
+// failingReader fails every read with its error.
+type failingReader struct {
+	err error
+}
+
+func (r *failingReader) Read([]byte) (int, error) {
+	return 0, r.err
+}
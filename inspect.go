@@ -0,0 +1,70 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"com.github/m-horky/playbook-verifier/pkg/verifier"
+)
+
+// printInspection writes the inspection of the plays in the output format.
+func printInspection(w io.Writer, inspections []verifier.PlayInspection, format string) error {
+	if format == FormatJSON {
+		return json.NewEncoder(w).Encode(inspections)
+	}
+
+	for _, inspection := range inspections {
+		fmt.Fprintf(w, "play %d: %s\n", inspection.Index, inspection.Name)
+		fmt.Fprintf(w, "  excluded:   %s\n", strings.Join(inspection.Excluded, ", "))
+		if inspection.Signature != nil {
+			signature := inspection.Signature
+			created := "unknown"
+			if !signature.Created.IsZero() {
+				created = signature.Created.Format(time.RFC3339)
+			}
+			fmt.Fprintf(w, "  signature:  %s, key %s, created %s, %s, %s\n",
+				signature.Scheme, signature.KeyID, created, signature.HashAlgorithm, signature.PublicKeyAlgorithm)
+			if signature.Fingerprint != "" {
+				fmt.Fprintf(w, "  fingerprint: %s\n", signature.Fingerprint)
+			}
+		}
+		if inspection.Digest != "" {
+			fmt.Fprintf(w, "  digest:     %s\n", inspection.Digest)
+			fmt.Fprintf(w, "  serialization: %s\n", inspection.SerializationVersion)
+			fmt.Fprintf(w, "  serialized: %s\n", inspection.Serialized)
+		}
+		if inspection.Error != "" {
+			fmt.Fprintf(w, "  error:      %s\n", inspection.Error)
+		}
+	}
+	return nil
+}
+
+// printDiagnoses writes why the plays of the report could not be verified and how to remedy it.
+func printDiagnoses(w io.Writer, report verifier.Report) {
+	for _, play := range report.Plays {
+		if play.Verified {
+			continue
+		}
+		diagnosis := play.Diagnosis
+		if diagnosis == nil {
+			fmt.Fprintf(w, "play %d (%q, document %d): %s\n", play.Index, play.Name, play.Document, play.Error)
+			continue
+		}
+		fmt.Fprintf(w, "play %d (%q, document %d): %s\n", play.Index, play.Name, play.Document, diagnosis.Cause)
+		fmt.Fprintf(w, "  hint: %s\n", diagnosis.Hint)
+		for _, attempt := range diagnosis.Attempts {
+			switch {
+			case attempt.Offset == nil:
+				fmt.Fprintf(w, "  %s: digest %s\n", attempt.Profile, attempt.Digest)
+			case *attempt.Offset < 0:
+				fmt.Fprintf(w, "  %s: digest %s, serialization equals the reference\n", attempt.Profile, attempt.Digest)
+			default:
+				fmt.Fprintf(w, "  %s: digest %s, serialization differs from the reference at byte %d\n", attempt.Profile, attempt.Digest, *attempt.Offset)
+			}
+		}
+	}
+}
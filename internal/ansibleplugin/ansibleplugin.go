@@ -0,0 +1,84 @@
+// Package ansibleplugin installs the Ansible callback plugin that runs the verifier before
+// ansible-playbook runs a playbook, so playbooks of Insights are verified even when they are
+// run by hand instead of by rhc or insights-client.
+//
+// The plugin verifies the playbooks tagged as Insights content, i.e. containing
+// insights_signature, and exits ansible-playbook if they are not verified: exceptions of
+// callbacks would only be shown as warnings. It is enabled as soon as it is in a directory
+// of callback plugins, e.g. DefaultDirectory, or one configured by callback_plugins in ansible.cfg.
+package ansibleplugin
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/template"
+	"unicode/utf8"
+)
+
+const (
+	// Name is the name of the callback plugin, and of its section in ansible.cfg.
+	Name = "playbook_verifier"
+	// DefaultDirectory is the directory of callback plugins that every ansible-playbook on the host loads.
+	DefaultDirectory = "/usr/share/ansible/plugins/callback"
+)
+
+//go:embed playbook_verifier.py.tmpl
+var pluginSource string
+
+var pluginTemplate = template.Must(template.New(Name).Parse(pluginSource))
+
+// plugin is what the template renders.
+type plugin struct {
+	Name string
+	// Verifier is the path to the verifier as a Python string literal.
+	Verifier string
+	// WarnOnlyExitCode is the exit code of the verifier for playbooks signed by warn-only keys,
+	// which are run with a warning.
+	WarnOnlyExitCode int
+}
+
+// Install writes the plugin calling the verifier at the path into the directory, and returns its path.
+// The plugin runs playbooks the verifier exits with warnOnlyExitCode for with a warning.
+//
+// An installed plugin is replaced atomically, so ansible-playbook never loads a partially written one.
+func Install(directory, verifier string, warnOnlyExitCode int) (string, error) {
+	if !filepath.IsAbs(verifier) {
+		return "", fmt.Errorf("path to the verifier is not absolute: %s", verifier)
+	}
+	// Python string literals escape the same characters as Go ones, as long as the path is UTF-8.
+	if !utf8.ValidString(verifier) {
+		return "", errors.New("path to the verifier is not valid UTF-8")
+	}
+	var source strings.Builder
+	if err := pluginTemplate.Execute(&source, plugin{Name: Name, Verifier: strconv.Quote(verifier), WarnOnlyExitCode: warnOnlyExitCode}); err != nil {
+		return "", err
+	}
+
+	if err := os.MkdirAll(directory, 0o755); err != nil {
+		return "", err
+	}
+	file, err := os.CreateTemp(directory, "."+Name+"-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(file.Name())
+	if _, err = file.WriteString(source.String()); err != nil {
+		file.Close()
+		return "", err
+	}
+	// ansible-playbook runs as any user
+	if err = file.Chmod(0o644); err != nil {
+		file.Close()
+		return "", err
+	}
+	if err = file.Close(); err != nil {
+		return "", err
+	}
+	path := filepath.Join(directory, Name+".py")
+	return path, os.Rename(file.Name(), path)
+}
@@ -0,0 +1,154 @@
+package ansibleplugin
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestInstall(t *testing.T) {
+	directory := filepath.Join(t.TempDir(), "callback")
+	path, err := Install(directory, `/usr/bin/playbook "verifier"`, 10)
+	if err != nil {
+		t.Fatalf("Install() error = %v", err)
+	}
+	if want := filepath.Join(directory, "playbook_verifier.py"); path != want {
+		t.Errorf("Install() = %s, want %s", path, want)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("os.Stat() error = %v", err)
+	}
+	if info.Mode().Perm() != 0o644 {
+		t.Errorf("plugin mode = %v, want 0644", info.Mode().Perm())
+	}
+	source, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("os.ReadFile() error = %v", err)
+	}
+	for _, want := range []string{`VERIFIER = "/usr/bin/playbook \"verifier\""`, "name: playbook_verifier", "section: callback_playbook_verifier", "WARN_ONLY_EXIT_CODE = 10"} {
+		if !strings.Contains(string(source), want) {
+			t.Errorf("plugin does not contain %q", want)
+		}
+	}
+
+	// installing it again replaces it
+	if _, err = Install(directory, "/usr/libexec/playbook-verifier", 10); err != nil {
+		t.Fatalf("Install() again error = %v", err)
+	}
+	entries, err := os.ReadDir(directory)
+	if err != nil || len(entries) != 1 {
+		t.Errorf("directory contains %v, %v, want only the plugin", entries, err)
+	}
+
+	for _, verifier := range []string{"playbook-verifier", "/usr/bin/\xff"} {
+		if _, err = Install(directory, verifier, 10); err == nil {
+			t.Errorf("Install(%q) succeeded", verifier)
+		}
+	}
+}
+
+// stubAnsible is the part of the API of ansible-core the plugin uses.
+var stubAnsible = map[string]string{
+	"ansible/__init__.py":         "",
+	"ansible/plugins/__init__.py": "",
+	"ansible/plugins/callback/__init__.py": `import os, sys
+class Display:
+    def error(self, msg): print("error: " + msg)
+    def warning(self, msg): print("warning: " + msg)
+    def vvv(self, msg): print(msg)
+class CallbackBase:
+    def __init__(self):
+        self._display = Display()
+    def get_option(self, name):
+        if name == "verify_all":
+            return os.environ.get("VERIFY_ALL") == "1"
+        raise KeyError(name)
+`,
+	"run.py": `import importlib.util, sys
+spec = importlib.util.spec_from_file_location("playbook_verifier", sys.argv[1])
+plugin = importlib.util.module_from_spec(spec)
+spec.loader.exec_module(plugin)
+class Playbook:
+    _file_name = sys.argv[2]
+try:
+    plugin.CallbackModule().v2_playbook_on_start(Playbook())
+    print("continued")
+except SystemExit as e:
+    print("exit %d" % e.code)
+`,
+}
+
+// TestPlugin runs the plugin with a stub of ansible-core and a fake verifier.
+func TestPlugin(t *testing.T) {
+	python, err := exec.LookPath("python3")
+	if err != nil {
+		t.Skipf("python3 is not installed: %v", err)
+	}
+	directory := t.TempDir()
+	for name, content := range stubAnsible {
+		path := filepath.Join(directory, name)
+		if err = os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err = os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// the fake verifier prints its arguments and fails if the playbook says so
+	verifier := filepath.Join(directory, "verifier")
+	script := "#!/bin/sh\necho \"verifier $*\" >&2\ngrep -q tampered \"$2\" && exit 3\ngrep -q staged \"$2\" && exit 10\necho \"verifier $*\" > \"$2.verified\"\n"
+	if err = os.WriteFile(verifier, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	plugin, err := Install(filepath.Join(directory, "callback"), verifier, 10)
+	if err != nil {
+		t.Fatalf("Install() error = %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		playbook  string
+		verifyAll bool
+		output    string
+		verified  bool
+	}{
+		{"verified", "- name: a\n  vars:\n    insights_signature: abc\n", false, "continued", true},
+		{"not verified", "- name: tampered\n  vars:\n    insights_signature: abc\n", false, "exit 1", false},
+		{"warn-only key", "- name: staged\n  vars:\n    insights_signature: abc\n", false, "continued", false},
+		{"not tagged", "- name: a\n", false, "continued", false},
+		{"not tagged, verify all", "- name: tampered\n", true, "exit 1", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			playbook := filepath.Join(t.TempDir(), "playbook.yml")
+			if err := os.WriteFile(playbook, []byte(tt.playbook), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			command := exec.Command(python, filepath.Join(directory, "run.py"), plugin, playbook)
+			command.Env = append(os.Environ(), "PYTHONPATH="+directory, "VERIFY_ALL=0")
+			if tt.verifyAll {
+				command.Env = append(command.Env, "VERIFY_ALL=1")
+			}
+			output, err := command.CombinedOutput()
+			if err != nil {
+				t.Fatalf("plugin error = %v: %s", err, output)
+			}
+			if lines := strings.Split(strings.TrimSpace(string(output)), "\n"); lines[len(lines)-1] != tt.output {
+				t.Errorf("plugin output = %s, want %s at the end", output, tt.output)
+			}
+			if tt.output != "continued" && !strings.Contains(string(output), "is not verified, refusing to run it: verifier --payload "+playbook) {
+				t.Errorf("plugin output = %s, want the reason", output)
+			}
+			// playbooks signed by warn-only keys run with a warning
+			if warned := strings.Contains(string(output), "warning: playbook "+playbook+": verifier --payload"); warned != strings.Contains(tt.playbook, "staged") {
+				t.Errorf("plugin output = %s, want a warning only for the warn-only key", output)
+			}
+			if _, err := os.Stat(playbook + ".verified"); (err == nil) != tt.verified {
+				t.Errorf("playbook verified = %v, want %v", err == nil, tt.verified)
+			}
+		})
+	}
+}
@@ -0,0 +1,253 @@
+// Package archive reads the playbooks of Insights remediations downloaded as archives,
+// which contain the playbook along with its metadata.
+//
+// Archives are read in memory; nothing is extracted to disk, so the names of their members
+// cannot be used to write outside of a directory. Tar archives may be compressed with gzip.
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"slices"
+	"strings"
+
+	"com.github/m-horky/playbook-verifier/internal/hygiene"
+	"com.github/m-horky/playbook-verifier/internal/manifest"
+)
+
+// Formats of archives.
+const (
+	FormatTar   = "tar"
+	FormatTarGz = "tar.gz"
+	FormatZip   = "zip"
+)
+
+var (
+	// ErrMalformedArchive means the archive could not be read or contains no single playbook.
+	ErrMalformedArchive = errors.New("malformed archive")
+	// ErrArchiveTooLarge means the members of the archive are larger than the limit.
+	ErrArchiveTooLarge = errors.New("archive is too large")
+	// ErrManifestMismatch means the members of the archive do not match its manifest.
+	ErrManifestMismatch = errors.New("archive does not match its manifest")
+)
+
+// MaxMembers is the maximal number of regular files of an archive. Empty files take no bytes
+// of the size limit, so their number is limited on its own.
+const MaxMembers = 1024
+
+// Archive is the content of the regular files of an archive.
+type Archive struct {
+	Format string
+	// members are the contents of the files, by their cleaned names.
+	members map[string][]byte
+}
+
+// Detect returns the format of the archive, or an empty string if the payload is not an archive.
+func Detect(payload []byte) string {
+	switch {
+	case bytes.HasPrefix(payload, []byte("\x1f\x8b")):
+		return FormatTarGz
+	case bytes.HasPrefix(payload, []byte("PK\x03\x04")), bytes.HasPrefix(payload, []byte("PK\x05\x06")):
+		return FormatZip
+	case len(payload) >= 263 && bytes.Equal(payload[257:262], []byte("ustar")):
+		return FormatTar
+	}
+	return ""
+}
+
+// Open reads the regular files of the archive. Directories and links are ignored.
+//
+// The files may not take more than maxSize bytes together once they are decompressed,
+// so a compressed archive cannot exhaust the memory. Non-positive maxSize disables the limit.
+func Open(payload []byte, maxSize int64) (*Archive, error) {
+	archive := &Archive{Format: Detect(payload), members: map[string][]byte{}}
+	var err error
+	switch archive.Format {
+	case FormatTar:
+		err = archive.readTar(bytes.NewReader(payload), maxSize)
+	case FormatTarGz:
+		var r *gzip.Reader
+		if r, err = gzip.NewReader(bytes.NewReader(payload)); err == nil {
+			err = archive.readTar(r, maxSize)
+		}
+	case FormatZip:
+		err = archive.readZip(payload, maxSize)
+	default:
+		return nil, fmt.Errorf("%w: unknown format", ErrMalformedArchive)
+	}
+	if err != nil {
+		if errors.Is(err, ErrArchiveTooLarge) || errors.Is(err, ErrMalformedArchive) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w: %w", ErrMalformedArchive, err)
+	}
+	return archive, nil
+}
+
+// readTar reads the regular files of the tar archive.
+func (a *Archive) readTar(r io.Reader, maxSize int64) error {
+	reader := tar.NewReader(r)
+	var size int64
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		content, err := readMember(reader, maxSize, &size)
+		if err != nil {
+			return err
+		}
+		if err = a.add(header.Name, content); err != nil {
+			return err
+		}
+	}
+}
+
+// readZip reads the regular files of the zip archive.
+func (a *Archive) readZip(payload []byte, maxSize int64) error {
+	reader, err := zip.NewReader(bytes.NewReader(payload), int64(len(payload)))
+	if err != nil {
+		return err
+	}
+	var size int64
+	for _, file := range reader.File {
+		if !file.Mode().IsRegular() {
+			continue
+		}
+		r, err := file.Open()
+		if err != nil {
+			return err
+		}
+		content, err := readMember(r, maxSize, &size)
+		r.Close()
+		if err != nil {
+			return err
+		}
+		if err = a.add(file.Name, content); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readMember reads the member, adding its length to the size of the archive read so far.
+func readMember(r io.Reader, maxSize int64, size *int64) ([]byte, error) {
+	if maxSize > 0 {
+		r = io.LimitReader(r, maxSize-*size+1)
+	}
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	*size += int64(len(content))
+	if maxSize > 0 && *size > maxSize {
+		return nil, fmt.Errorf("%w: limit is %d bytes", ErrArchiveTooLarge, maxSize)
+	}
+	return content, nil
+}
+
+// add stores the member under its cleaned name. A name that appears twice is refused,
+// since the member that would be used would depend on the reader.
+func (a *Archive) add(name string, content []byte) error {
+	name = cleanName(name)
+	if _, ok := a.members[name]; ok {
+		return fmt.Errorf("%w: member %s appears more than once", ErrMalformedArchive, name)
+	}
+	if len(a.members) >= MaxMembers {
+		return fmt.Errorf("%w: more than %d members", ErrArchiveTooLarge, MaxMembers)
+	}
+	a.members[name] = content
+	return nil
+}
+
+// cleanName returns the name of the member relative to the root of the archive, e.g. 'playbook.yml'
+// for './playbook.yml'.
+func cleanName(name string) string {
+	return strings.TrimPrefix(path.Clean("/"+name), "/")
+}
+
+// Names returns the sorted names of the members.
+func (a *Archive) Names() []string {
+	names := make([]string, 0, len(a.members))
+	for name := range a.members {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// Playbook returns the member of the name, or the only YAML file of the archive if the name is empty.
+func (a *Archive) Playbook(name string) (string, []byte, error) {
+	if name != "" {
+		name = cleanName(name)
+		content, ok := a.members[name]
+		if !ok {
+			return "", nil, fmt.Errorf("%w: no member %s", ErrMalformedArchive, name)
+		}
+		return name, content, nil
+	}
+
+	var playbooks []string
+	for _, name := range a.Names() {
+		if extension := path.Ext(name); extension == ".yml" || extension == ".yaml" {
+			playbooks = append(playbooks, name)
+		}
+	}
+	switch len(playbooks) {
+	case 0:
+		return "", nil, fmt.Errorf("%w: no YAML file", ErrMalformedArchive)
+	case 1:
+		return playbooks[0], a.members[playbooks[0]], nil
+	default:
+		return "", nil, fmt.Errorf("%w: several YAML files (%s), select the playbook", ErrMalformedArchive, strings.Join(playbooks, ", "))
+	}
+}
+
+// CheckManifest checks the members against the manifest of the name, see manifest.Parse.
+// The names of the members are relative to the directory of the manifest.
+//
+// Every member but the manifest must be listed, so no file can be added to the archive unnoticed.
+func (a *Archive) CheckManifest(name string) error {
+	name = cleanName(name)
+	content, ok := a.members[name]
+	if !ok {
+		return fmt.Errorf("%w: no manifest %s", ErrManifestMismatch, name)
+	}
+
+	entries, err := manifest.Parse(content)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrManifestMismatch, err)
+	}
+	listed := map[string]bool{name: true}
+	for _, entry := range entries {
+		member := cleanName(path.Join(path.Dir(name), entry.Name))
+		content, ok := a.members[member]
+		if !ok {
+			return fmt.Errorf("%w: member %s is missing", ErrManifestMismatch, member)
+		}
+		sum := sha256.Sum256(content)
+		if !hygiene.Equal(sum[:], entry.Digest) {
+			return fmt.Errorf("%w: digest of member %s differs", ErrManifestMismatch, member)
+		}
+		listed[member] = true
+	}
+	for _, member := range a.Names() {
+		if !listed[member] {
+			return fmt.Errorf("%w: member %s is not listed", ErrManifestMismatch, member)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,190 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"testing"
+)
+
+// member is a file of a test archive.
+type member struct {
+	name    string
+	content string
+}
+
+func tarArchive(t *testing.T, members ...member) []byte {
+	t.Helper()
+	var b bytes.Buffer
+	w := tar.NewWriter(&b)
+	if err := w.WriteHeader(&tar.Header{Name: "remediation/", Typeflag: tar.TypeDir, Mode: 0o755}); err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range members {
+		if err := w.WriteHeader(&tar.Header{Name: m.name, Mode: 0o644, Size: int64(len(m.content))}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(m.content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+func tarGzArchive(t *testing.T, members ...member) []byte {
+	t.Helper()
+	var b bytes.Buffer
+	w := gzip.NewWriter(&b)
+	if _, err := w.Write(tarArchive(t, members...)); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+func zipArchive(t *testing.T, members ...member) []byte {
+	t.Helper()
+	var b bytes.Buffer
+	w := zip.NewWriter(&b)
+	for _, m := range members {
+		f, err := w.Create(m.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = f.Write([]byte(m.content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+// sha256sums lists the digests of the members in the format of sha256sum, as it is created in their directory.
+func sha256sums(members ...member) member {
+	var b bytes.Buffer
+	for _, m := range members {
+		sum := sha256.Sum256([]byte(m.content))
+		fmt.Fprintf(&b, "%s  %s\n", hex.EncodeToString(sum[:]), path.Base(m.name))
+	}
+	return member{"remediation/SHA256SUMS", b.String()}
+}
+
+func TestOpen(t *testing.T) {
+	playbook := member{"remediation/playbook.yml", "- name: play\n"}
+	metadata := member{"./remediation/metadata.json", "{}"}
+	builders := map[string]func(*testing.T, ...member) []byte{
+		FormatTar:   tarArchive,
+		FormatTarGz: tarGzArchive,
+		FormatZip:   zipArchive,
+	}
+	for format, build := range builders {
+		t.Run(format, func(t *testing.T) {
+			payload := build(t, playbook, metadata)
+			if got := Detect(payload); got != format {
+				t.Fatalf("Detect() = %q, want %q", got, format)
+			}
+			archive, err := Open(payload, 0)
+			if err != nil {
+				t.Fatalf("Open() error = %v", err)
+			}
+			name, content, err := archive.Playbook("")
+			if err != nil || name != "remediation/playbook.yml" || string(content) != playbook.content {
+				t.Errorf("Playbook() = %q, %q, %v", name, content, err)
+			}
+			if _, content, err = archive.Playbook("remediation/metadata.json"); err != nil || string(content) != "{}" {
+				t.Errorf("Playbook(metadata) = %q, %v", content, err)
+			}
+		})
+	}
+}
+
+func TestDetect(t *testing.T) {
+	for _, payload := range []string{"", "- name: play\n", "---\nustar: yes\n"} {
+		if got := Detect([]byte(payload)); got != "" {
+			t.Errorf("Detect(%q) = %q, want no archive", payload, got)
+		}
+	}
+}
+
+func TestOpenErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload []byte
+		maxSize int64
+		want    error
+	}{
+		{"truncated", tarGzArchive(t, member{"playbook.yml", "- name: play\n"})[:20], 0, ErrMalformedArchive},
+		{"too large", tarGzArchive(t, member{"playbook.yml", string(make([]byte, 4096))}), 1024, ErrArchiveTooLarge},
+		{"too large together", zipArchive(t, member{"a.yml", string(make([]byte, 600))}, member{"b.yml", string(make([]byte, 600))}), 1024, ErrArchiveTooLarge},
+		{"duplicate member", tarArchive(t, member{"playbook.yml", "- name: a\n"}, member{"./playbook.yml", "- name: b\n"}), 0, ErrMalformedArchive},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Open(tt.payload, tt.maxSize); !errors.Is(err, tt.want) {
+				t.Errorf("Open() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestPlaybookSelection(t *testing.T) {
+	archive, err := Open(zipArchive(t, member{"a.yml", "- name: a\n"}, member{"b.yaml", "- name: b\n"}), 0)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	if _, _, err = archive.Playbook(""); !errors.Is(err, ErrMalformedArchive) {
+		t.Errorf("Playbook() error = %v, want several YAML files refused", err)
+	}
+	if _, _, err = archive.Playbook("c.yml"); !errors.Is(err, ErrMalformedArchive) {
+		t.Errorf("Playbook(missing) error = %v, want %v", err, ErrMalformedArchive)
+	}
+	if name, _, err := archive.Playbook("./b.yaml"); err != nil || name != "b.yaml" {
+		t.Errorf("Playbook(b.yaml) = %q, %v", name, err)
+	}
+}
+
+func TestCheckManifest(t *testing.T) {
+	playbook := member{"remediation/playbook.yml", "- name: play\n"}
+	metadata := member{"remediation/metadata.json", "{}"}
+	tampered := member{"remediation/metadata.json", `{"hosts": "all"}`}
+	extra := member{"remediation/extra.sh", "true\n"}
+
+	tests := []struct {
+		name    string
+		members []member
+		want    error
+	}{
+		{"matching", []member{playbook, metadata, sha256sums(playbook, metadata)}, nil},
+		{"binary mode", []member{playbook, {"remediation/SHA256SUMS", strings.Replace(sha256sums(playbook).content, "  ", " *", 1)}}, nil},
+		{"tampered member", []member{playbook, tampered, sha256sums(playbook, metadata)}, ErrManifestMismatch},
+		{"unlisted member", []member{playbook, metadata, extra, sha256sums(playbook, metadata)}, ErrManifestMismatch},
+		{"missing member", []member{playbook, sha256sums(playbook, metadata)}, ErrManifestMismatch},
+		{"no manifest", []member{playbook, metadata}, ErrManifestMismatch},
+		{"malformed manifest", []member{playbook, {"remediation/SHA256SUMS", "playbook.yml\n"}}, ErrManifestMismatch},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			archive, err := Open(tarArchive(t, tt.members...), 0)
+			if err != nil {
+				t.Fatalf("Open() error = %v", err)
+			}
+			if err = archive.CheckManifest("remediation/SHA256SUMS"); !errors.Is(err, tt.want) {
+				t.Errorf("CheckManifest() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
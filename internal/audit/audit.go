@@ -0,0 +1,113 @@
+// Package audit records the verification decisions, so security teams can reconstruct
+// which payloads have been approved for execution, when and for whom.
+//
+// Records of the file log are chained: each one contains the hash of the previous one,
+// so removing or changing a record breaks the chain at that point, see VerifyChain.
+// Records sent to journald rely on its Forward Secure Sealing instead.
+package audit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"slices"
+	"time"
+
+	"com.github/m-horky/playbook-verifier/pkg/verifier"
+)
+
+// Verdicts of the records.
+const (
+	// VerdictApproved means the payload has been verified and may be executed.
+	VerdictApproved = "approved"
+	// VerdictRejected means the payload must not be executed.
+	VerdictRejected = "rejected"
+)
+
+// Record is a single verification decision.
+type Record struct {
+	Time time.Time `json:"time"`
+	// Caller describes who asked for the verification, e.g. the parent process or the remote address.
+	Caller      string `json:"caller"`
+	ContentType string `json:"content_type"`
+	// PayloadDigest is the SHA-256 digest of the payload as received, 'sha256:<hex>'.
+	PayloadDigest string `json:"payload_digest"`
+	// KeyIDs are the IDs of the keys whose signatures have been verified.
+	KeyIDs  []string `json:"key_ids"`
+	Verdict string   `json:"verdict"`
+	// Error describes why the payload has been rejected.
+	Error string `json:"error,omitempty"`
+	// Previous is the hash of the previous record of the log, empty for the first one.
+	Previous string `json:"previous"`
+	// Hash is the SHA-256 digest of the record without the hash, set when the record is written.
+	Hash string `json:"hash,omitempty"`
+}
+
+// NewRecord describes the result of the verification of the payload.
+func NewRecord(caller string, contentType string, payload []byte, report verifier.Report, err error) Record {
+	digest := sha256.Sum256(payload)
+	record := Record{
+		Time:          time.Now().UTC(),
+		Caller:        caller,
+		ContentType:   contentType,
+		PayloadDigest: "sha256:" + hex.EncodeToString(digest[:]),
+		KeyIDs:        []string{},
+		Verdict:       VerdictApproved,
+	}
+	if report.KeyID != "" {
+		record.KeyIDs = append(record.KeyIDs, report.KeyID)
+	}
+	for _, play := range report.Plays {
+		if play.KeyID != "" && !slices.Contains(record.KeyIDs, play.KeyID) {
+			record.KeyIDs = append(record.KeyIDs, play.KeyID)
+		}
+	}
+	if err != nil || !report.Verified {
+		record.Verdict = VerdictRejected
+		record.Error = report.Error
+		if err != nil {
+			record.Error = err.Error()
+		}
+	}
+	return record
+}
+
+// seal chains the record to the previous hash and returns its JSON line.
+func (r *Record) seal(previous string) ([]byte, error) {
+	r.Previous = previous
+	r.Hash = ""
+	content, err := json.Marshal(r)
+	if err != nil {
+		return nil, err
+	}
+	digest := sha256.Sum256(content)
+	r.Hash = hex.EncodeToString(digest[:])
+	line, err := json.Marshal(r)
+	if err != nil {
+		return nil, err
+	}
+	return append(line, '\n'), nil
+}
+
+// Log stores the records.
+type Log interface {
+	Write(record Record) error
+	Close() error
+}
+
+// callerKey is the context key of the caller.
+type callerKey struct{}
+
+// WithCaller returns a context recording the caller of the verification.
+func WithCaller(ctx context.Context, caller string) context.Context {
+	return context.WithValue(ctx, callerKey{}, caller)
+}
+
+// Caller returns the caller recorded in the context, or 'unknown'.
+func Caller(ctx context.Context) string {
+	if caller, ok := ctx.Value(callerKey{}).(string); ok {
+		return caller
+	}
+	return "unknown"
+}
@@ -0,0 +1,181 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"com.github/m-horky/playbook-verifier/pkg/verifier"
+)
+
+func TestNewRecord(t *testing.T) {
+	report := verifier.Report{Verified: true, Plays: []verifier.PlayReport{{KeyID: "A"}, {KeyID: "B"}, {KeyID: "A"}}}
+	record := NewRecord("test", verifier.PlaybookContentType, []byte("payload"), report, nil)
+	if record.Verdict != VerdictApproved || !slices.Equal(record.KeyIDs, []string{"A", "B"}) || record.Error != "" {
+		t.Errorf("NewRecord() = %+v, want approved by A and B", record)
+	}
+	if record.PayloadDigest != "sha256:239f59ed55e737c77147cf55ad0c1b030b6d7ee748a7426952f9b852d5a935e5" {
+		t.Errorf("NewRecord() digest = %s", record.PayloadDigest)
+	}
+
+	record = NewRecord("test", verifier.PlaybookContentType, []byte("payload"), verifier.Report{}, errors.New("bad"))
+	if record.Verdict != VerdictRejected || record.Error != "bad" || record.KeyIDs == nil {
+		t.Errorf("NewRecord() = %+v, want rejected", record)
+	}
+}
+
+func TestCaller(t *testing.T) {
+	if got := Caller(context.Background()); got != "unknown" {
+		t.Errorf("Caller() = %s, want unknown", got)
+	}
+	if got := Caller(WithCaller(context.Background(), "http 127.0.0.1:1234")); got != "http 127.0.0.1:1234" {
+		t.Errorf("Caller() = %s, want http 127.0.0.1:1234", got)
+	}
+}
+
+func writeRecords(t *testing.T, log *FileLog, count int) {
+	t.Helper()
+	for i := range count {
+		record := NewRecord(fmt.Sprintf("record %d", i), verifier.PlaybookContentType, []byte{byte(i)}, verifier.Report{Verified: true}, nil)
+		if err := log.Write(record); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+	}
+}
+
+func TestFileLogChain(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	log, err := OpenFile(path, 0, 0)
+	if err != nil {
+		t.Fatalf("OpenFile() error = %v", err)
+	}
+	writeRecords(t, log, 3)
+	log.Close()
+
+	// A reopened log continues the chain
+	if log, err = OpenFile(path, 0, 0); err != nil {
+		t.Fatalf("OpenFile() error = %v", err)
+	}
+	writeRecords(t, log, 1)
+	log.Close()
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("os.ReadFile() error = %v", err)
+	}
+	if _, err = VerifyChain(bytes.NewReader(content), ""); err != nil {
+		t.Errorf("VerifyChain() error = %v", err)
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0o600 {
+		t.Errorf("audit log mode = %v, want 0600", info.Mode().Perm())
+	}
+
+	lines := strings.SplitAfter(string(content), "\n")
+	tests := []struct {
+		name string
+		log  string
+	}{
+		{"modified", strings.Replace(string(content), "record 1", "record 9", 1)},
+		{"removed", lines[0] + strings.Join(lines[2:], "")},
+		{"reordered", lines[1] + lines[0] + strings.Join(lines[2:], "")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := VerifyChain(strings.NewReader(tt.log), ""); err == nil {
+				t.Errorf("VerifyChain() error = nil, want an error")
+			}
+		})
+	}
+}
+
+func TestFileLogRotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	log, err := OpenFile(path, 512, 2)
+	if err != nil {
+		t.Fatalf("OpenFile() error = %v", err)
+	}
+	defer log.Close()
+	writeRecords(t, log, 20)
+
+	if _, err = os.Stat(path + ".3"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("rotated log beyond the kept ones exists: %v", err)
+	}
+	// The files are chained to each other
+	previous := ""
+	for _, name := range []string{path + ".2", path + ".1", path} {
+		content, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatalf("os.ReadFile() error = %v", err)
+		}
+		if len(content) > 512+300 {
+			t.Errorf("%s has %d bytes, want it rotated", filepath.Base(name), len(content))
+		}
+		if name != path+".2" {
+			if previous, err = VerifyChain(bytes.NewReader(content), previous); err != nil {
+				t.Errorf("VerifyChain(%s) error = %v", filepath.Base(name), err)
+			}
+			continue
+		}
+		// The oldest file does not start the chain, its first record follows a removed one
+		first, _, _ := strings.Cut(string(content), "\n")
+		rest := strings.TrimPrefix(string(content), first+"\n")
+		start, err := VerifyChain(strings.NewReader(first+"\n"), previousOf(t, first))
+		if err != nil {
+			t.Fatalf("VerifyChain() error = %v", err)
+		}
+		if previous, err = VerifyChain(strings.NewReader(rest), start); err != nil {
+			t.Errorf("VerifyChain(%s) error = %v", filepath.Base(name), err)
+		}
+	}
+}
+
+// previousOf returns the previous hash of the JSON record.
+func previousOf(t *testing.T, line string) string {
+	t.Helper()
+	_, after, _ := strings.Cut(line, `"previous":"`)
+	previous, _, _ := strings.Cut(after, `"`)
+	return previous
+}
+
+func TestFileLogProcesses(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	var wg sync.WaitGroup
+	for range 4 {
+		// Each log has its own file description, like separate processes
+		log, err := OpenFile(path, 2048, 10)
+		if err != nil {
+			t.Fatalf("OpenFile() error = %v", err)
+		}
+		defer log.Close()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			writeRecords(t, log, 10)
+		}()
+	}
+	wg.Wait()
+
+	previous, count := "", 0
+	names, _ := filepath.Glob(path + ".*")
+	slices.Reverse(names)
+	for _, name := range append(names, path) {
+		content, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatalf("os.ReadFile() error = %v", err)
+		}
+		count += strings.Count(string(content), "\n")
+		if previous, err = VerifyChain(bytes.NewReader(content), previous); err != nil {
+			t.Errorf("VerifyChain(%s) error = %v", filepath.Base(name), err)
+		}
+	}
+	if count != 40 {
+		t.Errorf("audit logs contain %d records, want 40", count)
+	}
+}
@@ -0,0 +1,34 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// VerifyChain checks that each record of the log is intact and chained to the one before it,
+// starting with the previous hash, e.g. the last hash of the rotated log. It returns the hash
+// of the last record.
+func VerifyChain(r io.Reader, previous string) (string, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1<<20)
+	for line := 1; scanner.Scan(); line++ {
+		var record Record
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return previous, fmt.Errorf("record %d is malformed: %w", line, err)
+		}
+		if record.Previous != previous {
+			return previous, fmt.Errorf("record %d does not follow the previous record", line)
+		}
+		hash := record.Hash
+		if _, err := record.seal(previous); err != nil {
+			return previous, err
+		}
+		if record.Hash != hash {
+			return previous, fmt.Errorf("record %d has been modified", line)
+		}
+		previous = hash
+	}
+	return previous, scanner.Err()
+}
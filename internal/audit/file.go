@@ -0,0 +1,191 @@
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"sync"
+)
+
+// Defaults of the rotation of the file log.
+const (
+	// DefaultMaxSize is the size the log is rotated at, in bytes.
+	DefaultMaxSize = 10 << 20
+	// DefaultKeep is the number of rotated logs that are kept.
+	DefaultKeep = 5
+)
+
+// FileLog appends the records to a file as JSON lines.
+//
+// Several processes may write to the same file: each write locks the file, reads the hash
+// of its last record and appends the new one. The file is rotated to '<path>.1' once it
+// reaches the maximal size, the older rotated files are shifted up to '<path>.<keep>'.
+// The first record of a new file is chained to the last one of the rotated file.
+type FileLog struct {
+	mutex   sync.Mutex
+	path    string
+	maxSize int64
+	keep    int
+	file    *os.File
+}
+
+// OpenFile opens the log, creating it if necessary. The rotation is disabled if maxSize is zero.
+func OpenFile(path string, maxSize int64, keep int) (*FileLog, error) {
+	file, err := openLogFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return &FileLog{path: path, maxSize: maxSize, keep: keep, file: file}, nil
+}
+
+// openLogFile opens the file for appending, readable only by its owner.
+func openLogFile(path string) (*os.File, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("could not open audit log: %w", err)
+	}
+	return file, nil
+}
+
+// Write chains the record to the last one of the log and appends it.
+func (l *FileLog) Write(record Record) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if err := l.lockCurrent(); err != nil {
+		return fmt.Errorf("could not lock audit log: %w", err)
+	}
+	// The file is replaced when the log is rotated, the lock moves to the new one
+	defer func() { unlockFile(l.file) }()
+
+	previous, size, err := l.lastHash()
+	if err != nil {
+		return err
+	}
+	if l.maxSize > 0 && size >= l.maxSize {
+		if err = l.rotate(); err != nil {
+			// A sandboxed process cannot rename files, the next one rotates the log
+			slog.Warn("could not rotate audit log", slog.Any("error", err))
+		} else if previous, _, err = l.lastHash(); err != nil {
+			return err
+		}
+	}
+
+	line, err := record.seal(previous)
+	if err != nil {
+		return fmt.Errorf("could not encode audit record: %w", err)
+	}
+	if _, err = l.file.Write(line); err != nil {
+		return fmt.Errorf("could not write audit record: %w", err)
+	}
+	return l.file.Sync()
+}
+
+// lockCurrent locks the file at the path. If another process has rotated the open file,
+// the new file is opened and locked instead.
+func (l *FileLog) lockCurrent() error {
+	for {
+		if err := lockFile(l.file); err != nil {
+			return err
+		}
+		current, err := os.Stat(l.path)
+		if err == nil {
+			var open os.FileInfo
+			if open, err = l.file.Stat(); err == nil && os.SameFile(current, open) {
+				return nil
+			}
+		}
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+
+		file, err := openLogFile(l.path)
+		if err != nil {
+			// The record is kept in the rotated file rather than lost
+			slog.Warn("could not reopen rotated audit log", slog.Any("error", err))
+			return nil
+		}
+		unlockFile(l.file)
+		l.file.Close()
+		l.file = file
+	}
+}
+
+// rotate shifts the rotated files, moves the file to '<path>.1' and opens and locks a new one.
+func (l *FileLog) rotate() error {
+	for i := l.keep; i > 0; i-- {
+		source := l.path
+		if i > 1 {
+			source = fmt.Sprintf("%s.%d", l.path, i-1)
+		}
+		if err := os.Rename(source, fmt.Sprintf("%s.%d", l.path, i)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	if l.keep == 0 {
+		if err := os.Remove(l.path); err != nil {
+			return err
+		}
+	}
+	return l.lockCurrent()
+}
+
+// Close closes the file.
+func (l *FileLog) Close() error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.file.Close()
+}
+
+// lastHash returns the hash of the last record of the log and the size of the open file.
+//
+// The last record of an empty file is the last record of the rotated file, if it is readable.
+func (l *FileLog) lastHash() (string, int64, error) {
+	hash, size, err := fileLastHash(l.file)
+	if err != nil || size > 0 || l.keep == 0 {
+		return hash, size, err
+	}
+	rotated, err := os.Open(l.path + ".1")
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			slog.Warn("could not read rotated audit log", slog.Any("error", err))
+		}
+		return "", 0, nil
+	}
+	defer rotated.Close()
+	hash, _, err = fileLastHash(rotated)
+	return hash, 0, err
+}
+
+// fileLastHash returns the hash of the last record of the file and the size of the file.
+func fileLastHash(file *os.File) (string, int64, error) {
+	info, err := file.Stat()
+	if err != nil {
+		return "", 0, fmt.Errorf("could not read audit log: %w", err)
+	}
+	size := info.Size()
+	// Records are short, the last one is at the end of the tail
+	const tail = 64 * 1024
+	offset := max(size-tail, 0)
+	content := make([]byte, size-offset)
+	if _, err = file.ReadAt(content, offset); err != nil && err != io.EOF {
+		return "", size, fmt.Errorf("could not read audit log: %w", err)
+	}
+
+	content = bytes.TrimRight(content, "\n")
+	if len(content) == 0 {
+		return "", size, nil
+	}
+	if i := bytes.LastIndexByte(content, '\n'); i >= 0 {
+		content = content[i+1:]
+	}
+	var record Record
+	if err = json.Unmarshal(content, &record); err != nil {
+		return "", size, fmt.Errorf("last record of the audit log is malformed: %w", err)
+	}
+	return record.Hash, size, nil
+}
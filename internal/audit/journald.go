@@ -0,0 +1,45 @@
+package audit
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/coreos/go-systemd/v22/journal"
+)
+
+// JournaldIdentifier is the SYSLOG_IDENTIFIER of the records sent to journald,
+// so they can be read with `journalctl -t insights-playbook-verifier-audit`.
+const JournaldIdentifier = "insights-playbook-verifier-audit"
+
+// JournaldLog sends the records to journald, with the fields of the record as journal fields.
+type JournaldLog struct{}
+
+// OpenJournald connects to journald, so the records can be sent from a sandbox as well.
+func OpenJournald() (JournaldLog, error) {
+	if !journal.Enabled() {
+		return JournaldLog{}, errors.New("journald is not available")
+	}
+	return JournaldLog{}, nil
+}
+
+// Write sends the record to journald.
+func (JournaldLog) Write(record Record) error {
+	fields := map[string]string{
+		"SYSLOG_IDENTIFIER":    JournaldIdentifier,
+		"AUDIT_CALLER":         record.Caller,
+		"AUDIT_CONTENT_TYPE":   record.ContentType,
+		"AUDIT_PAYLOAD_DIGEST": record.PayloadDigest,
+		"AUDIT_KEY_IDS":        strings.Join(record.KeyIDs, ","),
+		"AUDIT_VERDICT":        record.Verdict,
+	}
+	if record.Error != "" {
+		fields["AUDIT_ERROR"] = record.Error
+	}
+	message := "payload " + record.Verdict + " " + record.PayloadDigest
+	return journal.Send(message, journal.PriNotice, fields)
+}
+
+// Close does nothing.
+func (JournaldLog) Close() error {
+	return nil
+}
@@ -0,0 +1,13 @@
+//go:build !unix
+
+package audit
+
+import "os"
+
+// lockFile does nothing; the log is only shared by processes on the hosts insights-client runs on.
+func lockFile(file *os.File) error {
+	return nil
+}
+
+// unlockFile does nothing.
+func unlockFile(file *os.File) {}
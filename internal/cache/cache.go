@@ -0,0 +1,129 @@
+// Package cache stores the reports of verified payloads on disk, so identical payloads
+// do not have to be verified again.
+//
+// Only successful verifications are cached. The entries are keyed by the digest of the
+// payload and of everything else the verdict depends on (trusted and revoked keys,
+// content type, policy), so changing any of them invalidates the cache.
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"com.github/m-horky/playbook-verifier/internal/hygiene"
+	"com.github/m-horky/playbook-verifier/pkg/verifier"
+)
+
+// Cache is a directory of verification reports.
+type Cache struct {
+	directory string
+	ttl       time.Duration
+	now       func() time.Time
+}
+
+// entry is the content of a cache file.
+type entry struct {
+	// Key binds the entry to its key, so an entry copied under another name is not used.
+	Key     string          `json:"key"`
+	Created time.Time       `json:"created"`
+	Report  verifier.Report `json:"report"`
+}
+
+// New returns a cache stored in the directory, whose entries expire after the TTL.
+//
+// The directory is created if it does not exist. Since a cached report replaces
+// the verification, the directory must be owned by the user or root, and must not be
+// writable by other users.
+func New(directory string, ttl time.Duration) (*Cache, error) {
+	if err := os.MkdirAll(directory, 0o700); err != nil {
+		return nil, fmt.Errorf("could not create cache directory: %w", err)
+	}
+	info, err := os.Stat(directory)
+	if err != nil {
+		return nil, fmt.Errorf("could not open cache directory: %w", err)
+	}
+	if !info.IsDir() || info.Mode().Perm()&0o022 != 0 {
+		return nil, fmt.Errorf("cache directory %s must be a directory not writable by group or others", directory)
+	}
+	if err = checkOwner(directory, info); err != nil {
+		return nil, err
+	}
+	return &Cache{directory: directory, ttl: ttl, now: time.Now}, nil
+}
+
+// Key returns the cache key of the payload verified in the context.
+//
+// The context has to contain everything the verdict depends on besides the payload.
+func Key(payload []byte, context ...string) string {
+	hash := sha256.New()
+	hash.Write(payload)
+	for _, part := range context {
+		// length prefix keeps the parts from running into each other
+		fmt.Fprintf(hash, "\x00%d:%s", len(part), part)
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// Get returns the cached report, if there is one that has not expired.
+func (c *Cache) Get(key string) (verifier.Report, bool) {
+	content, err := os.ReadFile(c.path(key))
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			slog.Debug("could not read cache entry", slog.Any("error", err))
+		}
+		return verifier.Report{}, false
+	}
+
+	var cached entry
+	if err = json.Unmarshal(content, &cached); err != nil {
+		slog.Debug("could not parse cache entry", slog.Any("error", err))
+		return verifier.Report{}, false
+	}
+	if !hygiene.EqualString(cached.Key, key) || !cached.Report.Verified || c.now().Sub(cached.Created) > c.ttl {
+		slog.Debug("cache entry is stale", slog.String("key", key))
+		_ = os.Remove(c.path(key))
+		return verifier.Report{}, false
+	}
+
+	slog.Debug("using cached report", slog.String("key", key))
+	return cached.Report, true
+}
+
+// Put stores the report of a verified payload.
+func (c *Cache) Put(key string, report verifier.Report) error {
+	if !report.Verified {
+		return nil
+	}
+	content, err := json.Marshal(entry{Key: key, Created: c.now(), Report: report})
+	if err != nil {
+		return err
+	}
+
+	// write to a temporary file first, so readers never see a partial entry
+	file, err := os.CreateTemp(c.directory, ".entry-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+	if _, err = file.Write(content); err != nil {
+		file.Close()
+		return err
+	}
+	if err = file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), c.path(key))
+}
+
+// path returns the path of the cache entry.
+func (c *Cache) path(key string) string {
+	return filepath.Join(c.directory, key+".json")
+}
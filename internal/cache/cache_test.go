@@ -0,0 +1,109 @@
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"com.github/m-horky/playbook-verifier/pkg/verifier"
+)
+
+func TestCache(t *testing.T) {
+	c, err := New(filepath.Join(t.TempDir(), "cache"), time.Hour)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	c.now = func() time.Time { return now }
+
+	key := Key([]byte("payload"), "context")
+	if _, ok := c.Get(key); ok {
+		t.Fatal("Get() found entry in empty cache")
+	}
+
+	report := verifier.Report{Verified: true, Plays: []verifier.PlayReport{{Name: "play", KeyID: "08D82F6981A5FD2F", Verified: true}}}
+	if err = c.Put(key, report); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	cached, ok := c.Get(key)
+	if !ok || cached.Plays[0].KeyID != "08D82F6981A5FD2F" {
+		t.Errorf("Get() = %+v, %v", cached, ok)
+	}
+
+	if _, ok = c.Get(Key([]byte("payload"), "other context")); ok {
+		t.Error("Get() found entry for different context")
+	}
+
+	now = now.Add(2 * time.Hour)
+	if _, ok = c.Get(key); ok {
+		t.Error("Get() found expired entry")
+	}
+}
+
+func TestCacheFailedReport(t *testing.T) {
+	c, err := New(t.TempDir(), time.Hour)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	key := Key([]byte("payload"))
+	if err = c.Put(key, verifier.Report{Verified: false}); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	if _, ok := c.Get(key); ok {
+		t.Error("Get() found failed report")
+	}
+}
+
+func TestCacheCopiedEntry(t *testing.T) {
+	c, err := New(t.TempDir(), time.Hour)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	verified, unverified := Key([]byte("verified")), Key([]byte("unverified"))
+	if err = c.Put(verified, verifier.Report{Verified: true}); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	content, err := os.ReadFile(c.path(verified))
+	if err != nil {
+		t.Fatalf("os.ReadFile() error = %v", err)
+	}
+	if err = os.WriteFile(c.path(unverified), content, 0o600); err != nil {
+		t.Fatalf("os.WriteFile() error = %v", err)
+	}
+	if _, ok := c.Get(unverified); ok {
+		t.Error("Get() found entry copied from another key")
+	}
+}
+
+func TestCacheInsecureDirectory(t *testing.T) {
+	directory := t.TempDir()
+	if err := os.Chmod(directory, 0o777); err != nil {
+		t.Fatalf("os.Chmod() error = %v", err)
+	}
+	if _, err := New(directory, time.Hour); err == nil {
+		t.Error("New() accepted world-writable directory")
+	}
+}
+
+func TestCacheDirectoryOfOtherUser(t *testing.T) {
+	if os.Getuid() != 0 {
+		t.Skip("changing the owner of the directory requires root")
+	}
+	directory := t.TempDir()
+	if _, err := New(directory, time.Hour); err != nil {
+		t.Fatalf("New() error = %v for a directory of root", err)
+	}
+	if err := os.Chown(directory, 4242, 4242); err != nil {
+		t.Fatalf("os.Chown() error = %v", err)
+	}
+	if _, err := New(directory, time.Hour); err == nil {
+		t.Error("New() accepted directory of another user")
+	}
+}
+
+func TestKey(t *testing.T) {
+	if Key([]byte("a"), "bc") == Key([]byte("a"), "b", "c") {
+		t.Error("Key() does not separate the context")
+	}
+}
@@ -0,0 +1,23 @@
+//go:build unix
+
+package cache
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"syscall"
+)
+
+// checkOwner refuses a directory owned by another user than the one running the verifier or root,
+// since its owner can change its permissions and the entries in it.
+func checkOwner(directory string, info fs.FileInfo) error {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return fmt.Errorf("could not get owner of cache directory %s", directory)
+	}
+	if uid := int(stat.Uid); uid != os.Getuid() && uid != 0 {
+		return fmt.Errorf("cache directory %s must be owned by the user %d or root, not %d", directory, os.Getuid(), uid)
+	}
+	return nil
+}
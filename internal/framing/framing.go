@@ -0,0 +1,66 @@
+// Package framing implements the length-prefixed frames payloads and verdicts are streamed in
+// over a single pipe, see --batch-stdin.
+//
+// A frame is the length of its data as a 32-bit unsigned big-endian integer, followed by the data.
+// There are no delimiters or escapes, so any bytes, including NUL bytes and YAML document markers,
+// can be framed.
+package framing
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// headerSize is the size of the length prefix, in bytes.
+const headerSize = 4
+
+// ErrFrameTooLarge means the data of the frame is larger than the limit. The frame has been
+// skipped, so the next one can be read.
+var ErrFrameTooLarge = errors.New("frame is too large")
+
+// ReadFrame reads the data of the next frame. It returns io.EOF if the stream ends before
+// the frame, and io.ErrUnexpectedEOF if it ends inside of it.
+//
+// Frames larger than maxSize bytes are skipped and ErrFrameTooLarge is returned.
+// Non-positive maxSize disables the limit.
+func ReadFrame(r io.Reader, maxSize int64) ([]byte, error) {
+	var header [headerSize]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, err
+	}
+	length := int64(binary.BigEndian.Uint32(header[:]))
+	if maxSize > 0 && length > maxSize {
+		if _, err := io.CopyN(io.Discard, r, length); err != nil {
+			return nil, unexpectedEOF(err)
+		}
+		return nil, fmt.Errorf("%w: %d bytes, limit is %d bytes", ErrFrameTooLarge, length, maxSize)
+	}
+
+	data := make([]byte, length)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, unexpectedEOF(err)
+	}
+	return data, nil
+}
+
+// WriteFrame writes the data as a single frame.
+func WriteFrame(w io.Writer, data []byte) error {
+	if int64(len(data)) > math.MaxUint32 {
+		return fmt.Errorf("%w: %d bytes cannot be framed", ErrFrameTooLarge, len(data))
+	}
+	frame := make([]byte, headerSize, headerSize+len(data))
+	binary.BigEndian.PutUint32(frame, uint32(len(data)))
+	_, err := w.Write(append(frame, data...))
+	return err
+}
+
+// unexpectedEOF turns the end of the stream inside of a frame into io.ErrUnexpectedEOF.
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
@@ -0,0 +1,50 @@
+package framing
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestFrames(t *testing.T) {
+	payloads := [][]byte{[]byte("- name: play\n"), {}, []byte("---\n\x00\n...\n")}
+	var stream bytes.Buffer
+	for _, payload := range payloads {
+		if err := WriteFrame(&stream, payload); err != nil {
+			t.Fatalf("WriteFrame() error = %v", err)
+		}
+	}
+
+	for i, want := range payloads {
+		got, err := ReadFrame(&stream, 64)
+		if err != nil || !bytes.Equal(got, want) {
+			t.Errorf("ReadFrame() %d = %q, %v, want %q", i, got, err, want)
+		}
+	}
+	if _, err := ReadFrame(&stream, 64); err != io.EOF {
+		t.Errorf("ReadFrame() at the end error = %v, want %v", err, io.EOF)
+	}
+}
+
+func TestReadFrameErrors(t *testing.T) {
+	var stream bytes.Buffer
+	for _, payload := range []string{"too large for the limit", "next"} {
+		if err := WriteFrame(&stream, []byte(payload)); err != nil {
+			t.Fatalf("WriteFrame() error = %v", err)
+		}
+	}
+	if _, err := ReadFrame(&stream, 8); !errors.Is(err, ErrFrameTooLarge) {
+		t.Errorf("ReadFrame() error = %v, want %v", err, ErrFrameTooLarge)
+	}
+	// the large frame has been skipped
+	if got, err := ReadFrame(&stream, 8); err != nil || string(got) != "next" {
+		t.Errorf("ReadFrame() after large frame = %q, %v", got, err)
+	}
+
+	for _, truncated := range []string{"\x00\x00", "\x00\x00\x00\x05abc", "\x00\x00\x01\x00abc"} {
+		if _, err := ReadFrame(bytes.NewReader([]byte(truncated)), 8); err != io.ErrUnexpectedEOF {
+			t.Errorf("ReadFrame(%q) error = %v, want %v", truncated, err, io.ErrUnexpectedEOF)
+		}
+	}
+}
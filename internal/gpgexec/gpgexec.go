@@ -0,0 +1,149 @@
+// Package gpgexec verifies OpenPGP signatures by running gpgv, the way the Python
+// verifier does, for deployments that require GnuPG to do the cryptography.
+//
+// Every verification gets its own GNUPGHOME, a temporary directory readable only by
+// the current user, which is removed afterwards. Directories of verifications that are
+// interrupted by a signal are removed by RemoveHomes.
+//
+// The directories are created in the system temporary directory, or in the directory
+// passed to New. On SELinux systems, a GNUPGHOME in a configured directory gets the
+// security context of that directory, so the policy the administrator has written for it
+// applies instead of the type transitions of the temporary directory.
+package gpgexec
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// DefaultBinary is the program that verifies the signatures.
+const DefaultBinary = "gpgv"
+
+// homes are the GNUPGHOME directories of running verifications.
+var (
+	homesMutex sync.Mutex
+	homes      = map[string]bool{}
+)
+
+// Backend runs gpgv.
+type Backend struct {
+	binary string
+	// directory contains the GNUPGHOME directories; empty for the system temporary directory.
+	directory string
+}
+
+// New returns a backend running the binary, which is looked up in PATH.
+//
+// The GNUPGHOME directories are created in the directory, or in the system temporary
+// directory if it is empty.
+func New(binary string, directory string) (*Backend, error) {
+	path, err := exec.LookPath(binary)
+	if err != nil {
+		return nil, fmt.Errorf("could not find %s: %w", binary, err)
+	}
+	if directory != "" {
+		info, err := os.Stat(directory)
+		if err != nil {
+			return nil, fmt.Errorf("could not use temporary directory: %w", err)
+		}
+		if !info.IsDir() {
+			return nil, fmt.Errorf("could not use temporary directory: %s is not a directory", directory)
+		}
+	}
+	return &Backend{binary: path, directory: directory}, nil
+}
+
+// Verify checks the detached signature of the message against the binary public key.
+func (b *Backend) Verify(publicKey []byte, message []byte, signature []byte) error {
+	home, err := createHome(b.directory)
+	if err != nil {
+		return err
+	}
+	defer removeHome(home)
+
+	keyring := filepath.Join(home, "pubring.gpg")
+	signatureFile := filepath.Join(home, "signature")
+	if err = os.WriteFile(keyring, publicKey, 0o600); err != nil {
+		return fmt.Errorf("could not write public key: %w", err)
+	}
+	if err = os.WriteFile(signatureFile, signature, 0o600); err != nil {
+		return fmt.Errorf("could not write signature: %w", err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(b.binary, "--homedir", home, "--keyring", keyring, "--status-fd", "1", signatureFile, "-")
+	cmd.Env = []string{"GNUPGHOME=" + home, "LC_ALL=C"}
+	cmd.Stdin = bytes.NewReader(message)
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err = cmd.Run(); err != nil {
+		return fmt.Errorf("%s failed: %w: %s", filepath.Base(b.binary), err, strings.TrimSpace(stderr.String()))
+	}
+	if !hasValidSignature(stdout.String()) {
+		return errors.New(filepath.Base(b.binary) + " did not report a valid signature")
+	}
+	return nil
+}
+
+// hasValidSignature reports whether the status output of gpgv contains a valid signature.
+func hasValidSignature(status string) bool {
+	for _, line := range strings.Split(status, "\n") {
+		if strings.HasPrefix(line, "[GNUPG:] VALIDSIG ") {
+			return true
+		}
+	}
+	return false
+}
+
+// createHome creates a GNUPGHOME directory accessible only by the current user
+// in the directory, or in the system temporary directory if it is empty.
+func createHome(directory string) (string, error) {
+	homesMutex.Lock()
+	defer homesMutex.Unlock()
+	home, err := os.MkdirTemp(directory, "playbook-verifier-gnupg-")
+	if err != nil {
+		return "", fmt.Errorf("could not create GNUPGHOME: %w", err)
+	}
+	if err = os.Chmod(home, 0o700); err != nil {
+		_ = os.RemoveAll(home)
+		return "", fmt.Errorf("could not restrict GNUPGHOME: %w", err)
+	}
+	if directory != "" {
+		// Files created in the directory later inherit its context
+		if err = copySecurityContext(home, directory); err != nil {
+			_ = os.RemoveAll(home)
+			return "", fmt.Errorf("could not label GNUPGHOME: %w", err)
+		}
+	}
+	homes[home] = true
+	return home, nil
+}
+
+// removeHome removes the GNUPGHOME directory.
+func removeHome(home string) {
+	homesMutex.Lock()
+	defer homesMutex.Unlock()
+	if err := os.RemoveAll(home); err != nil {
+		slog.Warn("could not remove GNUPGHOME", slog.String("path", home), slog.Any("error", err))
+	}
+	delete(homes, home)
+}
+
+// RemoveHomes removes the GNUPGHOME directories of running verifications.
+//
+// It is meant to be called before the process exits on a signal, when deferred
+// functions do not run.
+func RemoveHomes() {
+	homesMutex.Lock()
+	defer homesMutex.Unlock()
+	for home := range homes {
+		_ = os.RemoveAll(home)
+		delete(homes, home)
+	}
+}
@@ -0,0 +1,111 @@
+package gpgexec
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"com.github/m-horky/playbook-verifier/pkg/verifier"
+)
+
+var testdata = filepath.Join("..", "..", "pkg", "verifier", "testdata")
+
+func readTestdata(t *testing.T, path ...string) []byte {
+	t.Helper()
+	content, err := os.ReadFile(filepath.Join(append([]string{testdata}, path...)...))
+	if err != nil {
+		t.Fatalf("os.ReadFile() error = %v", err)
+	}
+	return content
+}
+
+func TestVerify(t *testing.T) {
+	backend, err := New(DefaultBinary, "")
+	if err != nil {
+		t.Skipf("gpgv is not installed: %v", err)
+	}
+	keyring, err := verifier.NewKeyring(readTestdata(t, "keys", "test-public.asc"))
+	if err != nil {
+		t.Fatalf("NewKeyring() error = %v", err)
+	}
+	keyring.SetOpenPGPBackend(backend.Verify)
+	t.Setenv("TMPDIR", t.TempDir())
+	playbook := readTestdata(t, "detached", "playbook.yml")
+
+	tests := []struct {
+		name      string
+		payload   []byte
+		signature string
+		want      error
+	}{
+		{"armored", playbook, "playbook.yml.asc", nil},
+		{"binary", playbook, "playbook.yml.sig", nil},
+		{"modified", append(playbook, []byte("# comment\n")...), "playbook.yml.asc", verifier.ErrDigestMismatch},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report, err := verifier.VerifyDetached(tt.payload, readTestdata(t, "detached", tt.signature), keyring)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("VerifyDetached() error = %v, want %v", err, tt.want)
+			}
+			if tt.want == nil && report.KeyID != "08D82F6981A5FD2F" {
+				t.Errorf("VerifyDetached() key = %s, want 08D82F6981A5FD2F", report.KeyID)
+			}
+		})
+	}
+
+	entries, err := os.ReadDir(os.Getenv("TMPDIR"))
+	if err != nil || len(entries) != 0 {
+		t.Errorf("GNUPGHOME directories were not removed: %v, %v", entries, err)
+	}
+}
+
+func TestRemoveHomes(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+	home, err := createHome("")
+	if err != nil {
+		t.Fatalf("createHome() error = %v", err)
+	}
+	info, err := os.Stat(home)
+	if err != nil || info.Mode().Perm() != 0o700 {
+		t.Fatalf("GNUPGHOME = %v, %v, want mode 0700", info, err)
+	}
+
+	RemoveHomes()
+	if _, err = os.Stat(home); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("GNUPGHOME was not removed: %v", err)
+	}
+}
+
+func TestCreateHomeDirectory(t *testing.T) {
+	directory := t.TempDir()
+	home, err := createHome(directory)
+	if err != nil {
+		t.Fatalf("createHome() error = %v", err)
+	}
+	defer removeHome(home)
+	if filepath.Dir(home) != directory {
+		t.Errorf("createHome() = %s, want a directory in %s", home, directory)
+	}
+	info, err := os.Stat(home)
+	if err != nil || info.Mode().Perm() != 0o700 {
+		t.Errorf("GNUPGHOME = %v, %v, want mode 0700", info, err)
+	}
+}
+
+func TestNewDirectory(t *testing.T) {
+	if _, err := New(DefaultBinary, ""); err != nil {
+		t.Skipf("gpgv is not installed: %v", err)
+	}
+	file := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(file, nil, 0o600); err != nil {
+		t.Fatalf("os.WriteFile() error = %v", err)
+	}
+
+	for _, directory := range []string{file, filepath.Join(file, "missing")} {
+		if _, err := New(DefaultBinary, directory); err == nil {
+			t.Errorf("New(%s) error = nil, want an error", directory)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package gpgexec
+
+import (
+	"bytes"
+	"errors"
+
+	"golang.org/x/sys/unix"
+)
+
+// securityContextAttribute is the extended attribute holding the SELinux context of a file.
+const securityContextAttribute = "security.selinux"
+
+// copySecurityContext sets the SELinux context of the path to the context of the reference,
+// like `chcon --reference`.
+//
+// Nothing is done if the file system does not store SELinux contexts, e.g. when SELinux is disabled.
+func copySecurityContext(path string, reference string) error {
+	want, err := securityContext(reference)
+	if err != nil || want == nil {
+		return err
+	}
+	got, err := securityContext(path)
+	if err != nil || bytes.Equal(got, want) {
+		return err
+	}
+	return unix.Lsetxattr(path, securityContextAttribute, want, 0)
+}
+
+// securityContext returns the SELinux context of the path, or nil if it has none.
+func securityContext(path string) ([]byte, error) {
+	buffer := make([]byte, 256)
+	for {
+		size, err := unix.Lgetxattr(path, securityContextAttribute, buffer)
+		switch {
+		case errors.Is(err, unix.ENODATA), errors.Is(err, unix.ENOTSUP):
+			return nil, nil
+		case errors.Is(err, unix.ERANGE):
+			buffer = make([]byte, 2*len(buffer))
+			continue
+		case err != nil:
+			return nil, err
+		}
+		return buffer[:size], nil
+	}
+}
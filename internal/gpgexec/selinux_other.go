@@ -0,0 +1,8 @@
+//go:build !linux
+
+package gpgexec
+
+// copySecurityContext does nothing, SELinux contexts only exist on Linux.
+func copySecurityContext(path string, reference string) error {
+	return nil
+}
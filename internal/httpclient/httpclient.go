@@ -0,0 +1,166 @@
+// Package httpclient creates the HTTP clients of the online features (fetching key bundles,
+// Rekor lookups), so they reach the network the way insights-client on the same host does:
+// through its proxy, and trusting the Red Hat certificate authorities of subscription-manager.
+package httpclient
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"golang.org/x/net/http/httpproxy"
+	"gopkg.in/ini.v1"
+)
+
+const (
+	// DefaultInsightsConfig is the configuration file of insights-client the proxy is read from.
+	DefaultInsightsConfig = "/etc/insights-client/insights-client.conf"
+	// DefaultCADirectory is the directory of the certificate authorities of subscription-manager,
+	// containing the Red Hat CAs.
+	DefaultCADirectory = "/etc/rhsm/ca"
+	// DefaultTimeout limits the time of a request, including reading the response.
+	DefaultTimeout = 30 * time.Second
+)
+
+// insightsSection is the section of the insights-client configuration file.
+const insightsSection = "insights-client"
+
+// Config describes how the client reaches the network.
+type Config struct {
+	// InsightsConfig is the configuration file of insights-client whose 'proxy' option is used.
+	// Empty string or a missing file means none.
+	InsightsConfig string
+	// CADirectories are directories of PEM certificates (*.pem) trusted in addition to the roots.
+	// Missing directories are skipped.
+	CADirectories []string
+	// RootCAs replaces the roots of the system. Nil means the roots of the system.
+	RootCAs *x509.CertPool
+	// Timeout limits the time of a request. Zero means DefaultTimeout.
+	Timeout time.Duration
+	// VerifyConnection is called after the certificate chain has been verified, e.g. to check pins.
+	VerifyConnection func(tls.ConnectionState) error
+}
+
+// Defaults returns the configuration of the client on a host registered by insights-client.
+func Defaults() Config {
+	return Config{
+		InsightsConfig: DefaultInsightsConfig,
+		CADirectories:  []string{DefaultCADirectory},
+	}
+}
+
+// New returns a client of the configuration.
+//
+// The proxy of insights-client takes precedence over HTTPS_PROXY and HTTP_PROXY, as it does in
+// insights-client itself; NO_PROXY applies to both.
+func New(config Config) (*http.Client, error) {
+	roots, err := rootCAs(config.RootCAs, config.CADirectories)
+	if err != nil {
+		return nil, err
+	}
+	proxy, err := proxyFunc(config.InsightsConfig)
+	if err != nil {
+		return nil, err
+	}
+	timeout := config.Timeout
+	if timeout == 0 {
+		timeout = DefaultTimeout
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = proxy
+	transport.TLSClientConfig = &tls.Config{
+		MinVersion:       tls.VersionTLS12,
+		RootCAs:          roots,
+		VerifyConnection: config.VerifyConnection,
+	}
+	return &http.Client{Timeout: timeout, Transport: transport}, nil
+}
+
+// rootCAs returns the roots with the certificates of the directories added.
+// Nil means the roots of the system, which are not loaded unless certificates are added.
+func rootCAs(roots *x509.CertPool, directories []string) (*x509.CertPool, error) {
+	var paths []string
+	for _, directory := range directories {
+		matches, err := filepath.Glob(filepath.Join(directory, "*.pem"))
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, matches...)
+	}
+	if len(paths) == 0 {
+		return roots, nil
+	}
+
+	if roots == nil {
+		system, err := x509.SystemCertPool()
+		if err != nil {
+			slog.Debug("could not load system certificate authorities", slog.Any("error", err))
+			system = x509.NewCertPool()
+		}
+		roots = system
+	} else {
+		roots = roots.Clone()
+	}
+	for _, path := range paths {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("could not read certificate authority: %w", err)
+		}
+		if !roots.AppendCertsFromPEM(content) {
+			return nil, fmt.Errorf("no certificates found in %s", path)
+		}
+		slog.Debug("trusting certificate authority", slog.String("path", path))
+	}
+	return roots, nil
+}
+
+// proxyFunc returns the proxy of the request: the proxy of insights-client if it has one,
+// or the proxy of the environment.
+func proxyFunc(insightsConfig string) (func(*http.Request) (*url.URL, error), error) {
+	environment := httpproxy.FromEnvironment()
+	proxy, err := insightsProxy(insightsConfig)
+	if err != nil {
+		return nil, err
+	}
+	if proxy != "" {
+		proxyURL, err := url.Parse(proxy)
+		if err != nil || proxyURL.Host == "" {
+			return nil, fmt.Errorf("invalid proxy in %s: %q", insightsConfig, proxy)
+		}
+		slog.Debug("using proxy of insights-client", slog.String("proxy", proxyURL.Redacted()))
+		environment.HTTPProxy, environment.HTTPSProxy = proxy, proxy
+	}
+	proxyURL := environment.ProxyFunc()
+	return func(request *http.Request) (*url.URL, error) {
+		return proxyURL(request.URL)
+	}, nil
+}
+
+// insightsProxy returns the 'proxy' option of the configuration file of insights-client.
+func insightsProxy(path string) (string, error) {
+	if path == "" {
+		return "", nil
+	}
+	content, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("could not read insights-client configuration: %w", err)
+	}
+	config, err := ini.Load(content)
+	if err != nil {
+		return "", fmt.Errorf("could not parse insights-client configuration %s: %w", path, err)
+	}
+	return strings.TrimSpace(config.Section(insightsSection).Key("proxy").String()), nil
+}
@@ -0,0 +1,28 @@
+// Package hygiene contains helpers for handling digests and signature material.
+//
+// Digests and signatures are compared in constant time, so the time a comparison takes
+// does not leak how many leading bytes matched. Decoded signatures are wiped once they
+// have been checked, so no stray copies of them stay in memory longer than necessary.
+package hygiene
+
+import (
+	"crypto/subtle"
+	"runtime"
+)
+
+// Equal reports whether the byte slices are equal, in time independent of their content.
+func Equal(a, b []byte) bool {
+	return subtle.ConstantTimeCompare(a, b) == 1
+}
+
+// EqualString is like Equal, but for strings, e.g. hex-encoded digests.
+func EqualString(a, b string) bool {
+	return Equal([]byte(a), []byte(b))
+}
+
+// Wipe overwrites the slice with zeros.
+func Wipe(b []byte) {
+	clear(b)
+	// keep the compiler from eliding the writes to memory that is not read afterwards
+	runtime.KeepAlive(b)
+}
@@ -0,0 +1,31 @@
+package hygiene
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestEqual(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"", "", true},
+		{"digest", "digest", true},
+		{"digest", "digesT", false},
+		{"digest", "dig", false},
+	}
+	for _, tt := range tests {
+		if got := EqualString(tt.a, tt.b); got != tt.want {
+			t.Errorf("EqualString(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestWipe(t *testing.T) {
+	signature := []byte("-----BEGIN PGP SIGNATURE-----")
+	Wipe(signature[5:])
+	if !bytes.Equal(signature, append([]byte("-----"), make([]byte, len(signature)-5)...)) {
+		t.Errorf("Wipe() left %q", signature)
+	}
+}
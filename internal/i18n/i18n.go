@@ -0,0 +1,128 @@
+// Package i18n localizes the messages the verifier shows to end users, e.g. through insights-client.
+//
+// Only the short descriptions of failures and warnings are localized. Everything consumed by machines
+// (JSON reports, logs, exit codes, digests) stays the same in every locale.
+//
+// The English messages are the keys of the catalog. Translations are added to the catalog
+// with SetString, one language per file (e.g. 'messages_cs.go'), from an init function.
+package i18n
+
+import (
+	"errors"
+	"os"
+	"strings"
+
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+	"golang.org/x/text/message/catalog"
+
+	"com.github/m-horky/playbook-verifier/internal/manifest"
+	"com.github/m-horky/playbook-verifier/pkg/verifier"
+)
+
+// builder is the catalog of translated messages.
+var builder = catalog.NewBuilder(catalog.Fallback(language.English))
+
+// SetString adds the translation of the English message.
+//
+// It is meant to be called from init functions; it is not safe for concurrent use with NewPrinter.
+func SetString(tag language.Tag, key string, translation string) {
+	// the key and the translation are constant strings, they are always valid
+	_ = builder.SetString(tag, key, translation)
+}
+
+// Language returns the language of the user's locale that has the closest translation.
+//
+// The locale is read from the environment variables LC_ALL, LC_MESSAGES and LANG,
+// in this order. The 'C' and 'POSIX' locales and unknown ones fall back to English.
+func Language() language.Tag {
+	return localeLanguage(os.Getenv)
+}
+
+func localeLanguage(getenv func(string) string) language.Tag {
+	var locale string
+	for _, name := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		if locale = getenv(name); locale != "" {
+			break
+		}
+	}
+	// e.g. 'cs_CZ.UTF-8@euro'
+	locale, _, _ = strings.Cut(locale, ".")
+	locale, _, _ = strings.Cut(locale, "@")
+	if locale == "" || locale == "C" || locale == "POSIX" {
+		return language.English
+	}
+
+	tag, err := language.Parse(strings.ReplaceAll(locale, "_", "-"))
+	if err != nil {
+		return language.English
+	}
+	supported := append([]language.Tag{language.English}, builder.Languages()...)
+	_, index, confidence := language.NewMatcher(supported).Match(tag)
+	if confidence == language.No {
+		return language.English
+	}
+	return supported[index]
+}
+
+// NewPrinter returns a printer of the messages in the language of the user's locale.
+func NewPrinter() *message.Printer {
+	return message.NewPrinter(Language(), message.Catalog(builder))
+}
+
+// errorMessages maps the sentinel errors of the verifier to the messages shown to users.
+//
+// The errors of the manifest come first, since they wrap the error of its signature.
+var errorMessages = []struct {
+	err     error
+	message string
+}{
+	{manifest.ErrUntrusted, "The manifest of the files of the playbook is not signed by a trusted key."},
+	{manifest.ErrMalformedManifest, "The manifest of the files of the playbook is malformed."},
+	{manifest.ErrMismatch, "The files of the playbook, e.g. its roles, have been modified after they were signed."},
+	{verifier.ErrUnsupportedContentType, "The payload has an unsupported content type."},
+	{verifier.ErrMalformedYAML, "The playbook is not valid YAML."},
+	{verifier.ErrAmbiguousYAML, "The playbook contains YAML that can be read in more than one way."},
+	{verifier.ErrUnsupportedType, "The playbook contains a value that cannot be verified."},
+	{verifier.ErrUnknownKey, "The playbook contains a key unknown to Ansible."},
+	{verifier.ErrNoSignature, "The playbook is not signed."},
+	{verifier.ErrInvalidExclusion, "The playbook excludes content that has to be signed."},
+	{verifier.ErrMalformedSignature, "The signature of the playbook is malformed."},
+	{verifier.ErrInvalidKey, "A trusted key could not be loaded."},
+	{verifier.ErrWeakHashAlgorithm, "The playbook has been signed with a hash algorithm that is too weak."},
+	{verifier.ErrExpiredSignature, "The signature of the playbook has expired."},
+	{verifier.ErrRevokedKey, "The playbook has been signed with a revoked key."},
+	{verifier.ErrDigestMismatch, "The playbook has been modified after it was signed, or it was not signed by a trusted key."},
+	{verifier.ErrMemoryBudget, "The playbook is too large to be verified."},
+	{verifier.ErrPolicyViolation, "The playbook is signed, but the policy does not allow it."},
+	{verifier.ErrInternal, "The playbook could not be verified because of an internal error."},
+}
+
+// defaultErrorMessage is shown for errors that are not caused by the verification, e.g. I/O errors.
+const defaultErrorMessage = "The playbook could not be verified."
+
+// warnOnlyMessage warns that the playbook is verified, but by a key of a warn-only trust store.
+const warnOnlyMessage = "Warning: the playbook is signed by a key that is only trusted with a warning (%s)."
+
+// WarnOnlyMessage warns that the payload is verified by the warn-only keys, in the language of the printer.
+func WarnOnlyMessage(p *message.Printer, keyIDs []string) string {
+	return p.Sprintf(warnOnlyMessage, strings.Join(keyIDs, ", "))
+}
+
+// unsignedMessage warns that the playbook is accepted, but parts of it have not been verified.
+const unsignedMessage = "Warning: parts of the playbook are not signed and have not been verified."
+
+// UnsignedMessage warns that the payload contains unsigned content that has been accepted, in the language of the printer.
+func UnsignedMessage(p *message.Printer) string {
+	return p.Sprintf(unsignedMessage)
+}
+
+// ErrorMessage describes the error of the verification in the language of the printer.
+func ErrorMessage(p *message.Printer, err error) string {
+	for _, m := range errorMessages {
+		if errors.Is(err, m.err) {
+			return p.Sprintf(m.message)
+		}
+	}
+	return p.Sprintf(defaultErrorMessage)
+}
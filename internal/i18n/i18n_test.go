@@ -0,0 +1,79 @@
+package i18n
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+
+	"com.github/m-horky/playbook-verifier/pkg/verifier"
+)
+
+func TestLocaleLanguage(t *testing.T) {
+	SetString(language.German, defaultErrorMessage, "Das Playbook konnte nicht verifiziert werden.")
+
+	tests := []struct {
+		name        string
+		environment map[string]string
+		want        language.Tag
+	}{
+		{"unset", map[string]string{}, language.English},
+		{"C", map[string]string{"LANG": "C.UTF-8"}, language.English},
+		{"POSIX", map[string]string{"LC_ALL": "POSIX", "LANG": "de_DE.UTF-8"}, language.English},
+		{"translated", map[string]string{"LANG": "de_DE.UTF-8"}, language.German},
+		{"modifier", map[string]string{"LANG": "de_AT@euro"}, language.German},
+		{"LC_MESSAGES over LANG", map[string]string{"LC_MESSAGES": "de_CH", "LANG": "en_US.UTF-8"}, language.German},
+		{"not translated", map[string]string{"LANG": "ja_JP.UTF-8"}, language.English},
+		{"invalid", map[string]string{"LANG": "???"}, language.English},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := localeLanguage(func(name string) string { return tt.environment[name] })
+			if base, _ := got.Base(); base.String() != tt.want.String() {
+				t.Errorf("localeLanguage() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestErrorMessage(t *testing.T) {
+	SetString(language.German, defaultErrorMessage, "Das Playbook konnte nicht verifiziert werden.")
+	english := message.NewPrinter(language.English, message.Catalog(builder))
+	german := message.NewPrinter(language.German, message.Catalog(builder))
+
+	tests := []struct {
+		name    string
+		printer *message.Printer
+		err     error
+		want    string
+	}{
+		{"sentinel", english, fmt.Errorf("wrapped: %w", verifier.ErrDigestMismatch), "The playbook has been modified after it was signed, or it was not signed by a trusted key."},
+		{"other", english, errors.New("disk on fire"), "The playbook could not be verified."},
+		{"translated", german, errors.New("disk on fire"), "Das Playbook konnte nicht verifiziert werden."},
+		{"not translated", german, verifier.ErrNoSignature, "The playbook is not signed."},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ErrorMessage(tt.printer, tt.err); got != tt.want {
+				t.Errorf("ErrorMessage() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWarnOnlyMessage(t *testing.T) {
+	english := message.NewPrinter(language.English, message.Catalog(builder))
+	want := "Warning: the playbook is signed by a key that is only trusted with a warning (08D82F6981A5FD2F, E544FCAF4E2D23F9)."
+	if got := WarnOnlyMessage(english, []string{"08D82F6981A5FD2F", "E544FCAF4E2D23F9"}); got != want {
+		t.Errorf("WarnOnlyMessage() = %q, want %q", got, want)
+	}
+}
+
+func TestUnsignedMessage(t *testing.T) {
+	english := message.NewPrinter(language.English, message.Catalog(builder))
+	if got, want := UnsignedMessage(english), "Warning: parts of the playbook are not signed and have not been verified."; got != want {
+		t.Errorf("UnsignedMessage() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,84 @@
+package keystore
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"os"
+)
+
+// SigstoreCertificates returns the PEM-encoded certificates of the Sigstore certificate
+// authority stored in the file. Self-signed certificates are roots, the others intermediates.
+func SigstoreCertificates(path string) (roots, intermediates []*x509.Certificate, err error) {
+	certificates, err := Certificates(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, certificate := range certificates {
+		if bytes.Equal(certificate.RawIssuer, certificate.RawSubject) && certificate.CheckSignatureFrom(certificate) == nil {
+			roots = append(roots, certificate)
+		} else {
+			intermediates = append(intermediates, certificate)
+		}
+	}
+	return roots, intermediates, nil
+}
+
+// Certificates returns the PEM-encoded certificates stored in the file, e.g. a CA bundle.
+func Certificates(path string) ([]*x509.Certificate, error) {
+	blocks, err := readPEM(path, "CERTIFICATE")
+	if err != nil {
+		return nil, err
+	}
+	var certificates []*x509.Certificate
+	for _, block := range blocks {
+		certificate, err := x509.ParseCertificate(block)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse certificate in %s: %w", path, err)
+		}
+		certificates = append(certificates, certificate)
+	}
+	return certificates, nil
+}
+
+// RekorKeys returns the PEM-encoded public keys of the Rekor transparency logs stored in the file.
+func RekorKeys(path string) ([]crypto.PublicKey, error) {
+	blocks, err := readPEM(path, "PUBLIC KEY")
+	if err != nil {
+		return nil, err
+	}
+	var keys []crypto.PublicKey
+	for _, block := range blocks {
+		key, err := x509.ParsePKIXPublicKey(block)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse public key in %s: %w", path, err)
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// readPEM returns the content of the PEM blocks of the type in the file.
+func readPEM(path string, blockType string) ([][]byte, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var blocks [][]byte
+	for {
+		var block *pem.Block
+		block, content = pem.Decode(content)
+		if block == nil {
+			break
+		}
+		if block.Type == blockType {
+			blocks = append(blocks, block.Bytes)
+		}
+	}
+	if len(blocks) == 0 {
+		return nil, fmt.Errorf("no %s found in %s", blockType, path)
+	}
+	return blocks, nil
+}
@@ -0,0 +1,13 @@
+//go:build development
+
+package keystore
+
+import "embed"
+
+//go:embed keys/development/*.gpg keys/development/revoked.txt keys/development/self-test.yml
+var keys embed.FS
+
+const (
+	environment   = "development"
+	keysDirectory = "keys/development"
+)
@@ -0,0 +1,98 @@
+package keystore
+
+import (
+	"encoding/binary"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+// kernelKeyringSupported reports whether the kernel keyring is available on the platform.
+const kernelKeyringSupported = true
+
+// specialKeyrings are the keyrings of the process referred to by the shorthands of keyctl(1).
+var specialKeyrings = map[string]int{
+	"@t":  unix.KEY_SPEC_THREAD_KEYRING,
+	"@p":  unix.KEY_SPEC_PROCESS_KEYRING,
+	"@s":  unix.KEY_SPEC_SESSION_KEYRING,
+	"@u":  unix.KEY_SPEC_USER_KEYRING,
+	"@us": unix.KEY_SPEC_USER_SESSION_KEYRING,
+}
+
+// resolveKeyring returns the serial number of the keyring: a shorthand (e.g. '@u'), a serial number,
+// or the name of a keyring reachable from the session keyring, optionally prefixed by '%'.
+func resolveKeyring(keyring string) (int, error) {
+	if id, ok := specialKeyrings[keyring]; ok {
+		return unix.KeyctlGetKeyringID(id, false)
+	}
+	if id, err := strconv.Atoi(keyring); err == nil {
+		return id, nil
+	}
+	id, err := unix.KeyctlSearch(unix.KEY_SPEC_SESSION_KEYRING, "keyring", strings.TrimPrefix(keyring, "%"), 0)
+	if err != nil {
+		return 0, fmt.Errorf("could not find keyring %s: %w", keyring, err)
+	}
+	return id, nil
+}
+
+// readKernelKeyring returns the payloads of the 'user' keys linked to the keyring,
+// in the lexical order of their descriptions.
+func readKernelKeyring(keyring string) ([][]byte, error) {
+	id, err := resolveKeyring(keyring)
+	if err != nil {
+		return nil, err
+	}
+	content, err := readKey(id)
+	if err != nil {
+		return nil, fmt.Errorf("could not read keyring %s: %w", keyring, err)
+	}
+
+	type userKey struct {
+		description string
+		payload     []byte
+	}
+	var keys []userKey
+	// The payload of a keyring is the list of the serial numbers of its keys.
+	for ; len(content) >= 4; content = content[4:] {
+		serial := int(int32(binary.NativeEndian.Uint32(content)))
+		description, err := unix.KeyctlString(unix.KEYCTL_DESCRIBE, serial)
+		if err != nil {
+			return nil, fmt.Errorf("could not describe key %d: %w", serial, err)
+		}
+		// The description is 'type;uid;gid;perm;description'.
+		fields := strings.SplitN(description, ";", 5)
+		if len(fields) != 5 || fields[0] != "user" {
+			continue
+		}
+		payload, err := readKey(serial)
+		if err != nil {
+			return nil, fmt.Errorf("could not read key %s: %w", fields[4], err)
+		}
+		keys = append(keys, userKey{fields[4], payload})
+	}
+
+	slices.SortFunc(keys, func(a, b userKey) int { return strings.Compare(a.description, b.description) })
+	var publicKeys [][]byte
+	for _, key := range keys {
+		publicKeys = append(publicKeys, key.payload)
+	}
+	return publicKeys, nil
+}
+
+// readKey returns the payload of the key, growing the buffer until it fits.
+func readKey(id int) ([]byte, error) {
+	buffer := make([]byte, 4096)
+	for {
+		size, err := unix.KeyctlBuffer(unix.KEYCTL_READ, id, buffer, 0)
+		if err != nil {
+			return nil, err
+		}
+		if size <= len(buffer) {
+			return buffer[:size], nil
+		}
+		buffer = make([]byte, size)
+	}
+}
@@ -0,0 +1,33 @@
+package keystore
+
+import (
+	"strconv"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+func TestKernelKeyringStore(t *testing.T) {
+	keyring, err := unix.AddKey("keyring", "playbook-verifier-test", nil, unix.KEY_SPEC_PROCESS_KEYRING)
+	if err != nil {
+		t.Skipf("kernel keyring is not available: %v", err)
+	}
+	t.Cleanup(func() { _, _ = unix.KeyctlInt(unix.KEYCTL_INVALIDATE, keyring, 0, 0, 0) })
+	for description, payload := range map[string]string{"b-key": "second", "a-key": "first"} {
+		if _, err = unix.AddKey("user", description, []byte(payload), keyring); err != nil {
+			t.Fatalf("AddKey() error = %v", err)
+		}
+	}
+
+	publicKeys, err := KernelKeyringStore{Keyring: strconv.Itoa(keyring)}.PublicKeys()
+	if err != nil {
+		t.Fatalf("PublicKeys() error = %v", err)
+	}
+	if len(publicKeys) != 2 || string(publicKeys[0]) != "first" || string(publicKeys[1]) != "second" {
+		t.Errorf("PublicKeys() = %q, want the keys in the order of their descriptions", publicKeys)
+	}
+
+	if _, err = (KernelKeyringStore{Keyring: "no-such-keyring"}).PublicKeys(); err == nil {
+		t.Error("PublicKeys() error = nil for a missing keyring")
+	}
+}
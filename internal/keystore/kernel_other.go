@@ -0,0 +1,13 @@
+//go:build !linux
+
+package keystore
+
+import "errors"
+
+// kernelKeyringSupported reports whether the kernel keyring is available on the platform.
+const kernelKeyringSupported = false
+
+// readKernelKeyring is not supported outside of Linux.
+func readKernelKeyring(keyring string) ([][]byte, error) {
+	return nil, errors.New("kernel keyring is only supported on Linux")
+}
@@ -0,0 +1,102 @@
+// Package keystore holds the public keys the verifier trusts.
+//
+// The keys are embedded into the binary at build time. By default, the production
+// keys of Red Hat Insights are used; building with the 'staging' tag
+// (`go build -tags staging`) embeds the keys of the non-production signing pipeline instead,
+// and building with the 'development' tag embeds the key developers sign local playbooks with.
+// The keys of one environment are never trusted by the binaries of another one.
+//
+// Each environment is a directory of ASCII-armored keys. During key rotation it contains
+// both the old and the new key; they are tried in the lexical order of their file names.
+// Additional keys can be loaded at runtime from a directory with the same layout, and
+// the embedded keys can be replaced by the keys of other trust stores, see Store.
+//
+// The file 'revoked.txt' of each environment lists the fingerprints of signing keys
+// that have been revoked. The file 'self-test.yml' is a sample playbook signed by the key
+// of the environment; it has to be signed again whenever the key is replaced.
+package keystore
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+)
+
+// ErrNoEmbeddedKeys is returned when no key of the environment has been embedded into the binary.
+var ErrNoEmbeddedKeys = errors.New("no signing keys are embedded")
+
+// PublicKeys returns the ASCII-armored public keys embedded into the binary.
+//
+// It fails with ErrNoEmbeddedKeys rather than returning no keys, so that a binary built
+// before the keys of its environment were added says so instead of trusting nothing silently.
+func PublicKeys() ([][]byte, error) {
+	publicKeys, err := readPublicKeys(keys, keysDirectory)
+	if err != nil {
+		return nil, err
+	}
+	if len(publicKeys) == 0 {
+		return nil, fmt.Errorf("%w for the %s environment", ErrNoEmbeddedKeys, environment)
+	}
+	return publicKeys, nil
+}
+
+// RevokedKeys returns the embedded list of fingerprints of revoked signing keys.
+func RevokedKeys() ([]byte, error) {
+	return fs.ReadFile(keys, path.Join(keysDirectory, "revoked.txt"))
+}
+
+// SelfTestPlaybook returns the embedded sample playbook signed by the key of the environment.
+func SelfTestPlaybook() ([]byte, error) {
+	sample, err := fs.ReadFile(keys, path.Join(keysDirectory, "self-test.yml"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("no sample playbook is embedded for the %s environment: %w", environment, err)
+	}
+	return sample, err
+}
+
+// DirectoryPublicKeys returns the public keys stored in the directory.
+//
+// It allows disconnected environments that sign content with their own key to trust it
+// without rebuilding the binary. Only files with the extension '.asc' or '.gpg' (OpenPGP keys)
+// and '.pub' (minisign and signify keys) are read.
+func DirectoryPublicKeys(directory string) ([][]byte, error) {
+	publicKeys, err := readPublicKeys(os.DirFS(directory), ".")
+	if err != nil {
+		return nil, fmt.Errorf("could not read keys from %s: %w", directory, err)
+	}
+	return publicKeys, nil
+}
+
+// readPublicKeys reads the keys in the directory in the lexical order of their file names.
+func readPublicKeys(fsys fs.FS, directory string) ([][]byte, error) {
+	entries, err := fs.ReadDir(fsys, directory)
+	if err != nil {
+		return nil, err
+	}
+
+	var publicKeys [][]byte
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() || !isKeyFile(entry.Name()) {
+			continue
+		}
+		publicKey, err := fs.ReadFile(fsys, path.Join(directory, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		publicKeys = append(publicKeys, publicKey)
+	}
+	return publicKeys, nil
+}
+
+// isKeyFile reports whether the file name has an extension of a public key.
+func isKeyFile(name string) bool {
+	extension := path.Ext(name)
+	return extension == ".asc" || extension == ".gpg" || extension == ".pub"
+}
+
+// Environment returns the name of the signing environment the embedded keys belong to.
+func Environment() string {
+	return environment
+}
@@ -0,0 +1,63 @@
+package keystore
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"com.github/m-horky/playbook-verifier/pkg/verifier"
+)
+
+// TestEnvironmentKeys checks that the binaries of an environment only trust its own keys;
+// it is run by `go test` for production and with `-tags staging` or `-tags development` for the others.
+func TestEnvironmentKeys(t *testing.T) {
+	publicKeys, err := PublicKeys()
+	if environment == "production" && errors.Is(err, ErrNoEmbeddedKeys) {
+		if _, err = SelfTestPlaybook(); err == nil {
+			t.Error("SelfTestPlaybook() error = nil, want no sample without the keys")
+		}
+		return
+	}
+	if err != nil {
+		t.Fatalf("PublicKeys() error = %v", err)
+	}
+
+	others, err := filepath.Glob(filepath.Join("keys", "*", "*.gpg"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, other := range others {
+		if filepath.Base(filepath.Dir(other)) == environment {
+			continue
+		}
+		key, err := os.ReadFile(other)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if slices.ContainsFunc(publicKeys, func(publicKey []byte) bool { return bytes.Equal(publicKey, key) }) {
+			t.Errorf("PublicKeys() of the %s environment contain %s", environment, other)
+		}
+	}
+
+	keyring, err := verifier.NewKeyring(publicKeys...)
+	if err != nil {
+		t.Fatalf("NewKeyring() error = %v", err)
+	}
+	sample, err := SelfTestPlaybook()
+	if err != nil {
+		t.Fatalf("SelfTestPlaybook() error = %v", err)
+	}
+	report, err := verifier.VerifyContext(context.Background(), verifier.PlaybookContentType, sample, keyring, verifier.Policy{})
+	if err != nil {
+		t.Fatalf("VerifyContext() error = %v, want the sample signed by a key of the %s environment", err, environment)
+	}
+	if !slices.ContainsFunc(keyring.Fingerprints(), func(fingerprint string) bool {
+		return len(fingerprint) >= 16 && fingerprint[len(fingerprint)-16:] == report.Plays[0].KeyID
+	}) {
+		t.Errorf("sample signed by %s, want a key of %v", report.Plays[0].KeyID, keyring.Fingerprints())
+	}
+}
@@ -0,0 +1,15 @@
+//go:build !staging && !development
+
+package keystore
+
+import "embed"
+
+// The directory is embedded as a whole, since it contains no key until the production key is added.
+//
+//go:embed keys/production
+var keys embed.FS
+
+const (
+	environment   = "production"
+	keysDirectory = "keys/production"
+)
@@ -0,0 +1,239 @@
+package keystore
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"com.github/m-horky/playbook-verifier/internal/httpclient"
+)
+
+const (
+	// DefaultRemoteRefresh is the age of the cached key bundle after which it is fetched again.
+	DefaultRemoteRefresh = 24 * time.Hour
+	// RemoteSignatureSuffix is appended to the URL of the key bundle to get its detached signature.
+	RemoteSignatureSuffix = ".sig"
+	// remoteBundleFile is the name of the cached key bundle in the cache directory.
+	remoteBundleFile = "bundle.asc"
+	// maxRemoteSize limits the size of the key bundle and of its signature.
+	maxRemoteSize = 1 << 20
+)
+
+// RemoteStore holds the keys of a bundle fetched from an HTTPS endpoint, so keys can be
+// rotated without a new release.
+//
+// The bundle contains ASCII-armored public keys, and its detached signature is found at
+// the URL with RemoteSignatureSuffix. It is only accepted when Verify accepts the signature,
+// and when it is not older than the bundle accepted before, so an old bundle cannot be replayed.
+// When the endpoint cannot be reached, the cached bundle is used; when there is none,
+// the store holds no keys and the verifier falls back to the other stores.
+type RemoteStore struct {
+	URL string
+	// Pins are the base64 SHA-256 digests of the public keys (SubjectPublicKeyInfo) the certificate
+	// chain of the endpoint has to contain, as in HPKP. Empty means any certificate the roots trust.
+	Pins []string
+	// HTTP is how the endpoint is reached; its VerifyConnection is replaced by the check of the pins.
+	HTTP httpclient.Config
+	// CacheDir is the directory the accepted bundle is cached in. Empty means no cache.
+	CacheDir string
+	// Refresh is the age of the cached bundle after which it is fetched again.
+	Refresh time.Duration
+	// Verify checks the detached signature of the bundle and returns the time it was created.
+	Verify func(bundle, signature []byte) (time.Time, error)
+}
+
+// remoteBundle is a key bundle accepted by Verify.
+type remoteBundle struct {
+	bundle    []byte
+	signature []byte
+	created   time.Time
+}
+
+// PublicKeys implements Store.
+//
+// Errors of the endpoint are logged rather than returned, since the store is meant to work offline.
+func (s *RemoteStore) PublicKeys() ([][]byte, error) {
+	if s.Verify == nil {
+		return nil, errors.New("key bundle cannot be verified")
+	}
+	cached, age, err := s.readCache()
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		slog.Warn("ignoring cached key bundle", slog.String("directory", s.CacheDir), slog.Any("error", err))
+	}
+	if cached != nil && age < s.Refresh {
+		return [][]byte{cached.bundle}, nil
+	}
+
+	fetched, err := s.fetch()
+	switch {
+	case err != nil:
+		slog.Warn("could not fetch key bundle", slog.String("url", s.URL), slog.Any("error", err))
+	case cached != nil && fetched.created.Before(cached.created):
+		slog.Error("refusing key bundle older than the cached one", slog.String("url", s.URL),
+			slog.Time("created", fetched.created), slog.Time("cached", cached.created))
+	default:
+		if err = s.writeCache(fetched); err != nil {
+			slog.Warn("could not cache key bundle", slog.String("directory", s.CacheDir), slog.Any("error", err))
+		}
+		return [][]byte{fetched.bundle}, nil
+	}
+	if cached == nil {
+		slog.Warn("no key bundle available, using the other trust stores only", slog.String("url", s.URL))
+		return nil, nil
+	}
+	slog.Info("using cached key bundle", slog.String("url", s.URL), slog.Time("created", cached.created))
+	return [][]byte{cached.bundle}, nil
+}
+
+func (s *RemoteStore) String() string {
+	return s.URL
+}
+
+// fetch downloads the bundle and its signature and verifies them.
+func (s *RemoteStore) fetch() (*remoteBundle, error) {
+	config := s.HTTP
+	config.VerifyConnection = s.verifyPins
+	client, err := httpclient.New(config)
+	if err != nil {
+		return nil, err
+	}
+	// Redirects would leave the pinned endpoint.
+	client.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+	bundle, err := get(client, s.URL)
+	if err != nil {
+		return nil, err
+	}
+	signature, err := get(client, s.URL+RemoteSignatureSuffix)
+	if err != nil {
+		return nil, err
+	}
+	return s.verify(bundle, signature)
+}
+
+// verifyPins accepts the connection when its verified chain contains a pinned public key.
+func (s *RemoteStore) verifyPins(state tls.ConnectionState) error {
+	if len(s.Pins) == 0 {
+		return nil
+	}
+	for _, chain := range state.VerifiedChains {
+		for _, certificate := range chain {
+			if slices.Contains(s.Pins, Pin(certificate)) {
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("certificate of %s does not match any pin", state.ServerName)
+}
+
+// Pin returns the pin of the public key of the certificate: the base64 SHA-256 digest of its
+// SubjectPublicKeyInfo, as printed by
+// 'openssl x509 -pubkey -noout | openssl pkey -pubin -outform der | openssl dgst -sha256 -binary | base64'.
+func Pin(certificate *x509.Certificate) string {
+	digest := sha256.Sum256(certificate.RawSubjectPublicKeyInfo)
+	return base64.StdEncoding.EncodeToString(digest[:])
+}
+
+// get returns the body of the successful response to a GET request.
+func get(client *http.Client, url string) ([]byte, error) {
+	response, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s answered %s", url, response.Status)
+	}
+	content, err := io.ReadAll(io.LimitReader(response.Body, maxRemoteSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(content) > maxRemoteSize {
+		return nil, fmt.Errorf("%s is larger than %d bytes", url, maxRemoteSize)
+	}
+	return content, nil
+}
+
+// verify checks the signature of the bundle.
+func (s *RemoteStore) verify(bundle, signature []byte) (*remoteBundle, error) {
+	if !bytes.Contains(bundle, []byte("-----BEGIN PGP PUBLIC KEY BLOCK-----")) {
+		return nil, errors.New("key bundle does not contain ASCII-armored public keys")
+	}
+	created, err := s.Verify(bundle, signature)
+	if err != nil {
+		return nil, fmt.Errorf("invalid signature of key bundle: %w", err)
+	}
+	return &remoteBundle{bundle: bundle, signature: signature, created: created}, nil
+}
+
+// readCache returns the cached bundle, verified again since the cache is writable by other
+// means than the verifier, and its age.
+func (s *RemoteStore) readCache() (*remoteBundle, time.Duration, error) {
+	if s.CacheDir == "" {
+		return nil, 0, os.ErrNotExist
+	}
+	path := filepath.Join(s.CacheDir, remoteBundleFile)
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	bundle, err := os.ReadFile(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	signature, err := os.ReadFile(path + RemoteSignatureSuffix)
+	if err != nil {
+		return nil, 0, err
+	}
+	cached, err := s.verify(bundle, signature)
+	if err != nil {
+		return nil, 0, err
+	}
+	return cached, time.Since(info.ModTime()), nil
+}
+
+// writeCache replaces the cached bundle. The signature is written first, so an interrupted
+// write leaves a bundle whose signature does not match, which is ignored.
+func (s *RemoteStore) writeCache(accepted *remoteBundle) error {
+	if s.CacheDir == "" {
+		return nil
+	}
+	if err := os.MkdirAll(s.CacheDir, 0o700); err != nil {
+		return err
+	}
+	path := filepath.Join(s.CacheDir, remoteBundleFile)
+	if err := writeAtomically(path+RemoteSignatureSuffix, accepted.signature); err != nil {
+		return err
+	}
+	return writeAtomically(path, accepted.bundle)
+}
+
+// writeAtomically replaces the file through a temporary file in the same directory.
+func writeAtomically(path string, content []byte) error {
+	file, err := os.CreateTemp(filepath.Dir(path), "."+strings.TrimPrefix(filepath.Base(path), ".")+"-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+	if _, err = file.Write(content); err != nil {
+		file.Close()
+		return err
+	}
+	if err = file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), path)
+}
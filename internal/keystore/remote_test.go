@@ -0,0 +1,125 @@
+package keystore
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"com.github/m-horky/playbook-verifier/internal/httpclient"
+)
+
+// testBundle is the bundle served by testEndpoint, as a public key block.
+func testBundle(name string) string {
+	return "-----BEGIN PGP PUBLIC KEY BLOCK-----\n" + name + "\n-----END PGP PUBLIC KEY BLOCK-----\n"
+}
+
+// testSignature signs the bundle for testVerify: the signature is its creation time and digest.
+func testSignature(bundle string, created time.Time) string {
+	digest := sha256.Sum256([]byte(bundle))
+	return created.Format(time.RFC3339) + " " + hex.EncodeToString(digest[:])
+}
+
+// testVerify verifies signatures of testSignature.
+func testVerify(bundle, signature []byte) (time.Time, error) {
+	created, digest, _ := strings.Cut(string(signature), " ")
+	if want := sha256.Sum256(bundle); digest != hex.EncodeToString(want[:]) {
+		return time.Time{}, errors.New("bad signature")
+	}
+	return time.Parse(time.RFC3339, created)
+}
+
+// testEndpoint serves the bundle and its signature over HTTPS, and counts the requests.
+func testEndpoint(t *testing.T, bundle, signature *string, requests *int) (*httptest.Server, *RemoteStore) {
+	t.Helper()
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*requests++
+		switch r.URL.Path {
+		case "/keys.asc":
+			w.Write([]byte(*bundle))
+		case "/keys.asc.sig":
+			w.Write([]byte(*signature))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	store := &RemoteStore{
+		URL:      server.URL + "/keys.asc",
+		HTTP:     httpclient.Config{RootCAs: server.Client().Transport.(*http.Transport).TLSClientConfig.RootCAs},
+		CacheDir: t.TempDir(),
+		Verify:   testVerify,
+	}
+	return server, store
+}
+
+func TestRemoteStore(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+	bundle, requests := testBundle("first"), 0
+	signature := testSignature(bundle, now)
+	server, store := testEndpoint(t, &bundle, &signature, &requests)
+
+	keys, err := store.PublicKeys()
+	if err != nil || len(keys) != 1 || string(keys[0]) != bundle {
+		t.Fatalf("PublicKeys() = %q, %v, want the bundle", keys, err)
+	}
+
+	// The cached bundle is used until it is to be refreshed.
+	store.Refresh = time.Hour
+	bundle = testBundle("second")
+	signature = testSignature(bundle, now.Add(time.Minute))
+	requests = 0
+	if keys, _ = store.PublicKeys(); string(keys[0]) != testBundle("first") || requests != 0 {
+		t.Errorf("PublicKeys() = %q after %d requests, want the cached bundle", keys, requests)
+	}
+	store.Refresh = 0
+	if keys, _ = store.PublicKeys(); string(keys[0]) != bundle {
+		t.Errorf("PublicKeys() = %q, want the refreshed bundle", keys)
+	}
+
+	// An older bundle is not accepted.
+	bundle = testBundle("old")
+	signature = testSignature(bundle, now.Add(-time.Hour))
+	if keys, _ = store.PublicKeys(); string(keys[0]) != testBundle("second") {
+		t.Errorf("PublicKeys() = %q, want the cached bundle instead of an older one", keys)
+	}
+
+	// Neither is a bundle with an invalid signature.
+	bundle = testBundle("forged")
+	signature = testSignature(testBundle("third"), now.Add(time.Hour))
+	if keys, _ = store.PublicKeys(); string(keys[0]) != testBundle("second") {
+		t.Errorf("PublicKeys() = %q, want the cached bundle instead of a forged one", keys)
+	}
+
+	// The cache is used offline.
+	server.Close()
+	if keys, _ = store.PublicKeys(); string(keys[0]) != testBundle("second") {
+		t.Errorf("PublicKeys() = %q, want the cached bundle offline", keys)
+	}
+	// Without it, there are no keys.
+	store.CacheDir = t.TempDir()
+	if keys, err = store.PublicKeys(); err != nil || len(keys) != 0 {
+		t.Errorf("PublicKeys() = %q, %v, want no keys offline without a cache", keys, err)
+	}
+}
+
+func TestRemoteStorePins(t *testing.T) {
+	bundle, requests := testBundle("pinned"), 0
+	signature := testSignature(bundle, time.Now())
+	server, store := testEndpoint(t, &bundle, &signature, &requests)
+	store.CacheDir = ""
+
+	store.Pins = []string{"AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA="}
+	if keys, _ := store.PublicKeys(); len(keys) != 0 {
+		t.Errorf("PublicKeys() = %q, want no keys from an endpoint not matching the pins", keys)
+	}
+
+	store.Pins = append(store.Pins, Pin(server.Certificate()))
+	if keys, _ := store.PublicKeys(); len(keys) != 1 || string(keys[0]) != bundle {
+		t.Errorf("PublicKeys() = %q, want the bundle of the pinned endpoint", keys)
+	}
+}
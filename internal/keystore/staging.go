@@ -0,0 +1,13 @@
+//go:build staging && !development
+
+package keystore
+
+import "embed"
+
+//go:embed keys/staging/*.gpg keys/staging/revoked.txt keys/staging/self-test.yml
+var keys embed.FS
+
+const (
+	environment   = "staging"
+	keysDirectory = "keys/staging"
+)
@@ -0,0 +1,127 @@
+package keystore
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Store is a backend the trusted public keys are loaded from.
+//
+// Hardened environments may keep the trust anchor out of the binary and the file system,
+// in the kernel keyring or sealed by the TPM, and leave the embedded keys out.
+type Store interface {
+	// PublicKeys returns the public keys of the store, in the order they are tried.
+	PublicKeys() ([][]byte, error)
+	// String describes the store in logs, in the syntax of ParseStore.
+	String() string
+}
+
+// DefaultStore is the store of the keys embedded into the binary.
+const DefaultStore = "embedded"
+
+// ParseStore returns the store described by the specification:
+//
+//   - 'embedded' are the keys embedded into the binary,
+//   - 'dir:PATH' are the keys in the directory, see DirectoryPublicKeys,
+//   - 'keyring:KEYRING' are the keys in the Linux kernel keyring, see KernelKeyringStore,
+//   - 'tpm:OBJECT' are the keys sealed by the TPM, see TPMStore.
+//
+// The specification may be prefixed by the trust level of the keys of the store: 'trusted:',
+// the default, or 'warn-only:', see WarnOnlyStore.
+func ParseStore(spec string) (Store, error) {
+	if location, ok := strings.CutPrefix(spec, WarnOnly+":"); ok {
+		store, err := ParseStore(location)
+		if err != nil {
+			return nil, err
+		}
+		return WarnOnlyStore{store}, nil
+	}
+	spec = strings.TrimPrefix(spec, Trusted+":")
+	kind, location, _ := strings.Cut(spec, ":")
+	switch {
+	case spec == DefaultStore:
+		return EmbeddedStore{}, nil
+	case location == "":
+		return nil, fmt.Errorf("invalid trust store %q: expected %s, dir:PATH, keyring:KEYRING or tpm:OBJECT", spec, DefaultStore)
+	case kind == "dir":
+		return DirectoryStore{Directory: location}, nil
+	case kind == "keyring" && !kernelKeyringSupported:
+		return nil, fmt.Errorf("trust store %s is only supported on Linux", kind)
+	case kind == "keyring":
+		return KernelKeyringStore{Keyring: location}, nil
+	case kind == "tpm":
+		return &TPMStore{Object: location, Tools: DefaultTPMTools}, nil
+	default:
+		return nil, fmt.Errorf("unsupported trust store %q", kind)
+	}
+}
+
+// Trust levels of the keys of a store.
+const (
+	Trusted  = "trusted"
+	WarnOnly = "warn-only"
+)
+
+// WarnOnlyStore holds keys whose signatures are accepted with a warning only, e.g. the keys of
+// a new signing infrastructure during its staged rollout.
+type WarnOnlyStore struct {
+	Store
+}
+
+func (s WarnOnlyStore) String() string {
+	return WarnOnly + ":" + s.Store.String()
+}
+
+// Unwrap returns the store holding the keys, without their trust level.
+func Unwrap(store Store) Store {
+	if warnOnly, ok := store.(WarnOnlyStore); ok {
+		return warnOnly.Store
+	}
+	return store
+}
+
+// EmbeddedStore holds the keys embedded into the binary.
+type EmbeddedStore struct{}
+
+// PublicKeys implements Store.
+func (EmbeddedStore) PublicKeys() ([][]byte, error) {
+	return PublicKeys()
+}
+
+func (EmbeddedStore) String() string {
+	return DefaultStore
+}
+
+// DirectoryStore holds the keys stored in a directory.
+type DirectoryStore struct {
+	Directory string
+}
+
+// PublicKeys implements Store.
+func (s DirectoryStore) PublicKeys() ([][]byte, error) {
+	return DirectoryPublicKeys(s.Directory)
+}
+
+func (s DirectoryStore) String() string {
+	return "dir:" + s.Directory
+}
+
+// KernelKeyringStore holds the keys in a keyring of the Linux kernel, out of the file system.
+//
+// The keys are the payloads of the 'user' keys linked to the keyring, tried in the lexical
+// order of their descriptions. They are added e.g. by
+// 'keyctl padd user insights-2024 %:playbook-verifier < key.asc'.
+type KernelKeyringStore struct {
+	// Keyring is a shorthand of keyctl(1) (e.g. '@u'), a serial number,
+	// or the name of a keyring reachable from the session keyring.
+	Keyring string
+}
+
+// PublicKeys implements Store.
+func (s KernelKeyringStore) PublicKeys() ([][]byte, error) {
+	return readKernelKeyring(s.Keyring)
+}
+
+func (s KernelKeyringStore) String() string {
+	return "keyring:" + s.Keyring
+}
@@ -0,0 +1,103 @@
+package keystore
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseStore(t *testing.T) {
+	tests := []struct {
+		spec string
+		want string
+	}{
+		{"embedded", "embedded"},
+		{"dir:/etc/keys", "dir:/etc/keys"},
+		{"tpm:0x81010001", "tpm:0x81010001"},
+		{"trusted:dir:/etc/keys", "dir:/etc/keys"},
+		{"warn-only:dir:/etc/keys/new", "warn-only:dir:/etc/keys/new"},
+		{"warn-only:embedded", "warn-only:embedded"},
+	}
+	for _, tt := range tests {
+		store, err := ParseStore(tt.spec)
+		if err != nil {
+			t.Fatalf("ParseStore(%s) error = %v", tt.spec, err)
+		}
+		if store.String() != tt.want {
+			t.Errorf("ParseStore(%s) = %s, want %s", tt.spec, store, tt.want)
+		}
+	}
+
+	for _, spec := range []string{"", "dir", "dir:", "vault:secret/keys", "warn-only:", "warn-only:dir:"} {
+		if _, err := ParseStore(spec); err == nil {
+			t.Errorf("ParseStore(%q) error = nil, want error", spec)
+		}
+	}
+}
+
+// fakeTPMTools writes scripts mimicking tpm2-tools into a directory and returns their prefix.
+// The loaded and the persistent objects contain the path of the object they come from.
+func fakeTPMTools(t *testing.T) string {
+	t.Helper()
+	directory := t.TempDir()
+	scripts := map[string]string{
+		"createprimary": `touch "$5"`,
+		// -Q -C primary -u public -r private -c context
+		"load": `[ -f "$3" ] && echo "key of $5 and $7" > "$9"`,
+		// -Q -c object [-p auth]
+		"unseal": `[ "$4" = "-p" ] && [ "$5" != "pcr:sha256:7" ] && { echo "policy check failed" >&2; exit 1; }
+if [ -f "$3" ]; then cat "$3"; else echo "key of $3"; fi`,
+	}
+	for name, script := range scripts {
+		if err := os.WriteFile(filepath.Join(directory, "tpm2_"+name), []byte("#!/bin/sh\n"+script+"\n"), 0o755); err != nil {
+			t.Fatalf("os.WriteFile() error = %v", err)
+		}
+	}
+	return filepath.Join(directory, "tpm2_")
+}
+
+func TestTPMStorePersistent(t *testing.T) {
+	store := &TPMStore{Object: "0x81010001,0x81010002", Auth: "pcr:sha256:7", Tools: fakeTPMTools(t)}
+	keys, err := store.PublicKeys()
+	if err != nil {
+		t.Fatalf("PublicKeys() error = %v", err)
+	}
+	if len(keys) != 2 || string(keys[0]) != "key of 0x81010001\n" || string(keys[1]) != "key of 0x81010002\n" {
+		t.Errorf("PublicKeys() = %q", keys)
+	}
+
+	store.Auth = "pcr:sha256:0"
+	if _, err = store.PublicKeys(); err == nil || !strings.Contains(err.Error(), "policy check failed") {
+		t.Errorf("PublicKeys() error = %v, want the error of tpm2_unseal", err)
+	}
+}
+
+func TestTPMStoreDirectory(t *testing.T) {
+	sealed := t.TempDir()
+	for _, name := range []string{"b.pub", "b.priv", "a.pub", "a.priv"} {
+		if err := os.WriteFile(filepath.Join(sealed, name), nil, 0o600); err != nil {
+			t.Fatalf("os.WriteFile() error = %v", err)
+		}
+	}
+	contexts := t.TempDir()
+
+	store := &TPMStore{Object: sealed, Tools: fakeTPMTools(t), TmpDir: contexts}
+	if store.Persistent() {
+		t.Error("Persistent() = true for a directory")
+	}
+	keys, err := store.PublicKeys()
+	if err != nil {
+		t.Fatalf("PublicKeys() error = %v", err)
+	}
+	want := []string{
+		"key of " + filepath.Join(sealed, "a.pub") + " and " + filepath.Join(sealed, "a.priv") + "\n",
+		"key of " + filepath.Join(sealed, "b.pub") + " and " + filepath.Join(sealed, "b.priv") + "\n",
+	}
+	if len(keys) != 2 || string(keys[0]) != want[0] || string(keys[1]) != want[1] {
+		t.Errorf("PublicKeys() = %q, want %q", keys, want)
+	}
+	if entries, _ := os.ReadDir(contexts); len(entries) != 0 {
+		t.Errorf("temporary contexts were not removed: %v", entries)
+	}
+}
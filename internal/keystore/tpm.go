@@ -0,0 +1,121 @@
+package keystore
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultTPMTools is the prefix of the tpm2-tools programs TPMStore runs, looked up in PATH.
+const DefaultTPMTools = "tpm2_"
+
+// TPMStore holds keys sealed by the TPM, so they can only be read on the machine,
+// and only in the state the sealing policy allows (e.g. the PCRs of a measured boot).
+//
+// The keys are unsealed by tpm2-tools. The object is either a comma-separated list of
+// persistent handles (e.g. '0x81010001') of sealed objects, or a directory of sealed objects
+// saved as NAME.pub and NAME.priv pairs by 'tpm2_create', under the primary key created by
+// 'tpm2_createprimary -C o' with its default template. Keys of a directory are tried
+// in the lexical order of their names.
+type TPMStore struct {
+	Object string
+	// Auth is the authorization of the sealed objects passed to 'tpm2_unseal -p',
+	// e.g. 'pcr:sha256:0,7'. Empty means none.
+	Auth string
+	// Tools is the prefix of the tpm2-tools programs, see DefaultTPMTools.
+	Tools string
+	// TmpDir contains the temporary directory the objects of a directory are loaded through.
+	// Empty means the system temporary directory.
+	TmpDir string
+}
+
+// Persistent reports whether the object is made of persistent handles,
+// which are unsealed without temporary files.
+func (s *TPMStore) Persistent() bool {
+	return strings.HasPrefix(s.Object, "0x")
+}
+
+// PublicKeys implements Store.
+func (s *TPMStore) PublicKeys() ([][]byte, error) {
+	if s.Persistent() {
+		var publicKeys [][]byte
+		for _, handle := range strings.Split(s.Object, ",") {
+			publicKey, err := s.unseal(strings.TrimSpace(handle))
+			if err != nil {
+				return nil, err
+			}
+			publicKeys = append(publicKeys, publicKey)
+		}
+		return publicKeys, nil
+	}
+	return s.unsealDirectory()
+}
+
+func (s *TPMStore) String() string {
+	return "tpm:" + s.Object
+}
+
+// unsealDirectory loads the sealed objects of the directory under the primary key and unseals them.
+func (s *TPMStore) unsealDirectory() ([][]byte, error) {
+	names, err := filepath.Glob(filepath.Join(s.Object, "*.pub"))
+	if err != nil {
+		return nil, err
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("no sealed objects found in %s", s.Object)
+	}
+
+	// The contexts of the loaded objects have to be passed to the tools as files.
+	contexts, err := os.MkdirTemp(s.TmpDir, "playbook-verifier-tpm-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(contexts)
+	primary := filepath.Join(contexts, "primary.ctx")
+	if _, err = s.run("createprimary", "-Q", "-C", "o", "-c", primary); err != nil {
+		return nil, err
+	}
+
+	var publicKeys [][]byte
+	for _, public := range names {
+		name := strings.TrimSuffix(filepath.Base(public), ".pub")
+		private := strings.TrimSuffix(public, ".pub") + ".priv"
+		context := filepath.Join(contexts, name+".ctx")
+		if _, err = s.run("load", "-Q", "-C", primary, "-u", public, "-r", private, "-c", context); err != nil {
+			return nil, err
+		}
+		publicKey, err := s.unseal(context)
+		if err != nil {
+			return nil, err
+		}
+		publicKeys = append(publicKeys, publicKey)
+	}
+	return publicKeys, nil
+}
+
+// unseal returns the content of the sealed object, given by a handle or a context file.
+func (s *TPMStore) unseal(object string) ([]byte, error) {
+	args := []string{"-Q", "-c", object}
+	if s.Auth != "" {
+		args = append(args, "-p", s.Auth)
+	}
+	return s.run("unseal", args...)
+}
+
+// run runs the tpm2-tools program and returns its standard output.
+func (s *TPMStore) run(tool string, args ...string) ([]byte, error) {
+	tools := s.Tools
+	if tools == "" {
+		tools = DefaultTPMTools
+	}
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(tools+tool, args...)
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("%s failed: %w: %s", filepath.Base(cmd.Path), err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
@@ -0,0 +1,149 @@
+// Package manifest checks files against a manifest of their SHA-256 digests in the format
+// of sha256sum, e.g. the roles and templates a playbook uses.
+//
+// A manifest only protects the files if it is signed itself; the signature is checked
+// by the caller, like any detached signature.
+package manifest
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"com.github/m-horky/playbook-verifier/internal/hygiene"
+)
+
+var (
+	// ErrMalformedManifest means the manifest is not in the format of sha256sum.
+	ErrMalformedManifest = errors.New("malformed manifest")
+	// ErrMismatch means the files do not match the manifest.
+	ErrMismatch = errors.New("files do not match the manifest")
+	// ErrUntrusted means the signature of the manifest could not be verified.
+	ErrUntrusted = errors.New("manifest is not trusted")
+)
+
+// Entry is a file listed in the manifest.
+type Entry struct {
+	// Name is the slash-separated path of the file, relative to the directory of the manifest.
+	Name string
+	// Digest is the SHA-256 digest of the file.
+	Digest []byte
+}
+
+// Parse reads the entries of the manifest: a hex-encoded SHA-256 digest and the name of the file
+// on each line, separated by two spaces (text mode of sha256sum) or by a space and an asterisk
+// (binary mode). Names that are not local, e.g. '../x' or '/etc/x', are refused.
+func Parse(content []byte) ([]Entry, error) {
+	var entries []Entry
+	names := map[string]bool{}
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSuffix(scanner.Text(), "\r")
+		if text == "" {
+			continue
+		}
+		encoded, name, ok := strings.Cut(text, " ")
+		if ok && (strings.HasPrefix(name, " ") || strings.HasPrefix(name, "*")) {
+			name = name[1:]
+		}
+		digest, err := hex.DecodeString(encoded)
+		if !ok || err != nil || len(digest) != sha256.Size || name == "" {
+			return nil, fmt.Errorf("%w: line %d is not a digest and a name", ErrMalformedManifest, line)
+		}
+		name = path.Clean(name)
+		if !filepath.IsLocal(filepath.FromSlash(name)) {
+			return nil, fmt.Errorf("%w: line %d names %s outside of the directory of the manifest", ErrMalformedManifest, line, name)
+		}
+		if names[name] {
+			return nil, fmt.Errorf("%w: %s is listed more than once", ErrMalformedManifest, name)
+		}
+		names[name] = true
+		entries = append(entries, Entry{Name: name, Digest: digest})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrMalformedManifest, err)
+	}
+	return entries, nil
+}
+
+// CheckDirectory checks that every file of the directory tree is listed in the manifest and matches
+// its digest, so files can neither be changed nor added. The ignored paths, relative to the directory,
+// are not checked, e.g. the manifest and its signature.
+//
+// Symbolic links are refused, since they could point outside of the directory.
+func CheckDirectory(directory string, entries []Entry, ignored ...string) error {
+	listed := map[string][]byte{}
+	for _, entry := range entries {
+		listed[entry.Name] = entry.Digest
+	}
+	for _, name := range ignored {
+		if name = path.Clean(filepath.ToSlash(name)); listed[name] == nil {
+			listed[name] = nil
+		}
+	}
+
+	checked := map[string]bool{}
+	err := filepath.WalkDir(directory, func(file string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		relative, err := filepath.Rel(directory, file)
+		if err != nil {
+			return err
+		}
+		name := filepath.ToSlash(relative)
+		digest, ok := listed[name]
+		switch {
+		case !ok:
+			return fmt.Errorf("%w: %s is not listed", ErrMismatch, name)
+		case digest == nil:
+			return nil
+		case !d.Type().IsRegular():
+			return fmt.Errorf("%w: %s is not a regular file", ErrMismatch, name)
+		}
+		sum, err := hashFile(file)
+		if err != nil {
+			return err
+		}
+		if !hygiene.Equal(sum, digest) {
+			return fmt.Errorf("%w: digest of %s differs", ErrMismatch, name)
+		}
+		checked[name] = true
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if !checked[entry.Name] {
+			return fmt.Errorf("%w: %s is missing", ErrMismatch, entry.Name)
+		}
+	}
+	return nil
+}
+
+// hashFile returns the SHA-256 digest of the file.
+func hashFile(name string) ([]byte, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	hash := sha256.New()
+	if _, err = io.Copy(hash, file); err != nil {
+		return nil, err
+	}
+	return hash.Sum(nil), nil
+}
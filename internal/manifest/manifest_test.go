@@ -0,0 +1,118 @@
+package manifest
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeFiles creates the files in the directory and returns the manifest of their digests.
+func writeFiles(t *testing.T, directory string, files map[string]string) string {
+	t.Helper()
+	var b strings.Builder
+	for name, content := range files {
+		path := filepath.Join(directory, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		sum := sha256.Sum256([]byte(content))
+		fmt.Fprintf(&b, "%s  %s\n", hex.EncodeToString(sum[:]), name)
+	}
+	return b.String()
+}
+
+func TestParse(t *testing.T) {
+	digest := strings.Repeat("ab", sha256.Size)
+	tests := []struct {
+		name    string
+		content string
+		want    []string
+		err     error
+	}{
+		{"text mode", digest + "  roles/web/tasks/main.yml\n", []string{"roles/web/tasks/main.yml"}, nil},
+		{"binary mode", digest + " *playbook.yml\r\n\n", []string{"playbook.yml"}, nil},
+		{"cleaned name", digest + "  ./roles//web/../db/main.yml\n", []string{"roles/db/main.yml"}, nil},
+		{"empty", "", nil, nil},
+		{"no name", digest + "\n", nil, ErrMalformedManifest},
+		{"short digest", "abcd  playbook.yml\n", nil, ErrMalformedManifest},
+		{"outside", digest + "  ../playbook.yml\n", nil, ErrMalformedManifest},
+		{"absolute", digest + "  /etc/passwd\n", nil, ErrMalformedManifest},
+		{"listed twice", digest + "  a.yml\n" + digest + "  ./a.yml\n", nil, ErrMalformedManifest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, err := Parse([]byte(tt.content))
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Parse() error = %v, want %v", err, tt.err)
+			}
+			var names []string
+			for _, entry := range entries {
+				names = append(names, entry.Name)
+			}
+			if fmt.Sprint(names) != fmt.Sprint(tt.want) {
+				t.Errorf("Parse() = %v, want %v", names, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckDirectory(t *testing.T) {
+	files := map[string]string{
+		"playbook.yml":                   "- name: play\n",
+		"roles/web/tasks/main.yml":       "- name: task\n",
+		"roles/web/templates/index.html": "<html></html>\n",
+	}
+
+	tests := []struct {
+		name   string
+		change func(t *testing.T, directory string)
+		want   error
+	}{
+		{"unchanged", func(*testing.T, string) {}, nil},
+		{"modified", func(t *testing.T, directory string) {
+			writeFiles(t, directory, map[string]string{"roles/web/tasks/main.yml": "- name: other task\n"})
+		}, ErrMismatch},
+		{"added", func(t *testing.T, directory string) {
+			writeFiles(t, directory, map[string]string{"roles/web/vars/main.yml": "port: 80\n"})
+		}, ErrMismatch},
+		{"removed", func(t *testing.T, directory string) {
+			if err := os.Remove(filepath.Join(directory, "roles", "web", "templates", "index.html")); err != nil {
+				t.Fatal(err)
+			}
+		}, ErrMismatch},
+		{"replaced by a link", func(t *testing.T, directory string) {
+			path := filepath.Join(directory, "playbook.yml")
+			if err := os.Remove(path); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.Symlink(filepath.Join(directory, "roles", "web", "tasks", "main.yml"), path); err != nil {
+				t.Fatal(err)
+			}
+		}, ErrMismatch},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			directory := t.TempDir()
+			content := writeFiles(t, directory, files)
+			if err := os.WriteFile(filepath.Join(directory, "SHA256SUMS"), []byte(content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			entries, err := Parse([]byte(content))
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			tt.change(t, directory)
+			if err = CheckDirectory(directory, entries, "SHA256SUMS"); !errors.Is(err, tt.want) {
+				t.Errorf("CheckDirectory() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
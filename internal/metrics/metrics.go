@@ -0,0 +1,92 @@
+// Package metrics exposes Prometheus metrics of the verification service.
+package metrics
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"com.github/m-horky/playbook-verifier/pkg/verifier"
+)
+
+// Metrics holds the collectors of the service.
+type Metrics struct {
+	registry      *prometheus.Registry
+	verifications *prometheus.CounterVec
+	latency       prometheus.Histogram
+	trustedKeys   prometheus.Gauge
+}
+
+// New returns the metrics registered in a new registry.
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		verifications: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "playbook_verifier_verifications_total",
+			Help: "Number of verified payloads by result and by the reason of the failure.",
+		}, []string{"result", "reason"}),
+		latency: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    "playbook_verifier_verification_duration_seconds",
+			Help:    "Time it took to verify a payload.",
+			Buckets: prometheus.ExponentialBuckets(0.001, 2, 14),
+		}),
+		trustedKeys: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "playbook_verifier_trusted_keys",
+			Help: "Number of trusted public keys in the keyring.",
+		}),
+	}
+	m.registry.MustRegister(m.verifications, m.latency, m.trustedKeys)
+	return m
+}
+
+// ObserveVerification records the result and the duration of a single verification.
+func (m *Metrics) ObserveVerification(duration time.Duration, err error) {
+	m.latency.Observe(duration.Seconds())
+	if err == nil {
+		m.verifications.WithLabelValues("verified", "").Inc()
+		return
+	}
+	m.verifications.WithLabelValues("failed", Reason(err)).Inc()
+}
+
+// SetTrustedKeys records the number of keys in the keyring.
+func (m *Metrics) SetTrustedKeys(count int) {
+	m.trustedKeys.Set(float64(count))
+}
+
+// Handler returns the HTTP handler exposing the metrics.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// reasons maps the sentinel errors of the verifier to the values of the 'reason' label.
+var reasons = []struct {
+	err    error
+	reason string
+}{
+	{verifier.ErrUnsupportedContentType, "unsupported_content_type"},
+	{verifier.ErrMalformedYAML, "malformed_yaml"},
+	{verifier.ErrAmbiguousYAML, "ambiguous_yaml"},
+	{verifier.ErrUnsupportedType, "unsupported_type"},
+	{verifier.ErrUnknownKey, "unknown_key"},
+	{verifier.ErrNoSignature, "no_signature"},
+	{verifier.ErrInvalidExclusion, "invalid_exclusion"},
+	{verifier.ErrMalformedSignature, "malformed_signature"},
+	{verifier.ErrWeakHashAlgorithm, "weak_hash_algorithm"},
+	{verifier.ErrExpiredSignature, "expired_signature"},
+	{verifier.ErrRevokedKey, "revoked_key"},
+	{verifier.ErrDigestMismatch, "digest_mismatch"},
+}
+
+// Reason returns the reason of the verification failure, for use as a label value.
+func Reason(err error) string {
+	for _, r := range reasons {
+		if errors.Is(err, r.err) {
+			return r.reason
+		}
+	}
+	return "other"
+}
@@ -0,0 +1,53 @@
+package metrics
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"com.github/m-horky/playbook-verifier/pkg/verifier"
+)
+
+func TestReason(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{fmt.Errorf("play 0: %w", verifier.ErrDigestMismatch), "digest_mismatch"},
+		{fmt.Errorf("play 1: %w", verifier.ErrRevokedKey), "revoked_key"},
+		{verifier.ErrNoSignature, "no_signature"},
+		{errors.New("payload is too large"), "other"},
+	}
+	for _, tt := range tests {
+		if got := Reason(tt.err); got != tt.want {
+			t.Errorf("Reason(%v) = %s, want %s", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestHandler(t *testing.T) {
+	m := New()
+	m.SetTrustedKeys(2)
+	m.ObserveVerification(time.Millisecond, nil)
+	m.ObserveVerification(time.Millisecond, verifier.ErrNoSignature)
+	m.ObserveVerification(time.Millisecond, verifier.ErrNoSignature)
+
+	recorder := httptest.NewRecorder()
+	m.Handler().ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	body, _ := io.ReadAll(recorder.Body)
+
+	for _, line := range []string{
+		`playbook_verifier_verifications_total{reason="",result="verified"} 1`,
+		`playbook_verifier_verifications_total{reason="no_signature",result="failed"} 2`,
+		`playbook_verifier_verification_duration_seconds_count 3`,
+		`playbook_verifier_trusted_keys 2`,
+	} {
+		if !strings.Contains(string(body), line) {
+			t.Errorf("metrics do not contain %q:\n%s", line, body)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+// Package rekor fetches entries of the Rekor transparency log, so the Sigstore
+// backend can check their inclusion proofs online.
+package rekor
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"com.github/m-horky/playbook-verifier/pkg/verifier"
+)
+
+// DefaultURL is the public instance of Rekor.
+const DefaultURL = "https://rekor.sigstore.dev"
+
+// maxResponseSize limits the size of the response, entries are a few kilobytes.
+const maxResponseSize = 1 << 20
+
+// Client fetches entries of the log.
+type Client struct {
+	url    string
+	client *http.Client
+}
+
+// New returns a client of the Rekor instance at the URL, using the HTTP client,
+// see httpclient.New.
+func New(url string, client *http.Client) *Client {
+	return &Client{url: url, client: client}
+}
+
+// logEntry is an entry as returned by the API, keyed by its UUID.
+type logEntry struct {
+	Body         string `json:"body"`
+	LogIndex     int64  `json:"logIndex"`
+	Verification struct {
+		InclusionProof *struct {
+			Checkpoint string   `json:"checkpoint"`
+			Hashes     []string `json:"hashes"`
+			LogIndex   int64    `json:"logIndex"`
+			RootHash   string   `json:"rootHash"`
+			TreeSize   int64    `json:"treeSize"`
+		} `json:"inclusionProof"`
+	} `json:"verification"`
+}
+
+// Entry fetches the entry at the index, together with its inclusion proof.
+//
+// The entry is not verified, that is up to the caller.
+func (c *Client) Entry(logIndex int64) (*verifier.RekorEntry, error) {
+	endpoint := c.url + "/api/v1/log/entries?logIndex=" + url.QueryEscape(strconv.FormatInt(logIndex, 10))
+	response, err := c.client.Get(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("rekor answered %s", response.Status)
+	}
+	content, err := io.ReadAll(io.LimitReader(response.Body, maxResponseSize))
+	if err != nil {
+		return nil, err
+	}
+
+	var entries map[string]logEntry
+	if err = json.Unmarshal(content, &entries); err != nil {
+		return nil, fmt.Errorf("malformed response: %w", err)
+	}
+	if len(entries) > 1 {
+		return nil, fmt.Errorf("expected one entry, got %d", len(entries))
+	}
+	for _, entry := range entries {
+		return entry.rekorEntry(logIndex)
+	}
+	return nil, fmt.Errorf("entry %d not found", logIndex)
+}
+
+// rekorEntry converts the entry into the one the verifier checks.
+func (e *logEntry) rekorEntry(logIndex int64) (*verifier.RekorEntry, error) {
+	proof := e.Verification.InclusionProof
+	if proof == nil {
+		return nil, fmt.Errorf("entry %d has no inclusion proof", logIndex)
+	}
+	if e.LogIndex != logIndex {
+		return nil, fmt.Errorf("expected entry %d, got %d", logIndex, e.LogIndex)
+	}
+	body, err := base64.StdEncoding.DecodeString(e.Body)
+	if err != nil {
+		return nil, fmt.Errorf("malformed entry body: %w", err)
+	}
+	rootHash, err := hex.DecodeString(proof.RootHash)
+	if err != nil {
+		return nil, fmt.Errorf("malformed root hash: %w", err)
+	}
+	hashes := make([][]byte, 0, len(proof.Hashes))
+	for _, h := range proof.Hashes {
+		hash, err := hex.DecodeString(h)
+		if err != nil {
+			return nil, fmt.Errorf("malformed inclusion proof: %w", err)
+		}
+		hashes = append(hashes, hash)
+	}
+	return &verifier.RekorEntry{
+		Body:       body,
+		LogIndex:   proof.LogIndex,
+		TreeSize:   proof.TreeSize,
+		RootHash:   rootHash,
+		Hashes:     hashes,
+		Checkpoint: proof.Checkpoint,
+	}, nil
+}
@@ -0,0 +1,55 @@
+package rekor
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+const response = `{"24296fb24b8ad77a": {
+	"body": "eyJraW5kIjoiaGFzaGVkcmVrb3JkIn0=",
+	"integratedTime": 1717243200,
+	"logID": "c0d23d6ad406973f9559f3ba2d1ca01f84147d8ffc5b8445c224f98b9591801d",
+	"logIndex": 3,
+	"verification": {
+		"inclusionProof": {
+			"checkpoint": "rekor.test - 1\n5\nAAAA\n\n— rekor.test AAAA\n",
+			"hashes": ["00ff", "ff00"],
+			"logIndex": 3,
+			"rootHash": "abcd",
+			"treeSize": 5
+		},
+		"signedEntryTimestamp": "AAAA"
+	}
+}}`
+
+func TestEntry(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/log/entries" || r.URL.Query().Get("logIndex") != "3" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(response))
+	}))
+	defer server.Close()
+	client := New(server.URL, server.Client())
+
+	entry, err := client.Entry(3)
+	if err != nil {
+		t.Fatalf("Entry() error = %v", err)
+	}
+	if string(entry.Body) != `{"kind":"hashedrekord"}` {
+		t.Errorf("Entry() body = %s", entry.Body)
+	}
+	if entry.LogIndex != 3 || entry.TreeSize != 5 || !bytes.Equal(entry.RootHash, []byte{0xab, 0xcd}) {
+		t.Errorf("Entry() = %+v", entry)
+	}
+	if len(entry.Hashes) != 2 || !bytes.Equal(entry.Hashes[1], []byte{0xff, 0x00}) {
+		t.Errorf("Entry() hashes = %x", entry.Hashes)
+	}
+
+	if _, err = client.Entry(4); err == nil {
+		t.Errorf("Entry() of missing entry error = nil")
+	}
+}
@@ -0,0 +1,103 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/godbus/dbus/v5/introspect"
+
+	"com.github/m-horky/playbook-verifier/internal/audit"
+)
+
+// D-Bus names of the service.
+const (
+	DBusName      = "com.redhat.PlaybookVerifier"
+	DBusPath      = dbus.ObjectPath("/com/redhat/PlaybookVerifier")
+	DBusInterface = "com.redhat.PlaybookVerifier1"
+)
+
+// dbusIntrospection describes the interface of the D-Bus object.
+const dbusIntrospection = `
+<node>
+	<interface name="` + DBusInterface + `">
+		<!--
+			Verify verifies the payload of the content type, which defaults to Insights playbook
+			when empty. The plays are reported as (index, name, digest, key_id, excluded, verified, error,
+			trust_level, unsigned); a verified play whose trust level is 'warn-only' has to be reported
+			with a warning, and an unsigned play has been accepted without being verified.
+		-->
+		<method name="Verify">
+			<arg direction="in" type="ay" name="payload"/>
+			<arg direction="in" type="s" name="content_type"/>
+			<arg direction="out" type="b" name="verified"/>
+			<arg direction="out" type="s" name="error"/>
+			<arg direction="out" type="a(isssasbssb)" name="plays"/>
+		</method>
+	</interface>` + introspect.IntrospectDataString + `</node>`
+
+// DBusPlayReport is the verification report of a single play, sent as a D-Bus struct.
+type DBusPlayReport struct {
+	Index    int32
+	Name     string
+	Digest   string
+	KeyID    string
+	Excluded []string
+	Verified bool
+	Error    string
+	// TrustLevel is the trust level of the key that signed the play, e.g. verifier.TrustLevelWarnOnly.
+	TrustLevel string
+	// Unsigned is true if the play has no signature and has been accepted by the policy.
+	Unsigned bool
+}
+
+// dbusObject implements the D-Bus interface.
+type dbusObject struct {
+	verifier *Verifier
+}
+
+// Verify is the D-Bus method of the interface. The sender is the unique bus name of the caller.
+func (o *dbusObject) Verify(sender dbus.Sender, payload []byte, contentType string) (bool, string, []DBusPlayReport, *dbus.Error) {
+	ctx := audit.WithCaller(context.Background(), "dbus "+string(sender))
+	report, err := o.verifier.VerifyContext(ctx, contentType, payload)
+	if err != nil {
+		slog.Info("payload rejected", slog.Any("error", err))
+	}
+
+	plays := []DBusPlayReport{}
+	for _, play := range report.Plays {
+		plays = append(plays, DBusPlayReport{
+			Index:      int32(play.Index),
+			Name:       play.Name,
+			Digest:     play.Digest,
+			KeyID:      play.KeyID,
+			Excluded:   play.Excluded,
+			Verified:   play.Verified,
+			Error:      play.Error,
+			TrustLevel: play.TrustLevel,
+			Unsigned:   play.Unsigned,
+		})
+	}
+	return report.Verified, report.Error, plays, nil
+}
+
+// ExportDBus exports the verification object on the connection and requests the bus name.
+func ExportDBus(conn *dbus.Conn, v *Verifier) error {
+	object := &dbusObject{verifier: v}
+	if err := conn.Export(object, DBusPath, DBusInterface); err != nil {
+		return fmt.Errorf("could not export object: %w", err)
+	}
+	if err := conn.Export(introspect.Introspectable(dbusIntrospection), DBusPath, "org.freedesktop.DBus.Introspectable"); err != nil {
+		return fmt.Errorf("could not export introspection: %w", err)
+	}
+
+	reply, err := conn.RequestName(DBusName, dbus.NameFlagDoNotQueue)
+	if err != nil {
+		return fmt.Errorf("could not request name %s: %w", DBusName, err)
+	}
+	if reply != dbus.RequestNameReplyPrimaryOwner {
+		return fmt.Errorf("name %s is already taken", DBusName)
+	}
+	return nil
+}
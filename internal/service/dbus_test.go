@@ -0,0 +1,126 @@
+package service
+
+import (
+	"bufio"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/godbus/dbus/v5"
+
+	"com.github/m-horky/playbook-verifier/pkg/verifier"
+)
+
+// privateBus starts a dbus-daemon for the test and returns its address.
+func privateBus(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("dbus-daemon"); err != nil {
+		t.Skip("dbus-daemon is not installed")
+	}
+
+	cmd := exec.Command("dbus-daemon", "--session", "--nofork", "--print-address")
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatalf("StdoutPipe() error = %v", err)
+	}
+	if err = cmd.Start(); err != nil {
+		t.Fatalf("could not start dbus-daemon: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+	})
+
+	address, err := bufio.NewReader(stdout).ReadString('\n')
+	if err != nil {
+		t.Fatalf("could not read bus address: %v", err)
+	}
+	return strings.TrimSpace(address)
+}
+
+func connectBus(t *testing.T, address string) *dbus.Conn {
+	t.Helper()
+	conn, err := dbus.Connect(address)
+	if err != nil {
+		t.Fatalf("dbus.Connect() error = %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return conn
+}
+
+func TestDBusVerify(t *testing.T) {
+	address := privateBus(t)
+	if err := ExportDBus(connectBus(t, address), testVerifier(t)); err != nil {
+		t.Fatalf("ExportDBus() error = %v", err)
+	}
+
+	signed, err := os.ReadFile(filepath.Join(testdata, "golden", "cve-update.yml"))
+	if err != nil {
+		t.Fatalf("os.ReadFile() error = %v", err)
+	}
+
+	object := connectBus(t, address).Object(DBusName, DBusPath)
+	var verified bool
+	var message string
+	var plays []DBusPlayReport
+	if err = object.Call(DBusInterface+".Verify", 0, signed, "").Store(&verified, &message, &plays); err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	if !verified || message != "" || len(plays) != 1 || plays[0].KeyID != "08D82F6981A5FD2F" || plays[0].TrustLevel != verifier.TrustLevelTrusted {
+		t.Errorf("Verify() = %v, %q, %+v", verified, message, plays)
+	}
+
+	if err = object.Call(DBusInterface+".Verify", 0, []byte("- name: unsigned\n"), "").Store(&verified, &message, &plays); err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	if verified || message == "" {
+		t.Errorf("Verify() = %v, %q, want error", verified, message)
+	}
+}
+
+func TestDBusVerifyWarnOnly(t *testing.T) {
+	address := privateBus(t)
+	if err := ExportDBus(connectBus(t, address), warnOnlyVerifier(t)); err != nil {
+		t.Fatalf("ExportDBus() error = %v", err)
+	}
+
+	signed, err := os.ReadFile(filepath.Join(testdata, "golden", "cve-update.yml"))
+	if err != nil {
+		t.Fatalf("os.ReadFile() error = %v", err)
+	}
+	var verified bool
+	var message string
+	var plays []DBusPlayReport
+	if err = connectBus(t, address).Object(DBusName, DBusPath).Call(DBusInterface+".Verify", 0, signed, "").Store(&verified, &message, &plays); err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	if !verified || len(plays) != 1 || plays[0].TrustLevel != verifier.TrustLevelWarnOnly {
+		t.Errorf("Verify() = %v, %q, %+v, want a play signed by a warn-only key", verified, message, plays)
+	}
+}
+
+func TestDBusVerifyUnsigned(t *testing.T) {
+	address := privateBus(t)
+	v := testVerifier(t)
+	v.Policy.Unsigned = verifier.UnsignedAllow
+	if err := ExportDBus(connectBus(t, address), v); err != nil {
+		t.Fatalf("ExportDBus() error = %v", err)
+	}
+
+	signed, err := os.ReadFile(filepath.Join(testdata, "golden", "cve-update.yml"))
+	if err != nil {
+		t.Fatalf("os.ReadFile() error = %v", err)
+	}
+	var verified bool
+	var message string
+	var plays []DBusPlayReport
+	payload := append(signed, "- name: unsigned\n"...)
+	if err = connectBus(t, address).Object(DBusName, DBusPath).Call(DBusInterface+".Verify", 0, payload, "").Store(&verified, &message, &plays); err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	if !verified || len(plays) != 2 || plays[0].Unsigned || !plays[1].Unsigned || plays[1].Verified {
+		t.Errorf("Verify() = %v, %q, %+v, want the second play accepted unsigned", verified, message, plays)
+	}
+}
@@ -0,0 +1,58 @@
+package service
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/peer"
+
+	"com.github/m-horky/playbook-verifier/internal/audit"
+)
+
+//go:generate protoc -I ../../api --go_out=../.. --go_opt=module=com.github/m-horky/playbook-verifier --go-grpc_out=../.. --go-grpc_opt=module=com.github/m-horky/playbook-verifier playbook_verifier.proto
+
+// grpcServer implements the gRPC service.
+type grpcServer struct {
+	UnimplementedPlaybookVerifierServer
+	verifier *Verifier
+}
+
+// NewGRPCServer returns a gRPC server with the verification service registered.
+//
+// Clients use the generated client, see NewPlaybookVerifierClient.
+func NewGRPCServer(v *Verifier, options ...grpc.ServerOption) *grpc.Server {
+	if v.MaxSize > 0 {
+		// leave room for the framing of the message
+		options = append(options, grpc.MaxRecvMsgSize(int(v.MaxSize)+1024))
+	}
+	server := grpc.NewServer(options...)
+	RegisterPlaybookVerifierServer(server, &grpcServer{verifier: v})
+	return server
+}
+
+// VerifyPlaybook answers each request of the stream with the report of its payload.
+func (s *grpcServer) VerifyPlaybook(stream PlaybookVerifier_VerifyPlaybookServer) error {
+	ctx := stream.Context()
+	if client, ok := peer.FromContext(ctx); ok {
+		ctx = audit.WithCaller(ctx, "grpc "+client.Addr.Network()+" "+client.Addr.String())
+	}
+	for {
+		request, err := stream.Recv()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+
+		report, err := s.verifier.VerifyContext(ctx, request.GetContentType(), request.GetPayload())
+		if err != nil {
+			slog.Info("payload rejected", slog.Any("error", err))
+		}
+		if err = stream.Send(newVerifyResponse(report)); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package service
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+
+	"com.github/m-horky/playbook-verifier/pkg/verifier"
+)
+
+// testdata is the directory with the signed playbooks and the test key.
+const testdata = "../../pkg/verifier/testdata"
+
+func testVerifier(t *testing.T) *Verifier {
+	t.Helper()
+	publicKey, err := os.ReadFile(filepath.Join(testdata, "keys", "test-public.asc"))
+	if err != nil {
+		t.Fatalf("os.ReadFile() error = %v", err)
+	}
+	keyring, err := verifier.NewKeyring(publicKey)
+	if err != nil {
+		t.Fatalf("NewKeyring() error = %v", err)
+	}
+	return &Verifier{Keyring: keyring}
+}
+
+// warnOnlyVerifier returns a verifier that only trusts the test key with a warning.
+func warnOnlyVerifier(t *testing.T) *Verifier {
+	t.Helper()
+	v := testVerifier(t)
+	if err := v.Keyring.WarnOnly(v.Keyring.Fingerprints()...); err != nil {
+		t.Fatalf("WarnOnly() error = %v", err)
+	}
+	return v
+}
+
+// grpcStream serves the gRPC API of the verifier and opens the VerifyPlaybook stream on it.
+func grpcStream(t *testing.T, v *Verifier) PlaybookVerifier_VerifyPlaybookClient {
+	t.Helper()
+	socket := filepath.Join(t.TempDir(), "verifier.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatalf("net.Listen() error = %v", err)
+	}
+	server := NewGRPCServer(v)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("unix://"+socket, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("grpc.NewClient() error = %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	stream, err := NewPlaybookVerifierClient(conn).VerifyPlaybook(context.Background())
+	if err != nil {
+		t.Fatalf("VerifyPlaybook() error = %v", err)
+	}
+	return stream
+}
+
+func TestGRPCVerifyPlaybook(t *testing.T) {
+	stream := grpcStream(t, testVerifier(t))
+
+	signed, err := os.ReadFile(filepath.Join(testdata, "golden", "reboot.yml"))
+	if err != nil {
+		t.Fatalf("os.ReadFile() error = %v", err)
+	}
+	requests := []*VerifyRequest{
+		{Payload: signed},
+		{Payload: []byte("- name: unsigned\n  vars:\n    insights_signature: c2ln\n")},
+		{Payload: signed, ContentType: "text/plain"},
+	}
+	for _, request := range requests {
+		if err = stream.Send(request); err != nil {
+			t.Fatalf("Send() error = %v", err)
+		}
+	}
+	if err = stream.CloseSend(); err != nil {
+		t.Fatalf("CloseSend() error = %v", err)
+	}
+
+	var responses []*VerifyResponse
+	for range requests {
+		response, err := stream.Recv()
+		if err != nil {
+			t.Fatalf("Recv() error = %v", err)
+		}
+		responses = append(responses, response)
+	}
+
+	if !responses[0].Verified || len(responses[0].Plays) != 3 || responses[0].Plays[2].KeyId != "08D82F6981A5FD2F" {
+		t.Errorf("response 0 = %+v, want 3 verified plays", responses[0])
+	}
+	if len(responses[0].WarnOnlyKeyIds) != 0 || responses[0].Plays[2].TrustLevel != verifier.TrustLevelTrusted {
+		t.Errorf("response 0 = %+v, want plays signed by a trusted key", responses[0])
+	}
+	if len(responses[0].Plays[1].Excluded) != 2 || responses[0].Plays[1].Index != 1 {
+		t.Errorf("response 0 play 1 = %+v", responses[0].Plays[1])
+	}
+	if responses[1].Verified || responses[1].Error == "" {
+		t.Errorf("response 1 = %+v, want error", responses[1])
+	}
+	if responses[2].Verified || responses[2].Error == "" {
+		t.Errorf("response 2 = %+v, want error", responses[2])
+	}
+}
+
+func TestGRPCVerifyPlaybookWarnOnly(t *testing.T) {
+	stream := grpcStream(t, warnOnlyVerifier(t))
+
+	signed, err := os.ReadFile(filepath.Join(testdata, "golden", "cve-update.yml"))
+	if err != nil {
+		t.Fatalf("os.ReadFile() error = %v", err)
+	}
+	if err = stream.Send(&VerifyRequest{Payload: signed}); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	response, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("Recv() error = %v", err)
+	}
+	if !response.Verified || len(response.WarnOnlyKeyIds) != 1 || response.WarnOnlyKeyIds[0] != "08D82F6981A5FD2F" {
+		t.Errorf("response = %+v, want verified with the warn-only key", response)
+	}
+	if len(response.Plays) != 1 || response.Plays[0].TrustLevel != verifier.TrustLevelWarnOnly {
+		t.Errorf("plays = %+v, want a play signed by a warn-only key", response.Plays)
+	}
+}
+
+func TestGRPCVerifyPlaybookUnsigned(t *testing.T) {
+	v := testVerifier(t)
+	v.Policy.Unsigned = verifier.UnsignedWarn
+	stream := grpcStream(t, v)
+
+	signed, err := os.ReadFile(filepath.Join(testdata, "golden", "cve-update.yml"))
+	if err != nil {
+		t.Fatalf("os.ReadFile() error = %v", err)
+	}
+	unsigned := "- name: unsigned\n  hosts: all\n"
+	requests := []*VerifyRequest{
+		{Payload: append(slices.Clone(signed), unsigned...)},
+		{Payload: append(append(slices.Clone(signed), "---\n"...), unsigned...)},
+	}
+	for _, request := range requests {
+		if err = stream.Send(request); err != nil {
+			t.Fatalf("Send() error = %v", err)
+		}
+	}
+
+	response, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("Recv() error = %v", err)
+	}
+	if !response.Verified || len(response.Plays) != 2 || response.Plays[0].Unsigned || !response.Plays[1].Unsigned || response.Plays[1].Verified {
+		t.Errorf("response 0 = %+v, want the second play accepted unsigned", response)
+	}
+	response, err = stream.Recv()
+	if err != nil {
+		t.Fatalf("Recv() error = %v", err)
+	}
+	if !response.Verified || !slices.Equal(response.UnsignedDocuments, []int32{1}) || len(response.SkippedDocuments) != 0 {
+		t.Errorf("response 1 = %+v, want the second document accepted unsigned", response)
+	}
+}
@@ -0,0 +1,126 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+
+	"com.github/m-horky/playbook-verifier/internal/audit"
+	"com.github/m-horky/playbook-verifier/internal/i18n"
+	"com.github/m-horky/playbook-verifier/internal/summary"
+	"com.github/m-horky/playbook-verifier/pkg/verifier"
+)
+
+// NewHTTPHandler returns the handler of the HTTP API.
+//
+// POST /verify takes the payload as the request body and answers with the JSON
+// verification report. The content type of the payload is taken from the
+// Content-Type header and defaults to Insights playbook. The status code is
+// 200 when the payload is verified and 4xx when it is not. Clients that accept
+// text/html or text/markdown, e.g. the Insights remediation UI, get the summary
+// of the verification in that format instead of the report.
+//
+// GET /metrics exposes the metrics of the verifier, if it has any.
+func NewHTTPHandler(v *Verifier) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /verify", func(w http.ResponseWriter, r *http.Request) {
+		handleVerify(v, w, r)
+	})
+	if v.Metrics != nil {
+		mux.Handle("GET /metrics", v.Metrics.Handler())
+	}
+	return mux
+}
+
+// handleVerify verifies the payload in the request body.
+func handleVerify(v *Verifier, w http.ResponseWriter, r *http.Request) {
+	contentType := ""
+	if header := r.Header.Get("Content-Type"); header != "" {
+		mediaType, _, err := mime.ParseMediaType(header)
+		if err != nil {
+			http.Error(w, "invalid Content-Type header", http.StatusBadRequest)
+			return
+		}
+		contentType = mediaType
+	}
+
+	body := io.Reader(r.Body)
+	if v.MaxSize > 0 {
+		body = http.MaxBytesReader(w, r.Body, v.MaxSize)
+	}
+	payload, err := io.ReadAll(body)
+	if err != nil {
+		var maxBytesError *http.MaxBytesError
+		if errors.As(err, &maxBytesError) {
+			http.Error(w, "payload is too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, "could not read payload", http.StatusBadRequest)
+		return
+	}
+
+	// Callers may pass their trace context, so the verification is a part of their trace.
+	ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+	ctx = audit.WithCaller(ctx, "http "+r.RemoteAddr)
+	report, err := v.VerifyContext(ctx, contentType, payload)
+	if err != nil {
+		slog.Info("payload rejected", slog.String("remote", r.RemoteAddr), slog.Any("error", err))
+	}
+
+	if format, mediaType := summaryFormat(r.Header.Get("Accept")); format != "" {
+		description := i18n.ErrorMessage(i18n.NewPrinter(), err)
+		w.Header().Set("Content-Type", mediaType+"; charset=utf-8")
+		w.WriteHeader(httpStatus(err))
+		if err = summary.Render(w, format, report, description); err != nil {
+			slog.Error("could not write report", slog.Any("error", err))
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(httpStatus(err))
+	if err = json.NewEncoder(w).Encode(report); err != nil {
+		slog.Error("could not write report", slog.Any("error", err))
+	}
+}
+
+// summaryFormat returns the format of the summary and its media type for the first media type
+// of the Accept header that is one, or an empty format if the client wants the JSON report.
+func summaryFormat(accept string) (format, mediaType string) {
+	for _, value := range strings.Split(accept, ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(value))
+		if err != nil {
+			continue
+		}
+		switch mediaType {
+		case "text/html":
+			return summary.FormatHTML, mediaType
+		case "text/markdown":
+			return summary.FormatMarkdown, mediaType
+		case "application/json":
+			return "", ""
+		}
+	}
+	return "", ""
+}
+
+// httpStatus maps the verification error to the status code of the response.
+func httpStatus(err error) int {
+	switch {
+	case err == nil:
+		return http.StatusOK
+	case errors.Is(err, verifier.ErrUnsupportedContentType):
+		return http.StatusUnsupportedMediaType
+	case errors.Is(err, verifier.ErrMalformedYAML), errors.Is(err, verifier.ErrUnsupportedType):
+		return http.StatusBadRequest
+	default:
+		return http.StatusUnprocessableEntity
+	}
+}
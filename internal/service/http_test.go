@@ -0,0 +1,170 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"com.github/m-horky/playbook-verifier/internal/audit"
+	"com.github/m-horky/playbook-verifier/pkg/verifier"
+)
+
+func TestHTTPVerify(t *testing.T) {
+	signed, err := os.ReadFile(filepath.Join(testdata, "golden", "cve-update.yml"))
+	if err != nil {
+		t.Fatalf("os.ReadFile() error = %v", err)
+	}
+	v := testVerifier(t)
+	v.MaxSize = 4096
+	server := httptest.NewServer(NewHTTPHandler(v))
+	defer server.Close()
+
+	tests := []struct {
+		name        string
+		payload     []byte
+		contentType string
+		status      int
+	}{
+		{"verified", signed, verifier.PlaybookContentType, http.StatusOK},
+		{"default content type", signed, "", http.StatusOK},
+		{"unsigned", []byte("- name: unsigned\n"), "", http.StatusUnprocessableEntity},
+		{"malformed", []byte("- [unclosed"), "", http.StatusBadRequest},
+		{"unsupported content type", signed, "text/plain", http.StatusUnsupportedMediaType},
+		{"too large", bytes.Repeat([]byte("#"), 4097), "", http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request, err := http.NewRequest(http.MethodPost, server.URL+"/verify", bytes.NewReader(tt.payload))
+			if err != nil {
+				t.Fatalf("http.NewRequest() error = %v", err)
+			}
+			if tt.contentType != "" {
+				request.Header.Set("Content-Type", tt.contentType)
+			}
+			response, err := http.DefaultClient.Do(request)
+			if err != nil {
+				t.Fatalf("POST /verify error = %v", err)
+			}
+			defer response.Body.Close()
+
+			if response.StatusCode != tt.status {
+				t.Errorf("POST /verify status = %d, want %d", response.StatusCode, tt.status)
+			}
+			if response.StatusCode == http.StatusOK {
+				var report verifier.Report
+				if err = json.NewDecoder(response.Body).Decode(&report); err != nil {
+					t.Fatalf("could not decode report: %v", err)
+				}
+				if !report.Verified || len(report.Plays) != 1 {
+					t.Errorf("POST /verify report = %+v", report)
+				}
+			}
+		})
+	}
+}
+
+func TestHTTPVerifySummary(t *testing.T) {
+	signed, err := os.ReadFile(filepath.Join(testdata, "golden", "cve-update.yml"))
+	if err != nil {
+		t.Fatalf("os.ReadFile() error = %v", err)
+	}
+	server := httptest.NewServer(NewHTTPHandler(testVerifier(t)))
+	defer server.Close()
+
+	tests := []struct {
+		name        string
+		payload     []byte
+		accept      string
+		status      int
+		contentType string
+		body        string
+	}{
+		{"html", signed, "text/html,application/xhtml+xml;q=0.9", http.StatusOK, "text/html; charset=utf-8", `<section class="playbook-verification verified">`},
+		{"markdown", signed, "text/markdown", http.StatusOK, "text/markdown; charset=utf-8", "## Playbook verified\n"},
+		{"unsigned", []byte("- name: unsigned\n"), "text/markdown", http.StatusUnprocessableEntity, "text/markdown; charset=utf-8", "## Playbook not verified\n"},
+		{"json first", signed, "application/json, text/html", http.StatusOK, "application/json", `"verified":true`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request, err := http.NewRequest(http.MethodPost, server.URL+"/verify", bytes.NewReader(tt.payload))
+			if err != nil {
+				t.Fatalf("http.NewRequest() error = %v", err)
+			}
+			request.Header.Set("Accept", tt.accept)
+			response, err := http.DefaultClient.Do(request)
+			if err != nil {
+				t.Fatalf("POST /verify error = %v", err)
+			}
+			defer response.Body.Close()
+			body, err := io.ReadAll(response.Body)
+			if err != nil {
+				t.Fatalf("io.ReadAll() error = %v", err)
+			}
+
+			if response.StatusCode != tt.status {
+				t.Errorf("POST /verify status = %d, want %d", response.StatusCode, tt.status)
+			}
+			if got := response.Header.Get("Content-Type"); got != tt.contentType {
+				t.Errorf("POST /verify Content-Type = %q, want %q", got, tt.contentType)
+			}
+			if !strings.Contains(string(body), tt.body) {
+				t.Errorf("POST /verify body does not contain %q:\n%s", tt.body, body)
+			}
+		})
+	}
+}
+
+// memoryLog keeps the audit records in memory.
+type memoryLog struct {
+	records []audit.Record
+}
+
+func (l *memoryLog) Write(record audit.Record) error {
+	l.records = append(l.records, record)
+	return nil
+}
+
+func (l *memoryLog) Close() error {
+	return nil
+}
+
+func TestHTTPVerifyAudit(t *testing.T) {
+	signed, err := os.ReadFile(filepath.Join(testdata, "golden", "cve-update.yml"))
+	if err != nil {
+		t.Fatalf("os.ReadFile() error = %v", err)
+	}
+	log := &memoryLog{}
+	v := testVerifier(t)
+	v.Audit = log
+	server := httptest.NewServer(NewHTTPHandler(v))
+	defer server.Close()
+
+	for _, payload := range [][]byte{signed, []byte("- name: unsigned\n")} {
+		response, err := http.Post(server.URL+"/verify", "", bytes.NewReader(payload))
+		if err != nil {
+			t.Fatalf("POST /verify error = %v", err)
+		}
+		response.Body.Close()
+	}
+
+	if len(log.records) != 2 {
+		t.Fatalf("audit records = %+v, want 2", log.records)
+	}
+	approved, rejected := log.records[0], log.records[1]
+	if approved.Verdict != audit.VerdictApproved || len(approved.KeyIDs) != 1 || approved.KeyIDs[0] != "08D82F6981A5FD2F" {
+		t.Errorf("audit record = %+v, want approved by 08D82F6981A5FD2F", approved)
+	}
+	if rejected.Verdict != audit.VerdictRejected || rejected.Error == "" {
+		t.Errorf("audit record = %+v, want rejected", rejected)
+	}
+	if !strings.HasPrefix(approved.Caller, "http 127.0.0.1:") || approved.ContentType != verifier.PlaybookContentType {
+		t.Errorf("audit record caller = %s, content type = %s", approved.Caller, approved.ContentType)
+	}
+}
@@ -0,0 +1,32 @@
+package service
+
+import (
+	"com.github/m-horky/playbook-verifier/pkg/verifier"
+)
+
+// The messages of api/playbook_verifier.proto are generated into playbook_verifier.pb.go.
+
+// newVerifyResponse converts the report into the response message.
+func newVerifyResponse(report verifier.Report) *VerifyResponse {
+	response := &VerifyResponse{Verified: report.Verified, Error: report.Error, WarnOnlyKeyIds: report.WarnOnlyKeyIDs()}
+	for _, play := range report.Plays {
+		response.Plays = append(response.Plays, &PlayReport{
+			Index:      int32(play.Index),
+			Name:       play.Name,
+			Digest:     play.Digest,
+			KeyId:      play.KeyID,
+			Excluded:   play.Excluded,
+			Verified:   play.Verified,
+			Error:      play.Error,
+			TrustLevel: play.TrustLevel,
+			Unsigned:   play.Unsigned,
+		})
+	}
+	for _, document := range report.SkippedDocuments {
+		response.SkippedDocuments = append(response.SkippedDocuments, int32(document))
+	}
+	for _, document := range report.UnsignedDocuments {
+		response.UnsignedDocuments = append(response.UnsignedDocuments, int32(document))
+	}
+	return response
+}
@@ -0,0 +1,124 @@
+// gRPC API of the playbook verifier service (`playbook-verifier serve`).
+//
+// The Go code in internal/service is generated from this file with protoc-gen-go
+// and protoc-gen-go-grpc, see `go generate ./internal/service`.
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: playbook_verifier.proto
+
+package service
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	PlaybookVerifier_VerifyPlaybook_FullMethodName = "/playbookverifier.v1.PlaybookVerifier/VerifyPlaybook"
+)
+
+// PlaybookVerifierClient is the client API for PlaybookVerifier service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type PlaybookVerifierClient interface {
+	// VerifyPlaybook verifies each payload sent over the stream and answers
+	// with its report, in the order the payloads were sent.
+	VerifyPlaybook(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[VerifyRequest, VerifyResponse], error)
+}
+
+type playbookVerifierClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewPlaybookVerifierClient(cc grpc.ClientConnInterface) PlaybookVerifierClient {
+	return &playbookVerifierClient{cc}
+}
+
+func (c *playbookVerifierClient) VerifyPlaybook(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[VerifyRequest, VerifyResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &PlaybookVerifier_ServiceDesc.Streams[0], PlaybookVerifier_VerifyPlaybook_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[VerifyRequest, VerifyResponse]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type PlaybookVerifier_VerifyPlaybookClient = grpc.BidiStreamingClient[VerifyRequest, VerifyResponse]
+
+// PlaybookVerifierServer is the server API for PlaybookVerifier service.
+// All implementations must embed UnimplementedPlaybookVerifierServer
+// for forward compatibility.
+type PlaybookVerifierServer interface {
+	// VerifyPlaybook verifies each payload sent over the stream and answers
+	// with its report, in the order the payloads were sent.
+	VerifyPlaybook(grpc.BidiStreamingServer[VerifyRequest, VerifyResponse]) error
+	mustEmbedUnimplementedPlaybookVerifierServer()
+}
+
+// UnimplementedPlaybookVerifierServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedPlaybookVerifierServer struct{}
+
+func (UnimplementedPlaybookVerifierServer) VerifyPlaybook(grpc.BidiStreamingServer[VerifyRequest, VerifyResponse]) error {
+	return status.Errorf(codes.Unimplemented, "method VerifyPlaybook not implemented")
+}
+func (UnimplementedPlaybookVerifierServer) mustEmbedUnimplementedPlaybookVerifierServer() {}
+func (UnimplementedPlaybookVerifierServer) testEmbeddedByValue()                          {}
+
+// UnsafePlaybookVerifierServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to PlaybookVerifierServer will
+// result in compilation errors.
+type UnsafePlaybookVerifierServer interface {
+	mustEmbedUnimplementedPlaybookVerifierServer()
+}
+
+func RegisterPlaybookVerifierServer(s grpc.ServiceRegistrar, srv PlaybookVerifierServer) {
+	// If the following call pancis, it indicates UnimplementedPlaybookVerifierServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&PlaybookVerifier_ServiceDesc, srv)
+}
+
+func _PlaybookVerifier_VerifyPlaybook_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(PlaybookVerifierServer).VerifyPlaybook(&grpc.GenericServerStream[VerifyRequest, VerifyResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type PlaybookVerifier_VerifyPlaybookServer = grpc.BidiStreamingServer[VerifyRequest, VerifyResponse]
+
+// PlaybookVerifier_ServiceDesc is the grpc.ServiceDesc for PlaybookVerifier service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var PlaybookVerifier_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "playbookverifier.v1.PlaybookVerifier",
+	HandlerType: (*PlaybookVerifierServer)(nil),
+	Methods:     []grpc.MethodDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "VerifyPlaybook",
+			Handler:       _PlaybookVerifier_VerifyPlaybook_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "playbook_verifier.proto",
+}
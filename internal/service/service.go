@@ -0,0 +1,56 @@
+// Package service exposes the verifier to long-running agents, so they do not pay
+// the process startup and key loading cost for every payload.
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"com.github/m-horky/playbook-verifier/internal/audit"
+	"com.github/m-horky/playbook-verifier/internal/metrics"
+	"com.github/m-horky/playbook-verifier/pkg/verifier"
+)
+
+// Verifier verifies payloads with a fixed keyring and policy.
+type Verifier struct {
+	Keyring *verifier.Keyring
+	Policy  verifier.Policy
+	// MaxSize is the maximal size of the payload in bytes. Zero means no limit.
+	MaxSize int64
+	// Metrics record the verifications, if set.
+	Metrics *metrics.Metrics
+	// Audit records the verification decisions, if set. The caller is taken from the context, see audit.WithCaller.
+	Audit audit.Log
+}
+
+// Verify verifies the payload of the content type. Empty content type means a playbook.
+func (v *Verifier) Verify(contentType string, payload []byte) (verifier.Report, error) {
+	return v.VerifyContext(context.Background(), contentType, payload)
+}
+
+// VerifyContext is like Verify, but records the verification as a span of the context's trace.
+func (v *Verifier) VerifyContext(ctx context.Context, contentType string, payload []byte) (report verifier.Report, err error) {
+	if v.Metrics != nil {
+		defer func(start time.Time) {
+			v.Metrics.ObserveVerification(time.Since(start), err)
+		}(time.Now())
+	}
+	if v.Audit != nil {
+		defer func() {
+			if auditErr := v.Audit.Write(audit.NewRecord(audit.Caller(ctx), contentType, payload, report, err)); auditErr != nil {
+				slog.Error("could not record verification", slog.Any("error", auditErr))
+			}
+		}()
+	}
+
+	if contentType == "" {
+		contentType = verifier.PlaybookContentType
+	}
+	if v.MaxSize > 0 && int64(len(payload)) > v.MaxSize {
+		err = fmt.Errorf("payload is too large: limit is %d bytes", v.MaxSize)
+		return verifier.Report{Plays: []verifier.PlayReport{}, SkippedDocuments: []int{}, Error: err.Error()}, err
+	}
+	return verifier.VerifyContext(ctx, contentType, payload, v.Keyring, v.Policy)
+}
@@ -0,0 +1,148 @@
+// Package summary renders the verification report as a short summary for people, e.g. in the
+// Insights remediation UI, Cockpit or an email notification, see --report.
+//
+// The names of the plays come from the payload, which is not trusted until it is verified, so
+// they are always escaped: HTML by html/template, Markdown by escaping its special characters.
+package summary
+
+import (
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"strings"
+	"text/template"
+	"time"
+
+	"com.github/m-horky/playbook-verifier/pkg/verifier"
+)
+
+// Formats of the summary.
+const (
+	FormatMarkdown = "markdown"
+	FormatHTML     = "html"
+)
+
+// Formats returns the formats of the summary.
+func Formats() []string {
+	return []string{FormatMarkdown, FormatHTML}
+}
+
+// summary is what the templates render.
+type summary struct {
+	Verified bool
+	// Description is the localized description of the error, if the payload has not been verified.
+	Description string
+	// WarnOnly are the IDs of the keys that signed the verified payload, but are only trusted with a warning.
+	WarnOnly []string
+	Plays    []play
+	// Signer is the key of the detached signature, if the payload has one.
+	Signer           string
+	SkippedDocuments []int
+	// UnsignedDocuments have been passed on without being verified, see verifier.Policy.Unsigned.
+	UnsignedDocuments []int
+	Violations        []verifier.Violation
+}
+
+// play is a row of the table of plays.
+type play struct {
+	Index    int
+	Name     string
+	Tasks    int
+	Signer   string
+	Signed   string
+	Verified bool
+	Unsigned bool
+	Error    string
+}
+
+// newSummary returns the summary of the report.
+func newSummary(report verifier.Report, description string) summary {
+	s := summary{
+		Verified:          report.Verified,
+		Signer:            report.KeyID,
+		WarnOnly:          report.WarnOnlyKeyIDs(),
+		SkippedDocuments:  report.SkippedDocuments,
+		UnsignedDocuments: report.UnsignedDocuments,
+		Violations:        report.Violations,
+	}
+	if !report.Verified {
+		s.Description = description
+	}
+	for _, p := range report.Plays {
+		row := play{Index: p.Index, Name: p.Name, Tasks: p.Tasks, Signer: p.KeyID, Verified: p.Verified, Unsigned: p.Unsigned, Error: p.Error}
+		if p.Signature != nil {
+			if row.Signer == "" {
+				row.Signer = p.Signature.KeyID
+			}
+			if !p.Signature.Created.IsZero() {
+				row.Signed = p.Signature.Created.UTC().Format(time.DateTime + " UTC")
+			}
+		}
+		s.Plays = append(s.Plays, row)
+	}
+	return s
+}
+
+// Render writes the summary of the report in the format. The description of the error,
+// e.g. by i18n.ErrorMessage, is shown if the payload has not been verified.
+func Render(w io.Writer, format string, report verifier.Report, description string) error {
+	s := newSummary(report, description)
+	switch format {
+	case FormatMarkdown:
+		return markdownTemplate.Execute(w, s)
+	case FormatHTML:
+		return htmlTemplate.Execute(w, s)
+	default:
+		return fmt.Errorf("unsupported summary format: %s", format)
+	}
+}
+
+// markdownEscaper escapes the characters that have a meaning in Markdown or in the HTML
+// that Markdown may contain, and keeps everything on a single line of a table.
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`, "`", "\\`", "*", `\*`, "_", `\_`, "[", `\[`, "]", `\]`, "#", `\#`, "|", `\|`, "~", `\~`,
+	"<", "&lt;", ">", "&gt;", "&", "&amp;", "\r\n", " ", "\n", " ", "\r", " ",
+)
+
+var markdownTemplate = template.Must(template.New("markdown").Funcs(template.FuncMap{
+	"md": markdownEscaper.Replace,
+}).Parse(`## Playbook {{if .Verified}}verified{{else}}not verified{{end}}
+{{with .Description}}
+{{md .}}
+{{end}}{{with .WarnOnly}}
+**Warning:** signed by a key that is only trusted with a warning ({{range $i, $k := .}}{{if $i}}, {{end}}{{md $k}}{{end}}).
+{{end}}{{with .Signer}}
+Signed by key {{md .}}.
+{{end}}{{with .Plays}}
+| Play | Name | Tasks | Signed by | Signed at | Verdict |
+| ---: | --- | ---: | --- | --- | --- |
+{{range .}}| {{.Index}} | {{md .Name}} | {{.Tasks}} | {{md .Signer}} | {{.Signed}} | {{if .Verified}}verified{{else if .Unsigned}}not signed, passed on{{else}}not verified: {{md .Error}}{{end}} |
+{{end}}{{end}}{{with .SkippedDocuments}}
+Unsigned documents {{range $i, $d := .}}{{if $i}}, {{end}}{{$d}}{{end}} have not been verified.
+{{end}}{{with .UnsignedDocuments}}
+Unsigned documents {{range $i, $d := .}}{{if $i}}, {{end}}{{$d}}{{end}} have been passed on without being verified.
+{{end}}{{with .Violations}}
+Policy violations:
+{{range .}}
+- {{if ge .Play 0}}play {{.Play}}: {{end}}{{md .Message}} ({{md .Rule}}){{end}}
+{{end}}`))
+
+var htmlTemplate = htmltemplate.Must(htmltemplate.New("html").Parse(`<section class="playbook-verification {{if .Verified}}verified{{else}}not-verified{{end}}">
+<h2>Playbook {{if .Verified}}verified{{else}}not verified{{end}}</h2>
+{{with .Description}}<p class="description">{{.}}</p>
+{{end}}{{with .WarnOnly}}<p class="warning"><strong>Warning:</strong> signed by a key that is only trusted with a warning ({{range $i, $k := .}}{{if $i}}, {{end}}<code>{{$k}}</code>{{end}}).</p>
+{{end}}{{with .Signer}}<p>Signed by key <code>{{.}}</code>.</p>
+{{end}}{{with .Plays}}<table>
+<thead><tr><th>Play</th><th>Name</th><th>Tasks</th><th>Signed by</th><th>Signed at</th><th>Verdict</th></tr></thead>
+<tbody>
+{{range .}}<tr class="{{if .Verified}}verified{{else}}not-verified{{end}}"><td>{{.Index}}</td><td>{{.Name}}</td><td>{{.Tasks}}</td><td><code>{{.Signer}}</code></td><td>{{.Signed}}</td><td>{{if .Verified}}verified{{else if .Unsigned}}not signed, passed on{{else}}not verified: {{.Error}}{{end}}</td></tr>
+{{end}}</tbody>
+</table>
+{{end}}{{with .SkippedDocuments}}<p>Unsigned documents {{range $i, $d := .}}{{if $i}}, {{end}}{{$d}}{{end}} have not been verified.</p>
+{{end}}{{with .UnsignedDocuments}}<p>Unsigned documents {{range $i, $d := .}}{{if $i}}, {{end}}{{$d}}{{end}} have been passed on without being verified.</p>
+{{end}}{{with .Violations}}<p>Policy violations:</p>
+<ul>
+{{range .}}<li>{{if ge .Play 0}}play {{.Play}}: {{end}}{{.Message}} ({{.Rule}})</li>
+{{end}}</ul>
+{{end}}</section>
+`))
@@ -0,0 +1,131 @@
+package summary
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"com.github/m-horky/playbook-verifier/pkg/verifier"
+)
+
+var created = time.Date(2024, 7, 1, 12, 30, 0, 0, time.UTC)
+
+func verifiedReport() verifier.Report {
+	signature := &verifier.SignatureInfo{Scheme: "openpgp", KeyID: "08D82F6981A5FD2F", Created: created}
+	return verifier.Report{
+		Verified: true,
+		Plays: []verifier.PlayReport{
+			{Index: 0, Name: "Update openssl", Tasks: 2, KeyID: "08D82F6981A5FD2F", Signature: signature, Verified: true},
+			{Index: 1, Name: "Reboot system (if applicable)", Tasks: 1, KeyID: "08D82F6981A5FD2F", Signature: signature, Verified: true},
+		},
+		SkippedDocuments: []int{},
+	}
+}
+
+func TestRenderMarkdown(t *testing.T) {
+	var b bytes.Buffer
+	if err := Render(&b, FormatMarkdown, verifiedReport(), "ignored"); err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	want := `## Playbook verified
+
+| Play | Name | Tasks | Signed by | Signed at | Verdict |
+| ---: | --- | ---: | --- | --- | --- |
+| 0 | Update openssl | 2 | 08D82F6981A5FD2F | 2024-07-01 12:30:00 UTC | verified |
+| 1 | Reboot system (if applicable) | 1 | 08D82F6981A5FD2F | 2024-07-01 12:30:00 UTC | verified |
+`
+	if b.String() != want {
+		t.Errorf("Render() =\n%s\nwant\n%s", b.String(), want)
+	}
+}
+
+func TestRenderFailure(t *testing.T) {
+	report := verifier.Report{
+		Plays: []verifier.PlayReport{{
+			Index: 0, Name: "<script>alert(1)</script> | *bold*\n# heading", Tasks: 3,
+			Signature: &verifier.SignatureInfo{KeyID: "1234567890ABCDEF"},
+			Error:     "signature mismatch",
+		}},
+		SkippedDocuments: []int{1, 2},
+		Violations:       []verifier.Violation{{Rule: "max-tasks", Play: 0, Message: "play has 3 tasks"}},
+	}
+
+	tests := []struct {
+		format string
+		want   []string
+		unsafe []string
+	}{
+		{FormatMarkdown, []string{
+			"## Playbook not verified",
+			"The playbook has been modified.",
+			`| 0 | &lt;script&gt;alert(1)&lt;/script&gt; \| \*bold\* \# heading | 3 | 1234567890ABCDEF |  | not verified: signature mismatch |`,
+			"Unsigned documents 1, 2 have not been verified.",
+			"- play 0: play has 3 tasks (max-tasks)",
+		}, []string{"<script>", "*bold*"}},
+		{FormatHTML, []string{
+			`<section class="playbook-verification not-verified">`,
+			`<p class="description">The playbook has been modified.</p>`,
+			`<td>&lt;script&gt;alert(1)&lt;/script&gt; | *bold*`,
+			"<td><code>1234567890ABCDEF</code></td>",
+			"Unsigned documents 1, 2 have not been verified.",
+			"<li>play 0: play has 3 tasks (max-tasks)</li>",
+		}, []string{"<script>"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			var b bytes.Buffer
+			if err := Render(&b, tt.format, report, "The playbook has been modified."); err != nil {
+				t.Fatalf("Render() error = %v", err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(b.String(), want) {
+					t.Errorf("Render() does not contain %q:\n%s", want, b.String())
+				}
+			}
+			for _, unsafe := range tt.unsafe {
+				if strings.Contains(b.String(), unsafe) {
+					t.Errorf("Render() contains unescaped %q:\n%s", unsafe, b.String())
+				}
+			}
+		})
+	}
+
+	if err := Render(&bytes.Buffer{}, "pdf", report, ""); err == nil {
+		t.Error("Render(pdf) succeeded")
+	}
+}
+
+func TestRenderWarnOnly(t *testing.T) {
+	report := verifiedReport()
+	report.Plays[1].TrustLevel = verifier.TrustLevelWarnOnly
+	report.Plays[1].KeyID = "E544FCAF4E2D23F9"
+
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{FormatMarkdown, "## Playbook verified\n\n**Warning:** signed by a key that is only trusted with a warning (E544FCAF4E2D23F9).\n"},
+		{FormatHTML, `<p class="warning"><strong>Warning:</strong> signed by a key that is only trusted with a warning (<code>E544FCAF4E2D23F9</code>).</p>`},
+	}
+	for _, tt := range tests {
+		var b bytes.Buffer
+		if err := Render(&b, tt.format, report, ""); err != nil {
+			t.Fatalf("Render(%s) error = %v", tt.format, err)
+		}
+		if !strings.Contains(b.String(), tt.want) {
+			t.Errorf("Render(%s) does not contain %q:\n%s", tt.format, tt.want, b.String())
+		}
+	}
+}
+
+// A payload verified by a detached signature has no plays, only the key that signed it.
+func TestRenderDetached(t *testing.T) {
+	var b bytes.Buffer
+	if err := Render(&b, FormatMarkdown, verifier.Report{Verified: true, KeyID: "08D82F6981A5FD2F"}, ""); err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	if want := "## Playbook verified\n\nSigned by key 08D82F6981A5FD2F.\n"; b.String() != want {
+		t.Errorf("Render() =\n%q\nwant\n%q", b.String(), want)
+	}
+}
@@ -0,0 +1,270 @@
+// Package webhook implements a Kubernetes validating admission webhook, which refuses
+// objects embedding playbooks that are not verified.
+//
+// The AdmissionReview types are declared here rather than imported from k8s.io/api,
+// as the webhook only needs the handful of fields below.
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"path"
+	"slices"
+	"strings"
+
+	"com.github/m-horky/playbook-verifier/internal/audit"
+	"com.github/m-horky/playbook-verifier/internal/service"
+	"com.github/m-horky/playbook-verifier/pkg/verifier"
+)
+
+// MaxReviewSize is the maximal size of an AdmissionReview in bytes. It holds the object
+// and its old version, each up to the 1.5 MiB etcd accepts, with the overhead of JSON.
+const MaxReviewSize = 8 << 20
+
+// admissionAPIVersion is the only version of AdmissionReview the webhook speaks.
+const admissionAPIVersion = "admission.k8s.io/v1"
+
+// DefaultFields are the fields of custom resources the playbooks are embedded in, by kind.
+var DefaultFields = map[string][]string{
+	"JobTemplate": {"spec.playbook"},
+}
+
+// AdmissionReview is the request and the response of the webhook.
+type AdmissionReview struct {
+	APIVersion string             `json:"apiVersion"`
+	Kind       string             `json:"kind"`
+	Request    *AdmissionRequest  `json:"request,omitempty"`
+	Response   *AdmissionResponse `json:"response,omitempty"`
+}
+
+// GroupVersionKind identifies the type of the reviewed object.
+type GroupVersionKind struct {
+	Group   string `json:"group"`
+	Version string `json:"version"`
+	Kind    string `json:"kind"`
+}
+
+// UserInfo identifies the user who sent the reviewed request.
+type UserInfo struct {
+	Username string `json:"username"`
+}
+
+// AdmissionRequest describes the reviewed object.
+type AdmissionRequest struct {
+	UID       string           `json:"uid"`
+	Kind      GroupVersionKind `json:"kind"`
+	Namespace string           `json:"namespace,omitempty"`
+	Name      string           `json:"name,omitempty"`
+	Operation string           `json:"operation"`
+	UserInfo  UserInfo         `json:"userInfo"`
+	Object    json.RawMessage  `json:"object,omitempty"`
+}
+
+// AdmissionResponse is the decision of the webhook.
+type AdmissionResponse struct {
+	UID     string  `json:"uid"`
+	Allowed bool    `json:"allowed"`
+	Status  *Status `json:"status,omitempty"`
+	// Warnings are shown to the user who sent the request, e.g. by kubectl, even if it is allowed.
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// Status explains the refusal to the user.
+type Status struct {
+	Code    int32  `json:"code"`
+	Message string `json:"message"`
+}
+
+// configMap is the part of a ConfigMap the playbooks are taken from.
+type configMap struct {
+	Data       map[string]string `json:"data"`
+	BinaryData map[string][]byte `json:"binaryData"`
+}
+
+// embeddedPlaybook is a playbook found in the reviewed object.
+type embeddedPlaybook struct {
+	// location is the key or the field of the object the playbook is in.
+	location string
+	content  []byte
+}
+
+// Handler reviews the objects sent by the Kubernetes API server.
+type Handler struct {
+	verifier *service.Verifier
+	fields   map[string][]string
+	mux      *http.ServeMux
+}
+
+// NewHandler returns the handler of the webhook.
+//
+// POST /validate reviews the object of the AdmissionReview. The playbooks are the entries
+// of ConfigMaps whose keys end with .yml or .yaml, and the string fields of the other kinds
+// listed in fields as dot-separated paths (see DefaultFields). Objects without playbooks are allowed,
+// objects with a playbook that is not verified are refused with the verification error.
+//
+// GET /healthz answers when the webhook is ready.
+func NewHandler(v *service.Verifier, fields map[string][]string) *Handler {
+	h := &Handler{verifier: v, fields: fields, mux: http.NewServeMux()}
+	h.mux.HandleFunc("POST /validate", h.handleValidate)
+	h.mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	if v.Metrics != nil {
+		h.mux.Handle("GET /metrics", v.Metrics.Handler())
+	}
+	return h
+}
+
+// ServeHTTP implements http.Handler.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mux.ServeHTTP(w, r)
+}
+
+// handleValidate answers the AdmissionReview in the request body.
+func (h *Handler) handleValidate(w http.ResponseWriter, r *http.Request) {
+	var review AdmissionReview
+	content, err := io.ReadAll(http.MaxBytesReader(w, r.Body, MaxReviewSize))
+	if err == nil {
+		err = json.Unmarshal(content, &review)
+	}
+	if err != nil || review.Request == nil {
+		http.Error(w, "invalid AdmissionReview", http.StatusBadRequest)
+		return
+	}
+	if review.APIVersion != admissionAPIVersion {
+		http.Error(w, "unsupported AdmissionReview version "+review.APIVersion, http.StatusBadRequest)
+		return
+	}
+
+	response := h.review(r.Context(), review.Request)
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(AdmissionReview{APIVersion: admissionAPIVersion, Kind: "AdmissionReview", Response: response})
+	if err != nil {
+		slog.Error("could not write review", slog.Any("error", err))
+	}
+}
+
+// review verifies the playbooks of the object and decides whether it is admitted.
+func (h *Handler) review(ctx context.Context, request *AdmissionRequest) *AdmissionResponse {
+	logger := slog.With(
+		slog.String("uid", request.UID),
+		slog.String("kind", request.Kind.Kind),
+		slog.String("object", path.Join(request.Namespace, request.Name)),
+		slog.String("user", request.UserInfo.Username),
+	)
+	response := &AdmissionResponse{UID: request.UID, Allowed: true}
+	if request.Operation == "DELETE" || len(request.Object) == 0 {
+		return response
+	}
+
+	playbooks, err := h.playbooks(request.Kind.Kind, request.Object)
+	if err != nil {
+		logger.Info("object refused", slog.Any("error", err))
+		response.Allowed = false
+		response.Status = &Status{Code: http.StatusBadRequest, Message: err.Error()}
+		return response
+	}
+
+	ctx = audit.WithCaller(ctx, "webhook "+request.UserInfo.Username)
+	var refusals []string
+	for _, playbook := range playbooks {
+		report, err := h.verifier.VerifyContext(ctx, "", playbook.content)
+		if err != nil {
+			refusals = append(refusals, fmt.Sprintf("%s: %s", playbook.location, err))
+			continue
+		}
+		if keyIDs := report.WarnOnlyKeyIDs(); len(keyIDs) > 0 {
+			response.Warnings = append(response.Warnings, fmt.Sprintf("%s: playbook is signed by warn-only keys %s", playbook.location, strings.Join(keyIDs, ", ")))
+		}
+		if report.HasUnsigned() && h.verifier.Policy.Unsigned == verifier.UnsignedWarn {
+			response.Warnings = append(response.Warnings, fmt.Sprintf("%s: playbook contains unsigned content that has not been verified", playbook.location))
+		}
+	}
+	if len(refusals) > 0 {
+		logger.Info("object refused", slog.Any("errors", refusals))
+		response.Allowed = false
+		response.Status = &Status{Code: http.StatusForbidden, Message: "playbook is not verified: " + strings.Join(refusals, "; ")}
+		return response
+	}
+	if len(response.Warnings) > 0 {
+		logger.Warn("object admitted with warnings", slog.Any("warnings", response.Warnings))
+		return response
+	}
+	logger.Debug("object admitted", slog.Int("playbooks", len(playbooks)))
+	return response
+}
+
+// playbooks returns the playbooks embedded in the object of the kind, in a stable order.
+func (h *Handler) playbooks(kind string, object json.RawMessage) ([]embeddedPlaybook, error) {
+	if kind == "ConfigMap" {
+		var cm configMap
+		if err := json.Unmarshal(object, &cm); err != nil {
+			return nil, fmt.Errorf("invalid ConfigMap: %w", err)
+		}
+		var playbooks []embeddedPlaybook
+		for key, value := range cm.Data {
+			if isPlaybookKey(key) {
+				playbooks = append(playbooks, embeddedPlaybook{location: "data." + key, content: []byte(value)})
+			}
+		}
+		for key, value := range cm.BinaryData {
+			if isPlaybookKey(key) {
+				playbooks = append(playbooks, embeddedPlaybook{location: "binaryData." + key, content: value})
+			}
+		}
+		slices.SortFunc(playbooks, func(a, b embeddedPlaybook) int { return strings.Compare(a.location, b.location) })
+		return playbooks, nil
+	}
+
+	fields := h.fields[kind]
+	if len(fields) == 0 {
+		return nil, nil
+	}
+	var document map[string]any
+	if err := json.Unmarshal(object, &document); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", kind, err)
+	}
+	var playbooks []embeddedPlaybook
+	for _, field := range fields {
+		value, err := lookupField(document, field)
+		if err != nil {
+			return nil, err
+		}
+		if value != "" {
+			playbooks = append(playbooks, embeddedPlaybook{location: field, content: []byte(value)})
+		}
+	}
+	return playbooks, nil
+}
+
+// isPlaybookKey reports whether the ConfigMap key names a playbook.
+func isPlaybookKey(key string) bool {
+	return strings.HasSuffix(key, ".yml") || strings.HasSuffix(key, ".yaml")
+}
+
+// lookupField returns the string at the dot-separated path of the document.
+// A missing field is an empty string, as the object embeds no playbook there.
+func lookupField(document map[string]any, field string) (string, error) {
+	var value any = document
+	for _, name := range strings.Split(field, ".") {
+		object, ok := value.(map[string]any)
+		if !ok {
+			return "", nil
+		}
+		if value, ok = object[name]; !ok {
+			return "", nil
+		}
+	}
+	switch value := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return value, nil
+	default:
+		return "", fmt.Errorf("field %s is not a string", field)
+	}
+}
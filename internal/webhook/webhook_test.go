@@ -0,0 +1,168 @@
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"com.github/m-horky/playbook-verifier/internal/service"
+	"com.github/m-horky/playbook-verifier/pkg/verifier"
+)
+
+// testdata is the directory with the signed playbooks and the test key.
+const testdata = "../../pkg/verifier/testdata"
+
+func testHandler(t *testing.T) *Handler {
+	t.Helper()
+	publicKey, err := os.ReadFile(filepath.Join(testdata, "keys", "test-public.asc"))
+	if err != nil {
+		t.Fatalf("os.ReadFile() error = %v", err)
+	}
+	keyring, err := verifier.NewKeyring(publicKey)
+	if err != nil {
+		t.Fatalf("NewKeyring() error = %v", err)
+	}
+	return NewHandler(&service.Verifier{Keyring: keyring}, DefaultFields)
+}
+
+// validate sends the object of the kind to the webhook and returns its response.
+func validate(t *testing.T, h *Handler, kind string, object any) *AdmissionResponse {
+	t.Helper()
+	raw, err := json.Marshal(object)
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	body, err := json.Marshal(AdmissionReview{
+		APIVersion: admissionAPIVersion,
+		Kind:       "AdmissionReview",
+		Request:    &AdmissionRequest{UID: "uid-1", Kind: GroupVersionKind{Kind: kind}, Operation: "CREATE", Object: raw},
+	})
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+
+	recorder := httptest.NewRecorder()
+	h.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/validate", bytes.NewReader(body)))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", recorder.Code, recorder.Body)
+	}
+	var review AdmissionReview
+	if err = json.Unmarshal(recorder.Body.Bytes(), &review); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	if review.Response == nil || review.Response.UID != "uid-1" {
+		t.Fatalf("response = %+v, want the UID of the request", review.Response)
+	}
+	return review.Response
+}
+
+func TestWebhookConfigMap(t *testing.T) {
+	h := testHandler(t)
+	signed, err := os.ReadFile(filepath.Join(testdata, "golden", "cve-update.yml"))
+	if err != nil {
+		t.Fatalf("os.ReadFile() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		object  any
+		allowed bool
+	}{
+		{"signed", map[string]any{"data": map[string]string{"site.yml": string(signed), "README": "text"}}, true},
+		{"signed binary", map[string]any{"binaryData": map[string][]byte{"site.yaml": signed}}, true},
+		{"no playbooks", map[string]any{"data": map[string]string{"config.ini": "[main]\n"}}, true},
+		{"unsigned", map[string]any{"data": map[string]string{"site.yml": string(signed), "other.yml": "- name: unsigned\n"}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response := validate(t, h, "ConfigMap", tt.object)
+			if response.Allowed != tt.allowed {
+				t.Errorf("allowed = %v, want %v (status %+v)", response.Allowed, tt.allowed, response.Status)
+			}
+			if !tt.allowed && (response.Status == nil || !strings.Contains(response.Status.Message, "data.other.yml")) {
+				t.Errorf("status = %+v, want the refused key", response.Status)
+			}
+		})
+	}
+}
+
+func TestWebhookJobTemplate(t *testing.T) {
+	h := testHandler(t)
+	signed, err := os.ReadFile(filepath.Join(testdata, "golden", "cve-update.yml"))
+	if err != nil {
+		t.Fatalf("os.ReadFile() error = %v", err)
+	}
+
+	if response := validate(t, h, "JobTemplate", map[string]any{"spec": map[string]any{"playbook": string(signed)}}); !response.Allowed || len(response.Warnings) != 0 {
+		t.Errorf("signed JobTemplate = %+v, want allowed without warnings", response)
+	}
+	if response := validate(t, h, "JobTemplate", map[string]any{"spec": map[string]any{"playbook": "- name: unsigned\n"}}); response.Allowed {
+		t.Error("unsigned JobTemplate allowed")
+	}
+	if response := validate(t, h, "JobTemplate", map[string]any{"spec": map[string]any{"playbook": 42}}); response.Allowed || response.Status.Code != http.StatusBadRequest {
+		t.Errorf("JobTemplate with a number = %+v, want refused", response)
+	}
+	if response := validate(t, h, "Secret", map[string]any{"data": map[string]string{"site.yml": "- name: unsigned\n"}}); !response.Allowed {
+		t.Error("kind without playbooks refused")
+	}
+}
+
+func TestWebhookWarnOnly(t *testing.T) {
+	h := testHandler(t)
+	if err := h.verifier.Keyring.WarnOnly(h.verifier.Keyring.Fingerprints()...); err != nil {
+		t.Fatalf("WarnOnly() error = %v", err)
+	}
+	signed, err := os.ReadFile(filepath.Join(testdata, "golden", "cve-update.yml"))
+	if err != nil {
+		t.Fatalf("os.ReadFile() error = %v", err)
+	}
+
+	response := validate(t, h, "JobTemplate", map[string]any{"spec": map[string]any{"playbook": string(signed)}})
+	want := []string{"spec.playbook: playbook is signed by warn-only keys 08D82F6981A5FD2F"}
+	if !response.Allowed || !slices.Equal(response.Warnings, want) {
+		t.Errorf("JobTemplate signed by a warn-only key = %+v, want allowed with warnings %q", response, want)
+	}
+}
+
+func TestWebhookUnsigned(t *testing.T) {
+	signed, err := os.ReadFile(filepath.Join(testdata, "golden", "cve-update.yml"))
+	if err != nil {
+		t.Fatalf("os.ReadFile() error = %v", err)
+	}
+	object := map[string]any{"spec": map[string]any{"playbook": string(signed) + "- name: unsigned\n"}}
+
+	tests := []struct {
+		unsigned string
+		allowed  bool
+		warnings []string
+	}{
+		{verifier.UnsignedReject, false, nil},
+		{verifier.UnsignedWarn, true, []string{"spec.playbook: playbook contains unsigned content that has not been verified"}},
+		{verifier.UnsignedAllow, true, nil},
+	}
+	for _, tt := range tests {
+		h := testHandler(t)
+		h.verifier.Policy.Unsigned = tt.unsigned
+		response := validate(t, h, "JobTemplate", object)
+		if response.Allowed != tt.allowed || !slices.Equal(response.Warnings, tt.warnings) {
+			t.Errorf("JobTemplate with an unsigned play under %s = %+v, want allowed %v with warnings %q", tt.unsigned, response, tt.allowed, tt.warnings)
+		}
+	}
+}
+
+func TestWebhookInvalidReview(t *testing.T) {
+	h := testHandler(t)
+	for _, body := range []string{"", "{}", `{"apiVersion": "admission.k8s.io/v1beta1", "request": {"uid": "1"}}`} {
+		recorder := httptest.NewRecorder()
+		h.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/validate", strings.NewReader(body)))
+		if recorder.Code != http.StatusBadRequest {
+			t.Errorf("status of %q = %d, want %d", body, recorder.Code, http.StatusBadRequest)
+		}
+	}
+}
@@ -0,0 +1,226 @@
+// Package yggdrasil deploys the verifier as a worker of yggdrasil, the dispatcher of rhc.
+//
+// The worker receives the payloads yggdrasil dispatches from MQTT over D-Bus, verifies them
+// and forwards only the verified ones to the worker that runs them (rhc-worker-playbook).
+// Rejected payloads are reported back with the Event signal and never reach the runner.
+// Payloads signed by warn-only keys are forwarded, and the keys are reported in the
+// 'warn_only_key_ids' data of the events. Payloads with unsigned content accepted by the policy
+// are forwarded with the 'unsigned' data of the events set to 'true'.
+package yggdrasil
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/godbus/dbus/v5/introspect"
+	"github.com/godbus/dbus/v5/prop"
+
+	"com.github/m-horky/playbook-verifier/internal/audit"
+	"com.github/m-horky/playbook-verifier/internal/service"
+	"com.github/m-horky/playbook-verifier/pkg/verifier"
+)
+
+// D-Bus names of the yggdrasil worker protocol.
+const (
+	WorkerInterface  = "com.redhat.Yggdrasil1.Worker1"
+	workerNamePrefix = WorkerInterface + "."
+	workerPathPrefix = "/com/redhat/Yggdrasil1/Worker1/"
+)
+
+// DefaultForward is the directive of rhc-worker-playbook, the worker verified playbooks are forwarded to.
+const DefaultForward = "rhc_worker_playbook"
+
+// ContentTypeMetadata is the metadata key of the content type of the payload.
+// Payloads without it are verified as Insights playbooks.
+const ContentTypeMetadata = "content_type"
+
+// EventName is the name of a worker event, as numbered by yggdrasil.
+type EventName uint32
+
+// Events emitted while a payload is processed.
+const (
+	EventBegin   EventName = 1
+	EventEnd     EventName = 2
+	EventWorking EventName = 3
+)
+
+// directivePattern matches the directives that are valid D-Bus object path elements.
+var directivePattern = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
+
+// WorkerName returns the bus name of the worker handling the directive.
+func WorkerName(directive string) string {
+	return workerNamePrefix + directive
+}
+
+// WorkerPath returns the object path of the worker handling the directive.
+func WorkerPath(directive string) dbus.ObjectPath {
+	return dbus.ObjectPath(workerPathPrefix + directive)
+}
+
+// workerIntrospection describes the interface of the worker object.
+const workerIntrospection = `
+<node>
+	<interface name="` + WorkerInterface + `">
+		<method name="Dispatch">
+			<arg direction="in" type="s" name="addr"/>
+			<arg direction="in" type="s" name="id"/>
+			<arg direction="in" type="s" name="response_to"/>
+			<arg direction="in" type="a{ss}" name="metadata"/>
+			<arg direction="in" type="ay" name="data"/>
+		</method>
+		<property name="RemoteContent" type="b" access="read"/>
+		<property name="Features" type="a{ss}" access="read"/>
+		<signal name="Event">
+			<arg type="u" name="name"/>
+			<arg type="s" name="message_id"/>
+			<arg type="s" name="response_to"/>
+			<arg type="a{ss}" name="data"/>
+		</signal>
+	</interface>` + prop.IntrospectDataString + introspect.IntrospectDataString + `</node>`
+
+// Worker verifies the payloads dispatched to its directive and forwards the verified ones.
+type Worker struct {
+	conn      *dbus.Conn
+	verifier  *service.Verifier
+	directive string
+	forward   string
+	// pending tracks the payloads being processed.
+	pending sync.WaitGroup
+	// mu guards stopped, so no payload is added to pending once Wait has been called.
+	mu      sync.Mutex
+	stopped bool
+}
+
+// NewWorker returns the worker of the directive, forwarding verified payloads to the forward directive.
+func NewWorker(conn *dbus.Conn, v *service.Verifier, directive, forward string) (*Worker, error) {
+	for _, d := range []string{directive, forward} {
+		if !directivePattern.MatchString(d) {
+			return nil, fmt.Errorf("invalid directive %q: only letters, digits and underscores are allowed", d)
+		}
+	}
+	if directive == forward {
+		return nil, fmt.Errorf("directive %s cannot forward to itself", directive)
+	}
+	return &Worker{conn: conn, verifier: v, directive: directive, forward: forward}, nil
+}
+
+// Export exports the worker object on the connection and requests its bus name,
+// which makes yggdrasil dispatch the payloads of the directive to it.
+func (w *Worker) Export() error {
+	path := WorkerPath(w.directive)
+	if err := w.conn.ExportMethodTable(map[string]any{"Dispatch": w.Dispatch}, path, WorkerInterface); err != nil {
+		return fmt.Errorf("could not export object: %w", err)
+	}
+	_, err := prop.Export(w.conn, path, prop.Map{
+		WorkerInterface: {
+			// yggdrasil fetches the content for the worker, the payload is never a URL.
+			"RemoteContent": {Value: false, Emit: prop.EmitConst},
+			"Features":      {Value: map[string]string{"forward": w.forward}, Emit: prop.EmitConst},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("could not export properties: %w", err)
+	}
+	if err = w.conn.Export(introspect.Introspectable(workerIntrospection), path, "org.freedesktop.DBus.Introspectable"); err != nil {
+		return fmt.Errorf("could not export introspection: %w", err)
+	}
+
+	name := WorkerName(w.directive)
+	reply, err := w.conn.RequestName(name, dbus.NameFlagDoNotQueue)
+	if err != nil {
+		return fmt.Errorf("could not request name %s: %w", name, err)
+	}
+	if reply != dbus.RequestNameReplyPrimaryOwner {
+		return fmt.Errorf("name %s is already taken", name)
+	}
+	return nil
+}
+
+// Dispatch is the D-Bus method yggdrasil delivers the payloads with. It returns immediately,
+// the payload is verified and forwarded in the background and the progress reported by the Event signal.
+func (w *Worker) Dispatch(addr, id, responseTo string, metadata map[string]string, data []byte) *dbus.Error {
+	slog.Debug("payload dispatched", slog.String("directive", addr), slog.String("message_id", id))
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.stopped {
+		return dbus.MakeFailedError(errors.New("worker is stopping"))
+	}
+	w.pending.Add(1)
+	go func() {
+		defer w.pending.Done()
+		w.process(id, responseTo, metadata, data)
+	}()
+	return nil
+}
+
+// Wait refuses the payloads dispatched from now on and waits until the others are processed.
+func (w *Worker) Wait() {
+	w.mu.Lock()
+	w.stopped = true
+	w.mu.Unlock()
+	w.pending.Wait()
+}
+
+// process verifies the payload and forwards it if it is verified.
+func (w *Worker) process(id, responseTo string, metadata map[string]string, data []byte) {
+	w.emit(EventBegin, id, responseTo, nil)
+
+	ctx := audit.WithCaller(context.Background(), "yggdrasil "+id)
+	report, err := w.verifier.VerifyContext(ctx, metadata[ContentTypeMetadata], data)
+	if err != nil {
+		slog.Info("payload rejected", slog.String("message_id", id), slog.Any("error", err))
+		w.emit(EventEnd, id, responseTo, map[string]string{"verified": "false", "error": report.Error})
+		return
+	}
+
+	// payloads signed by warn-only keys or with unsigned content are forwarded, and yggdrasil is told so
+	verified := map[string]string{"verified": "true"}
+	if keyIDs := report.WarnOnlyKeyIDs(); len(keyIDs) > 0 {
+		slog.Warn("payload signed by warn-only keys", slog.String("message_id", id), slog.Any("keys", keyIDs))
+		verified["warn_only_key_ids"] = strings.Join(keyIDs, ",")
+	}
+	if report.HasUnsigned() {
+		if w.verifier.Policy.Unsigned == verifier.UnsignedWarn {
+			slog.Warn("payload contains unsigned content", slog.String("message_id", id))
+		}
+		verified["unsigned"] = "true"
+	}
+	w.emit(EventWorking, id, responseTo, verified)
+	// only the documents that were verified are forwarded
+	if w.verifier.Policy.NormalizePayload {
+		data = verifier.NormalizePayload(data)
+	}
+	if err = w.forwardPayload(id, responseTo, metadata, verifier.VerifiedPayload(data, report)); err != nil {
+		slog.Error("could not forward payload", slog.String("message_id", id), slog.String("directive", w.forward), slog.Any("error", err))
+		verified["error"] = err.Error()
+		w.emit(EventEnd, id, responseTo, verified)
+		return
+	}
+	slog.Info("payload forwarded", slog.String("message_id", id), slog.String("directive", w.forward))
+	w.emit(EventEnd, id, responseTo, verified)
+}
+
+// forwardPayload dispatches the payload to the worker of the forward directive.
+func (w *Worker) forwardPayload(id, responseTo string, metadata map[string]string, data []byte) error {
+	if metadata == nil {
+		metadata = map[string]string{}
+	}
+	object := w.conn.Object(WorkerName(w.forward), WorkerPath(w.forward))
+	return object.Call(WorkerInterface+".Dispatch", 0, w.forward, id, responseTo, metadata, data).Err
+}
+
+// emit emits the Event signal of the payload.
+func (w *Worker) emit(name EventName, id, responseTo string, data map[string]string) {
+	if data == nil {
+		data = map[string]string{}
+	}
+	if err := w.conn.Emit(WorkerPath(w.directive), WorkerInterface+".Event", uint32(name), id, responseTo, data); err != nil {
+		slog.Warn("could not emit event", slog.String("message_id", id), slog.Any("error", err))
+	}
+}
@@ -0,0 +1,251 @@
+package yggdrasil
+
+import (
+	"bufio"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+
+	"com.github/m-horky/playbook-verifier/internal/service"
+	"com.github/m-horky/playbook-verifier/pkg/verifier"
+)
+
+// testdata is the directory with the signed playbooks and the test key.
+const testdata = "../../pkg/verifier/testdata"
+
+// privateBus starts a dbus-daemon for the test and returns its address.
+func privateBus(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("dbus-daemon"); err != nil {
+		t.Skip("dbus-daemon is not installed")
+	}
+
+	cmd := exec.Command("dbus-daemon", "--session", "--nofork", "--print-address")
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatalf("StdoutPipe() error = %v", err)
+	}
+	if err = cmd.Start(); err != nil {
+		t.Fatalf("could not start dbus-daemon: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+	})
+
+	address, err := bufio.NewReader(stdout).ReadString('\n')
+	if err != nil {
+		t.Fatalf("could not read bus address: %v", err)
+	}
+	return strings.TrimSpace(address)
+}
+
+func connectBus(t *testing.T, address string) *dbus.Conn {
+	t.Helper()
+	conn, err := dbus.Connect(address)
+	if err != nil {
+		t.Fatalf("dbus.Connect() error = %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return conn
+}
+
+// runner is the worker the verified payloads are forwarded to.
+type runner struct {
+	payloads chan []byte
+}
+
+func (r *runner) Dispatch(addr, id, responseTo string, metadata map[string]string, data []byte) *dbus.Error {
+	r.payloads <- data
+	return nil
+}
+
+// testVerifier returns a verifier that trusts the test key.
+func testVerifier(t *testing.T) *service.Verifier {
+	t.Helper()
+	publicKey, err := os.ReadFile(filepath.Join(testdata, "keys", "test-public.asc"))
+	if err != nil {
+		t.Fatalf("os.ReadFile() error = %v", err)
+	}
+	keyring, err := verifier.NewKeyring(publicKey)
+	if err != nil {
+		t.Fatalf("NewKeyring() error = %v", err)
+	}
+	return &service.Verifier{Keyring: keyring}
+}
+
+// testWorker exports a worker of the verifier, which must not be changed afterwards,
+// since the worker verifies the dispatched payloads concurrently.
+func testWorker(t *testing.T, address string, v *service.Verifier) *Worker {
+	t.Helper()
+	w, err := NewWorker(connectBus(t, address), v, "playbook_verifier", DefaultForward)
+	if err != nil {
+		t.Fatalf("NewWorker() error = %v", err)
+	}
+	if err = w.Export(); err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	return w
+}
+
+// exportRunner exports a runner of the forward directive on the bus.
+func exportRunner(t *testing.T, address string) *runner {
+	t.Helper()
+	r := &runner{payloads: make(chan []byte, 2)}
+	runnerConn := connectBus(t, address)
+	if err := runnerConn.Export(r, WorkerPath(DefaultForward), WorkerInterface); err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	if _, err := runnerConn.RequestName(WorkerName(DefaultForward), dbus.NameFlagDoNotQueue); err != nil {
+		t.Fatalf("RequestName() error = %v", err)
+	}
+	return r
+}
+
+// dispatch dispatches the payloads to the worker by their message IDs and returns the data
+// of their end events.
+func dispatch(t *testing.T, address string, w *Worker, payloads map[string][]byte) map[string]map[string]string {
+	t.Helper()
+	client := connectBus(t, address)
+	if err := client.AddMatchSignal(dbus.WithMatchInterface(WorkerInterface), dbus.WithMatchMember("Event")); err != nil {
+		t.Fatalf("AddMatchSignal() error = %v", err)
+	}
+	signals := make(chan *dbus.Signal, 16)
+	client.Signal(signals)
+
+	object := client.Object(WorkerName("playbook_verifier"), WorkerPath("playbook_verifier"))
+	for id, payload := range payloads {
+		if err := object.Call(WorkerInterface+".Dispatch", 0, "playbook_verifier", id, "", map[string]string{}, payload).Err; err != nil {
+			t.Fatalf("Dispatch() error = %v", err)
+		}
+	}
+	w.Wait()
+
+	ends := map[string]map[string]string{}
+	for len(ends) < len(payloads) {
+		select {
+		case signal := <-signals:
+			if EventName(signal.Body[0].(uint32)) == EventEnd {
+				ends[signal.Body[1].(string)] = signal.Body[3].(map[string]string)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("end events = %v, want all payloads", ends)
+		}
+	}
+	return ends
+}
+
+func TestWorkerDispatch(t *testing.T) {
+	address := privateBus(t)
+	w := testWorker(t, address, testVerifier(t))
+	r := exportRunner(t, address)
+
+	signed, err := os.ReadFile(filepath.Join(testdata, "golden", "cve-update.yml"))
+	if err != nil {
+		t.Fatalf("os.ReadFile() error = %v", err)
+	}
+	ends := dispatch(t, address, w, map[string][]byte{"signed": signed, "unsigned": []byte("- name: unsigned\n")})
+
+	select {
+	case forwarded := <-r.payloads:
+		if string(forwarded) != string(signed) {
+			t.Errorf("forwarded payload = %q, want the signed playbook", forwarded)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("signed payload was not forwarded")
+	}
+	select {
+	case forwarded := <-r.payloads:
+		t.Errorf("unsigned payload was forwarded: %q", forwarded)
+	default:
+	}
+
+	if ends["signed"]["verified"] != "true" || ends["signed"]["error"] != "" || ends["signed"]["warn_only_key_ids"] != "" || ends["signed"]["unsigned"] != "" {
+		t.Errorf("end event of signed payload = %v", ends["signed"])
+	}
+	if ends["unsigned"]["verified"] != "false" || ends["unsigned"]["error"] == "" {
+		t.Errorf("end event of unsigned payload = %v", ends["unsigned"])
+	}
+
+	var remoteContent bool
+	object := connectBus(t, address).Object(WorkerName("playbook_verifier"), WorkerPath("playbook_verifier"))
+	if err = object.StoreProperty(WorkerInterface+".RemoteContent", &remoteContent); err != nil || remoteContent {
+		t.Errorf("RemoteContent = %v, %v, want false", remoteContent, err)
+	}
+}
+
+func TestWorkerDispatchWarnOnly(t *testing.T) {
+	v := testVerifier(t)
+	if err := v.Keyring.WarnOnly(v.Keyring.Fingerprints()...); err != nil {
+		t.Fatalf("WarnOnly() error = %v", err)
+	}
+	address := privateBus(t)
+	w := testWorker(t, address, v)
+	r := exportRunner(t, address)
+
+	signed, err := os.ReadFile(filepath.Join(testdata, "golden", "cve-update.yml"))
+	if err != nil {
+		t.Fatalf("os.ReadFile() error = %v", err)
+	}
+	ends := dispatch(t, address, w, map[string][]byte{"signed": signed})
+
+	select {
+	case <-r.payloads:
+	case <-time.After(5 * time.Second):
+		t.Fatal("payload signed by a warn-only key was not forwarded")
+	}
+	if ends["signed"]["verified"] != "true" || ends["signed"]["warn_only_key_ids"] != "08D82F6981A5FD2F" {
+		t.Errorf("end event = %v, want the warn-only key", ends["signed"])
+	}
+}
+
+func TestWorkerDispatchUnsigned(t *testing.T) {
+	v := testVerifier(t)
+	v.Policy.Unsigned = verifier.UnsignedWarn
+	address := privateBus(t)
+	w := testWorker(t, address, v)
+	r := exportRunner(t, address)
+
+	signed, err := os.ReadFile(filepath.Join(testdata, "golden", "cve-update.yml"))
+	if err != nil {
+		t.Fatalf("os.ReadFile() error = %v", err)
+	}
+	payload := append(signed, "- name: unsigned\n"...)
+	ends := dispatch(t, address, w, map[string][]byte{"partially signed": payload})
+
+	select {
+	case forwarded := <-r.payloads:
+		if string(forwarded) != string(payload) {
+			t.Errorf("forwarded payload = %q, want the whole payload", forwarded)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("payload with an unsigned play was not forwarded")
+	}
+	if ends["partially signed"]["verified"] != "true" || ends["partially signed"]["unsigned"] != "true" {
+		t.Errorf("end event = %v, want the unsigned content reported", ends["partially signed"])
+	}
+}
+
+func TestNewWorkerDirective(t *testing.T) {
+	for _, directive := range []string{"", "rhc-worker-playbook", "a/b", DefaultForward} {
+		if _, err := NewWorker(nil, nil, directive, DefaultForward); err == nil {
+			t.Errorf("NewWorker(%q) error = nil, want error", directive)
+		}
+	}
+}
+
+func TestWorkerDispatchAfterWait(t *testing.T) {
+	w, err := NewWorker(nil, nil, "playbook_verifier", DefaultForward)
+	if err != nil {
+		t.Fatalf("NewWorker() error = %v", err)
+	}
+	w.Wait()
+	if err := w.Dispatch("playbook_verifier", "late", "", map[string]string{}, []byte("- name: late\n")); err == nil {
+		t.Error("Dispatch() error = nil after Wait(), want the payload refused")
+	}
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+
+	"github.com/coreos/go-systemd/v22/journal"
+)
+
+// journaldSupported reports whether the logs can be sent to systemd-journald.
+const journaldSupported = true
+
+// journaldIdentifier is the SYSLOG_IDENTIFIER of the log entries.
+const journaldIdentifier = "insights-playbook-verifier"
+
+// JournaldHandler is a slog.Handler sending records to systemd-journald.
+//
+// Log levels are mapped to syslog priorities and attributes are sent as journal fields,
+// so `journalctl -p` filtering works as expected.
+type JournaldHandler struct {
+	level slog.Leveler
+	// prefix is the field name prefix of the current group.
+	prefix string
+	fields map[string]string
+}
+
+// NewJournaldHandler creates a handler logging records at or above the level.
+func NewJournaldHandler(level slog.Leveler) *JournaldHandler {
+	return &JournaldHandler{level: level, fields: map[string]string{"SYSLOG_IDENTIFIER": journaldIdentifier}}
+}
+
+// newJournaldHandler creates the handler of the journald log format.
+func newJournaldHandler(level slog.Leveler) slog.Handler {
+	return NewJournaldHandler(level)
+}
+
+func (h *JournaldHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+func (h *JournaldHandler) Handle(_ context.Context, record slog.Record) error {
+	fields := make(map[string]string, len(h.fields)+record.NumAttrs())
+	for name, value := range h.fields {
+		fields[name] = value
+	}
+	record.Attrs(func(attr slog.Attr) bool {
+		addJournaldField(fields, h.prefix, attr)
+		return true
+	})
+	return journal.Send(record.Message, journaldPriority(record.Level), fields)
+}
+
+func (h *JournaldHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	clone := h.clone()
+	for _, attr := range attrs {
+		addJournaldField(clone.fields, clone.prefix, attr)
+	}
+	return clone
+}
+
+func (h *JournaldHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	clone := h.clone()
+	clone.prefix = h.prefix + journaldFieldName(name) + "_"
+	return clone
+}
+
+func (h *JournaldHandler) clone() *JournaldHandler {
+	fields := make(map[string]string, len(h.fields))
+	for name, value := range h.fields {
+		fields[name] = value
+	}
+	return &JournaldHandler{level: h.level, prefix: h.prefix, fields: fields}
+}
+
+// addJournaldField converts the attribute into one or more journal fields.
+func addJournaldField(fields map[string]string, prefix string, attr slog.Attr) {
+	attr.Value = attr.Value.Resolve()
+	if attr.Equal(slog.Attr{}) {
+		return
+	}
+	name := journaldFieldName(attr.Key)
+	if attr.Value.Kind() == slog.KindGroup {
+		groupPrefix := prefix
+		if name != "" {
+			groupPrefix += name + "_"
+		}
+		for _, groupAttr := range attr.Value.Group() {
+			addJournaldField(fields, groupPrefix, groupAttr)
+		}
+		return
+	}
+	if name == "" {
+		return
+	}
+	fields[prefix+name] = attr.Value.String()
+}
+
+// journaldFieldName converts the key into a valid journal field name.
+//
+// Field names may only contain uppercase letters, digits and underscores,
+// and must not start with an underscore, which is reserved for trusted fields.
+func journaldFieldName(key string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_':
+			return r
+		default:
+			return '_'
+		}
+	}, key)
+	return strings.TrimLeft(name, "_")
+}
+
+// journaldPriority maps the slog level to a syslog priority.
+func journaldPriority(level slog.Level) journal.Priority {
+	switch {
+	case level >= slog.LevelError:
+		return journal.PriErr
+	case level >= slog.LevelWarn:
+		return journal.PriWarning
+	case level >= slog.LevelInfo:
+		return journal.PriInfo
+	default:
+		return journal.PriDebug
+	}
+}
+
+// isJournalStream reports whether standard error is connected to systemd-journald.
+func isJournalStream() bool {
+	ok, err := journal.StderrIsJournalStream()
+	return err == nil && ok
+}
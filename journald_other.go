@@ -0,0 +1,21 @@
+//go:build !linux
+
+package main
+
+import (
+	"log/slog"
+	"os"
+)
+
+// journaldSupported reports whether the logs can be sent to systemd-journald.
+const journaldSupported = false
+
+// newJournaldHandler is never called, the journald log format is rejected by parseArguments.
+func newJournaldHandler(level slog.Leveler) slog.Handler {
+	return slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: level})
+}
+
+// isJournalStream reports whether standard error is connected to systemd-journald.
+func isJournalStream() bool {
+	return false
+}
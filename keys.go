@@ -0,0 +1,239 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"syscall"
+	"time"
+
+	"com.github/m-horky/playbook-verifier/internal/gpgexec"
+	"com.github/m-horky/playbook-verifier/internal/httpclient"
+	"com.github/m-horky/playbook-verifier/internal/keystore"
+	"com.github/m-horky/playbook-verifier/internal/rekor"
+	"com.github/m-horky/playbook-verifier/pkg/verifier"
+)
+
+// mustLoadKeyring creates the keyring of the keys of the trust stores and the keys from
+// the key directories, or exits. The keys of warn-only trust stores are only trusted with a warning,
+// even if another trust store contains them too.
+//
+// A binary without embedded keys only fails if no other store or directory has any keys either.
+func mustLoadKeyring(arguments *Arguments) *verifier.Keyring {
+	var publicKeys [][]byte
+	var warnOnly []string
+	for _, store := range arguments.Stores {
+		storeKeys, err := store.PublicKeys()
+		if errors.Is(err, keystore.ErrNoEmbeddedKeys) {
+			// the other stores and the key directories may still hold the keys, e.g. on air-gapped hosts
+			slog.Warn("trust store holds no keys", slog.String("store", store.String()), slog.Any("error", err))
+			continue
+		}
+		if err != nil {
+			slog.Error("could not load keys from trust store", slog.String("store", store.String()), slog.Any("error", err))
+			os.Exit(ExitIOError)
+		}
+		slog.Debug("using keys from trust store", slog.String("store", store.String()), slog.Int("keys", len(storeKeys)))
+		publicKeys = append(publicKeys, storeKeys...)
+		if _, ok := store.(keystore.WarnOnlyStore); ok && len(storeKeys) > 0 {
+			storeKeyring, err := verifier.NewKeyring(storeKeys...)
+			if err != nil {
+				slog.Error("could not load keys from trust store", slog.String("store", store.String()), slog.Any("error", err))
+				os.Exit(exitCode(err))
+			}
+			warnOnly = append(warnOnly, storeKeyring.Fingerprints()...)
+		}
+	}
+	for _, directory := range arguments.KeyDirectories {
+		directoryKeys, err := keystore.DirectoryPublicKeys(directory)
+		if err != nil {
+			slog.Error("could not load keys from directory", slog.Any("error", err))
+			os.Exit(ExitIOError)
+		}
+		slog.Debug("using keys from directory", slog.String("directory", directory), slog.Int("keys", len(directoryKeys)))
+		publicKeys = append(publicKeys, directoryKeys...)
+	}
+	if len(publicKeys) == 0 {
+		slog.Error("no trusted keys in the trust stores and key directories", slog.Any("stores", arguments.TrustStores), slog.Any("directories", arguments.KeyDirectories))
+		os.Exit(ExitIOError)
+	}
+	keyring := mustNewKeyring(publicKeys, warnOnly, arguments)
+	if slices.Contains(arguments.TrustStores, keystore.DefaultStore) {
+		slog.Debug("using embedded keys", slog.String("environment", keystore.Environment()))
+	}
+	if arguments.KeyURL != "" {
+		// The bundle has to be signed by a key trusted without it.
+		remoteKeys, err := remoteStore(arguments, keyring).PublicKeys()
+		if err != nil {
+			slog.Error("could not load keys from key bundle", slog.String("url", arguments.KeyURL), slog.Any("error", err))
+			os.Exit(ExitIOError)
+		}
+		if len(remoteKeys) > 0 {
+			slog.Debug("using keys from key bundle", slog.String("url", arguments.KeyURL))
+			keyring = mustNewKeyring(append(publicKeys, remoteKeys...), warnOnly, arguments)
+		}
+	}
+	if arguments.GPGBackend == GPGBackendExec {
+		backend, err := gpgexec.New(gpgexec.DefaultBinary, arguments.TmpDir)
+		if err != nil {
+			slog.Error("could not set up GPG backend", slog.Any("error", err))
+			os.Exit(ExitIOError)
+		}
+		keyring.SetOpenPGPBackend(backend.Verify)
+		// The service removes the directories itself while shutting down gracefully.
+		if arguments.Command != CommandServe {
+			go removeGPGHomesOnSignal()
+		}
+	}
+	if arguments.CABundle != "" {
+		if err := trustCertificateAuthorities(keyring, arguments.CABundle); err != nil {
+			slog.Error("could not load CA bundle", slog.Any("error", err))
+			os.Exit(ExitIOError)
+		}
+	}
+	if arguments.SigstoreRoots != "" {
+		if err := trustSigstore(keyring, arguments); err != nil {
+			slog.Error("could not load Sigstore trust root", slog.Any("error", err))
+			os.Exit(ExitIOError)
+		}
+	}
+	return keyring
+}
+
+// mustNewKeyring creates the keyring of the public keys without the revoked keys, or exits.
+// The keys of the warnOnly fingerprints are only trusted with a warning.
+func mustNewKeyring(publicKeys [][]byte, warnOnly []string, arguments *Arguments) *verifier.Keyring {
+	keyring, err := verifier.NewKeyring(publicKeys...)
+	if err != nil {
+		slog.Error("could not create keyring", slog.Any("error", err))
+		os.Exit(exitCode(err))
+	}
+	slog.Debug("keyring loaded", slog.Any("keys", keyring.KeyIDs()))
+	if err = revokeKeys(keyring, arguments.RevokedKeys); err != nil {
+		slog.Error("could not load revoked keys", slog.Any("error", err))
+		os.Exit(ExitIOError)
+	}
+	if err = keyring.WarnOnly(warnOnly...); err != nil {
+		slog.Error("could not load warn-only keys", slog.Any("error", err))
+		os.Exit(exitCode(err))
+	}
+	if len(warnOnly) > 0 {
+		slog.Debug("keys are only trusted with a warning", slog.Any("keys", keyring.WarnOnlyKeys()))
+	}
+	return keyring
+}
+
+// remoteStore returns the store of the key bundle of the arguments, whose signature
+// is verified by the keyring. Bundles signed by warn-only keys are refused.
+func remoteStore(arguments *Arguments, keyring *verifier.Keyring) *keystore.RemoteStore {
+	store := &keystore.RemoteStore{
+		URL:     arguments.KeyURL,
+		Pins:    arguments.KeyURLPins,
+		HTTP:    httpConfig(arguments),
+		Refresh: arguments.KeyURLRefresh,
+		Verify: func(bundle, signature []byte) (time.Time, error) {
+			report, err := verifier.VerifyDetached(bundle, signature, keyring)
+			if err != nil || report.Signature == nil {
+				return time.Time{}, err
+			}
+			// its keys would be trusted fully, more than the key that vouches for them
+			if report.TrustLevel == verifier.TrustLevelWarnOnly {
+				return time.Time{}, fmt.Errorf("key bundle is signed by the warn-only key %s", report.KeyID)
+			}
+			return report.Signature.Created, nil
+		},
+	}
+	// The bundle is cached next to the verification reports, unless nothing may be written.
+	if !arguments.NoTmpFiles {
+		store.CacheDir = filepath.Join(arguments.CacheDir, "keys")
+	}
+	return store
+}
+
+// httpConfig returns how the online features reach the network.
+func httpConfig(arguments *Arguments) httpclient.Config {
+	return httpclient.Config{
+		InsightsConfig: arguments.InsightsClientConfig,
+		CADirectories:  arguments.TLSCADirectories,
+	}
+}
+
+// removeGPGHomesOnSignal removes the temporary GNUPGHOME directories when the process
+// is interrupted, since deferred functions do not run then.
+func removeGPGHomesOnSignal() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	received := <-signals
+	gpgexec.RemoveHomes()
+	exit(128 + int(received.(syscall.Signal)))
+}
+
+// trustCertificateAuthorities makes the keyring accept PKCS#7 signatures of the CA bundle.
+func trustCertificateAuthorities(keyring *verifier.Keyring, path string) error {
+	certificates, err := keystore.Certificates(path)
+	if err != nil {
+		return err
+	}
+	if err = keyring.TrustCertificateAuthorities(certificates...); err != nil {
+		return err
+	}
+	slog.Debug("certificate authorities trusted", slog.Int("certificates", len(certificates)))
+	return nil
+}
+
+// trustSigstore makes the keyring accept Sigstore bundles of the identities in the arguments.
+func trustSigstore(keyring *verifier.Keyring, arguments *Arguments) error {
+	roots, intermediates, err := keystore.SigstoreCertificates(arguments.SigstoreRoots)
+	if err != nil {
+		return err
+	}
+	rekorKeys, err := keystore.RekorKeys(arguments.SigstoreRekorKeys)
+	if err != nil {
+		return err
+	}
+	root := verifier.SigstoreTrustRoot{
+		Roots:         roots,
+		Intermediates: intermediates,
+		RekorKeys:     rekorKeys,
+		Identities:    arguments.SigstoreIdentities,
+	}
+	if arguments.SigstoreRekorURL != "" {
+		client, err := httpclient.New(httpConfig(arguments))
+		if err != nil {
+			return err
+		}
+		root.FetchEntry = rekor.New(arguments.SigstoreRekorURL, client).Entry
+	}
+	if err = keyring.TrustSigstore(root); err != nil {
+		return err
+	}
+	slog.Debug("Sigstore trusted", slog.Any("identities", root.Identities))
+	return nil
+}
+
+// revokeKeys refuses the keys listed in the embedded revocation list and in the file, if set.
+func revokeKeys(keyring *verifier.Keyring, path string) error {
+	revoked, err := keystore.RevokedKeys()
+	if err != nil {
+		return err
+	}
+	if path != "" {
+		local, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		revoked = append(append(revoked, '\n'), local...)
+	}
+	fingerprints := verifier.ParseRevocationList(revoked)
+	if err = keyring.Revoke(fingerprints...); err != nil {
+		return err
+	}
+	if len(fingerprints) > 0 {
+		slog.Debug("keys revoked", slog.Any("keys", fingerprints))
+	}
+	return nil
+}
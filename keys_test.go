@@ -0,0 +1,81 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestKeyDirectoryOnly verifies with the keys of --gpg-key-dir alone, next to the embedded store,
+// which has no keys in builds of an environment whose keys have not been added.
+func TestKeyDirectoryOnly(t *testing.T) {
+	playbook := signedPlaybook(t, testPlaybook)
+	path := filepath.Join(t.TempDir(), "playbook.yml")
+	if err := os.WriteFile(path, playbook, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	config := filepath.Join(t.TempDir(), "playbook-verifier.conf")
+	if err := os.WriteFile(config, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	args := []string{"--config", config, "--audit-log", "", "--no-cache", "--payload", path}
+
+	t.Run("key directory", func(t *testing.T) {
+		stdout, stderr, code := runMain(t, nil, nil, append(args, "--gpg-key-dir", testKeyDirectory(t))...)
+		if code != ExitOK {
+			t.Fatalf("exit code = %d, want %d; stderr:\n%s", code, ExitOK, stderr)
+		}
+		if stdout != string(playbook) {
+			t.Errorf("stdout = %q, want the playbook", stdout)
+		}
+	})
+
+	t.Run("no keys", func(t *testing.T) {
+		emptyDir := t.TempDir()
+		_, stderr, code := runMain(t, nil, nil, append(args, "--trust-store", "dir:"+emptyDir, "--gpg-key-dir", emptyDir)...)
+		if code != ExitIOError {
+			t.Errorf("exit code = %d, want %d", code, ExitIOError)
+		}
+		if !strings.Contains(stderr, "no trusted keys") {
+			t.Errorf("stderr = %q, want the missing keys explained", stderr)
+		}
+	})
+}
+
+func TestRemoteStoreVerify(t *testing.T) {
+	detached := filepath.Join("pkg", "verifier", "testdata", "detached")
+	bundle, err := os.ReadFile(filepath.Join(detached, "playbook.yml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	signature, err := os.ReadFile(filepath.Join(detached, "playbook.yml.asc"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		warnOnly bool
+		wantErr  bool
+	}{
+		{name: "signed by a trusted key"},
+		{name: "signed by a warn-only key", warnOnly: true, wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			arguments := parseTestArguments(t)
+			keyring := mustLoadKeyring(arguments)
+			arguments.KeyURL = "https://keys.example.com/bundle.asc"
+			if test.warnOnly {
+				if err := keyring.WarnOnly(keyring.Fingerprints()...); err != nil {
+					t.Fatal(err)
+				}
+			}
+			_, err := remoteStore(arguments, keyring).Verify(bundle, signature)
+			if (err != nil) != test.wantErr {
+				t.Errorf("Verify() error = %v, want error %t", err, test.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+)
+
+// Log formats.
+const (
+	// LogFormatAuto uses journald when running under systemd, text otherwise.
+	LogFormatAuto     = "auto"
+	LogFormatText     = "text"
+	LogFormatJSON     = "json"
+	LogFormatJournald = "journald"
+)
+
+// setupLogging configures the default logger according to the arguments.
+//
+// The log file, if any, stays open for the lifetime of the process.
+func setupLogging(arguments *Arguments) error {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(arguments.LogLevel)); err != nil {
+		return err
+	}
+
+	var destination io.Writer = os.Stderr
+	if arguments.LogFile != "" {
+		file, err := os.OpenFile(arguments.LogFile, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+		if err != nil {
+			return fmt.Errorf("could not open log file: %w", err)
+		}
+		destination = file
+	}
+
+	format := arguments.LogFormat
+	if format == LogFormatAuto {
+		format = LogFormatText
+		if arguments.LogFile == "" && isJournalStream() {
+			format = LogFormatJournald
+		}
+	}
+
+	options := &slog.HandlerOptions{Level: level}
+	var handler slog.Handler
+	switch format {
+	case LogFormatJournald:
+		handler = newJournaldHandler(level)
+	case LogFormatJSON:
+		handler = slog.NewJSONHandler(destination, options)
+	default:
+		handler = slog.NewTextHandler(destination, options)
+	}
+
+	slog.SetDefault(slog.New(handler))
+	return nil
+}
@@ -1,210 +1,311 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"log/slog"
 	"os"
-	"reflect"
 	"strings"
+	"time"
 
-	"gopkg.in/yaml.v2"
+	"com.github/m-horky/playbook-verifier/internal/cache"
+	"com.github/m-horky/playbook-verifier/internal/hygiene"
+	"com.github/m-horky/playbook-verifier/internal/i18n"
+	"com.github/m-horky/playbook-verifier/internal/summary"
+	"com.github/m-horky/playbook-verifier/pkg/verifier"
 )
 
-var DynamicLabels = map[string]any{"hosts": nil, "vars": nil}
-
-type PlaybookError struct {
-	message string
+type PlaybookSource struct {
+	stdin bool
+	path  string
+	// descriptor is set when the playbook is read from the inherited file descriptor fd.
+	descriptor bool
+	fd         uintptr
 }
 
-func (e PlaybookError) Error() string {
-	return fmt.Sprintf("playbook error: %s", e.message)
-}
+// NewPlaybookSource detects the location for the playbook.
+//
+// A non-negative fd passed via `--payload-fd` is a file descriptor inherited from the parent
+// process, e.g. a pipe, so the playbook never has to be written to disk.
+// Otherwise, the payload is a path passed via `--payload` (or its environment variables,
+// including `PLAYBOOK_SOURCE`, see applyEnvironment).
+// If it is not set or the path is '-', the source will be set to standard input.
+func NewPlaybookSource(payload string, fd int) PlaybookSource {
+	if fd >= 0 {
+		source := PlaybookSource{descriptor: true, fd: uintptr(fd)}
+		slog.Debug("determined playbook source", slog.String("source", source.String()))
+		return source
+	}
 
-type VerificationError struct {
-	message string
+	path := payload
+	if path == "-" {
+		path = ""
+	}
+	source := PlaybookSource{stdin: path == "", path: path}
+	slog.Debug("determined playbook source", slog.String("source", source.String()))
+	return source
 }
 
-func (e VerificationError) Error() string {
-	return fmt.Sprintf("verification error: %s", e.message)
+func (s PlaybookSource) String() string {
+	if s.descriptor {
+		return fmt.Sprintf("fd %d", s.fd)
+	}
+	if s.stdin {
+		return "stdin"
+	}
+	return s.path
 }
 
-type PlaybookSource struct {
-	stdin bool
-	path  string
-}
+func main() {
+	// Setup
+	arguments := mustParseArguments()
+	if err := setupLogging(arguments); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %s\n", err)
+		os.Exit(ExitIOError)
+	}
+	ctx := setupTracing()
+	defer shutdownTracing()
 
-func UnmarshalPlaybook(playbook []byte) (yaml.MapSlice, error) {
-	var data []yaml.MapSlice
-	if err := yaml.Unmarshal(playbook, &data); err != nil {
-		return nil, err
+	// Describe the build
+	if arguments.Version {
+		exit(printVersion(os.Stdout, arguments.Format))
 	}
 
-	if len(data) == 0 {
-		return nil, PlaybookError{"playbook contains no data"}
+	// Make ansible-playbook verify playbooks of Insights
+	if arguments.AnsiblePluginDir != "" {
+		exit(installAnsiblePlugin(arguments.AnsiblePluginDir))
 	}
-	if len(data) > 1 {
-		return nil, PlaybookError{"input cannot contain more than one playbook"}
+
+	// Run the service
+	if arguments.Command == CommandServe {
+		exit(serve(arguments, mustLoadKeyring(arguments)))
 	}
-	return data[0], nil
-}
 
-// GetPlaybookExclusions extracts dynamic keys that are meant to be excluded from the playbook hash.
-func GetPlaybookExclusions(p *yaml.MapSlice) ([][]string, error) {
-	rawExclusions := ""
-	for _, item := range *p {
-		if item.Key.(string) != "vars" {
-			continue
-		}
-		vars := item.Value.(yaml.MapSlice)
-		for _, pair := range vars {
-			if pair.Key.(string) != "insights_signature_exclude" {
-				continue
-			}
-			rawExclusions = pair.Value.(string)
-			break
-		}
+	// Guard the Kubernetes API
+	if arguments.Command == CommandWebhook {
+		exit(serveWebhook(arguments, mustLoadKeyring(arguments)))
 	}
 
-	if rawExclusions == "" {
-		return nil, PlaybookError{"playbook doesn't contain key 'insights_signature_exclude'"}
+	// Check the environment
+	if arguments.Command == CommandSelfTest {
+		exit(selfTest(ctx, os.Stdout, arguments))
 	}
 
-	var exclusions [][]string
-	for _, exclusion := range strings.Split(rawExclusions, ",") {
-		exclusionBits := strings.TrimPrefix(exclusion, "/")
-		exclusions = append(exclusions, strings.Split(exclusionBits, "/"))
+	// Verify an ansible-runner project before it is run
+	if arguments.Command == CommandRunnerHook {
+		exit(runnerHook(ctx, arguments, mustLoadKeyring(arguments)))
 	}
-	return exclusions, nil
-}
 
-func CleanPlaybook(p *yaml.MapSlice) (*yaml.MapSlice, error) {
-	exclusions, err := GetPlaybookExclusions(p)
+	// Verify a whole directory
+	if arguments.PayloadDir != "" {
+		exit(verifyDirectory(ctx, arguments, mustLoadKeyring(arguments)))
+	}
+
+	// Verify a stream of payloads
+	if arguments.BatchStdin {
+		exit(verifyStream(ctx, os.Stdin, os.Stdout, arguments, mustLoadKeyring(arguments)))
+	}
+
+	// Load playbook
+	var rawPlaybook []byte
+	var err error
+	if arguments.PayloadURL != "" {
+		rawPlaybook, err = downloadPlaybook(ctx, arguments)
+	} else {
+		rawPlaybook, err = readPlaybook(NewPlaybookSource(arguments.Payload, arguments.PayloadFD), arguments.MaxSize, arguments.ReadTimeout)
+	}
 	if err != nil {
-		return nil, err
+		slog.Error("error getting playbook content", slog.Any("error", err))
+		exit(ExitIOError)
 	}
 
-	clean := yaml.MapSlice{}
-	for _, directValue := range *p {
-		directValueName := directValue.Key.(string)
-		skipDirectValue := false
-
-		if reflect.TypeOf(directValue.Value) == reflect.TypeOf(yaml.MapSlice{}) {
-			// nested exclusion
-			newDirectValue := yaml.MapSlice{}
-
-			for _, nestedValue := range directValue.Value.(yaml.MapSlice) {
-				nestedValueName := nestedValue.Key.(string)
-				skipNestedValue := false
-
-				for _, exclusion := range exclusions {
-					if directValueName == exclusion[0] && len(exclusion) == 2 && nestedValueName == exclusion[1] {
-						skipNestedValue = true
-					}
-				}
-
-				if skipNestedValue {
-					slog.Info("excluding nested", slog.String("path", directValueName+"/"+nestedValueName))
-					continue
-				}
-
-				slog.Debug("including nested", slog.String("path", directValueName+"/"+nestedValueName))
-				newDirectValue = append(newDirectValue, yaml.MapItem{Key: nestedValue.Key, Value: nestedValue.Value})
-			}
-
-			directValue = yaml.MapItem{Key: directValue.Key, Value: newDirectValue}
-		} else {
-			// simple exclusion
-			for _, exclusion := range exclusions {
-				if directValueName == exclusion[0] && len(exclusion) == 1 {
-					skipDirectValue = true
-				}
-			}
-			if skipDirectValue {
-				slog.Info("excluding direct", slog.String("path", directValueName))
-				continue
-			}
-		}
+	// Unpack it from a remediation archive
+	rawPlaybook, err = unpackPlaybook(rawPlaybook, arguments)
+	if err != nil {
+		slog.Error("could not unpack playbook", slog.Any("error", err))
+		exit(exitCode(err))
+	}
 
-		slog.Debug("including direct", slog.String("path", directValueName))
-		clean = append(clean, directValue)
+	// Print what has to be signed
+	if arguments.Command == CommandStripSignature {
+		mustSandbox(arguments)
+		exit(stripSignatures(os.Stdout, rawPlaybook))
 	}
 
-	slog.Debug("playbook cleaned")
-	return &clean, nil
-}
+	// Sign it for a test environment
+	if arguments.Command == CommandSign {
+		exit(signPlaybook(os.Stdout, rawPlaybook, arguments))
+	}
 
-// NewPlaybookSource detects the location for the playbook.
-//
-// If environment variable `PLAYBOOK_SOURCE` is set, it is interpreted as a path on a filesystem.
-// If the variable is empty or not set, the source will be set to standard input.
-func NewPlaybookSource() PlaybookSource {
-	path := os.Getenv("PLAYBOOK_SOURCE")
-	source := PlaybookSource{stdin: path == "", path: path}
-	slog.Debug("determined playbook source", slog.Any("source", source))
-	return source
-}
+	// Print what the signer has to sign, exactly
+	if arguments.EmitCanonical != "" {
+		exit(emitCanonical(rawPlaybook, arguments))
+	}
 
-func (s PlaybookSource) String() string {
-	if s.stdin {
-		return "stdin"
+	// Explain what would be verified
+	if arguments.Inspect {
+		mustSandbox(arguments)
+		inspections, err := verifier.InspectPlaybook(rawPlaybook)
+		if err != nil {
+			slog.Error("could not inspect playbook", slog.Any("error", err))
+			exit(exitCode(err))
+		}
+		if err = printInspection(os.Stdout, inspections, arguments.Format); err != nil {
+			slog.Error("could not print inspection", slog.Any("error", err))
+			exit(ExitIOError)
+		}
+		exit(ExitOK)
 	}
-	return s.path
-}
 
-func main() {
-	// Setup
-	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelDebug}))
-	slog.SetDefault(logger)
+	// Load trusted keys
+	keyring := mustLoadKeyring(arguments)
+	auditLog := openAuditLog(arguments)
 
-	// Load playbook from stdin
-	source := NewPlaybookSource()
-	rawPlaybook, err := readPlaybook(source)
-	if err != nil {
-		slog.Error("error getting playbook content", slog.Any("error", err))
-		return
+	var signature []byte
+	if arguments.Signature != "" {
+		signature, err = readSignature(arguments.Signature, arguments.MaxSize)
+		if err != nil {
+			slog.Error("error getting detached signature", slog.Any("error", err))
+			exit(ExitIOError)
+		}
 	}
+	mustSandbox(arguments)
 
-	// Convert it into object
-	dirty, err := UnmarshalPlaybook(rawPlaybook)
+	// Verify it
+	var report verifier.Report
+	if arguments.Signature != "" {
+		report, err = verifier.VerifyDetachedContext(ctx, rawPlaybook, signature, keyring, arguments.Policy())
+		hygiene.Wipe(signature)
+	} else {
+		report, err = verifyCached(ctx, arguments, rawPlaybook, keyring)
+	}
+	if err == nil && arguments.FilesManifest != "" {
+		if err = checkFilesManifest(arguments, keyring); err != nil {
+			report.Verified = false
+			report.Error = err.Error()
+		}
+	}
+	recordVerification(auditLog, arguments.ContentType, rawPlaybook, report, err)
 	if err != nil {
-		slog.Error("could not parse playbook", slog.Any("error", err))
-		return
+		slog.Error("could not verify playbook", slog.Any("error", err))
+	}
+	if arguments.ShowDiff && arguments.Format != FormatJSON {
+		for _, play := range report.Plays {
+			fmt.Fprint(os.Stderr, play.Diff)
+		}
+	}
+	if arguments.Format == FormatJSON {
+		if encodeErr := json.NewEncoder(os.Stdout).Encode(report); encodeErr != nil {
+			slog.Error("could not print report", slog.Any("error", encodeErr))
+			exit(ExitIOError)
+		}
+		exit(verdictCode(report, err))
+	}
+	if arguments.Report != "" {
+		if renderErr := summary.Render(os.Stdout, arguments.Report, report, i18n.ErrorMessage(i18n.NewPrinter(), err)); renderErr != nil {
+			slog.Error("could not print report", slog.Any("error", renderErr))
+			exit(ExitIOError)
+		}
+		exit(verdictCode(report, err))
 	}
-
-	// Delete dynamic elements
-	clean, err := CleanPlaybook(&dirty)
 	if err != nil {
-		slog.Error("could not clean playbook", slog.Any("error", err))
+		// the description for the user is localized, the log of the error is not
+		fmt.Fprintln(os.Stderr, i18n.ErrorMessage(i18n.NewPrinter(), err))
+		printDiagnoses(os.Stderr, report)
+		exit(exitCode(err))
 	}
 
-	// Serialize it
-	serialized, err := MarshallPlaybook(clean)
-	if err != nil {
-		slog.Error("could not serialize playbook", slog.Any("error", err))
+	printWarnings(os.Stderr, "", report, arguments.Unsigned)
+
+	// Print the verified documents of the original playbook, byte for byte
+	if arguments.Output != "" {
+		if err = writeOutput(arguments, verifiedPayload(rawPlaybook, report, arguments)); err != nil {
+			slog.Error("could not write playbook", slog.String("path", arguments.Output), slog.Any("error", err))
+			exit(ExitIOError)
+		}
+		exit(verdictCode(report, nil))
 	}
-	fmt.Println(string(serialized))
+	if _, err = os.Stdout.Write(verifiedPayload(rawPlaybook, report, arguments)); err != nil {
+		slog.Error("could not print playbook", slog.Any("error", err))
+		exit(ExitIOError)
+	}
+	exit(verdictCode(report, nil))
+}
 
-	// Create a hash
+// printWarnings writes the localized warnings about the verified report, prefixed by the payload
+// they are about, if there are several. Unsigned content is only warned about with the 'warn' handling.
+func printWarnings(w io.Writer, prefix string, report verifier.Report, unsigned string) {
+	if keyIDs := report.WarnOnlyKeyIDs(); len(keyIDs) > 0 {
+		fmt.Fprintln(w, prefix+i18n.WarnOnlyMessage(i18n.NewPrinter(), keyIDs))
+	}
+	if report.HasUnsigned() && unsigned == verifier.UnsignedWarn {
+		fmt.Fprintln(w, prefix+i18n.UnsignedMessage(i18n.NewPrinter()))
+	}
+}
 
-	// Verify the hash
+// verifiedPayload returns the documents of the payload the report verified, normalized if the arguments ask for it.
+func verifiedPayload(payload []byte, report verifier.Report, arguments *Arguments) []byte {
+	if arguments.NormalizePayload {
+		payload = verifier.NormalizePayload(payload)
+	}
+	return verifier.VerifiedPayload(payload, report)
+}
 
-	// Print the original playbook
-	return
+// mustSandbox restricts the process if the arguments ask for it, or exits.
+//
+// It is called once everything has been read, so a vulnerability of the parsers cannot
+// be used to reach the host.
+func mustSandbox(arguments *Arguments) {
+	if arguments.Sandbox != SandboxStrict {
+		return
+	}
+	if os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") != "" || os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") != "" {
+		slog.Warn("spans cannot be exported from the sandbox")
+	}
+	if err := applySandbox(); err != nil {
+		slog.Error("could not apply sandbox", slog.Any("error", err))
+		exit(ExitIOError)
+	}
 }
 
 // readPlaybook reads the playbook verifier from either stdin or from a file.
-func readPlaybook(source PlaybookSource) ([]byte, error) {
+//
+// The playbook must not be larger than maxSize bytes. Standard input and file descriptors
+// have to be closed before the timeout passes, so a caller that never closes the pipe
+// does not block forever.
+func readPlaybook(source PlaybookSource, maxSize int64, timeout time.Duration) ([]byte, error) {
 	var rawPlaybook []byte
-	if source.stdin {
-		playbook, err := io.ReadAll(os.Stdin)
+	if source.descriptor {
+		file := os.NewFile(source.fd, source.String())
+		if file == nil {
+			err := fmt.Errorf("invalid file descriptor %d", source.fd)
+			slog.Error("could not read playbook from file descriptor", slog.Any("error", err))
+			return []byte{}, err
+		}
+		defer file.Close()
+		playbook, err := readLimited(file, maxSize, timeout)
+		if err != nil {
+			slog.Error("could not read playbook from file descriptor", slog.Any("error", err))
+			return []byte{}, err
+		}
+		rawPlaybook = playbook
+	} else if source.stdin {
+		playbook, err := readLimited(os.Stdin, maxSize, timeout)
 		if err != nil {
 			slog.Error("could not read playbook from stdin", slog.Any("error", err))
 			return []byte{}, err
 		}
 		rawPlaybook = playbook
 	} else {
-		playbook, err := os.ReadFile(source.path)
+		file, err := os.Open(source.path)
+		if err != nil {
+			slog.Error("could not read playbook from file", slog.Any("error", err))
+			return []byte{}, err
+		}
+		defer file.Close()
+		playbook, err := readLimited(file, maxSize, 0)
 		if err != nil {
 			slog.Error("could not read playbook from file", slog.Any("error", err))
 			return []byte{}, err
@@ -212,6 +313,52 @@ func readPlaybook(source PlaybookSource) ([]byte, error) {
 		rawPlaybook = playbook
 	}
 
-	slog.Debug("playbook loaded")
+	slog.Debug("playbook loaded", slog.Int("size", len(rawPlaybook)))
 	return rawPlaybook, nil
 }
+
+// readSignature reads the detached signature from a file.
+func readSignature(path string, maxSize int64) ([]byte, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return readLimited(file, maxSize, 0)
+}
+
+// verifyCached verifies the payload, reusing the report of a previous successful
+// verification of the same payload if the cache is enabled.
+func verifyCached(ctx context.Context, arguments *Arguments, payload []byte, keyring *verifier.Keyring) (verifier.Report, error) {
+	policy := arguments.Policy()
+	// Signatures expire while their verdict is cached, so it cannot be reused.
+	// The cache writes its entries through temporary files.
+	if arguments.NoCache || arguments.NoTmpFiles || arguments.Sandbox == SandboxStrict || policy.MaxSignatureAge > 0 {
+		return verifier.VerifyContext(ctx, arguments.ContentType, payload, keyring, policy)
+	}
+
+	c, err := cache.New(arguments.CacheDir, arguments.CacheTTL)
+	if err != nil {
+		slog.Debug("cache is disabled", slog.Any("error", err))
+		return verifier.VerifyContext(ctx, arguments.ContentType, payload, keyring, policy)
+	}
+	key := cache.Key(payload,
+		arguments.ContentType,
+		fmt.Sprintf("%+v", policy),
+		strings.Join(keyring.Fingerprints(), ","),
+		strings.Join(keyring.Revoked(), ","),
+		strings.Join(keyring.WarnOnlyKeys(), ","),
+	)
+	if report, ok := c.Get(key); ok {
+		slog.Info("playbook verified by cache")
+		return report, nil
+	}
+
+	report, err := verifier.VerifyContext(ctx, arguments.ContentType, payload, keyring, policy)
+	if err == nil {
+		if cacheErr := c.Put(key, report); cacheErr != nil {
+			slog.Debug("could not cache report", slog.Any("error", cacheErr))
+		}
+	}
+	return report, err
+}
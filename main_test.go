@@ -0,0 +1,233 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"com.github/m-horky/playbook-verifier/pkg/verifier"
+)
+
+// runMainVariable makes the test binary run main instead of the tests, see runMain.
+const runMainVariable = "GO_TEST_RUN_PLAYBOOK_VERIFIER"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(runMainVariable) == "1" {
+		main()
+		return
+	}
+	os.Exit(m.Run())
+}
+
+// testdataKeys is the directory of the keys the test playbooks are signed with.
+var testdataKeys = filepath.Join("pkg", "verifier", "testdata", "keys")
+
+// testTrustStore returns the --trust-store of the test key.
+func testTrustStore(t *testing.T) string {
+	t.Helper()
+	return "dir:" + testKeyDirectory(t)
+}
+
+// testKeyDirectory returns a directory of the test key, see --gpg-key-dir.
+func testKeyDirectory(t *testing.T) string {
+	t.Helper()
+	key, err := os.ReadFile(filepath.Join(testdataKeys, "test-public.asc"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	directory := t.TempDir()
+	if err = os.WriteFile(filepath.Join(directory, "test-public.asc"), key, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return directory
+}
+
+// signedPlaybook returns the playbook signed by the test key.
+func signedPlaybook(t *testing.T, playbook string) []byte {
+	t.Helper()
+	privateKey, err := os.ReadFile(filepath.Join(testdataKeys, "test-private.asc"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := verifier.NewSigner(privateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed, err := verifier.SignPlaybook([]byte(playbook), signer, verifier.Policy{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+// testPlaybook is a playbook the tests sign or tamper with.
+const testPlaybook = `- name: Insights remediation
+  hosts: localhost
+  vars:
+    insights_signature_exclude: /hosts,/vars/insights_signature
+  tasks:
+    - name: Say hello
+      debug:
+        msg: hello
+`
+
+// testArguments returns the arguments that keep main away from the files of the host:
+// an empty configuration file, the test key, no audit log and no cache.
+func testArguments(t *testing.T) []string {
+	t.Helper()
+	config := filepath.Join(t.TempDir(), "playbook-verifier.conf")
+	if err := os.WriteFile(config, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return []string{"--config", config, "--trust-store", testTrustStore(t), "--audit-log", "", "--no-cache"}
+}
+
+// parseTestArguments returns the parsed testArguments followed by the arguments,
+// for tests that call the verification functions directly.
+func parseTestArguments(t *testing.T, args ...string) *Arguments {
+	t.Helper()
+	clearEnvironment(t, "PLAYBOOK_VERIFIER_CONFIG", "PLAYBOOK_SOURCE")
+	arguments, err := parseArguments(append(testArguments(t), args...))
+	if err != nil {
+		t.Fatalf("parseArguments() error = %v", err)
+	}
+	return arguments
+}
+
+// tamperedPlaybook returns the signed test playbook with a changed task.
+func tamperedPlaybook(t *testing.T) []byte {
+	t.Helper()
+	return bytes.Replace(signedPlaybook(t, testPlaybook), []byte("msg: hello"), []byte("msg: goodbye"), 1)
+}
+
+// runMain runs main in a child process with the arguments, the input and the environment
+// in addition to the one of the tests, and returns its outputs and exit code.
+func runMain(t *testing.T, stdin io.Reader, environment []string, args ...string) (stdout, stderr string, code int) {
+	t.Helper()
+	command := mainCommand(environment, args...)
+	command.Stdin = stdin
+	return runCommand(t, command)
+}
+
+// mainCommand returns the command that runs main in a child process, see runMain.
+func mainCommand(environment []string, args ...string) *exec.Cmd {
+	command := exec.Command(os.Args[0], args...)
+	command.Env = append(os.Environ(), runMainVariable+"=1", "PLAYBOOK_SOURCE=")
+	command.Env = append(command.Env, environment...)
+	return command
+}
+
+// runCommand runs the command of mainCommand and returns its outputs and exit code.
+func runCommand(t *testing.T, command *exec.Cmd) (stdout, stderr string, code int) {
+	t.Helper()
+	var out, errOut bytes.Buffer
+	command.Stdout, command.Stderr = &out, &errOut
+	err := command.Run()
+	var exitError *exec.ExitError
+	if err != nil && !errors.As(err, &exitError) {
+		t.Fatalf("could not run main: %v", err)
+	}
+	return out.String(), errOut.String(), command.ProcessState.ExitCode()
+}
+
+func TestPayloadFD(t *testing.T) {
+	playbook := signedPlaybook(t, testPlaybook)
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+	go func() {
+		writer.Write(playbook)
+		writer.Close()
+	}()
+
+	// the first of ExtraFiles is file descriptor 3 of the child
+	command := mainCommand(nil, append(testArguments(t), "--payload-fd", "3")...)
+	command.ExtraFiles = []*os.File{reader}
+	stdout, stderr, code := runCommand(t, command)
+	if code != ExitOK {
+		t.Fatalf("exit code = %d, want %d; stderr:\n%s", code, ExitOK, stderr)
+	}
+	if stdout != string(playbook) {
+		t.Errorf("stdout = %q, want the playbook", stdout)
+	}
+}
+
+func TestPayloadSourcePrecedence(t *testing.T) {
+	tests := []struct {
+		name        string
+		args        []string
+		environment map[string]string
+		want        string
+		wantErr     string
+	}{
+		{name: "standard input", want: "stdin"},
+		{name: "payload", args: []string{"--payload", "/flag/playbook.yml"}, want: "/flag/playbook.yml"},
+		{name: "standard input by payload", args: []string{"--payload", "-"}, want: "stdin"},
+		{name: "file descriptor", args: []string{"--payload-fd", "3"}, want: "fd 3"},
+		{
+			name:        "PLAYBOOK_SOURCE over payload",
+			args:        []string{"--payload", "/flag/playbook.yml"},
+			environment: map[string]string{"PLAYBOOK_SOURCE": "/source/playbook.yml"},
+			want:        "/source/playbook.yml",
+		},
+		{
+			name:    "file descriptor and payload",
+			args:    []string{"--payload-fd", "3", "--payload", "/flag/playbook.yml"},
+			wantErr: "--payload-fd cannot be combined with --payload",
+		},
+		{
+			name:        "file descriptor and PLAYBOOK_SOURCE",
+			args:        []string{"--payload-fd", "3"},
+			environment: map[string]string{"PLAYBOOK_SOURCE": "/source/playbook.yml"},
+			wantErr:     "--payload-fd cannot be combined with --payload",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			clearEnvironment(t, "PLAYBOOK_VERIFIER_CONFIG", "PLAYBOOK_VERIFIER_PAYLOAD", "PLAYBOOK_VERIFIER_PAYLOAD_FD", "PLAYBOOK_SOURCE")
+			for variable, value := range test.environment {
+				t.Setenv(variable, value)
+			}
+			config := filepath.Join(t.TempDir(), "playbook-verifier.conf")
+			if err := os.WriteFile(config, nil, 0o600); err != nil {
+				t.Fatal(err)
+			}
+			arguments, err := parseArguments(append([]string{"--config", config}, test.args...))
+			if test.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+					t.Fatalf("parseArguments() error = %v, want %q", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseArguments() error = %v", err)
+			}
+			if got := NewPlaybookSource(arguments.Payload, arguments.PayloadFD).String(); got != test.want {
+				t.Errorf("source = %s, want %s", got, test.want)
+			}
+		})
+	}
+}
+
+// A verification failure is logged once, by main, whatever the format of the report.
+func TestVerificationErrorLoggedOnce(t *testing.T) {
+	for _, format := range []string{"text", "json"} {
+		t.Run(format, func(t *testing.T) {
+			args := append(testArguments(t), "--log-format", "text", "--format", format)
+			_, stderr, code := runMain(t, bytes.NewReader(tamperedPlaybook(t)), nil, args...)
+			if code != ExitSignatureMismatch {
+				t.Fatalf("exit code = %d, want %d; stderr:\n%s", code, ExitSignatureMismatch, stderr)
+			}
+			if count := strings.Count(stderr, "level=ERROR"); count != 1 {
+				t.Errorf("stderr has %d errors, want 1:\n%s", count, stderr)
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// DefaultOutputMode is the default permissions of the --output file: readable by its owner only,
+// like the other files the verifier writes.
+const DefaultOutputMode os.FileMode = 0o600
+
+// rename moves the written temporary file over the --output file, tests replace it.
+var rename = os.Rename
+
+// writeOutput replaces the file at --output with the verified payload. It is written to a temporary
+// file in the same directory first, given its mode and owner, and renamed, so nothing ever runs
+// a partially written playbook, and a playbook that is not verified never replaces the previous one.
+func writeOutput(arguments *Arguments, content []byte) error {
+	path := arguments.Output
+	file, err := os.CreateTemp(filepath.Dir(path), ".playbook-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+	if err = writeOutputFile(file, content, arguments); err != nil {
+		file.Close()
+		return err
+	}
+	if err = file.Close(); err != nil {
+		return err
+	}
+	slog.Debug("writing playbook", slog.String("path", path), slog.String("mode", arguments.OutputMode.String()))
+	return rename(file.Name(), path)
+}
+
+// writeOutputFile writes the content into the temporary file and makes it durable, with the mode
+// and owner of the arguments. The mode is set after the owner, since changing the owner may
+// clear the setuid and setgid bits.
+func writeOutputFile(file *os.File, content []byte, arguments *Arguments) error {
+	if _, err := file.Write(content); err != nil {
+		return err
+	}
+	if arguments.OutputUID >= 0 || arguments.OutputGID >= 0 {
+		if err := file.Chown(arguments.OutputUID, arguments.OutputGID); err != nil {
+			return err
+		}
+	}
+	if err := file.Chmod(arguments.OutputMode); err != nil {
+		return err
+	}
+	// the content has to be on the disk before the rename makes it visible
+	return file.Sync()
+}
+
+// parseOwner returns the user and group IDs of 'USER[:GROUP]', names or numeric IDs.
+// The group is -1 if it is not set, so it is not changed.
+func parseOwner(owner string) (uid, gid int, err error) {
+	name, group, hasGroup := strings.Cut(owner, ":")
+	uid, err = lookupID(name, func(name string) (string, error) {
+		u, err := user.Lookup(name)
+		if err != nil {
+			return "", err
+		}
+		return u.Uid, nil
+	})
+	if err != nil {
+		return -1, -1, fmt.Errorf("invalid owner %s: %w", owner, err)
+	}
+	if !hasGroup {
+		return uid, -1, nil
+	}
+	gid, err = lookupID(group, func(name string) (string, error) {
+		g, err := user.LookupGroup(name)
+		if err != nil {
+			return "", err
+		}
+		return g.Gid, nil
+	})
+	if err != nil {
+		return -1, -1, fmt.Errorf("invalid owner %s: %w", owner, err)
+	}
+	return uid, gid, nil
+}
+
+// lookupID returns the numeric ID, or the ID of the name found by lookup.
+func lookupID(value string, lookup func(string) (string, error)) (int, error) {
+	if value == "" {
+		return -1, errors.New("missing name")
+	}
+	if id, err := strconv.Atoi(value); err == nil && id >= 0 {
+		return id, nil
+	}
+	id, err := lookup(value)
+	if err != nil {
+		return -1, err
+	}
+	return strconv.Atoi(id)
+}
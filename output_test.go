@@ -0,0 +1,193 @@
+//go:build unix
+
+package main
+
+import (
+	"errors"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"testing"
+)
+
+// replaceRename makes writeOutput call the function instead of renaming the temporary file.
+func replaceRename(t *testing.T, f func(oldpath, newpath string) error) {
+	t.Helper()
+	previous := rename
+	rename = f
+	t.Cleanup(func() { rename = previous })
+}
+
+// testOwner returns an owner writeOutput can give the file: any as root, the current one otherwise.
+func testOwner() (uid, gid int) {
+	if os.Getuid() == 0 {
+		return 4242, 4343
+	}
+	return os.Getuid(), os.Getgid()
+}
+
+func TestWriteOutput(t *testing.T) {
+	directory := t.TempDir()
+	path := filepath.Join(directory, "playbook.yml")
+	if err := os.WriteFile(path, []byte("previous playbook\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	uid, gid := testOwner()
+	arguments := &Arguments{Output: path, OutputMode: 0o640, OutputUID: uid, OutputGID: gid}
+
+	// the temporary file has to be complete when it replaces the previous one
+	replaceRename(t, func(oldpath, newpath string) error {
+		if filepath.Dir(oldpath) != directory {
+			t.Errorf("temporary file %s, want it in %s", oldpath, directory)
+		}
+		info, err := os.Stat(oldpath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Mode().Perm() != arguments.OutputMode {
+			t.Errorf("mode before the rename = %s, want %s", info.Mode().Perm(), arguments.OutputMode)
+		}
+		stat := info.Sys().(*syscall.Stat_t)
+		if int(stat.Uid) != uid || int(stat.Gid) != gid {
+			t.Errorf("owner before the rename = %d:%d, want %d:%d", stat.Uid, stat.Gid, uid, gid)
+		}
+		return os.Rename(oldpath, newpath)
+	})
+
+	content := []byte("- hosts: localhost\n")
+	if err := writeOutput(arguments, content); err != nil {
+		t.Fatalf("writeOutput() error = %v", err)
+	}
+	written, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(written) != string(content) {
+		t.Errorf("--output = %q, want %q", written, content)
+	}
+	assertNoTemporaryFiles(t, directory)
+}
+
+func TestWriteOutputRenameFails(t *testing.T) {
+	directory := t.TempDir()
+	path := filepath.Join(directory, "playbook.yml")
+	previous := []byte("previous playbook\n")
+	if err := os.WriteFile(path, previous, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	renameErr := errors.New("rename failed")
+	replaceRename(t, func(oldpath, newpath string) error { return renameErr })
+
+	err := writeOutput(&Arguments{Output: path, OutputMode: DefaultOutputMode, OutputUID: -1, OutputGID: -1}, []byte("- hosts: localhost\n"))
+	if !errors.Is(err, renameErr) {
+		t.Fatalf("writeOutput() error = %v, want %v", err, renameErr)
+	}
+	written, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(written) != string(previous) {
+		t.Errorf("--output = %q, want the previous playbook kept", written)
+	}
+	assertNoTemporaryFiles(t, directory)
+}
+
+// assertNoTemporaryFiles checks that writeOutput left only the --output file in the directory.
+func assertNoTemporaryFiles(t *testing.T, directory string) {
+	t.Helper()
+	leftovers, err := filepath.Glob(filepath.Join(directory, ".playbook-*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(leftovers) > 0 {
+		t.Errorf("temporary files left: %v", leftovers)
+	}
+}
+
+func TestParseOwner(t *testing.T) {
+	current, err := user.Current()
+	if err != nil {
+		t.Skipf("no current user: %v", err)
+	}
+	group, err := user.LookupGroupId(current.Gid)
+	if err != nil {
+		t.Skipf("no group of the current user: %v", err)
+	}
+	uid, _ := strconv.Atoi(current.Uid)
+	gid, _ := strconv.Atoi(current.Gid)
+
+	tests := []struct {
+		owner   string
+		uid     int
+		gid     int
+		wantErr bool
+	}{
+		{owner: "1000", uid: 1000, gid: -1},
+		{owner: "1000:2000", uid: 1000, gid: 2000},
+		{owner: current.Username, uid: uid, gid: -1},
+		{owner: current.Username + ":" + group.Name, uid: uid, gid: gid},
+		{owner: current.Username + ":2000", uid: uid, gid: 2000},
+		{owner: "1000:" + group.Name, uid: 1000, gid: gid},
+		{owner: "", wantErr: true},
+		{owner: ":1000", wantErr: true},
+		{owner: "1000:", wantErr: true},
+		{owner: "-1", wantErr: true},
+		{owner: "no-such-user-of-playbook-verifier", wantErr: true},
+		{owner: "1000:no-such-group-of-playbook-verifier", wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.owner, func(t *testing.T) {
+			uid, gid, err := parseOwner(test.owner)
+			if test.wantErr {
+				if err == nil {
+					t.Errorf("parseOwner() = %d, %d, want an error", uid, gid)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseOwner() error = %v", err)
+			}
+			if uid != test.uid || gid != test.gid {
+				t.Errorf("parseOwner() = %d, %d, want %d, %d", uid, gid, test.uid, test.gid)
+			}
+		})
+	}
+}
+
+func TestLookupID(t *testing.T) {
+	lookup := func(name string) (string, error) {
+		switch name {
+		case "operator":
+			return "1001", nil
+		case "broken":
+			return "not-a-number", nil
+		default:
+			return "", errors.New("unknown name")
+		}
+	}
+	tests := []struct {
+		value   string
+		want    int
+		wantErr bool
+	}{
+		{value: "0", want: 0},
+		{value: "1000", want: 1000},
+		{value: "operator", want: 1001},
+		{value: "", wantErr: true},
+		{value: "unknown", wantErr: true},
+		{value: "broken", wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.value, func(t *testing.T) {
+			got, err := lookupID(test.value, lookup)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("lookupID() error = %v, want error %t", err, test.wantErr)
+			}
+			if err == nil && got != test.want {
+				t.Errorf("lookupID() = %d, want %d", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,167 @@
+package verifier
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// hugePlaybook returns an unsigned play with the number of tasks, like the largest remediations.
+func hugePlaybook(tasks int) []byte {
+	var huge strings.Builder
+	huge.WriteString("- name: Huge remediation\n  hosts: all\n  become: true\n  vars:\n")
+	huge.WriteString("    insights_signature_exclude: /hosts,/vars/insights_signature\n  tasks:\n")
+	for i := range tasks {
+		fmt.Fprintf(&huge, "    - name: 'Fix CVE-2024-%05d: update package-%d'\n", i, i)
+		fmt.Fprintf(&huge, "      yum:\n        name: [package-%d, package-%d-libs]\n        state: latest\n", i, i)
+		fmt.Fprintf(&huge, "      when: ansible_distribution_major_version | int >= %d\n", 7+i%3)
+		fmt.Fprintf(&huge, "      tags: [cve, batch-%d]\n", i/100)
+	}
+	return []byte(huge.String())
+}
+
+// benchmarkPlaybooks returns signed playbooks of increasing size: a single play, a few plays,
+// and a play with thousands of tasks, like the largest remediations.
+func benchmarkPlaybooks(b *testing.B) []struct {
+	name     string
+	playbook []byte
+} {
+	b.Helper()
+	slog.SetDefault(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	signed, err := SignPlaybook(hugePlaybook(5000), testSigner(b), Policy{})
+	if err != nil {
+		b.Fatalf("SignPlaybook() error = %v", err)
+	}
+
+	return []struct {
+		name     string
+		playbook []byte
+	}{
+		{"small", readTestdata(b, filepath.Join("testdata", "golden", "cve-update.yml"))},
+		{"medium", readTestdata(b, filepath.Join("testdata", "golden", "reboot.yml"))},
+		{"huge", signed},
+	}
+}
+
+func BenchmarkParse(b *testing.B) {
+	for _, bb := range benchmarkPlaybooks(b) {
+		b.Run(bb.name, func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(bb.playbook)))
+			for range b.N {
+				if _, err := UnmarshalPlaybook(bb.playbook); err != nil {
+					b.Fatalf("UnmarshalPlaybook() error = %v", err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkClean(b *testing.B) {
+	for _, bb := range benchmarkPlaybooks(b) {
+		plays, err := UnmarshalPlaybook(bb.playbook)
+		if err != nil {
+			b.Fatalf("UnmarshalPlaybook() error = %v", err)
+		}
+		b.Run(bb.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for range b.N {
+				for _, play := range plays {
+					if _, _, err := cleanPlaybook(play); err != nil {
+						b.Fatalf("cleanPlaybook() error = %v", err)
+					}
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkSerialize(b *testing.B) {
+	for _, bb := range benchmarkPlaybooks(b) {
+		plays, err := UnmarshalPlaybook(bb.playbook)
+		if err != nil {
+			b.Fatalf("UnmarshalPlaybook() error = %v", err)
+		}
+		for i, play := range plays {
+			if plays[i], _, err = cleanPlaybook(play); err != nil {
+				b.Fatalf("cleanPlaybook() error = %v", err)
+			}
+		}
+		b.Run(bb.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for range b.N {
+				for _, play := range plays {
+					if _, err := MarshallPlaybook(play); err != nil {
+						b.Fatalf("MarshallPlaybook() error = %v", err)
+					}
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkStream serializes the plays straight into the hash, like the verification does.
+func BenchmarkStream(b *testing.B) {
+	for _, bb := range benchmarkPlaybooks(b) {
+		plays, err := UnmarshalPlaybook(bb.playbook)
+		if err != nil {
+			b.Fatalf("UnmarshalPlaybook() error = %v", err)
+		}
+		for i, play := range plays {
+			if plays[i], _, err = cleanPlaybook(play); err != nil {
+				b.Fatalf("cleanPlaybook() error = %v", err)
+			}
+		}
+		b.Run(bb.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for range b.N {
+				for _, play := range plays {
+					if err := WritePlaybook(sha256.New(), play); err != nil {
+						b.Fatalf("WritePlaybook() error = %v", err)
+					}
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkVerify(b *testing.B) {
+	keyring := testKeyring(b)
+	for _, bb := range benchmarkPlaybooks(b) {
+		b.Run(bb.name, func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(bb.playbook)))
+			for range b.N {
+				if _, err := VerifyPlaybook(bb.playbook, keyring, Policy{}); err != nil {
+					b.Fatalf("VerifyPlaybook() error = %v", err)
+				}
+			}
+		})
+	}
+}
+
+// TestSerializationAllocations keeps the serialization of large plays within its budget of allocations,
+// which grows with the number of scalars, not with the size of the serialized play.
+func TestSerializationAllocations(t *testing.T) {
+	if raceEnabled {
+		t.Skip("the race detector changes the allocations")
+	}
+	const tasks = 1000
+	plays, err := UnmarshalPlaybook(hugePlaybook(tasks))
+	if err != nil {
+		t.Fatalf("UnmarshalPlaybook() error = %v", err)
+	}
+	allocations := testing.AllocsPerRun(5, func() {
+		if _, err := MarshallPlaybook(plays[0]); err != nil {
+			t.Fatalf("MarshallPlaybook() error = %v", err)
+		}
+	})
+	// every task has 9 scalars
+	if budget := float64(20 * tasks); allocations > budget {
+		t.Errorf("MarshallPlaybook() allocated %.0f times, budget is %.0f", allocations, budget)
+	}
+}
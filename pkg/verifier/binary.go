@@ -0,0 +1,57 @@
+package verifier
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	yaml3 "gopkg.in/yaml.v3"
+)
+
+// Binary is the data of a '!!binary' scalar, which PyYAML constructs as bytes (str on Python 2).
+type Binary []byte
+
+// parseBinary decodes the base64 data of the scalar. Line breaks and spaces of block
+// scalars are ignored, like Python's base64.decodebytes() does.
+func parseBinary(node *yaml3.Node) (Binary, error) {
+	data, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(node.Value), ""))
+	if err != nil {
+		return nil, PlaybookError{ErrMalformedYAML, fmt.Sprintf("binary data at line %d is not base64-encoded", node.Line), err}
+	}
+	return Binary(data), nil
+}
+
+// formatBinary quotes the data the same way repr() of bytes does: like a string,
+// but every byte that is not printable ASCII is escaped. Python 2 has no 'b' prefix,
+// since its bytes are plain str objects.
+func (s serializer) formatBinary(data Binary) string {
+	quote := byte('\'')
+	if strings.IndexByte(string(data), '\'') >= 0 && strings.IndexByte(string(data), '"') < 0 {
+		quote = '"'
+	}
+
+	var b strings.Builder
+	if !s.python2 {
+		b.WriteByte('b')
+	}
+	b.WriteByte(quote)
+	for _, c := range data {
+		switch {
+		case c == quote || c == '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case c == '\t':
+			b.WriteString(`\t`)
+		case c == '\n':
+			b.WriteString(`\n`)
+		case c == '\r':
+			b.WriteString(`\r`)
+		case c < ' ' || c >= 0x7f:
+			fmt.Fprintf(&b, `\x%02x`, c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	b.WriteByte(quote)
+	return b.String()
+}
@@ -0,0 +1,17 @@
+package verifier
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestUnmarshalPlaybookInvalidBinary(t *testing.T) {
+	for _, data := range []string{"aGVsbG8", "aGVs*G8=", "'not base64'"} {
+		t.Run(data, func(t *testing.T) {
+			playbook := "- name: invalid\n  vars:\n    data: !!binary " + data + "\n"
+			if _, err := UnmarshalPlaybook([]byte(playbook)); !errors.Is(err, ErrMalformedYAML) {
+				t.Errorf("UnmarshalPlaybook() error = %v, want %v", err, ErrMalformedYAML)
+			}
+		})
+	}
+}
@@ -0,0 +1,182 @@
+package verifier
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"time"
+	"unsafe"
+
+	yaml3 "gopkg.in/yaml.v3"
+)
+
+// maxAliasedNodes limits the number of nodes created by expanding aliases, so documents
+// like 'billion laughs' cannot exhaust the memory.
+const maxAliasedNodes = 1 << 16
+
+// canonicalize returns the document with its aliases and merge keys resolved the way
+// the Python implementation (PyYAML) resolves them.
+//
+// Aliases are replaced by copies of their anchored nodes. The keys of mappings merged
+// with '<<' come first, in the order PyYAML flattens them (of a list of mappings, the
+// last one first), followed by the keys of the mapping itself. A key that appears more
+// than once keeps its first position and its last value, as in a Python dictionary;
+// so keys of the mapping override merged keys, and earlier merged mappings override
+// later ones. The same applies to duplicate keys of the mapping itself, which are only
+// left in the document if lintDocument allowed them. The anchors are dropped.
+func canonicalize(document *yaml3.Node) (*yaml3.Node, error) {
+	c := &canonicalizer{}
+	return c.resolve(document, false)
+}
+
+// canonicalizer resolves the nodes of a single document.
+type canonicalizer struct {
+	aliased int
+	// size is the estimated size of the resolved nodes in bytes, including the nodes
+	// of the previous documents of the payload.
+	size int64
+	// budget limits the size; zero means no limit.
+	budget int64
+}
+
+// nodeSize is the size of a node without its strings and children.
+const nodeSize = int64(unsafe.Sizeof(yaml3.Node{}))
+
+// resolve returns a copy of the node without aliases and merge keys.
+func (c *canonicalizer) resolve(n *yaml3.Node, aliased bool) (*yaml3.Node, error) {
+	// The strings are shared with the original node, but the scalars constructed from them are copies
+	c.size += nodeSize + int64(len(n.Value)+len(n.Tag)+8*len(n.Content))
+	if c.budget != 0 && c.size > c.budget {
+		return nil, PlaybookError{ErrMemoryBudget, fmt.Sprintf("payload needs more than %d bytes of memory", c.budget), nil}
+	}
+	if aliased {
+		c.aliased++
+		if c.aliased > maxAliasedNodes {
+			return nil, PlaybookError{ErrMalformedYAML, "document contains too many aliases", nil}
+		}
+	}
+	if n.Kind == yaml3.AliasNode {
+		if n.Alias == nil {
+			return nil, PlaybookError{ErrMalformedYAML, fmt.Sprintf("unknown anchor '%s' at line %d", n.Value, n.Line), nil}
+		}
+		return c.resolve(n.Alias, true)
+	}
+
+	resolved := *n
+	resolved.Anchor = ""
+	resolved.Content = make([]*yaml3.Node, 0, len(n.Content))
+	for _, child := range n.Content {
+		r, err := c.resolve(child, aliased)
+		if err != nil {
+			return nil, err
+		}
+		resolved.Content = append(resolved.Content, r)
+	}
+	if n.Kind == yaml3.MappingNode {
+		return flattenMapping(&resolved)
+	}
+	return &resolved, nil
+}
+
+// flattenMapping replaces the merge keys of the resolved mapping with the merged keys.
+func flattenMapping(n *yaml3.Node) (*yaml3.Node, error) {
+	var merged, own []*yaml3.Node
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		key, value := n.Content[i], n.Content[i+1]
+		if !isMergeKey(key) {
+			own = append(own, key, value)
+			continue
+		}
+		switch value.Kind {
+		case yaml3.MappingNode:
+			merged = append(merged, value.Content...)
+		case yaml3.SequenceNode:
+			for j := len(value.Content) - 1; j >= 0; j-- {
+				if value.Content[j].Kind != yaml3.MappingNode {
+					return nil, PlaybookError{ErrMalformedYAML, fmt.Sprintf("merge key at line %d expects mappings", key.Line), nil}
+				}
+				merged = append(merged, value.Content[j].Content...)
+			}
+		default:
+			return nil, PlaybookError{ErrMalformedYAML, fmt.Sprintf("merge key at line %d expects a mapping", key.Line), nil}
+		}
+	}
+
+	// Deduplicate the keys like a Python dictionary built from the pairs.
+	pairs := append(merged, own...)
+	positions := map[string]int{}
+	n.Content = nil
+	for i := 0; i+1 < len(pairs); i += 2 {
+		key, value := pairs[i], pairs[i+1]
+		if id, ok := keyIdentity(key); ok {
+			if position, ok := positions[id]; ok {
+				n.Content[position+1] = value
+				continue
+			}
+			positions[id] = len(n.Content)
+		}
+		n.Content = append(n.Content, key, value)
+	}
+	return n, nil
+}
+
+// keyIdentity returns a string that is equal for the keys a Python dictionary
+// considers equal, e.g. 'yes', 'true' and '1'.
+//
+// Only scalar keys have an identity; NaN does not either, since it is never equal.
+func keyIdentity(key *yaml3.Node) (string, bool) {
+	if key.Kind == yaml3.AliasNode && key.Alias != nil {
+		key = key.Alias
+	}
+	if key.Kind != yaml3.ScalarNode {
+		return "", false
+	}
+	value, err := constructScalar(key)
+	if err != nil {
+		// the playbook is refused once its scalars are constructed
+		return "s:" + key.Value, true
+	}
+	switch v := value.(type) {
+	case nil:
+		return "null", true
+	case string:
+		return "s:" + v, true
+	case bool:
+		if v {
+			return "n:1", true
+		}
+		return "n:0", true
+	case int64:
+		return "n:" + strconv.FormatInt(v, 10), true
+	case *big.Int:
+		return "n:" + v.String(), true
+	case float64:
+		if math.IsNaN(v) {
+			return "", false
+		}
+		if v == math.Trunc(v) && !math.IsInf(v, 0) {
+			return "n:" + big.NewFloat(v).Text('f', 0), true
+		}
+		return "f:" + strconv.FormatFloat(v, 'g', -1, 64), true
+	case Timestamp:
+		// aware datetimes are equal if they are the same instant, naive ones never equal them
+		switch {
+		case v.Date:
+			return "d:" + v.Time().Format(time.DateOnly), true
+		case v.Zoned:
+			return "z:" + v.Time().Format(time.RFC3339Nano), true
+		default:
+			return "t:" + v.Time().Format(time.RFC3339Nano), true
+		}
+	case Binary:
+		return "b:" + string(v), true
+	default:
+		return fmt.Sprintf("%T:%v", v, v), true
+	}
+}
+
+// isMergeKey reports whether the key is the merge key '<<'.
+func isMergeKey(key *yaml3.Node) bool {
+	return key.Kind == yaml3.ScalarNode && scalarTag(key) == "!!merge"
+}
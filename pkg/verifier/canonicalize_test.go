@@ -0,0 +1,76 @@
+package verifier
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestUnmarshalPlaybookCanonical(t *testing.T) {
+	tests := []struct {
+		name string
+		play string
+		want string
+	}{
+		{
+			"alias",
+			"- a: &x [1, 2]\n  b: *x\n",
+			"ordereddict([('a', [1, 2]), ('b', [1, 2])])",
+		},
+		{
+			"merge is overridden by own keys",
+			"- a: &x {k: 1, j: 2}\n  b:\n    k: 3\n    <<: *x\n    z: 0\n",
+			"ordereddict([('a', ordereddict([('k', 1), ('j', 2)])), ('b', ordereddict([('k', 3), ('j', 2), ('z', 0)]))])",
+		},
+		{
+			"earlier merged mapping wins",
+			"- a: &x {k: 1}\n  b: &y {k: 2, m: 5}\n  c:\n    <<: [*x, *y]\n",
+			"ordereddict([('a', ordereddict([('k', 1)])), ('b', ordereddict([('k', 2), ('m', 5)])), ('c', ordereddict([('k', 1), ('m', 5)]))])",
+		},
+		{
+			"nested merges",
+			"- a: &x {k: 1}\n  b: &y {<<: *x, m: 2}\n  c: {<<: *y, o: 3}\n",
+			"ordereddict([('a', ordereddict([('k', 1)])), ('b', ordereddict([('k', 1), ('m', 2)])), ('c', ordereddict([('k', 1), ('m', 2), ('o', 3)]))])",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plays, err := UnmarshalPlaybook([]byte(tt.play))
+			if err != nil {
+				t.Fatalf("UnmarshalPlaybook() error = %v", err)
+			}
+			serialized, err := MarshallPlaybook(plays[0])
+			if err != nil {
+				t.Fatalf("MarshallPlaybook() error = %v", err)
+			}
+			if string(serialized) != tt.want {
+				t.Errorf("MarshallPlaybook() =\n%s\nwant\n%s", serialized, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnmarshalPlaybookCanonicalErrors(t *testing.T) {
+	laughs := "- a: &a [x, x, x, x, x, x, x, x, x, x]\n"
+	for i, name := range []string{"b", "c", "d", "e", "f", "g"} {
+		previous := string(rune('a' + i))
+		laughs += "  " + name + ": &" + name + " [" + strings.Repeat("*"+previous+", ", 9) + "*" + previous + "]\n"
+	}
+
+	tests := []struct {
+		name string
+		play string
+	}{
+		{"too many aliases", laughs},
+		{"merge of scalar", "- a: &x 1\n  b: {<<: *x}\n"},
+		{"merge of list of scalars", "- a: &x 1\n  b: {<<: [*x]}\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := UnmarshalPlaybook([]byte(tt.play))
+			if !errors.Is(err, ErrMalformedYAML) {
+				t.Errorf("UnmarshalPlaybook() error = %v, want %v", err, ErrMalformedYAML)
+			}
+		})
+	}
+}
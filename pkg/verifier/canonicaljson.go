@@ -0,0 +1,219 @@
+package verifier
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	yaml3 "gopkg.in/yaml.v3"
+)
+
+// maxSafeInteger is the largest integer every JSON implementation represents exactly (2^53 - 1).
+const maxSafeInteger = 1<<53 - 1
+
+// canonicalJSONSerialization implements SerializationV2, the JSON Canonicalization Scheme
+// (RFC 8785).
+//
+// Unlike repr(), the form is defined by a standard and can be reproduced in any language:
+// keys of mappings are sorted, numbers are formatted like ECMAScript does and strings
+// are escaped like JSON.stringify() does. Values that have no exact JSON representation
+// (keys that are not strings, integers beyond 2^53, NaN, infinities, timestamps and binary data) are rejected.
+type canonicalJSONSerialization struct{}
+
+func (canonicalJSONSerialization) Version() string { return SerializationV2 }
+
+// Marshal serializes the play as canonical JSON. There are no profiles of the serialization.
+func (canonicalJSONSerialization) Marshal(play *yaml3.Node, _ string) ([]byte, error) {
+	var b strings.Builder
+	if err := writeCanonicalJSON(&b, play); err != nil {
+		return nil, err
+	}
+	return []byte(b.String()), nil
+}
+
+// writeCanonicalJSON writes the node as canonical JSON.
+func writeCanonicalJSON(b *strings.Builder, node *yaml3.Node) error {
+	switch node.Kind {
+	case yaml3.MappingNode:
+		return writeCanonicalJSONObject(b, node)
+	case yaml3.SequenceNode:
+		b.WriteByte('[')
+		for i, element := range node.Content {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			if err := writeCanonicalJSON(b, element); err != nil {
+				return err
+			}
+		}
+		b.WriteByte(']')
+		return nil
+	case yaml3.ScalarNode:
+		value, err := constructScalar(node)
+		if err != nil {
+			return err
+		}
+		return writeCanonicalJSONScalar(b, value)
+	default:
+		return PlaybookError{ErrUnsupportedType, fmt.Sprintf("cannot serialize node of kind %d at line %d, resolve its aliases first", node.Kind, node.Line), nil}
+	}
+}
+
+// writeCanonicalJSONScalar writes the value constructed from a scalar, see constructScalar.
+func writeCanonicalJSONScalar(b *strings.Builder, item any) error {
+	switch v := item.(type) {
+	case nil:
+		b.WriteString("null")
+	case bool:
+		b.WriteString(strconv.FormatBool(v))
+	case string:
+		return writeCanonicalJSONString(b, v)
+	case int64:
+		return writeCanonicalJSONInteger(b, v)
+	case *big.Int:
+		return PlaybookError{ErrUnsupportedType, fmt.Sprintf("integer %s cannot be represented in JSON exactly", v), nil}
+	case float64:
+		number, err := formatCanonicalJSONNumber(v)
+		if err != nil {
+			return err
+		}
+		b.WriteString(number)
+	case Timestamp:
+		return PlaybookError{ErrUnsupportedType, fmt.Sprintf("timestamp '%s' has no JSON representation, quote it", v.Text), nil}
+	case Binary:
+		return PlaybookError{ErrUnsupportedType, "binary data has no JSON representation, use a base64-encoded string", nil}
+	default:
+		return PlaybookError{ErrUnsupportedType, fmt.Sprintf("cannot serialize value of type %T", item), nil}
+	}
+	return nil
+}
+
+// writeCanonicalJSONObject writes the mapping with its keys sorted by their UTF-16 code units.
+func writeCanonicalJSONObject(b *strings.Builder, m *yaml3.Node) error {
+	type member struct {
+		key   string
+		units []uint16
+		value *yaml3.Node
+	}
+	members := make([]member, 0, len(m.Content)/2)
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		key, ok := scalarValue(m.Content[i]).(string)
+		if !ok {
+			return PlaybookError{ErrUnsupportedType, fmt.Sprintf("cannot serialize key of type %s at line %d", yamlTypeName(m.Content[i]), m.Content[i].Line), nil}
+		}
+		members = append(members, member{key, utf16.Encode([]rune(key)), m.Content[i+1]})
+	}
+	slices.SortFunc(members, func(a, b member) int { return slices.Compare(a.units, b.units) })
+
+	b.WriteByte('{')
+	for i, member := range members {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		if err := writeCanonicalJSONString(b, member.key); err != nil {
+			return err
+		}
+		b.WriteByte(':')
+		if err := writeCanonicalJSON(b, member.value); err != nil {
+			return err
+		}
+	}
+	b.WriteByte('}')
+	return nil
+}
+
+func writeCanonicalJSONInteger(b *strings.Builder, i int64) error {
+	if i > maxSafeInteger || i < -maxSafeInteger {
+		return PlaybookError{ErrUnsupportedType, fmt.Sprintf("integer %d cannot be represented in JSON exactly", i), nil}
+	}
+	b.WriteString(strconv.FormatInt(i, 10))
+	return nil
+}
+
+// writeCanonicalJSONString quotes the string the same way JSON.stringify() does.
+//
+// Only the quote, the backslash and control characters are escaped; the rest is kept as UTF-8.
+func writeCanonicalJSONString(b *strings.Builder, s string) error {
+	if !utf8.ValidString(s) {
+		return PlaybookError{ErrUnsupportedType, "cannot serialize string that is not valid UTF-8", nil}
+	}
+	b.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			b.WriteString(`\"`)
+		case '\\':
+			b.WriteString(`\\`)
+		case '\b':
+			b.WriteString(`\b`)
+		case '\f':
+			b.WriteString(`\f`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		default:
+			if r < ' ' {
+				fmt.Fprintf(b, `\u%04x`, r)
+			} else {
+				b.WriteRune(r)
+			}
+		}
+	}
+	b.WriteByte('"')
+	return nil
+}
+
+// formatCanonicalJSONNumber formats the number the same way ECMAScript's Number.prototype.toString() does.
+//
+// It uses the shortest representation that round-trips. It switches to scientific notation
+// when the decimal exponent is lower than -6 or at least 21, and prints the exponent
+// with a sign, but without padding.
+func formatCanonicalJSONNumber(f float64) (string, error) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return "", PlaybookError{ErrUnsupportedType, fmt.Sprintf("number %v cannot be represented in JSON", f), nil}
+	}
+	if f == 0 {
+		// including negative zero
+		return "0", nil
+	}
+
+	// shortest representation, e.g. '-1.2345e+06'
+	formatted := strconv.FormatFloat(f, 'e', -1, 64)
+	sign := ""
+	if strings.HasPrefix(formatted, "-") {
+		sign = "-"
+		formatted = formatted[1:]
+	}
+	mantissa, rawExponent, _ := strings.Cut(formatted, "e")
+	digits := strings.Replace(mantissa, ".", "", 1)
+	exponent, _ := strconv.Atoi(rawExponent)
+
+	// position of the decimal point relative to the start of the digits
+	point := exponent + 1
+	switch {
+	case point > 21 || point <= -6:
+		if len(digits) > 1 {
+			digits = digits[:1] + "." + digits[1:]
+		}
+		exponentSign := "+"
+		if exponent < 0 {
+			exponentSign = "-"
+			exponent = -exponent
+		}
+		return fmt.Sprintf("%s%se%s%d", sign, digits, exponentSign, exponent), nil
+	case point <= 0:
+		return sign + "0." + strings.Repeat("0", -point) + digits, nil
+	case point >= len(digits):
+		return sign + digits + strings.Repeat("0", point-len(digits)), nil
+	default:
+		return sign + digits[:point] + "." + digits[point:], nil
+	}
+}
@@ -0,0 +1,120 @@
+package verifier
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	yaml3 "gopkg.in/yaml.v3"
+)
+
+// Expected values were taken from RFC 8785 and produced by ECMAScript's JSON.stringify().
+func TestFormatCanonicalJSONNumber(t *testing.T) {
+	tests := []struct {
+		number float64
+		want   string
+	}{
+		{0, "0"},
+		{math.Copysign(0, -1), "0"},
+		{30, "30"},
+		{-2.25, "-2.25"},
+		{4.50, "4.5"},
+		{2e-3, "0.002"},
+		{0.000001, "0.000001"},
+		{1e-7, "1e-7"},
+		{1e-27, "1e-27"},
+		{333333333.33333329, "333333333.3333333"},
+		{999999999999999700000, "999999999999999700000"},
+		{1e21, "1e+21"},
+		{1e30, "1e+30"},
+		{math.SmallestNonzeroFloat64, "5e-324"},
+		{-math.SmallestNonzeroFloat64, "-5e-324"},
+		{math.MaxFloat64, "1.7976931348623157e+308"},
+		{9.999999999999997e+22, "9.999999999999997e+22"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			got, err := formatCanonicalJSONNumber(tt.number)
+			if err != nil {
+				t.Fatalf("formatCanonicalJSONNumber(%v) error = %v", tt.number, err)
+			}
+			if got != tt.want {
+				t.Errorf("formatCanonicalJSONNumber(%v) = %s, want %s", tt.number, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMarshalCanonicalJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		play string
+		want string
+	}{
+		{
+			"scalars",
+			"{name: play, become: yes, gather_facts: ~, serial: 2.0}",
+			`{"become":true,"gather_facts":null,"name":"play","serial":2}`,
+		},
+		{
+			"nested",
+			"{tasks: [{shell: echo, args: {}}], hosts: all}",
+			`{"hosts":"all","tasks":[{"args":{},"shell":"echo"}]}`,
+		},
+		{
+			"strings",
+			`{s: "\"it's\"\\\b\f\n\r\t\x01\x7f é \u2028"}`,
+			"{\"s\":\"\\\"it's\\\"\\\\\\b\\f\\n\\r\\t\\u0001\x7f é \u2028\"}",
+		},
+		{
+			// RFC 8785, section 3.2.3: keys are sorted by their UTF-16 code units
+			"sorting",
+			`{"\u20ac": Euro Sign, "\r": Carriage Return, "\ufb33": Hebrew Letter Dalet With Dagesh, ` +
+				`"1": One, "\U0001f600": "Emoji: Grinning Face", "\x80": Control, "\xf6": Latin Small Letter O With Diaeresis}`,
+			"{\"\\r\":\"Carriage Return\",\"1\":\"One\",\"\u0080\":\"Control\",\"\u00f6\":\"Latin Small Letter O With Diaeresis\"," +
+				"\"\u20ac\":\"Euro Sign\",\"\U0001f600\":\"Emoji: Grinning Face\",\"\ufb33\":\"Hebrew Letter Dalet With Dagesh\"}",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := canonicalJSONSerialization{}.Marshal(parsePlay(t, tt.play), "")
+			if err != nil {
+				t.Fatalf("Marshal() error = %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("Marshal() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMarshalCanonicalJSONUnsupported(t *testing.T) {
+	tests := []struct {
+		name string
+		play string
+	}{
+		{"integer key", "{1: one}"},
+		{"large integer", "{i: 9007199254740992}"},
+		{"large unsigned integer", "{i: 18446744073709551615}"},
+		{"infinity", "{f: .inf}"},
+		{"not a number", "{f: .nan}"},
+		{"timestamp", "{t: 2024-01-01}"},
+		{"binary", "{b: !!binary ZGF0YQ==}"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := (canonicalJSONSerialization{}).Marshal(parsePlay(t, tt.play), ""); !errors.Is(err, ErrUnsupportedType) {
+				t.Errorf("Marshal() error = %v, want %v", err, ErrUnsupportedType)
+			}
+		})
+	}
+
+	// yaml.v3 refuses to parse invalid UTF-8, so the play is built by hand
+	play := &yaml3.Node{Kind: yaml3.MappingNode, Content: []*yaml3.Node{
+		{Kind: yaml3.ScalarNode, Value: "s"},
+		{Kind: yaml3.ScalarNode, Value: "\xff", Style: yaml3.DoubleQuotedStyle},
+	}}
+	if _, err := (canonicalJSONSerialization{}).Marshal(play, ""); !errors.Is(err, ErrUnsupportedType) {
+		t.Errorf("Marshal() of invalid UTF-8 error = %v, want %v", err, ErrUnsupportedType)
+	}
+}
@@ -0,0 +1,76 @@
+package verifier
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	yaml3 "gopkg.in/yaml.v3"
+)
+
+// The digests in testdata/compat were recorded by the yaml.v2 implementation of the decoder.
+// Every play of the corpus is constructed by yaml.v2 the same way PyYAML constructs it,
+// so the yaml.v3 implementation must serialize it into the same bytes.
+func TestSerializationCompatibility(t *testing.T) {
+	scanner := bufio.NewScanner(bytes.NewReader(readTestdata(t, filepath.Join("testdata", "compat", "digests.txt"))))
+	playbooks := map[string][]*yaml3.Node{}
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 4 {
+			t.Fatalf("malformed line %q", line)
+		}
+		path, variant, want := fields[0], fields[2], fields[3]
+		index, err := strconv.Atoi(fields[1])
+		if err != nil {
+			t.Fatalf("malformed line %q", line)
+		}
+		version, profile, _ := strings.Cut(variant, "/")
+
+		t.Run(strings.Join(fields[:3], " "), func(t *testing.T) {
+			plays, ok := playbooks[path]
+			if !ok {
+				plays = cleanCompatPlaybook(t, path)
+				playbooks[path] = plays
+			}
+			if index >= len(plays) {
+				t.Fatalf("playbook has %d plays", len(plays))
+			}
+			serialization, err := LookupSerialization(version)
+			if err != nil {
+				t.Fatalf("LookupSerialization() error = %v", err)
+			}
+			got := "error"
+			if serialized, err := serialization.Marshal(plays[index], profile); err == nil {
+				digest := sha256.Sum256(serialized)
+				got = hex.EncodeToString(digest[:])
+			}
+			if got != want {
+				t.Errorf("digest = %s, want %s", got, want)
+			}
+		})
+	}
+}
+
+// cleanCompatPlaybook returns the cleaned plays of the playbook; plays without exclusions are kept as they are.
+func cleanCompatPlaybook(t *testing.T, path string) []*yaml3.Node {
+	t.Helper()
+	plays, err := UnmarshalPlaybook(readTestdata(t, filepath.FromSlash(path)))
+	if err != nil {
+		t.Fatalf("UnmarshalPlaybook() error = %v", err)
+	}
+	for i := range plays {
+		if clean, err := CleanPlaybook(plays[i]); err == nil {
+			plays[i] = clean
+		}
+	}
+	return plays
+}
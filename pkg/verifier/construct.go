@@ -0,0 +1,218 @@
+package verifier
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+
+	yaml3 "gopkg.in/yaml.v3"
+)
+
+// The implicit resolvers of PyYAML, which resolve plain scalars by the rules of YAML 1.1.
+// yaml.v3 resolves them by the rules of YAML 1.2 (e.g. 'yes' is a string), so the scalars
+// are resolved and constructed here, the way PyYAML's SafeLoader does.
+var (
+	boolPattern  = regexp.MustCompile(`^(?:yes|Yes|YES|no|No|NO|true|True|TRUE|false|False|FALSE|on|On|ON|off|Off|OFF)$`)
+	floatPattern = regexp.MustCompile(`^(?:[-+]?(?:[0-9][0-9_]*)\.[0-9_]*(?:[eE][-+][0-9]+)?` +
+		`|\.[0-9][0-9_]*(?:[eE][-+][0-9]+)?` +
+		`|[-+]?[0-9][0-9_]*(?::[0-5]?[0-9])+\.[0-9_]*` +
+		`|[-+]?\.(?:inf|Inf|INF)` +
+		`|\.(?:nan|NaN|NAN))$`)
+	intPattern = regexp.MustCompile(`^(?:[-+]?0b[0-1_]+` +
+		`|[-+]?0[0-7_]+` +
+		`|[-+]?(?:0|[1-9][0-9_]*)` +
+		`|[-+]?0x[0-9a-fA-F_]+` +
+		`|[-+]?[1-9][0-9_]*(?::[0-5]?[0-9])+)$`)
+	nullPattern = regexp.MustCompile(`^(?:~|null|Null|NULL|)$`)
+)
+
+// implicitFirst are the first characters of the plain scalars any of the implicit resolvers may match.
+// Like PyYAML, which looks its resolvers up by the first character, other scalars are strings at once.
+const implicitFirst = "yYnNtTfFoO-+0123456789.<~="
+
+// quotedStyles are the styles of scalars that are never resolved implicitly.
+const quotedStyles = yaml3.SingleQuotedStyle | yaml3.DoubleQuotedStyle | yaml3.LiteralStyle | yaml3.FoldedStyle
+
+// scalarTag returns the tag PyYAML resolves the scalar to: its explicit tag, '!!str' for
+// quoted and block scalars, or the tag of the first implicit resolver matching a plain scalar.
+func scalarTag(node *yaml3.Node) string {
+	switch {
+	case node.Style&yaml3.TaggedStyle != 0:
+		return node.ShortTag()
+	case node.Style&quotedStyles != 0:
+		return "!!str"
+	}
+	value := node.Value
+	switch {
+	case value == "":
+		return "!!null"
+	case strings.IndexByte(implicitFirst, value[0]) < 0:
+		return "!!str"
+	case boolPattern.MatchString(value):
+		return "!!bool"
+	case floatPattern.MatchString(value):
+		return "!!float"
+	case intPattern.MatchString(value):
+		return "!!int"
+	case value == "<<":
+		return "!!merge"
+	case nullPattern.MatchString(value):
+		return "!!null"
+	case timestampPattern.MatchString(value):
+		return "!!timestamp"
+	case value == "=":
+		return "!!value"
+	}
+	return "!!str"
+}
+
+// constructScalar returns the value PyYAML constructs from the scalar: nil, bool, int64
+// (*big.Int beyond its range), float64, string, Timestamp or Binary.
+func constructScalar(node *yaml3.Node) (any, error) {
+	switch tag := scalarTag(node); tag {
+	case "!!str":
+		return node.Value, nil
+	case "!!null":
+		return nil, nil
+	case "!!bool":
+		return constructBool(node)
+	case "!!int":
+		return constructInt(node)
+	case "!!float":
+		return constructFloat(node)
+	case "!!timestamp":
+		return ParseTimestamp(node.Value)
+	case "!!binary":
+		return parseBinary(node)
+	default:
+		// e.g. '<<' outside of a key or '=', which PyYAML cannot construct either
+		return nil, PlaybookError{ErrMalformedYAML, fmt.Sprintf("scalar '%s' at line %d of tag '%s' cannot be constructed", node.Value, node.Line, tag), nil}
+	}
+}
+
+// constructBool is PyYAML's construct_yaml_bool.
+func constructBool(node *yaml3.Node) (bool, error) {
+	switch strings.ToLower(node.Value) {
+	case "yes", "true", "on":
+		return true, nil
+	case "no", "false", "off":
+		return false, nil
+	}
+	return false, PlaybookError{ErrMalformedYAML, fmt.Sprintf("'%s' at line %d is not a boolean", node.Value, node.Line), nil}
+}
+
+// constructInt is PyYAML's construct_yaml_int: binary, octal, hexadecimal and sexagesimal
+// (base 60, e.g. '1:20' is 80) integers of any size.
+func constructInt(node *yaml3.Node) (any, error) {
+	value := strings.ReplaceAll(node.Value, "_", "")
+	negative := strings.HasPrefix(value, "-")
+	if negative || strings.HasPrefix(value, "+") {
+		value = value[1:]
+	}
+
+	n := new(big.Int)
+	ok := true
+	switch {
+	case value == "":
+		ok = false
+	case strings.HasPrefix(value, "0b"):
+		_, ok = n.SetString(value[2:], 2)
+	case strings.HasPrefix(value, "0x"):
+		_, ok = n.SetString(value[2:], 16)
+	case value[0] == '0':
+		_, ok = n.SetString(strings.TrimPrefix(strings.TrimPrefix(value, "0o"), "0O"), 8)
+	case strings.Contains(value, ":"):
+		base := big.NewInt(1)
+		parts := strings.Split(value, ":")
+		for i := len(parts) - 1; i >= 0 && ok; i-- {
+			digit, valid := new(big.Int).SetString(parts[i], 10)
+			if ok = valid; ok {
+				n.Add(n, digit.Mul(digit, base))
+				base.Mul(base, big.NewInt(60))
+			}
+		}
+	default:
+		_, ok = n.SetString(value, 10)
+	}
+	if !ok {
+		return nil, PlaybookError{ErrMalformedYAML, fmt.Sprintf("'%s' at line %d is not an integer", node.Value, node.Line), nil}
+	}
+	if negative {
+		n.Neg(n)
+	}
+	if n.IsInt64() {
+		return n.Int64(), nil
+	}
+	return n, nil
+}
+
+// constructFloat is PyYAML's construct_yaml_float, including sexagesimal floats (e.g. '1:20.5').
+func constructFloat(node *yaml3.Node) (float64, error) {
+	value := strings.ToLower(strings.ReplaceAll(node.Value, "_", ""))
+	sign := 1.0
+	if strings.HasPrefix(value, "-") {
+		sign = -1
+	}
+	if sign < 0 || strings.HasPrefix(value, "+") {
+		value = value[1:]
+	}
+
+	switch {
+	case value == ".inf":
+		return sign * math.Inf(1), nil
+	case value == ".nan":
+		return math.NaN(), nil
+	case strings.Contains(value, ":"):
+		parts := strings.Split(value, ":")
+		result, base := 0.0, 1.0
+		for i := len(parts) - 1; i >= 0; i-- {
+			digit, err := strconv.ParseFloat(parts[i], 64)
+			if err != nil {
+				return 0, PlaybookError{ErrMalformedYAML, fmt.Sprintf("'%s' at line %d is not a float", node.Value, node.Line), err}
+			}
+			result += digit * base
+			base *= 60
+		}
+		return sign * result, nil
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil && !isRangeError(err) {
+		return 0, PlaybookError{ErrMalformedYAML, fmt.Sprintf("'%s' at line %d is not a float", node.Value, node.Line), err}
+	}
+	return sign * f, nil
+}
+
+// isRangeError reports whether strconv failed only because the number overflows,
+// which Python turns into an infinity as well.
+func isRangeError(err error) bool {
+	numError, ok := err.(*strconv.NumError)
+	return ok && numError.Err == strconv.ErrRange
+}
+
+// scalarValue returns the value constructed from the node, or nil if it is not a scalar.
+// The scalars of decoded plays have all been constructed once, so the error is not expected.
+func scalarValue(node *yaml3.Node) any {
+	if node == nil || node.Kind != yaml3.ScalarNode {
+		return nil
+	}
+	value, _ := constructScalar(node)
+	return value
+}
+
+// constructScalars constructs every scalar of the node, so a playbook with a scalar PyYAML
+// refuses to load (e.g. an invalid date) is refused at once.
+func constructScalars(node *yaml3.Node) error {
+	if node.Kind == yaml3.ScalarNode {
+		_, err := constructScalar(node)
+		return err
+	}
+	for _, child := range node.Content {
+		if err := constructScalars(child); err != nil {
+			return err
+		}
+	}
+	return nil
+}
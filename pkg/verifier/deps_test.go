@@ -0,0 +1,25 @@
+package verifier
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+// TestNoExternalProcesses ensures the package cannot run gpg or any other binary,
+// so it keeps working in minimal container images and on ostree systems.
+func TestNoExternalProcesses(t *testing.T) {
+	goBinary, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go is not installed")
+	}
+	output, err := exec.Command(goBinary, "list", "-deps", ".").Output()
+	if err != nil {
+		t.Fatalf("go list error = %v", err)
+	}
+	for _, dependency := range strings.Fields(string(output)) {
+		if dependency == "os/exec" || dependency == "plugin" {
+			t.Errorf("package depends on %s", dependency)
+		}
+	}
+}
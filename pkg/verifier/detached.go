@@ -0,0 +1,95 @@
+package verifier
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log/slog"
+)
+
+// VerifyDetached checks a signature distributed separately from the payload,
+// e.g. created by 'gpg --armor --detach-sign playbook.yml'.
+//
+// The signature covers the payload byte by byte, so nothing can be excluded from it
+// and the plays are not verified on their own.
+func VerifyDetached(payload []byte, signature []byte, keyring *Keyring) (Report, error) {
+	return VerifyDetachedPolicy(payload, signature, keyring, Policy{})
+}
+
+// VerifyDetachedPolicy checks a detached signature like VerifyDetached, and enforces
+// the validity window of the policy on it.
+//
+// Checking the payload out on Windows may convert its line endings to CRLF. If the signature
+// does not match such payload, it is checked against the payload with LF line endings too.
+func VerifyDetachedPolicy(payload []byte, signature []byte, keyring *Keyring, policy Policy) (Report, error) {
+	return VerifyDetachedContext(context.Background(), payload, signature, keyring, policy)
+}
+
+// VerifyDetachedContext is like VerifyDetachedPolicy, but logs to the logger of the context.
+func VerifyDetachedContext(ctx context.Context, payload []byte, signature []byte, keyring *Keyring, policy Policy) (Report, error) {
+	logger := Logger(ctx)
+	if policy.NormalizePayload {
+		payload = NormalizePayload(payload)
+	}
+	report := Report{Plays: []PlayReport{}, SkippedDocuments: []int{}, Digest: hex.EncodeToString(Hash(payload))}
+	report.Signature = parseSignatureMetadata(logger, signature)
+
+	keyID, err := keyring.verify(logger, payload, signature)
+	if errors.Is(err, ErrDigestMismatch) && bytes.Contains(payload, []byte("\r\n")) {
+		normalized := bytes.ReplaceAll(payload, []byte("\r\n"), []byte("\n"))
+		if normalizedKeyID, normalizedErr := keyring.verify(logger, normalized, signature); normalizedErr == nil {
+			logger.Debug("signature matches the payload with LF line endings")
+			report.Digest = hex.EncodeToString(Hash(normalized))
+			keyID, err = normalizedKeyID, nil
+		}
+	}
+	if err == nil {
+		err = checkSignatureAge(report.Signature, policy)
+	}
+	if err != nil {
+		err = withArtifactsHint(err, payloadArtifacts(payload))
+		report.Error = err.Error()
+		return report, err
+	}
+
+	report.KeyID = keyID
+	report.TrustLevel = keyring.TrustLevel(keyID)
+	if report.TrustLevel == TrustLevelWarnOnly {
+		logger.Warn("payload signed by a warn-only key", slog.String("key", keyID))
+	}
+	if len(policy.Rules) > 0 {
+		if err = checkDetachedRules(payload, &report, policy); err != nil {
+			report.Error = err.Error()
+			return report, err
+		}
+	}
+	logger.Info("payload verified", slog.String("key", keyID))
+	report.Verified = true
+	return report, nil
+}
+
+// checkDetachedRules evaluates the rules of the policy on the payload verified by a detached signature,
+// adding the violations to the report. The key is checked once, the content of every play.
+func checkDetachedRules(payload []byte, report *Report, policy Policy) error {
+	violations := checkKey(policy, -1, report.KeyID, report.Signature)
+	decoder := NewPlaybookDecoder(bytes.NewReader(payload))
+	decoder.AllowDuplicateKeys = policy.AllowDuplicateKeys
+	decoder.MemoryBudget = policy.MemoryBudget
+	for index := 0; ; {
+		plays, err := decoder.Decode()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return PlaybookError{ErrMalformedYAML, "payload could not be parsed to evaluate the rules", err}
+		}
+		for i := range plays {
+			violations = append(violations, checkPlay(policy, index, plays[i])...)
+			index++
+		}
+	}
+	report.Violations = violations
+	return violationsError(violations)
+}
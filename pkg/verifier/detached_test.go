@@ -0,0 +1,93 @@
+package verifier
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestVerifyDetached(t *testing.T) {
+	keyring := testKeyring(t)
+	playbook := readTestdata(t, filepath.Join("testdata", "detached", "playbook.yml"))
+
+	tests := []struct {
+		name      string
+		payload   []byte
+		signature string
+		want      error
+	}{
+		{"armored", playbook, "playbook.yml.asc", nil},
+		{"binary", playbook, "playbook.yml.sig", nil},
+		{"modified", append(playbook, []byte("# comment\n")...), "playbook.yml.asc", ErrDigestMismatch},
+		{"CRLF line endings", bytes.ReplaceAll(playbook, []byte("\n"), []byte("\r\n")), "playbook.yml.asc", nil},
+		{"modified CRLF line endings", bytes.ReplaceAll(append(playbook, []byte("# comment\n")...), []byte("\n"), []byte("\r\n")), "playbook.yml.asc", ErrDigestMismatch},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			signature := readTestdata(t, filepath.Join("testdata", "detached", tt.signature))
+			report, err := VerifyDetached(tt.payload, signature, keyring)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("VerifyDetached() error = %v, want %v", err, tt.want)
+			}
+			if report.Verified != (tt.want == nil) {
+				t.Errorf("VerifyDetached() verified = %v", report.Verified)
+			}
+			if tt.want == nil && report.KeyID != "08D82F6981A5FD2F" {
+				t.Errorf("VerifyDetached() key = %s, want 08D82F6981A5FD2F", report.KeyID)
+			}
+			if report.Signature == nil || report.Signature.KeyID != "08D82F6981A5FD2F" {
+				t.Errorf("VerifyDetached() signature = %+v, want key 08D82F6981A5FD2F", report.Signature)
+			}
+		})
+	}
+}
+
+// The messages of a detached verification go to the logger of the Verifier, not to the default one.
+func TestVerifierVerifyDetachedLogger(t *testing.T) {
+	playbook := readTestdata(t, filepath.Join("testdata", "detached", "playbook.yml"))
+	signature := readTestdata(t, filepath.Join("testdata", "detached", "playbook.yml.asc"))
+
+	var logs bytes.Buffer
+	v, err := NewVerifier(WithKeyring(testKeyring(t)), WithLogger(slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug}))))
+	if err != nil {
+		t.Fatalf("NewVerifier() error = %v", err)
+	}
+	var global bytes.Buffer
+	previous := slog.Default()
+	t.Cleanup(func() { slog.SetDefault(previous) })
+	slog.SetDefault(slog.New(slog.NewTextHandler(&global, &slog.HandlerOptions{Level: slog.LevelDebug})))
+
+	crlf := bytes.ReplaceAll(playbook, []byte("\n"), []byte("\r\n"))
+	if report, err := v.VerifyDetached(context.Background(), crlf, signature); err != nil || !report.Verified {
+		t.Fatalf("VerifyDetached() = %+v, %v, want verified", report, err)
+	}
+	for _, message := range []string{"signature metadata", "key did not verify signature", "signature matches the payload with LF line endings", "payload verified"} {
+		if !strings.Contains(logs.String(), message) {
+			t.Errorf("logs of the verifier do not contain %q:\n%s", message, logs.String())
+		}
+	}
+	if global.Len() != 0 {
+		t.Errorf("verification logged to the default logger:\n%s", global.String())
+	}
+}
+
+func TestVerifyDetachedRules(t *testing.T) {
+	playbook := readTestdata(t, filepath.Join("testdata", "detached", "playbook.yml"))
+	signature := readTestdata(t, filepath.Join("testdata", "detached", "playbook.yml.asc"))
+
+	policy := Policy{Rules: []Rule{{Name: "key", RequireKeyIDs: []string{"08D82F6981A5FD2F"}}}}
+	if report, err := VerifyDetachedPolicy(playbook, signature, testKeyring(t), policy); err != nil || len(report.Violations) != 0 {
+		t.Errorf("VerifyDetachedPolicy() = %+v, %v, want verified", report.Violations, err)
+	}
+
+	policy = Policy{Rules: []Rule{{Name: "key", RequireKeyIDs: []string{"E544FCAF4E2D23F9"}}, {Name: "tasks", MaxTasks: 1000}}}
+	report, err := VerifyDetachedPolicy(playbook, signature, testKeyring(t), policy)
+	if !errors.Is(err, ErrPolicyViolation) || report.Verified || len(report.Violations) != 1 || report.Violations[0].Play != -1 {
+		t.Errorf("VerifyDetachedPolicy() = %v, %+v, %v, want a violation of the key rule", report.Verified, report.Violations, err)
+	}
+}
@@ -0,0 +1,184 @@
+package verifier
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	yaml3 "gopkg.in/yaml.v3"
+
+	"com.github/m-horky/playbook-verifier/internal/hygiene"
+)
+
+// Causes of a failed verification, see Diagnosis.
+const (
+	// CauseMissingSignature means the play carries no signature.
+	CauseMissingSignature = "missing_signature"
+	// CauseMalformedSignature means the signature could not be decoded.
+	CauseMalformedSignature = "malformed_signature"
+	// CauseUnknownKey means the signature was created by a key that is not trusted.
+	CauseUnknownKey = "unknown_key"
+	// CauseDigestMismatch means the signature was not created over the serialized play.
+	CauseDigestMismatch = "digest_mismatch"
+	// CauseRevokedKey means the signature was created by a revoked key.
+	CauseRevokedKey = "revoked_key"
+	// CauseExpiredSignature means the signature is older than the policy allows, or from the future.
+	CauseExpiredSignature = "expired_signature"
+	// CauseMisplacedSignature means the signature was created over another play of the playbook.
+	CauseMisplacedSignature = "misplaced_signature"
+)
+
+// Diagnosis explains why a signature could not be verified and how to remedy it.
+type Diagnosis struct {
+	// Cause is one of the Cause constants.
+	Cause string `json:"cause"`
+	// KeyID is the ID of the key that claims to have created the signature, if it is known.
+	KeyID string `json:"key_id,omitempty"`
+	// Attempts are the serialization profiles the signature was checked in, for a digest mismatch.
+	Attempts []SerializationAttempt `json:"attempts,omitempty"`
+	// SignedPlay is the index of the play the signature was created for, for a misplaced signature.
+	SignedPlay *int `json:"signed_play,omitempty"`
+	// Hint suggests how to remedy the failure.
+	Hint string `json:"hint"`
+}
+
+// SerializationAttempt is a serialization profile a signature did not match.
+type SerializationAttempt struct {
+	Profile string `json:"profile"`
+	// Digest is the hex-encoded digest of the play serialized in the profile.
+	Digest string `json:"digest,omitempty"`
+	// Offset is the first byte where the serialization differs from the reference serialization
+	// of the policy, or -1 when they are equal. It is not set without a reference.
+	Offset *int `json:"offset,omitempty"`
+}
+
+// diagnose explains the error of the verification of the signature, or returns nil
+// if the error is not about the signature.
+func diagnose(err error, signature *SignatureInfo, keyring *Keyring) *Diagnosis {
+	var diagnosis Diagnosis
+	if signature != nil {
+		diagnosis.KeyID = signature.KeyID
+	}
+	switch {
+	case errors.Is(err, ErrNoSignature):
+		diagnosis.Cause = CauseMissingSignature
+		diagnosis.Hint = "the play is not signed, or its 'insights_signature' variables were removed; " +
+			"make sure it comes from Insights and was not edited after it was downloaded"
+	case errors.Is(err, ErrMalformedSignature):
+		diagnosis.Cause = CauseMalformedSignature
+		diagnosis.Hint = "'insights_signature' is not a base64-encoded signature; " +
+			"check that it was not truncated, re-wrapped or re-quoted in transport"
+	case errors.Is(err, ErrRevokedKey):
+		diagnosis.Cause = CauseRevokedKey
+		diagnosis.Hint = "the key that signed the play has been revoked; request the playbook again, so it is signed by a current key"
+	case errors.Is(err, ErrExpiredSignature):
+		diagnosis.Cause = CauseExpiredSignature
+		diagnosis.Hint = "the signature is older than --max-signature-age or from the future; " +
+			"request the playbook again, and check that the clock of the host is synchronized"
+	case errors.Is(err, ErrDigestMismatch) && diagnosis.KeyID != "" && keyring != nil && !keyring.knowsKey(diagnosis.KeyID):
+		diagnosis.Cause = CauseUnknownKey
+		diagnosis.Hint = fmt.Sprintf("the play was signed by key %s, which is not trusted; "+
+			"check that the playbook comes from the expected environment (e.g. production or staging), "+
+			"or add the public key with --trust-store or --gpg-key-dir", diagnosis.KeyID)
+	case errors.Is(err, ErrDigestMismatch):
+		diagnosis.Cause = CauseDigestMismatch
+		diagnosis.Hint = "the play was changed after it was signed, or it was serialized differently by the signer; " +
+			"compare its canonical form (--emit-canonical) with the one of the signer (--reference-serialization), " +
+			"or try other --serialization-profiles"
+	default:
+		return nil
+	}
+	return &diagnosis
+}
+
+// knowsKey reports whether the key ID belongs to a trusted key or one of its subkeys.
+func (k *Keyring) knowsKey(keyID string) bool {
+	for _, entity := range k.entities {
+		if strings.EqualFold(entity.PrimaryKey.KeyIdString(), keyID) {
+			return true
+		}
+		for _, subkey := range entity.Subkeys {
+			if strings.EqualFold(subkey.PublicKey.KeyIdString(), keyID) {
+				return true
+			}
+		}
+	}
+	for _, key := range k.ed25519Keys {
+		if strings.EqualFold(key.keyID(), keyID) {
+			return true
+		}
+	}
+	return false
+}
+
+// locateSignatures finds the plays the signatures of the plays with a digest mismatch were created for,
+// so plays that were reordered or whose signatures were swapped are told apart from tampered ones.
+//
+// The plays are the parsed plays of the reports, in the same order.
+func locateSignatures(reports []PlayReport, plays []*yaml3.Node, keyring *Keyring) {
+	for i := range reports {
+		diagnosis := reports[i].Diagnosis
+		if diagnosis == nil || diagnosis.Cause != CauseDigestMismatch {
+			continue
+		}
+		signature, err := GetPlaybookSignature(plays[i])
+		if err != nil {
+			continue
+		}
+		for j, other := range reports {
+			digest, err := hex.DecodeString(other.Digest)
+			if j == i || err != nil || len(digest) == 0 {
+				continue
+			}
+			if _, err = VerifySignature(digest, signature, keyring); err != nil {
+				continue
+			}
+			diagnosis.Cause = CauseMisplacedSignature
+			diagnosis.SignedPlay = &j
+			diagnosis.Attempts = nil
+			diagnosis.Hint = fmt.Sprintf("the signature was created for play %d (%q); "+
+				"the plays were reordered or their signatures were swapped after they were signed", j, other.Name)
+			break
+		}
+		hygiene.Wipe(signature)
+	}
+}
+
+// compareReference sets the offsets of the attempts of the diagnosed play to where its serializations
+// differ from the reference serialization.
+func compareReference(diagnosis *Diagnosis, dirty *yaml3.Node, reference []byte) {
+	if diagnosis == nil || diagnosis.Cause != CauseDigestMismatch || len(diagnosis.Attempts) == 0 {
+		return
+	}
+	serialization, err := playSerialization(dirty)
+	if err != nil {
+		return
+	}
+	clean, _, err := cleanPlaybook(dirty)
+	if err != nil {
+		return
+	}
+	for i := range diagnosis.Attempts {
+		serialized, err := serialization.Marshal(clean, diagnosis.Attempts[i].Profile)
+		if err != nil {
+			continue
+		}
+		offset := firstDifference(serialized, reference)
+		diagnosis.Attempts[i].Offset = &offset
+	}
+}
+
+// firstDifference returns the offset of the first byte where a and b differ, or -1 when they are equal.
+// When one is a prefix of the other, they differ at the end of the shorter one.
+func firstDifference(a, b []byte) int {
+	for i := range min(len(a), len(b)) {
+		if a[i] != b[i] {
+			return i
+		}
+	}
+	if len(a) == len(b) {
+		return -1
+	}
+	return min(len(a), len(b))
+}
@@ -0,0 +1,127 @@
+package verifier
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestDiagnosis(t *testing.T) {
+	signed := readTestdata(t, filepath.Join("testdata", "golden", "cve-update.yml"))
+	minisignKeyring, err := NewKeyring(readTestdata(t, filepath.Join("testdata", "keys", "test-minisign.pub")))
+	if err != nil {
+		t.Fatalf("NewKeyring() error = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		playbook []byte
+		keyring  *Keyring
+		play     int
+		cause    string
+		keyID    string
+	}{
+		{"missing signature", append(bytes.Clone(signed), []byte("- name: unsigned\n")...), testKeyring(t), 1, CauseMissingSignature, ""},
+		{"malformed signature", bytes.Replace(signed, []byte("insights_signature: LS0t"), []byte("insights_signature: '!!'\n    unused: LS0t"), 1), testKeyring(t), 0, CauseMalformedSignature, ""},
+		{"unknown key", signed, minisignKeyring, 0, CauseUnknownKey, "08D82F6981A5FD2F"},
+		{"digest mismatch", bytes.Replace(signed, []byte("restarted"), []byte("stopped"), 1), testKeyring(t), 0, CauseDigestMismatch, "08D82F6981A5FD2F"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report, err := VerifyPlaybook(tt.playbook, tt.keyring, Policy{})
+			if err == nil {
+				t.Fatal("VerifyPlaybook() error = nil")
+			}
+			diagnosis := report.Plays[tt.play].Diagnosis
+			if diagnosis == nil || diagnosis.Cause != tt.cause || diagnosis.KeyID != tt.keyID || diagnosis.Hint == "" {
+				t.Errorf("play %d diagnosis = %+v, want cause %s of key %q", tt.play, diagnosis, tt.cause, tt.keyID)
+			}
+		})
+	}
+}
+
+func TestDiagnosisMisplacedSignature(t *testing.T) {
+	signed := readTestdata(t, filepath.Join("testdata", "golden", "reboot.yml"))
+	lines := bytes.SplitAfter(signed, []byte("\n"))
+	var signatures []int
+	for i, line := range lines {
+		if bytes.Contains(line, []byte("insights_signature: ")) {
+			signatures = append(signatures, i)
+		}
+	}
+	if len(signatures) != 3 {
+		t.Fatalf("found %d signatures, want 3", len(signatures))
+	}
+	// the first two plays swap their signatures, the third one is left as it is
+	lines[signatures[0]], lines[signatures[1]] = lines[signatures[1]], lines[signatures[0]]
+	swapped := bytes.Join(lines, nil)
+
+	report, err := VerifyPlaybook(append([]byte("- name: unsigned\n---\n"), swapped...), testKeyring(t), Policy{})
+	if !errors.Is(err, ErrDigestMismatch) {
+		t.Fatalf("VerifyPlaybook() error = %v, want %v", err, ErrDigestMismatch)
+	}
+	for i, want := range []int{1, 0} {
+		play := report.Plays[i]
+		diagnosis := play.Diagnosis
+		if diagnosis == nil || diagnosis.Cause != CauseMisplacedSignature || diagnosis.SignedPlay == nil || *diagnosis.SignedPlay != want {
+			t.Errorf("play %d diagnosis = %+v, want signature of play %d", i, diagnosis, want)
+		}
+		if play.Document != 1 {
+			t.Errorf("play %d is in document %d, want 1", i, play.Document)
+		}
+	}
+	if !report.Plays[2].Verified || report.Plays[2].Diagnosis != nil {
+		t.Errorf("play 2 = %+v, want it verified", report.Plays[2])
+	}
+}
+
+func TestDiagnosisReference(t *testing.T) {
+	signed := readTestdata(t, filepath.Join("testdata", "golden", "cve-update.yml"))
+	reference := bytes.TrimSuffix(readTestdata(t, filepath.Join("testdata", "golden", "cve-update.serialized")), []byte("\n"))
+	tampered := bytes.Replace(signed, []byte("restarted"), []byte("stopped"), 1)
+
+	policy := Policy{SerializationProfiles: []string{ProfilePython3, ProfilePython2}, ReferenceSerializations: [][]byte{reference}}
+	report, err := VerifyPlaybook(tampered, testKeyring(t), policy)
+	if err == nil {
+		t.Fatal("VerifyPlaybook() error = nil")
+	}
+	diagnosis := report.Plays[0].Diagnosis
+	if diagnosis == nil || len(diagnosis.Attempts) != 2 {
+		t.Fatalf("diagnosis = %+v, want attempts of both profiles", diagnosis)
+	}
+	want := bytes.Index(reference, []byte("restarted"))
+	for i, profile := range []string{ProfilePython3, ProfilePython2} {
+		attempt := diagnosis.Attempts[i]
+		if attempt.Profile != profile || attempt.Digest == "" || attempt.Offset == nil {
+			t.Fatalf("attempt %d = %+v, want profile %s with a digest and an offset", i, attempt, profile)
+		}
+	}
+	if offset := *diagnosis.Attempts[0].Offset; offset != want {
+		t.Errorf("offset of %s = %d, want %d", ProfilePython3, offset, want)
+	}
+
+	// The signer may have hashed the tampered play too, then the signature is at fault.
+	policy = Policy{ReferenceSerializations: [][]byte{bytes.Replace(reference, []byte("restarted"), []byte("stopped"), 1)}}
+	report, _ = VerifyPlaybook(tampered, testKeyring(t), policy)
+	if offset := *report.Plays[0].Diagnosis.Attempts[0].Offset; offset != -1 {
+		t.Errorf("offset = %d, want -1 for a serialization equal to the reference", offset)
+	}
+}
+
+func TestFirstDifference(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"abc", "abc", -1},
+		{"abc", "abd", 2},
+		{"ab", "abc", 2},
+		{"", "a", 0},
+	}
+	for _, tt := range tests {
+		if got := firstDifference([]byte(tt.a), []byte(tt.b)); got != tt.want {
+			t.Errorf("firstDifference(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
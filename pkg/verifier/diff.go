@@ -0,0 +1,183 @@
+package verifier
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	yaml3 "gopkg.in/yaml.v3"
+)
+
+// diffContext is the number of unchanged lines printed around each change.
+const diffContext = 3
+
+// DiffPlay returns a unified diff between the play and its cleaned form,
+// both formatted as YAML.
+//
+// The diff shows what the exclusions removed before the play was hashed.
+func DiffPlay(dirty *yaml3.Node, clean *yaml3.Node, index int) (string, error) {
+	original, err := formatPlay(dirty)
+	if err != nil {
+		return "", PlaybookError{ErrUnsupportedType, "could not format play", err}
+	}
+	cleaned, err := formatPlay(clean)
+	if err != nil {
+		return "", PlaybookError{ErrUnsupportedType, "could not format cleaned play", err}
+	}
+
+	return unifiedDiff(
+		fmt.Sprintf("play %d (original)", index), splitLines(string(original)),
+		fmt.Sprintf("play %d (cleaned)", index), splitLines(string(cleaned)),
+	), nil
+}
+
+// formatPlay formats the play as an item of a list of plays, indented by two spaces.
+func formatPlay(play *yaml3.Node) ([]byte, error) {
+	var b bytes.Buffer
+	encoder := yaml3.NewEncoder(&b)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&yaml3.Node{Kind: yaml3.SequenceNode, Content: []*yaml3.Node{play}}); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// splitLines splits the text into lines without their line endings.
+func splitLines(text string) []string {
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// diffOperation is a single line of the edit script.
+type diffOperation struct {
+	kind byte // ' ', '-' or '+'
+	line string
+}
+
+// unifiedDiff formats the differences between the lines in the unified diff format.
+//
+// An empty string is returned if the lines are equal.
+func unifiedDiff(fromName string, from []string, toName string, to []string) string {
+	operations := editScript(from, to)
+
+	var b strings.Builder
+	for start := 0; start < len(operations); {
+		// find the next change
+		for start < len(operations) && operations[start].kind == ' ' {
+			start++
+		}
+		if start == len(operations) {
+			break
+		}
+
+		// extend the hunk while changes are closer than twice the context
+		hunkStart := max(start-diffContext, 0)
+		end := start
+		for i := start; i < len(operations); i++ {
+			if operations[i].kind != ' ' {
+				end = i + 1
+			} else if i-end >= 2*diffContext {
+				break
+			}
+		}
+		hunkEnd := min(end+diffContext, len(operations))
+
+		if b.Len() == 0 {
+			fmt.Fprintf(&b, "--- %s\n+++ %s\n", fromName, toName)
+		}
+		fromLine, toLine := 1, 1
+		for _, op := range operations[:hunkStart] {
+			if op.kind != '+' {
+				fromLine++
+			}
+			if op.kind != '-' {
+				toLine++
+			}
+		}
+		fromCount, toCount := 0, 0
+		for _, op := range operations[hunkStart:hunkEnd] {
+			if op.kind != '+' {
+				fromCount++
+			}
+			if op.kind != '-' {
+				toCount++
+			}
+		}
+		fmt.Fprintf(&b, "@@ -%d,%d +%d,%d @@\n", fromLine, fromCount, toLine, toCount)
+		for _, op := range operations[hunkStart:hunkEnd] {
+			fmt.Fprintf(&b, "%c%s\n", op.kind, op.line)
+		}
+		start = hunkEnd
+	}
+	return b.String()
+}
+
+// editScript returns the shortest sequence of operations turning the lines 'from'
+// into the lines 'to', using the algorithm of Eugene W. Myers.
+func editScript(from []string, to []string) []diffOperation {
+	n, m := len(from), len(to)
+	offset := n + m
+	frontier := make([]int, 2*offset+2)
+	var trace [][]int
+
+	// find the length of the shortest edit script, remembering the frontiers
+	var distance int
+search:
+	for distance = 0; distance <= n+m; distance++ {
+		trace = append(trace, append([]int(nil), frontier...))
+		for k := -distance; k <= distance; k += 2 {
+			var x int
+			if k == -distance || (k != distance && frontier[offset+k-1] < frontier[offset+k+1]) {
+				x = frontier[offset+k+1]
+			} else {
+				x = frontier[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && from[x] == to[y] {
+				x, y = x+1, y+1
+			}
+			frontier[offset+k] = x
+			if x >= n && y >= m {
+				break search
+			}
+		}
+	}
+
+	// walk the frontiers backwards to recover the operations
+	var operations []diffOperation
+	x, y := n, m
+	for d := distance; d > 0; d-- {
+		previous := trace[d]
+		k := x - y
+		var previousK int
+		if k == -d || (k != d && previous[offset+k-1] < previous[offset+k+1]) {
+			previousK = k + 1
+		} else {
+			previousK = k - 1
+		}
+		previousX := previous[offset+previousK]
+		previousY := previousX - previousK
+		for x > previousX && y > previousY {
+			x, y = x-1, y-1
+			operations = append(operations, diffOperation{' ', from[x]})
+		}
+		if x == previousX {
+			y--
+			operations = append(operations, diffOperation{'+', to[y]})
+		} else {
+			x--
+			operations = append(operations, diffOperation{'-', from[x]})
+		}
+	}
+	for x > 0 && y > 0 {
+		x, y = x-1, y-1
+		operations = append(operations, diffOperation{' ', from[x]})
+	}
+
+	for i, j := 0, len(operations)-1; i < j; i, j = i+1, j-1 {
+		operations[i], operations[j] = operations[j], operations[i]
+	}
+	return operations
+}
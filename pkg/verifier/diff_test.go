@@ -0,0 +1,54 @@
+package verifier
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	tests := []struct {
+		name string
+		from string
+		to   string
+		want string
+	}{
+		{"equal", "a\nb", "a\nb", ""},
+		{"removed", "a\nb\nc", "a\nc", "--- from\n+++ to\n@@ -1,3 +1,2 @@\n a\n-b\n c\n"},
+		{"changed", "a\nb", "a\nc", "--- from\n+++ to\n@@ -1,2 +1,2 @@\n a\n-b\n+c\n"},
+		{
+			name: "separate hunks",
+			from: "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11",
+			to:   "2\n3\n4\n5\n6\n7\n8\n9\n10",
+			want: "--- from\n+++ to\n@@ -1,4 +1,3 @@\n-1\n 2\n 3\n 4\n@@ -8,4 +7,3 @@\n 8\n 9\n 10\n-11\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := unifiedDiff("from", strings.Split(tt.from, "\n"), "to", strings.Split(tt.to, "\n"))
+			if got != tt.want {
+				t.Errorf("unifiedDiff() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiffPlay(t *testing.T) {
+	plays, err := UnmarshalPlaybook([]byte("- hosts: all\n  vars:\n    insights_signature_exclude: /hosts\n"))
+	if err != nil {
+		t.Fatalf("UnmarshalPlaybook() error = %v", err)
+	}
+	clean, err := CleanPlaybook(plays[0])
+	if err != nil {
+		t.Fatalf("CleanPlaybook() error = %v", err)
+	}
+
+	got, err := DiffPlay(plays[0], clean, 0)
+	if err != nil {
+		t.Fatalf("DiffPlay() error = %v", err)
+	}
+	want := "--- play 0 (original)\n+++ play 0 (cleaned)\n@@ -1,3 +1,2 @@\n-- hosts: all\n-  vars:\n+- vars:\n     insights_signature_exclude: /hosts\n"
+	if got != want {
+		t.Errorf("DiffPlay() =\n%s\nwant\n%s", got, want)
+	}
+}
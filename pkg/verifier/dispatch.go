@@ -0,0 +1,145 @@
+package verifier
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// PlaybookContentType is the content type of Insights remediation playbooks.
+const PlaybookContentType = "application/vnd.redhat.playbook+vnd.yaml"
+
+// Handler verifies a payload of a specific content type.
+type Handler func(payload []byte, keyring *Keyring, policy Policy) (Report, error)
+
+// ContextHandler is like Handler, but receives the context of the verification,
+// so it can create spans of its own stages.
+type ContextHandler func(ctx context.Context, payload []byte, keyring *Keyring, policy Policy) (Report, error)
+
+// handlers maps content types to their verification handlers.
+var handlers = newRegistry(map[string]ContextHandler{
+	PlaybookContentType: VerifyPlaybookContext,
+})
+
+// RegisterHandler makes the handler responsible for payloads of the content type.
+//
+// It is safe for concurrent use with Verify.
+func RegisterHandler(contentType string, handler Handler) {
+	handlers.register(contentType, func(_ context.Context, payload []byte, keyring *Keyring, policy Policy) (Report, error) {
+		return handler(payload, keyring, policy)
+	})
+}
+
+// RegisterContextHandler is like RegisterHandler, but for handlers that receive the context.
+func RegisterContextHandler(contentType string, handler ContextHandler) {
+	handlers.register(contentType, handler)
+}
+
+// ContentTypes returns the content types that have a registered handler.
+func ContentTypes() []string {
+	return handlers.names()
+}
+
+// Verify reads the payload from the reader and verifies it as configured by the options.
+//
+// Playbooks are decoded one YAML document at a time as they are read; payloads of other
+// content types are read whole and passed to their handler. The keyring has to be set
+// by WithKeyring. The verification stops once the context is done, returning its error.
+func Verify(ctx context.Context, r io.Reader, opts ...Option) (Report, error) {
+	v, err := NewVerifier(opts...)
+	if err != nil {
+		return Report{Plays: []PlayReport{}, SkippedDocuments: []int{}, Error: err.Error()}, err
+	}
+	return v.Verify(ctx, r)
+}
+
+// Verifier verifies payloads with the keyring, policy, logger and observer it has been created with.
+//
+// A Verifier is safe for concurrent use by multiple goroutines: its settings cannot be changed
+// once it is created, and every verification keeps its state to itself. The keyring must not
+// be changed (e.g. by Keyring.Revoke) while it is used.
+type Verifier struct {
+	options
+}
+
+// NewVerifier creates a verifier configured by the options. The keyring has to be set by WithKeyring.
+func NewVerifier(opts ...Option) (*Verifier, error) {
+	v := &Verifier{options{contentType: PlaybookContentType}}
+	for _, opt := range opts {
+		opt(&v.options)
+	}
+	if v.keyring == nil {
+		return nil, VerificationError{ErrInvalidKey, "no keyring given", nil}
+	}
+	return v, nil
+}
+
+// Verify reads the payload from the reader and verifies it, see the package-level Verify.
+func (v *Verifier) Verify(ctx context.Context, r io.Reader) (Report, error) {
+	ctx = v.context(ctx)
+	r = contextReader{ctx: ctx, r: r}
+	if v.contentType == PlaybookContentType {
+		report, err := VerifyPlaybookReaderContext(ctx, r, v.keyring, v.policy)
+		observeVerdict(ctx, report, err)
+		return report, err
+	}
+	payload, err := io.ReadAll(r)
+	if err != nil {
+		if ctx.Err() == nil {
+			err = fmt.Errorf("could not read payload: %w", err)
+		}
+		return Report{Plays: []PlayReport{}, SkippedDocuments: []int{}, Error: err.Error()}, err
+	}
+	return VerifyContext(ctx, v.contentType, payload, v.keyring, v.policy)
+}
+
+// VerifyPayload verifies the payload of the content type, see VerifyContext.
+// Empty content type means the content type of the verifier.
+func (v *Verifier) VerifyPayload(ctx context.Context, contentType string, payload []byte) (Report, error) {
+	if contentType == "" {
+		contentType = v.contentType
+	}
+	return VerifyContext(v.context(ctx), contentType, payload, v.keyring, v.policy)
+}
+
+// VerifyDetached verifies the payload against the detached signature, see VerifyDetachedContext.
+func (v *Verifier) VerifyDetached(ctx context.Context, payload []byte, signature []byte) (Report, error) {
+	ctx = v.context(ctx)
+	report, err := VerifyDetachedContext(ctx, payload, signature, v.keyring, v.policy)
+	observeVerdict(ctx, report, err)
+	return report, err
+}
+
+// context returns the context of a verification, which carries the logger and the observer of the verifier.
+func (v *Verifier) context(ctx context.Context) context.Context {
+	if v.logger != nil {
+		ctx = contextWithLogger(ctx, v.logger)
+	}
+	if v.observer != nil {
+		ctx = contextWithObserver(ctx, v.observer)
+	}
+	return ctx
+}
+
+// VerifyContext passes the payload to the handler registered for the content type,
+// recording the verification as a span of the context's trace.
+func VerifyContext(ctx context.Context, contentType string, payload []byte, keyring *Keyring, policy Policy) (report Report, err error) {
+	ctx, s := startStage(ctx, StageVerify,
+		attribute.String("payload.content_type", contentType),
+		attribute.Int("payload.size", len(payload)),
+	)
+	defer func() {
+		s.span.SetAttributes(attribute.Bool("payload.verified", report.Verified))
+		s.end(err)
+		observeVerdict(ctx, report, err)
+	}()
+
+	handler, ok := handlers.lookup(contentType)
+	if !ok {
+		err := PlaybookError{ErrUnsupportedContentType, fmt.Sprintf("no handler for content type '%s'", contentType), nil}
+		return Report{Plays: []PlayReport{}, SkippedDocuments: []int{}, Error: err.Error()}, err
+	}
+	return handler(ctx, payload, keyring, policy)
+}
@@ -0,0 +1,185 @@
+package verifier
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+
+	yaml3 "gopkg.in/yaml.v3"
+)
+
+// DocumentRange is the position of a YAML document in the payload.
+//
+// The documents of a payload are contiguous: every byte of the payload, including
+// comments between documents, belongs to exactly one of them.
+type DocumentRange struct {
+	// Index is the position of the document in the payload.
+	Index int `json:"index"`
+	// Offset is the position of the first byte of the document in the payload.
+	Offset int64 `json:"offset"`
+	// Length is the number of bytes of the document.
+	Length int64 `json:"length"`
+	// Skipped is set when the document has not been verified, see Report.SkippedDocuments.
+	Skipped bool `json:"skipped,omitempty"`
+}
+
+// VerifiedPayload returns the documents of the payload the report verified, byte for byte
+// as they were in the payload, without the documents that were skipped.
+//
+// The payload must be the one the report was created for, after normalization if the policy
+// asked for it. It is returned as it is when the report has no documents (e.g. it was verified
+// by a detached signature, which covers all of it) or when no document was skipped.
+func VerifiedPayload(payload []byte, report Report) []byte {
+	if len(report.Documents) == 0 || len(report.SkippedDocuments) == 0 {
+		return payload
+	}
+	var verified []byte
+	for _, document := range report.Documents {
+		end := document.Offset + document.Length
+		if document.Skipped || document.Offset < 0 || end > int64(len(payload)) {
+			continue
+		}
+		verified = append(verified, payload[document.Offset:end]...)
+	}
+	return verified
+}
+
+// rawDocument is a YAML document as it was read from the stream.
+type rawDocument struct {
+	offset  int64
+	content []byte
+	// line is the line of the first byte of the document in the stream, from 1.
+	line int
+}
+
+// documentSplitter splits a stream of YAML documents into the bytes of each document,
+// so they can be decoded one at a time and printed exactly as they were read.
+//
+// A line starting with the directives end marker '---' is never content of a document,
+// so the stream is split before every such line that follows the content of a document.
+// Comments between documents belong to the document before them. After the document
+// end marker '...', a directive starts the next document as well; content without
+// a marker is left to the parser, which refuses it like PyYAML does.
+type documentSplitter struct {
+	r      *bufio.Reader
+	offset int64
+	line   int
+	// pending is the line that has been read, but belongs to the next document.
+	pending []byte
+}
+
+func newDocumentSplitter(r io.Reader) *documentSplitter {
+	return &documentSplitter{r: bufio.NewReader(r), line: 1}
+}
+
+// next returns the next document, or io.EOF if the stream has no more bytes.
+func (s *documentSplitter) next() (rawDocument, error) {
+	document := rawDocument{offset: s.offset, line: s.line}
+	// content is set once the document has content, started once it has the '---' marker,
+	// and ended once it has the '...' marker.
+	content, started, ended := false, false, false
+	for {
+		line := s.pending
+		s.pending = nil
+		if line == nil {
+			var err error
+			line, err = s.r.ReadBytes('\n')
+			if err != nil && err != io.EOF {
+				return rawDocument{}, err
+			}
+			if len(line) == 0 {
+				if len(document.content) == 0 {
+					return rawDocument{}, io.EOF
+				}
+				return document, nil
+			}
+		}
+
+		split := false
+		switch {
+		case isMarker(line, "---"):
+			split = content || started || ended
+			started = true
+		case isMarker(line, "..."):
+			ended = true
+		case ended && line[0] == '%':
+			split = true
+		}
+		if split && len(document.content) > 0 {
+			s.pending = line
+			return document, nil
+		}
+		if isContent(line) && !isMarker(line, "...") {
+			content = true
+		}
+
+		document.content = append(document.content, line...)
+		s.offset += int64(len(line))
+		s.line++
+	}
+}
+
+// isMarker reports whether the line starts with the document marker, followed by a space or its end.
+func isMarker(line []byte, marker string) bool {
+	rest, ok := bytes.CutPrefix(line, []byte(marker))
+	return ok && (len(rest) == 0 || rest[0] == ' ' || rest[0] == '\t' || rest[0] == '\r' || rest[0] == '\n')
+}
+
+// isContent reports whether the line is anything else than blank, a comment or a directive.
+func isContent(line []byte) bool {
+	trimmed := bytes.TrimLeft(line, " \t\r\n")
+	if len(trimmed) == 0 {
+		return false
+	}
+	// a byte order mark may precede the first line
+	trimmed = bytes.TrimPrefix(trimmed, byteOrderMark)
+	return len(trimmed) > 0 && trimmed[0] != '#' && !(line[0] == '%' && len(trimmed) == len(line))
+}
+
+// decodeDocument parses the single YAML document. The lines of its nodes and errors are
+// the lines in the stream, not in the document.
+func decodeDocument(raw rawDocument) (*yaml3.Node, error) {
+	decoder := yaml3.NewDecoder(bytes.NewReader(raw.content))
+	var document yaml3.Node
+	if err := decoder.Decode(&document); err != nil {
+		if err == io.EOF {
+			return nil, io.EOF
+		}
+		return nil, shiftErrorLines(err, raw.line-1)
+	}
+	var extra yaml3.Node
+	if err := decoder.Decode(&extra); err != io.EOF {
+		return nil, fmt.Errorf("document at line %d could not be split from the next one", raw.line)
+	}
+	shiftLines(&document, raw.line-1)
+	return &document, nil
+}
+
+// shiftLines moves the nodes down by the number of lines.
+func shiftLines(node *yaml3.Node, lines int) {
+	if lines == 0 {
+		return
+	}
+	node.Line += lines
+	for _, child := range node.Content {
+		shiftLines(child, lines)
+	}
+}
+
+// errorLine matches the position yaml.v3 prefixes its syntax errors with.
+var errorLine = regexp.MustCompile(`^yaml: line (\d+):`)
+
+// shiftErrorLines moves the line of the syntax error down by the number of lines.
+func shiftErrorLines(err error, lines int) error {
+	position := errorLine.FindStringSubmatchIndex(err.Error())
+	if lines == 0 || position == nil {
+		return err
+	}
+	message := err.Error()
+	line, _ := strconv.Atoi(message[position[2]:position[3]])
+	return errors.New(message[:position[2]] + strconv.Itoa(line+lines) + message[position[3]:])
+}
@@ -0,0 +1,130 @@
+package verifier
+
+import (
+	"bytes"
+	"io"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	yaml3 "gopkg.in/yaml.v3"
+)
+
+func TestDocumentSplitter(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload string
+		want    []string
+	}{
+		{"single", "- a\n- b\n", []string{"- a\n- b\n"}},
+		{"no final newline", "- a", []string{"- a"}},
+		{"leading comments", "# first\n---\n- a\n", []string{"# first\n---\n- a\n"}},
+		{"markers", "---\n- a\n---\n- b\n", []string{"---\n- a\n", "---\n- b\n"}},
+		{"bare first document", "- a\n--- # second\n- b\n", []string{"- a\n", "--- # second\n- b\n"}},
+		{"empty document", "---\n---\n- a\n", []string{"---\n", "---\n- a\n"}},
+		{"content on marker", "--- [a]\n--- [b]\n", []string{"--- [a]\n", "--- [b]\n"}},
+		{"comments between", "- a\n# after a\n---\n- b\n", []string{"- a\n# after a\n", "---\n- b\n"}},
+		{"end marker", "- a\n...\n# trailing\n", []string{"- a\n...\n# trailing\n"}},
+		{"bare after end marker", "- a\n...\n- b\n", []string{"- a\n...\n- b\n"}},
+		{"directive after end marker", "- a\n...\n%YAML 1.1\n---\n- b\n", []string{"- a\n...\n", "%YAML 1.1\n---\n- b\n"}},
+		{"indented marker", "- |\n  ---\n  text\n", []string{"- |\n  ---\n  text\n"}},
+		{"marker prefix", "- a\n---b: 1\n", []string{"- a\n---b: 1\n"}},
+		{"CRLF", "---\r\n- a\r\n---\r\n- b\r\n", []string{"---\r\n- a\r\n", "---\r\n- b\r\n"}},
+		{"byte order mark", "\xef\xbb\xbf# comment\n---\n- a\n", []string{"\xef\xbb\xbf# comment\n---\n- a\n"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			splitter := newDocumentSplitter(strings.NewReader(tt.payload))
+			var got []string
+			var offset int64
+			for {
+				document, err := splitter.next()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					t.Fatalf("next() error = %v", err)
+				}
+				if document.offset != offset {
+					t.Errorf("offset of document %d = %d, want %d", len(got), document.offset, offset)
+				}
+				offset += int64(len(document.content))
+				got = append(got, string(document.content))
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("documents = %q, want %q", got, tt.want)
+			}
+
+			// yaml.v3 finds the same documents in the whole stream, or refuses it
+			decoder := yaml3.NewDecoder(strings.NewReader(tt.payload))
+			count := 0
+			for {
+				var node yaml3.Node
+				err := decoder.Decode(&node)
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					count = -1
+					break
+				}
+				count++
+			}
+			if count != -1 && count != len(tt.want) {
+				t.Errorf("yaml.v3 decoded %d documents, want %d", count, len(tt.want))
+			}
+		})
+	}
+}
+
+// The lines of errors and nodes are the ones yaml.v3 reports for the whole stream.
+func TestDecodeDocumentLines(t *testing.T) {
+	payload := "- name: first\n---\n- name: second\n  hosts: [unclosed\n"
+	decoder := NewPlaybookDecoder(strings.NewReader(payload))
+	plays, err := decoder.Decode()
+	if err != nil || len(plays) != 1 {
+		t.Fatalf("Decode() = %v, %v", plays, err)
+	}
+	stream := yaml3.NewDecoder(strings.NewReader(payload))
+	var node yaml3.Node
+	_ = stream.Decode(&node)
+	want := stream.Decode(&node)
+	if _, err = decoder.Decode(); err == nil || want == nil || !strings.HasSuffix(err.Error(), want.Error()) {
+		t.Errorf("Decode() error = %v, want %v", err, want)
+	}
+
+	plays, err = UnmarshalPlaybook([]byte("---\n- name: first\n---\n\n- name: second\n"))
+	if err != nil {
+		t.Fatalf("UnmarshalPlaybook() error = %v", err)
+	}
+	if line := plays[1].Line; line != 5 {
+		t.Errorf("line of the second play = %d, want 5", line)
+	}
+}
+
+func TestVerifiedPayload(t *testing.T) {
+	signed := readTestdata(t, filepath.Join("testdata", "golden", "reboot.yml"))
+	unsigned := []byte("# not signed\n---\n- name: unsigned\n  tasks:\n    - shell: rm -rf /tmp/x\n")
+	payload := append(bytes.Clone(unsigned), append([]byte("---\n"), signed...)...)
+
+	report, err := VerifyPlaybook(payload, testKeyring(t), Policy{})
+	if err != nil {
+		t.Fatalf("VerifyPlaybook() error = %v", err)
+	}
+	if len(report.Documents) != 2 || !report.Documents[0].Skipped || report.Documents[1].Skipped {
+		t.Fatalf("Documents = %+v, want the first one skipped", report.Documents)
+	}
+	if got, want := VerifiedPayload(payload, report), append([]byte("---\n"), signed...); !bytes.Equal(got, want) {
+		t.Errorf("VerifiedPayload() = %q, want the signed document", got)
+	}
+
+	// nothing was skipped, the payload is verified as it is
+	report, err = VerifyPlaybook(signed, testKeyring(t), Policy{})
+	if err != nil {
+		t.Fatalf("VerifyPlaybook() error = %v", err)
+	}
+	if got := VerifiedPayload(signed, report); !bytes.Equal(got, signed) {
+		t.Errorf("VerifiedPayload() = %q, want the payload", got)
+	}
+}
@@ -0,0 +1,121 @@
+package verifier
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Sentinel errors describing why a playbook could not be verified.
+//
+// All errors returned by the package wrap one of them, so they can be told apart
+// with errors.Is. The underlying cause, if any, is wrapped as well.
+var (
+	// ErrUnsupportedContentType means there is no handler for the content type of the payload.
+	ErrUnsupportedContentType = errors.New("unsupported content type")
+	// ErrMalformedYAML means the playbook is not a valid YAML list of plays.
+	ErrMalformedYAML = errors.New("malformed YAML")
+	// ErrAmbiguousYAML means the playbook uses a YAML feature the Go and the Python parsers
+	// may interpret differently, e.g. custom tags or duplicate keys.
+	ErrAmbiguousYAML = errors.New("ambiguous YAML")
+	// ErrUnsupportedType means the play contains a value that cannot be serialized.
+	ErrUnsupportedType = errors.New("unsupported type")
+	// ErrUnknownKey means the play contains a top-level key the strict policy does not allow.
+	ErrUnknownKey = errors.New("unknown key")
+	// ErrNoSignature means the play does not contain the signature or its exclusions.
+	ErrNoSignature = errors.New("no signature")
+	// ErrInvalidExclusion means the play tries to exclude a path that has to be signed.
+	ErrInvalidExclusion = errors.New("invalid exclusion")
+	// ErrMalformedSignature means the signature could not be decoded.
+	ErrMalformedSignature = errors.New("malformed signature")
+	// ErrInvalidKey means a trusted public key could not be loaded.
+	ErrInvalidKey = errors.New("invalid key")
+	// ErrWeakHashAlgorithm means the play has been hashed with an algorithm the policy does not allow.
+	ErrWeakHashAlgorithm = errors.New("weak hash algorithm")
+	// ErrExpiredSignature means the signature was created longer ago than the policy allows,
+	// or in the future.
+	ErrExpiredSignature = errors.New("expired signature")
+	// ErrRevokedKey means the signature was created by a key that has been revoked.
+	ErrRevokedKey = errors.New("revoked key")
+	// ErrDigestMismatch means the signature was not created over the play by any trusted key.
+	ErrDigestMismatch = errors.New("digest mismatch")
+	// ErrMemoryBudget means the payload needs more memory than the policy allows.
+	ErrMemoryBudget = errors.New("memory budget exceeded")
+	// ErrPolicyViolation means the payload is signed, but it does not comply with the rules of the policy.
+	ErrPolicyViolation = errors.New("policy violation")
+	// ErrInternal means the verification failed on a bug of the verifier, e.g. a panic.
+	ErrInternal = errors.New("internal error")
+)
+
+// PlaybookError means the playbook could not be processed.
+type PlaybookError struct {
+	kind    error
+	message string
+	err     error
+}
+
+func (e PlaybookError) Error() string {
+	return formatError("playbook error", e.message, e.err)
+}
+
+func (e PlaybookError) Unwrap() []error {
+	return unwrapErrors(e.kind, e.err)
+}
+
+// VerificationError means the playbook is signed, but its signature cannot be trusted.
+type VerificationError struct {
+	kind    error
+	message string
+	err     error
+}
+
+func (e VerificationError) Error() string {
+	return formatError("verification error", e.message, e.err)
+}
+
+func (e VerificationError) Unwrap() []error {
+	return unwrapErrors(e.kind, e.err)
+}
+
+// MissingSignatureError means the playbook is not signed.
+type MissingSignatureError struct {
+	kind    error
+	message string
+	err     error
+}
+
+func (e MissingSignatureError) Error() string {
+	return formatError("missing signature", e.message, e.err)
+}
+
+func (e MissingSignatureError) Unwrap() []error {
+	return unwrapErrors(e.kind, e.err)
+}
+
+// PolicyError means the playbook is verified, but the rules of the policy refuse it.
+type PolicyError struct {
+	kind    error
+	message string
+	err     error
+}
+
+func (e PolicyError) Error() string {
+	return formatError("policy error", e.message, e.err)
+}
+
+func (e PolicyError) Unwrap() []error {
+	return unwrapErrors(e.kind, e.err)
+}
+
+func formatError(prefix string, message string, err error) string {
+	if err == nil {
+		return fmt.Sprintf("%s: %s", prefix, message)
+	}
+	return fmt.Sprintf("%s: %s: %s", prefix, message, err)
+}
+
+func unwrapErrors(kind error, err error) []error {
+	if err == nil {
+		return []error{kind}
+	}
+	return []error{kind, err}
+}
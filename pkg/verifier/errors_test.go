@@ -0,0 +1,52 @@
+package verifier
+
+import (
+	"errors"
+	"testing"
+)
+
+func cleanRawPlaybook(playbook string) error {
+	plays, err := UnmarshalPlaybook([]byte(playbook))
+	if err != nil {
+		return err
+	}
+	_, err = CleanPlaybook(plays[0])
+	return err
+}
+
+func TestErrorsIs(t *testing.T) {
+	_, unsupportedErr := MarshallPlaybook(parsePlay(t, "{[complex]: key}"))
+
+	tests := []struct {
+		name string
+		err  error
+		want error
+	}{
+		{"malformed YAML", cleanRawPlaybook("- [unclosed"), ErrMalformedYAML},
+		{"no plays", cleanRawPlaybook("[]"), ErrMalformedYAML},
+		{"no exclusions", cleanRawPlaybook("- name: unsigned"), ErrNoSignature},
+		{"invalid exclusion", cleanRawPlaybook("- vars:\n    insights_signature_exclude: /tasks"), ErrInvalidExclusion},
+		{"unsupported type", unsupportedErr, ErrUnsupportedType},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !errors.Is(tt.err, tt.want) {
+				t.Errorf("error %v is not %v", tt.err, tt.want)
+			}
+		})
+	}
+}
+
+func TestErrorsAs(t *testing.T) {
+	err := cleanRawPlaybook("- name: unsigned")
+
+	var missingSignatureError MissingSignatureError
+	if !errors.As(err, &missingSignatureError) {
+		t.Errorf("error %v is not MissingSignatureError", err)
+	}
+	var playbookError PlaybookError
+	if errors.As(err, &playbookError) {
+		t.Errorf("error %v should not be PlaybookError", err)
+	}
+}
@@ -0,0 +1,63 @@
+package verifier
+
+import (
+	"io"
+	"log/slog"
+	"testing"
+
+	yaml3 "gopkg.in/yaml.v3"
+)
+
+// fuzzSeeds are playbooks exercising the different shapes the parser can produce.
+var fuzzSeeds = []string{
+	"- name: ping\n  hosts: all\n  vars:\n    insights_signature_exclude: /hosts,/vars/insights_signature\n    insights_signature: c2lnbmF0dXJl\n  tasks:\n    - ping:\n",
+	"- vars:\n    insights_signature_exclude: /vars/*,/tasks/*/vars/stamp\n  tasks:\n    - vars: {stamp: 1}\n",
+	"- vars:\n    insights_signature_exclude: 1\n",
+	"- vars: [a, b]\n",
+	"- {1: one, true: yes, null: ~, 1.5: [1, 2]}\n",
+	"- ? [complex]\n  : key\n",
+	"- &anchor {a: 1}\n- *anchor\n",
+	"[]",
+	"- !!binary aGVsbG8=\n",
+}
+
+func FuzzVerificationPipeline(f *testing.F) {
+	slog.SetDefault(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	for _, seed := range fuzzSeeds {
+		f.Add([]byte(seed))
+	}
+
+	f.Fuzz(func(t *testing.T, playbook []byte) {
+		plays, err := UnmarshalPlaybook(playbook)
+		if err != nil {
+			return
+		}
+		for i := range plays {
+			_, _ = GetPlaybookSignature(plays[i])
+			clean, err := CleanPlaybook(plays[i])
+			if err != nil {
+				continue
+			}
+			_, _ = MarshallPlaybook(clean)
+		}
+	})
+}
+
+func FuzzMarshallPlaybookItem(f *testing.F) {
+	for _, seed := range fuzzSeeds {
+		f.Add([]byte(seed))
+	}
+
+	f.Fuzz(func(t *testing.T, document []byte) {
+		var node yaml3.Node
+		if err := yaml3.Unmarshal(document, &node); err != nil || len(node.Content) == 0 {
+			return
+		}
+		canonical, err := canonicalize(&node)
+		if err != nil {
+			return
+		}
+		_, _ = serializer{}.marshallItem(canonical.Content[0])
+		_, _ = canonicalJSONSerialization{}.Marshal(canonical.Content[0], "")
+	})
+}
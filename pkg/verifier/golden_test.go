@@ -0,0 +1,109 @@
+package verifier
+
+import (
+	"bytes"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	yaml3 "gopkg.in/yaml.v3"
+)
+
+// The golden corpus in testdata/golden consists of synthetic playbooks signed by the key
+// in testdata/keys. The serialized form and the digest of each of their plays, stored next
+// to them, were produced by testdata/golden/generate.py, a second implementation of the
+// serialization on top of PyYAML written after the Python verifier of insights-core; it is not
+// insights-core itself and none of the playbooks comes from Red Hat Insights.
+//
+// The script only supports exclusions of at most two segments, so the deep, wildcard and
+// list exclusions are not part of the corpus and only have the tests of this package.
+func TestGoldenCorpus(t *testing.T) {
+	playbooks, err := filepath.Glob(filepath.Join("testdata", "golden", "*.yml"))
+	if err != nil {
+		t.Fatalf("filepath.Glob() error = %v", err)
+	}
+	if len(playbooks) == 0 {
+		t.Fatal("golden corpus is empty")
+	}
+
+	keyring := testKeyring(t)
+
+	for _, path := range playbooks {
+		name := strings.TrimSuffix(filepath.Base(path), ".yml")
+		t.Run(name, func(t *testing.T) {
+			raw := readTestdata(t, path)
+			wantSerialized := bytes.Split(bytes.TrimSuffix(readTestdata(t, strings.TrimSuffix(path, ".yml")+".serialized"), []byte("\n")), []byte("\n"))
+			wantDigests := strings.Fields(string(readTestdata(t, strings.TrimSuffix(path, ".yml")+".digest")))
+
+			plays, err := UnmarshalPlaybook(raw)
+			if err != nil {
+				t.Fatalf("UnmarshalPlaybook() error = %v", err)
+			}
+			if len(plays) != len(wantSerialized) || len(plays) != len(wantDigests) {
+				t.Fatalf("got %d plays, want %d serialized and %d digests", len(plays), len(wantSerialized), len(wantDigests))
+			}
+
+			for i := range plays {
+				clean, err := CleanPlaybook(plays[i])
+				if err != nil {
+					t.Fatalf("play %d: CleanPlaybook() error = %v", i, err)
+				}
+				serialized, err := MarshallPlaybook(clean)
+				if err != nil {
+					t.Fatalf("play %d: MarshallPlaybook() error = %v", i, err)
+				}
+				if !bytes.Equal(serialized, wantSerialized[i]) {
+					t.Errorf("play %d: MarshallPlaybook() =\n%s\nwant\n%s", i, serialized, wantSerialized[i])
+				}
+				algorithm, err := playHashAlgorithm(plays[i], Policy{})
+				if err != nil {
+					t.Fatalf("play %d: playHashAlgorithm() error = %v", i, err)
+				}
+				if digest := hex.EncodeToString(algorithm.Sum(serialized)); digest != wantDigests[i] {
+					t.Errorf("play %d: %s digest = %s, want %s", i, algorithm.Name(), digest, wantDigests[i])
+				}
+			}
+
+			report, err := VerifyPlaybook(raw, keyring, Policy{})
+			if err != nil {
+				t.Fatalf("VerifyPlaybook() error = %v", err)
+			}
+			for i, play := range report.Plays {
+				if play.Digest != wantDigests[i] {
+					t.Errorf("play %d: report digest = %s, want %s", i, play.Digest, wantDigests[i])
+				}
+			}
+		})
+	}
+}
+
+// testKeyring returns a keyring containing the public test key from testdata/keys.
+func testKeyring(t testing.TB) *Keyring {
+	t.Helper()
+	keyring, err := NewKeyring(readTestdata(t, filepath.Join("testdata", "keys", "test-public.asc")))
+	if err != nil {
+		t.Fatalf("NewKeyring() error = %v", err)
+	}
+	return keyring
+}
+
+func readTestdata(t testing.TB, path string) []byte {
+	t.Helper()
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("os.ReadFile() error = %v", err)
+	}
+	return content
+}
+
+// parsePlay decodes the play written as a YAML mapping, e.g. '{name: play}'.
+func parsePlay(t *testing.T, play string) *yaml3.Node {
+	t.Helper()
+	plays, err := UnmarshalPlaybook([]byte("- " + play))
+	if err != nil {
+		t.Fatalf("UnmarshalPlaybook(%q) error = %v", play, err)
+	}
+	return plays[0]
+}
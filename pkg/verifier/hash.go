@@ -0,0 +1,163 @@
+package verifier
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/sha512"
+	"fmt"
+	"hash"
+	"io"
+	"log/slog"
+
+	"golang.org/x/crypto/sha3"
+	yaml3 "gopkg.in/yaml.v3"
+)
+
+// DefaultHashAlgorithm is the algorithm of plays that do not declare one.
+const DefaultHashAlgorithm = "sha256"
+
+// HashAlgorithm computes the digest of the serialized play.
+type HashAlgorithm interface {
+	// Name is the name of the algorithm in 'insights_signature_hash', e.g. 'sha256'.
+	Name() string
+	// Strength is the security level of the algorithm in bits. Algorithms with lower
+	// strength than the minimum of the policy are refused.
+	Strength() int
+	// Sum returns the digest of the data.
+	Sum(data []byte) []byte
+}
+
+// StreamingHashAlgorithm is a HashAlgorithm that can hash data written in parts,
+// see StreamingSerialization.
+type StreamingHashAlgorithm interface {
+	HashAlgorithm
+	// New returns a new hash of the algorithm, whose Sum is the same as the Sum of the algorithm.
+	New() hash.Hash
+}
+
+// hashAlgorithm implements StreamingHashAlgorithm with a function of the standard library.
+type hashAlgorithm struct {
+	name     string
+	strength int
+	new      func() hash.Hash
+}
+
+func (a hashAlgorithm) Name() string  { return a.name }
+func (a hashAlgorithm) Strength() int { return a.strength }
+
+func (a hashAlgorithm) New() hash.Hash { return a.new() }
+
+func (a hashAlgorithm) Sum(data []byte) []byte {
+	h := a.new()
+	h.Write(data)
+	return h.Sum(nil)
+}
+
+// hashAlgorithms maps the names of the algorithms to their implementations.
+var hashAlgorithms = newRegistry(map[string]HashAlgorithm{
+	"sha256":   hashAlgorithm{"sha256", 128, sha256.New},
+	"sha512":   hashAlgorithm{"sha512", 256, sha512.New},
+	"sha3-256": hashAlgorithm{"sha3-256", 128, sha3.New256},
+	"sha3-512": hashAlgorithm{"sha3-512", 256, sha3.New512},
+})
+
+// RegisterHashAlgorithm makes the algorithm available under its name.
+//
+// It is safe for concurrent use with Verify.
+func RegisterHashAlgorithm(algorithm HashAlgorithm) {
+	hashAlgorithms.register(algorithm.Name(), algorithm)
+}
+
+// HashAlgorithms returns the names of the available algorithms.
+func HashAlgorithms() []string {
+	return hashAlgorithms.names()
+}
+
+// LookupHashAlgorithm returns the algorithm of the name. Empty name means the default algorithm.
+func LookupHashAlgorithm(name string) (HashAlgorithm, error) {
+	if name == "" {
+		name = DefaultHashAlgorithm
+	}
+	algorithm, ok := hashAlgorithms.lookup(name)
+	if !ok {
+		return nil, VerificationError{ErrMalformedSignature, fmt.Sprintf("unsupported hash algorithm '%s'", name), nil}
+	}
+	return algorithm, nil
+}
+
+// Hash computes the SHA-256 digest of the serialized playbook.
+//
+// The digest is returned in its raw binary form, as the signature is created over
+// these bytes, not over their hexadecimal representation.
+func Hash(serialized []byte) []byte {
+	digest := sha256.Sum256(serialized)
+	return digest[:]
+}
+
+// playHashAlgorithm returns the algorithm the play has been hashed with.
+//
+// The play declares it in 'insights_signature_hash'; plays that do not declare it
+// use the algorithm of the policy. Since the variable is a part of the signed content,
+// it cannot be changed without invalidating the signature, but the policy's minimum
+// is enforced anyway, so a play signed with a weaker algorithm is never accepted.
+func playHashAlgorithm(p *yaml3.Node, policy Policy) (HashAlgorithm, error) {
+	value, _ := getPlaybookVariable(p, "insights_signature_hash")
+	name, ok := stringValue(value)
+	if !ok {
+		return nil, VerificationError{ErrMalformedSignature, fmt.Sprintf("'insights_signature_hash' must be a string, not %s", yamlTypeName(value)), nil}
+	}
+	if name == "" {
+		name = policy.HashAlgorithm
+	}
+
+	algorithm, err := LookupHashAlgorithm(name)
+	if err != nil {
+		return nil, err
+	}
+	minimum, err := LookupHashAlgorithm(policy.MinimumHashAlgorithm)
+	if err != nil {
+		return nil, err
+	}
+	if algorithm.Strength() < minimum.Strength() {
+		return nil, VerificationError{ErrWeakHashAlgorithm, fmt.Sprintf("hash algorithm '%s' is weaker than the required '%s'", algorithm.Name(), minimum.Name()), nil}
+	}
+	return algorithm, nil
+}
+
+// sumPlay serializes the cleaned play and hashes it. It returns the digest and the size of the serialized play.
+//
+// When both the serialization and the algorithm stream, the play is serialized directly into the hash,
+// so a play of megabytes is not copied into memory first. The serialized play is only kept when debug
+// messages are logged, since it is logged too.
+func sumPlay(ctx context.Context, play *yaml3.Node, serialization Serialization, profile string, algorithm HashAlgorithm) ([]byte, int, error) {
+	streaming, ok := serialization.(StreamingSerialization)
+	hashing, hashes := algorithm.(StreamingHashAlgorithm)
+	Logger(ctx).Debug("starting serialization", slog.String("profile", profile))
+	if !ok || !hashes || Logger(ctx).Enabled(ctx, slog.LevelDebug) {
+		serialized, err := serialization.Marshal(play, profile)
+		if err != nil {
+			return nil, 0, err
+		}
+		Logger(ctx).Debug("playbook serialized", slog.String("serialized", string(serialized)))
+		return algorithm.Sum(serialized), len(serialized), nil
+	}
+
+	h := hashing.New()
+	counter := &countingWriter{w: h}
+	if err := streaming.Stream(counter, play, profile); err != nil {
+		return nil, 0, err
+	}
+	return h.Sum(nil), counter.n, nil
+}
+
+// countingWriter counts the bytes written to the writer.
+type countingWriter struct {
+	w io.Writer
+	n int
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += n
+	return n, err
+}
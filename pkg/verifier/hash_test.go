@@ -0,0 +1,139 @@
+package verifier
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+// Expected digests were produced by the Python verifier:
+// hashlib.sha256(str(snippet).encode("UTF-8")).hexdigest()
+func TestHash(t *testing.T) {
+	tests := []struct {
+		name       string
+		serialized string
+		digest     string
+	}{
+		{
+			name:       "empty play",
+			serialized: "ordereddict()",
+			digest:     "c0a28ef32528f623ab8955c5b15199c5e5d41fe92b8cf3bf92c9550b480f5328",
+		},
+		{
+			name:       "non-ascii",
+			serialized: "ordereddict([('name', 'Aktualizace balíčků')])",
+			digest:     "ccede36c4d8feba3f846dcca3946129e3a562e4ccbbf3b96dd985982fe8eea62",
+		},
+		{
+			name: "remediation",
+			serialized: "ordereddict([('name', 'Insights remediation for CVE-2024-0001'), " +
+				"('vars', ordereddict([('insights_signature_exclude', '/hosts,/vars/insights_signature')])), " +
+				"('become', True), " +
+				"('tasks', [ordereddict([('name', 'Update vulnerable packages'), " +
+				"('yum', ordereddict([('name', 'openssl'), ('state', 'latest')]))])])])",
+			digest: "6b40cd821e74bce936976cf48c42ea80db38e63c1007143a50e73b951ab11717",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := hex.EncodeToString(Hash([]byte(tt.serialized)))
+			if got != tt.digest {
+				t.Errorf("Hash() = %s, want %s", got, tt.digest)
+			}
+		})
+	}
+}
+
+func TestHashPlaybook(t *testing.T) {
+	playbook := []byte(`- name: Insights remediation for CVE-2024-0001
+  hosts: "@@HOSTS@@"
+  vars:
+    insights_signature_exclude: /hosts,/vars/insights_signature
+    insights_signature: c2lnbmF0dXJl
+  become: true
+  tasks:
+    - name: Update vulnerable packages
+      yum:
+        name: openssl
+        state: latest
+`)
+	want := "6b40cd821e74bce936976cf48c42ea80db38e63c1007143a50e73b951ab11717"
+
+	plays, err := UnmarshalPlaybook(playbook)
+	if err != nil {
+		t.Fatalf("UnmarshalPlaybook() error = %v", err)
+	}
+	clean, err := CleanPlaybook(plays[0])
+	if err != nil {
+		t.Fatalf("CleanPlaybook() error = %v", err)
+	}
+	serialized, err := MarshallPlaybook(clean)
+	if err != nil {
+		t.Fatalf("MarshallPlaybook() error = %v", err)
+	}
+
+	if got := hex.EncodeToString(Hash(serialized)); got != want {
+		t.Errorf("Hash() = %s, want %s", got, want)
+	}
+}
+
+func TestPlayHashAlgorithm(t *testing.T) {
+	keyring := testKeyring(t)
+	playbook := readTestdata(t, filepath.Join("testdata", "golden", "hash-algorithms.yml"))
+	legacy := readTestdata(t, filepath.Join("testdata", "golden", "cve-update.yml"))
+
+	tests := []struct {
+		name     string
+		playbook []byte
+		policy   Policy
+		want     error
+	}{
+		{"declared algorithms", playbook, Policy{}, nil},
+		{"minimum met", playbook, Policy{MinimumHashAlgorithm: "sha3-256"}, nil},
+		{"declared algorithm below minimum", playbook, Policy{MinimumHashAlgorithm: "sha512"}, ErrWeakHashAlgorithm},
+		{"default algorithm below minimum", legacy, Policy{MinimumHashAlgorithm: "sha512"}, ErrWeakHashAlgorithm},
+		{"default algorithm mismatch", legacy, Policy{HashAlgorithm: "sha512"}, ErrDigestMismatch},
+		{"unsupported minimum", legacy, Policy{MinimumHashAlgorithm: "md5"}, ErrMalformedSignature},
+		{"unsupported algorithm", bytes.Replace(playbook, []byte("sha512"), []byte("md5"), 1), Policy{}, ErrMalformedSignature},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := VerifyPlaybook(tt.playbook, keyring, tt.policy)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("VerifyPlaybook() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+// Plays serialized straight into the hash have the same digest and size as those serialized in memory.
+func TestSumPlay(t *testing.T) {
+	plays, err := UnmarshalPlaybook(readTestdata(t, filepath.Join("testdata", "golden", "hash-algorithms.yml")))
+	if err != nil {
+		t.Fatalf("UnmarshalPlaybook() error = %v", err)
+	}
+	for i, play := range plays {
+		clean, err := CleanPlaybook(play)
+		if err != nil {
+			t.Fatalf("play %d: CleanPlaybook() error = %v", i, err)
+		}
+		algorithm, err := playHashAlgorithm(play, Policy{})
+		if err != nil {
+			t.Fatalf("play %d: playHashAlgorithm() error = %v", i, err)
+		}
+		for _, profile := range SerializationProfiles() {
+			serialized, err := MarshallPlaybookProfile(clean, profile)
+			if err != nil {
+				t.Fatalf("play %d: MarshallPlaybookProfile(%s) error = %v", i, profile, err)
+			}
+			digest, size, err := sumPlay(context.Background(), clean, reprSerialization{}, profile, algorithm)
+			if err != nil || !bytes.Equal(digest, algorithm.Sum(serialized)) || size != len(serialized) {
+				t.Errorf("play %d: sumPlay(%s) = %x, %d, %v, want %x, %d", i, profile, digest, size, err, algorithm.Sum(serialized), len(serialized))
+			}
+		}
+	}
+}
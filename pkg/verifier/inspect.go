@@ -0,0 +1,249 @@
+package verifier
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	yaml3 "gopkg.in/yaml.v3"
+)
+
+// PlayInspection describes what would be hashed and verified for a single play.
+type PlayInspection struct {
+	// Index is the position of the play in the playbook.
+	Index int `json:"index"`
+	// Name is the value of the 'name' key of the play.
+	Name string `json:"name"`
+	// Excluded are the paths that have been removed from the play before hashing.
+	Excluded []string `json:"excluded"`
+	// SerializationVersion is the version of the serialization the play is hashed in.
+	SerializationVersion string `json:"serialization_version,omitempty"`
+	// Serialized is the canonical form of the play the digest is computed from.
+	Serialized string `json:"serialized,omitempty"`
+	// Digest is the hex-encoded digest of the serialized play.
+	Digest string `json:"digest,omitempty"`
+	// HashAlgorithm is the name of the algorithm the digest has been computed with.
+	HashAlgorithm string         `json:"hash_algorithm,omitempty"`
+	Signature     *SignatureInfo `json:"signature,omitempty"`
+	// Error describes why the play could not be inspected.
+	Error string `json:"error,omitempty"`
+}
+
+// SignatureInfo is the metadata of a signature.
+type SignatureInfo struct {
+	// Scheme is the signature scheme, e.g. 'openpgp' or 'minisign'.
+	Scheme string `json:"scheme"`
+	// KeyID is the ID of the key that claims to have created the signature.
+	KeyID string `json:"key_id,omitempty"`
+	// Fingerprint is the fingerprint of the key that claims to have created the signature.
+	Fingerprint        string    `json:"fingerprint,omitempty"`
+	Created            time.Time `json:"created"`
+	HashAlgorithm      string    `json:"hash_algorithm"`
+	PublicKeyAlgorithm string    `json:"public_key_algorithm"`
+	// Version is the version of the signature format, if the scheme has one.
+	Version int `json:"version,omitempty"`
+}
+
+// InspectPlaybook runs the verification pipeline up to the point of checking the signatures.
+//
+// For each play it returns the canonical serialized form, its digest, the paths that were
+// excluded and the metadata of the signature. Nothing is verified; the function is meant
+// for debugging playbooks whose signatures do not match.
+func InspectPlaybook(playbook []byte) ([]PlayInspection, error) {
+	plays, err := UnmarshalPlaybook(playbook)
+	if err != nil {
+		return nil, err
+	}
+
+	inspections := []PlayInspection{}
+	for i := range plays {
+		inspections = append(inspections, InspectPlay(plays[i], i))
+	}
+	return inspections, nil
+}
+
+// InspectPlay describes what would be hashed and verified for the play.
+func InspectPlay(dirty *yaml3.Node, index int) PlayInspection {
+	inspection := PlayInspection{Index: index, Name: getPlayName(dirty), Excluded: []string{}}
+
+	if signature, err := GetPlaybookSignature(dirty); err != nil {
+		inspection.Error = err.Error()
+	} else if info, err := ParseSignature(signature); err != nil {
+		inspection.Error = err.Error()
+	} else {
+		inspection.Signature = info
+	}
+
+	clean, excluded, err := cleanPlaybook(dirty)
+	if err != nil {
+		inspection.Error = err.Error()
+		return inspection
+	}
+	inspection.Excluded = append(inspection.Excluded, excludedPaths(excluded)...)
+
+	serialization, err := playSerialization(dirty)
+	if err != nil {
+		inspection.Error = err.Error()
+		return inspection
+	}
+	inspection.SerializationVersion = serialization.Version()
+	serialized, err := serialization.Marshal(clean, DefaultSerializationProfile)
+	if err != nil {
+		inspection.Error = err.Error()
+		return inspection
+	}
+	inspection.Serialized = string(serialized)
+
+	algorithm, err := playHashAlgorithm(dirty, Policy{})
+	if err != nil {
+		inspection.Error = err.Error()
+		return inspection
+	}
+	inspection.HashAlgorithm = algorithm.Name()
+	inspection.Digest = hex.EncodeToString(algorithm.Sum(serialized))
+	return inspection
+}
+
+// ParseSignature reads the metadata of either ASCII-armored or binary OpenPGP signature,
+// of a minisign or signify signature, of a Sigstore bundle or of a PKCS#7 signature.
+func ParseSignature(signature []byte) (*SignatureInfo, error) {
+	if isMinisignData(signature) {
+		return parseMinisignSignatureInfo(signature)
+	}
+	if isSigstoreBundle(signature) {
+		return parseSigstoreSignatureInfo(signature)
+	}
+	if _, ok := pkcs7DER(signature); ok {
+		return parsePKCS7SignatureInfo(signature)
+	}
+	sig, err := readSignaturePacket(signature)
+	if err != nil {
+		return nil, err
+	}
+
+	info := &SignatureInfo{
+		Scheme:             SchemeOpenPGP,
+		Created:            sig.CreationTime.UTC(),
+		HashAlgorithm:      sig.Hash.String(),
+		PublicKeyAlgorithm: publicKeyAlgorithmName(sig.PubKeyAlgo),
+		Version:            sig.Version,
+	}
+	if sig.IssuerKeyId != nil {
+		info.KeyID = fmt.Sprintf("%016X", *sig.IssuerKeyId)
+	}
+	if len(sig.IssuerFingerprint) > 0 {
+		info.Fingerprint = strings.ToUpper(hex.EncodeToString(sig.IssuerFingerprint))
+	}
+	return info, nil
+}
+
+// parseSignatureMetadata returns the metadata of the signature for the verification report,
+// logging it to the logger.
+//
+// Signatures whose metadata cannot be read are reported without it; the verification
+// explains what is wrong with them.
+func parseSignatureMetadata(logger *slog.Logger, signature []byte) *SignatureInfo {
+	info, err := ParseSignature(signature)
+	if err != nil {
+		logger.Debug("could not read signature metadata", slog.Any("error", err))
+		return nil
+	}
+	logger.Debug("signature metadata", slog.String("scheme", info.Scheme), slog.String("key_id", info.KeyID),
+		slog.String("fingerprint", info.Fingerprint), slog.Time("created", info.Created),
+		slog.String("hash_algorithm", info.HashAlgorithm), slog.Int("version", info.Version))
+	return info
+}
+
+// parseMinisignSignatureInfo reads the metadata of a minisign or signify signature.
+//
+// The signatures do not record when they were created, so Created is left empty.
+func parseMinisignSignatureInfo(signature []byte) (*SignatureInfo, error) {
+	sig, err := parseMinisignSignature(signature)
+	if err != nil {
+		return nil, err
+	}
+	info := &SignatureInfo{Scheme: sig.scheme(), KeyID: sig.keyIDString(), HashAlgorithm: "none", PublicKeyAlgorithm: "Ed25519"}
+	if sig.algorithm == minisignAlgorithmHashed {
+		info.HashAlgorithm = "BLAKE2b-512"
+	}
+	return info, nil
+}
+
+// parseSigstoreSignatureInfo reads the metadata of a Sigstore bundle.
+//
+// The key ID is the identity of the signer, as claimed by the certificate.
+func parseSigstoreSignatureInfo(signature []byte) (*SignatureInfo, error) {
+	var bundle sigstoreBundle
+	if err := json.Unmarshal(signature, &bundle); err != nil {
+		return nil, VerificationError{ErrMalformedSignature, "Sigstore bundle is not valid JSON", err}
+	}
+	certificates, err := bundle.certificates()
+	if err != nil {
+		return nil, err
+	}
+	leaf := certificates[0]
+	fingerprint := sha256.Sum256(leaf.Raw)
+	info := &SignatureInfo{
+		Scheme:             SchemeSigstore,
+		Fingerprint:        strings.ToUpper(hex.EncodeToString(fingerprint[:])),
+		HashAlgorithm:      "SHA-256",
+		PublicKeyAlgorithm: leaf.PublicKeyAlgorithm.String(),
+	}
+	if identity, err := certificateIdentity(leaf); err == nil {
+		info.KeyID = identity.String()
+	}
+	if entries := bundle.VerificationMaterial.TlogEntries; len(entries) > 0 {
+		info.Created = time.Unix(entries[0].IntegratedTime, 0).UTC()
+	}
+	return info, nil
+}
+
+// readSignaturePacket parses the first packet of the signature, which has to be a signature.
+func readSignaturePacket(signature []byte) (*packet.Signature, error) {
+	var r io.Reader = bytes.NewReader(signature)
+	if bytes.HasPrefix(signature, []byte("-----BEGIN PGP SIGNATURE-----")) {
+		block, err := armor.Decode(r)
+		if err != nil {
+			return nil, VerificationError{ErrMalformedSignature, "signature is not valid ASCII armor", err}
+		}
+		r = block.Body
+	}
+
+	p, err := packet.Read(r)
+	if err != nil {
+		return nil, VerificationError{ErrMalformedSignature, "signature is not a valid OpenPGP packet", err}
+	}
+	sig, ok := p.(*packet.Signature)
+	if !ok {
+		return nil, VerificationError{ErrMalformedSignature, fmt.Sprintf("expected signature packet, got %T", p), nil}
+	}
+	return sig, nil
+}
+
+// publicKeyAlgorithmName returns the name of the public key algorithm of the signature.
+func publicKeyAlgorithmName(algorithm packet.PublicKeyAlgorithm) string {
+	switch algorithm {
+	case packet.PubKeyAlgoRSA, packet.PubKeyAlgoRSASignOnly:
+		return "RSA"
+	case packet.PubKeyAlgoDSA:
+		return "DSA"
+	case packet.PubKeyAlgoECDSA:
+		return "ECDSA"
+	case packet.PubKeyAlgoEdDSA:
+		return "EdDSA"
+	case packet.PubKeyAlgoEd25519:
+		return "Ed25519"
+	case packet.PubKeyAlgoEd448:
+		return "Ed448"
+	default:
+		return fmt.Sprintf("unknown (%d)", algorithm)
+	}
+}
@@ -0,0 +1,62 @@
+package verifier
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestInspectPlaybook(t *testing.T) {
+	playbook := readTestdata(t, filepath.Join("testdata", "golden", "cve-update.yml"))
+	serialized := strings.TrimSuffix(string(readTestdata(t, filepath.Join("testdata", "golden", "cve-update.serialized"))), "\n")
+	digest := strings.TrimSpace(string(readTestdata(t, filepath.Join("testdata", "golden", "cve-update.digest"))))
+
+	inspections, err := InspectPlaybook(playbook)
+	if err != nil {
+		t.Fatalf("InspectPlaybook() error = %v", err)
+	}
+	if len(inspections) != 1 {
+		t.Fatalf("InspectPlaybook() returned %d plays, want 1", len(inspections))
+	}
+
+	inspection := inspections[0]
+	if inspection.Serialized != serialized {
+		t.Errorf("serialized = %s, want %s", inspection.Serialized, serialized)
+	}
+	if inspection.Digest != digest {
+		t.Errorf("digest = %s, want %s", inspection.Digest, digest)
+	}
+	if strings.Join(inspection.Excluded, ",") != "/hosts,/vars/insights_signature" {
+		t.Errorf("excluded = %v", inspection.Excluded)
+	}
+	if inspection.Signature == nil || inspection.Signature.KeyID != "08D82F6981A5FD2F" {
+		t.Errorf("signature = %+v, want key 08D82F6981A5FD2F", inspection.Signature)
+	}
+}
+
+func TestInspectPlaybookUnsigned(t *testing.T) {
+	inspections, err := InspectPlaybook([]byte("- name: unsigned\n"))
+	if err != nil {
+		t.Fatalf("InspectPlaybook() error = %v", err)
+	}
+	if inspections[0].Error == "" || inspections[0].Signature != nil {
+		t.Errorf("InspectPlaybook() = %+v, want error", inspections[0])
+	}
+}
+
+func TestParseSignature(t *testing.T) {
+	for _, name := range []string{"playbook.yml.asc", "playbook.yml.sig"} {
+		t.Run(name, func(t *testing.T) {
+			info, err := ParseSignature(readTestdata(t, filepath.Join("testdata", "detached", name)))
+			if err != nil {
+				t.Fatalf("ParseSignature() error = %v", err)
+			}
+			if info.Scheme != SchemeOpenPGP || info.KeyID != "08D82F6981A5FD2F" || info.Version != 4 || info.Created.IsZero() {
+				t.Errorf("ParseSignature() = %+v", info)
+			}
+			if info.Fingerprint != "F1384BE3CDF0D33E73A1F3F708D82F6981A5FD2F" {
+				t.Errorf("fingerprint = %s, want F1384BE3CDF0D33E73A1F3F708D82F6981A5FD2F", info.Fingerprint)
+			}
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package verifier
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+)
+
+// Keyring is an ordered list of trusted public keys.
+//
+// Multiple keys are trusted at the same time during key rotation, when playbooks
+// may be signed by either the old or the new signing key.
+//
+// A Keyring may be used by concurrent verifications once it is set up; the methods
+// that change it (e.g. Revoke or TrustSigstore) must not be called while it is in use.
+type Keyring struct {
+	entities openpgp.EntityList
+	// ed25519Keys are the trusted minisign and signify keys.
+	ed25519Keys []ed25519Key
+	// sigstore is the trust root of Sigstore bundles, if they are trusted.
+	sigstore *sigstoreTrust
+	// authorities are the certificate authorities of PKCS#7 signatures, if they are trusted.
+	authorities *authorityTrust
+	// openpgpBackend replaces the built-in OpenPGP implementation, if it is set.
+	openpgpBackend OpenPGPBackend
+	// revoked are the fingerprints or key IDs of keys whose signatures are refused.
+	revoked []string
+	// warnOnly are the fingerprints or key IDs of keys whose signatures are accepted with a warning.
+	warnOnly []string
+}
+
+// NewKeyring loads the public keys in the order they were passed in.
+//
+// The keys are either ASCII-armored OpenPGP keys, or minisign or signify public keys.
+func NewKeyring(publicKeys ...[]byte) (*Keyring, error) {
+	keyring := &Keyring{}
+	for i, publicKey := range publicKeys {
+		if isMinisignData(publicKey) {
+			key, err := parseMinisignPublicKey(publicKey)
+			if err != nil {
+				return nil, VerificationError{ErrInvalidKey, fmt.Sprintf("could not load public key #%d", i), err}
+			}
+			keyring.ed25519Keys = append(keyring.ed25519Keys, key)
+			continue
+		}
+		entities, err := openpgp.ReadArmoredKeyRing(bytes.NewReader(publicKey))
+		if err != nil {
+			return nil, VerificationError{ErrInvalidKey, fmt.Sprintf("could not load public key #%d", i), err}
+		}
+		keyring.entities = append(keyring.entities, entities...)
+	}
+
+	if len(keyring.entities) == 0 && len(keyring.ed25519Keys) == 0 {
+		return nil, VerificationError{ErrInvalidKey, "keyring contains no public keys", nil}
+	}
+	return keyring, nil
+}
+
+// KeyIDs returns the IDs of the trusted keys.
+func (k *Keyring) KeyIDs() []string {
+	var ids []string
+	for _, entity := range k.entities {
+		ids = append(ids, entity.PrimaryKey.KeyIdString())
+	}
+	for _, key := range k.ed25519Keys {
+		ids = append(ids, key.keyID())
+	}
+	return ids
+}
+
+// Fingerprints returns the fingerprints of the trusted keys.
+//
+// Ed25519 keys have no fingerprint; their public key is returned instead. The Sigstore
+// trust root is described by the fingerprints of its root certificates, the IDs of its
+// transparency logs and the trusted identities; the certificate authorities by the
+// fingerprints of their certificates.
+func (k *Keyring) Fingerprints() []string {
+	var fingerprints []string
+	for _, entity := range k.entities {
+		fingerprints = append(fingerprints, strings.ToUpper(hex.EncodeToString(entity.PrimaryKey.Fingerprint)))
+	}
+	for _, key := range k.ed25519Keys {
+		fingerprints = append(fingerprints, strings.ToUpper(hex.EncodeToString(key.key)))
+	}
+	if k.sigstore != nil {
+		fingerprints = append(fingerprints, k.sigstore.fingerprints()...)
+	}
+	if k.authorities != nil {
+		fingerprints = append(fingerprints, k.authorities.fingerprints()...)
+	}
+	return fingerprints
+}
+
+// OpenPGPBackend checks the detached OpenPGP signature of the message against the public key,
+// which is serialized in the binary OpenPGP format. It returns nil if the signature is valid.
+type OpenPGPBackend func(publicKey []byte, message []byte, signature []byte) error
+
+// SetOpenPGPBackend makes the keyring check OpenPGP signatures with the backend instead of
+// the built-in implementation, e.g. to run gpgv like the Python verifier does.
+func (k *Keyring) SetOpenPGPBackend(backend OpenPGPBackend) {
+	k.openpgpBackend = backend
+}
+
+// Verify checks the detached signature of the digest against each trusted key in order.
+//
+// The signature scheme is detected from the signature itself. The ID of the key that created
+// the signature is returned. Signatures created by a revoked key are refused.
+func (k *Keyring) Verify(digest []byte, signature []byte) (string, error) {
+	return k.verify(Logger(context.Background()), digest, signature)
+}
+
+// verify is Verify that logs the details of the verification to the logger.
+func (k *Keyring) verify(logger *slog.Logger, digest []byte, signature []byte) (string, error) {
+	return detectScheme(signature).verify(logger, k, digest, signature)
+}
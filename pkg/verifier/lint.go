@@ -0,0 +1,61 @@
+package verifier
+
+import (
+	"fmt"
+
+	yaml3 "gopkg.in/yaml.v3"
+)
+
+// allowedTags are the tags a playbook may set explicitly.
+//
+// Other tags, e.g. '!!set', '!!python/object' or '!vault', are constructed differently
+// (or not at all) by the Go and the Python YAML parsers, so a play using them could be
+// hashed one way and executed another.
+var allowedTags = map[string]any{
+	"!!str":   nil,
+	"!!int":   nil,
+	"!!float": nil,
+	"!!bool":  nil,
+	"!!null":  nil,
+	"!!map":   nil,
+	"!!seq":   nil,
+	// binary data is decoded by constructScalar
+	"!!binary": nil,
+}
+
+// lintDocument refuses YAML features the Go and the Python parsers may interpret
+// differently: explicit tags that are not allowedTags and, unless allowed, duplicate keys.
+//
+// Duplicate keys that are allowed are resolved by canonicalize the way Python does it.
+func lintDocument(document *yaml3.Node, allowDuplicateKeys bool) error {
+	if document.Style&yaml3.TaggedStyle != 0 {
+		if _, ok := allowedTags[document.ShortTag()]; !ok {
+			return PlaybookError{ErrAmbiguousYAML, fmt.Sprintf("tag '%s' at line %d is not allowed", document.Tag, document.Line), nil}
+		}
+	}
+
+	if document.Kind == yaml3.MappingNode && !allowDuplicateKeys {
+		keys := map[string]int{}
+		for i := 0; i+1 < len(document.Content); i += 2 {
+			key := document.Content[i]
+			if isMergeKey(key) {
+				continue
+			}
+			id, ok := keyIdentity(key)
+			if !ok {
+				continue
+			}
+			if line, ok := keys[id]; ok {
+				return PlaybookError{ErrAmbiguousYAML, fmt.Sprintf("key '%s' at line %d duplicates the key at line %d", key.Value, key.Line, line), nil}
+			}
+			keys[id] = key.Line
+		}
+	}
+
+	for _, child := range document.Content {
+		if err := lintDocument(child, allowDuplicateKeys); err != nil {
+			return err
+		}
+	}
+	return nil
+}
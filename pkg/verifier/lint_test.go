@@ -0,0 +1,74 @@
+package verifier
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestLintDocument(t *testing.T) {
+	tests := []struct {
+		name    string
+		play    string
+		wantErr bool
+	}{
+		{"plain", "- name: play\n  vars: {a: 1, b: '1'}\n", false},
+		{"allowed tags", "- name: !!str play\n  vars: !!map {a: !!int '1'}\n", false},
+		{"merge keys", "- a: &x {k: 1}\n  b: {<<: *x, <<: *x, k: 2}\n", false},
+		{"binary tag", "- name: play\n  vars: {a: !!binary aGVsbG8=}\n", false},
+		{"python tag", "- name: !!python/object/apply:os.system [id]\n", true},
+		{"custom tag", "- name: play\n  vars: {a: !vault secret}\n", true},
+		{"tagged document", "!!set\n? play\n", true},
+		{"duplicate key", "- name: play\n  hosts: all\n  name: other\n", true},
+		{"duplicate nested key", "- tasks:\n    - shell: id\n      shell: whoami\n", true},
+		{"duplicate boolean key", "- vars: {yes: 1, true: 2}\n", true},
+		{"duplicate number key", "- vars: {1: a, 1.0: b}\n", true},
+		{"string and number key", "- vars: {1: a, '1': b}\n", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := UnmarshalPlaybook([]byte(tt.play))
+			if (err != nil) != tt.wantErr || (err != nil && !errors.Is(err, ErrAmbiguousYAML)) {
+				t.Errorf("UnmarshalPlaybook() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// Allowed duplicate keys are resolved like a Python dictionary: the first key
+// keeps its position and the last value wins.
+func TestPlaybookDecoderAllowDuplicateKeys(t *testing.T) {
+	tests := []struct {
+		play string
+		want string
+	}{
+		{"- a: 1\n  b: 2\n  a: 3\n", "ordereddict([('a', 3), ('b', 2)])"},
+		{"- yes: 1\n  true: 2\n  1: 3\n", "ordereddict([(True, 3)])"},
+		{"- 1: a\n  1.0: b\n  '1': c\n", "ordereddict([(1, 'b'), ('1', 'c')])"},
+	}
+	for _, tt := range tests {
+		decoder := NewPlaybookDecoder(bytes.NewReader([]byte(tt.play)))
+		decoder.AllowDuplicateKeys = true
+		plays, err := decoder.Decode()
+		if err != nil {
+			t.Fatalf("Decode() error = %v", err)
+		}
+		serialized, err := MarshallPlaybook(plays[0])
+		if err != nil {
+			t.Fatalf("MarshallPlaybook() error = %v", err)
+		}
+		if string(serialized) != tt.want {
+			t.Errorf("MarshallPlaybook() = %s, want %s", serialized, tt.want)
+		}
+	}
+}
+
+func TestVerifyPlaybookDuplicateKeys(t *testing.T) {
+	playbook := []byte("- name: play\n  name: other\n  vars:\n    insights_signature: c2ln\n    insights_signature_exclude: /vars/insights_signature\n")
+	if _, err := VerifyPlaybook(playbook, testKeyring(t), Policy{}); !errors.Is(err, ErrAmbiguousYAML) {
+		t.Errorf("VerifyPlaybook() error = %v, want %v", err, ErrAmbiguousYAML)
+	}
+	if _, err := VerifyPlaybook(playbook, testKeyring(t), Policy{AllowDuplicateKeys: true}); errors.Is(err, ErrAmbiguousYAML) {
+		t.Errorf("VerifyPlaybook() error = %v, want other than %v", err, ErrAmbiguousYAML)
+	}
+}
@@ -0,0 +1,26 @@
+package verifier
+
+import (
+	"context"
+	"log/slog"
+)
+
+// loggerKey is the key of the logger of a Verifier in the context of its verifications.
+type loggerKey struct{}
+
+// contextWithLogger returns the context of a verification that logs to the logger.
+func contextWithLogger(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, logger)
+}
+
+// Logger returns the logger of the verification the context belongs to: the logger
+// of the Verifier, or the default logger of slog outside of a Verifier.
+//
+// Handlers registered by RegisterContextHandler log through it, so their messages
+// end up where those of the Verifier do.
+func Logger(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(loggerKey{}).(*slog.Logger); ok {
+		return logger
+	}
+	return slog.Default()
+}
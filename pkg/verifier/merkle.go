@@ -0,0 +1,109 @@
+package verifier
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+
+	"com.github/m-horky/playbook-verifier/internal/hygiene"
+)
+
+// Merkle tree hashing of transparency logs, as defined by RFC 6962.
+
+// merkleLeafHash returns the hash of the leaf of the tree.
+func merkleLeafHash(leaf []byte) []byte {
+	hash := sha256.Sum256(append([]byte{0x00}, leaf...))
+	return hash[:]
+}
+
+// merkleNodeHash returns the hash of the inner node of the tree.
+func merkleNodeHash(left, right []byte) []byte {
+	hash := sha256.New()
+	hash.Write([]byte{0x01})
+	hash.Write(left)
+	hash.Write(right)
+	return hash.Sum(nil)
+}
+
+// verifyInclusion checks that the leaf is at the index of the tree of the size with the root hash.
+//
+// The algorithm is the one of RFC 9162, section 2.1.3.2.
+func verifyInclusion(index, size int64, leafHash []byte, proof [][]byte, rootHash []byte) error {
+	if index < 0 || index >= size {
+		return fmt.Errorf("index %d is outside of the tree of size %d", index, size)
+	}
+	fn, sn := index, size-1
+	hash := leafHash
+	for _, sibling := range proof {
+		if sn == 0 {
+			return fmt.Errorf("inclusion proof is too long")
+		}
+		if fn&1 == 1 || fn == sn {
+			hash = merkleNodeHash(sibling, hash)
+			for fn&1 == 0 && fn != 0 {
+				fn >>= 1
+				sn >>= 1
+			}
+		} else {
+			hash = merkleNodeHash(hash, sibling)
+		}
+		fn >>= 1
+		sn >>= 1
+	}
+	if sn != 0 {
+		return fmt.Errorf("inclusion proof is too short")
+	}
+	if !hygiene.Equal(hash, rootHash) {
+		return fmt.Errorf("inclusion proof does not lead to the root hash")
+	}
+	return nil
+}
+
+// checkpoint is the signed tree head of a transparency log, in the signed note format.
+type checkpoint struct {
+	origin   string
+	size     int64
+	rootHash []byte
+	// text is the signed part of the note.
+	text []byte
+	// signatures are the decoded signatures of the note, including their key hints.
+	signatures [][]byte
+}
+
+// parseCheckpoint decodes the signed note of the checkpoint.
+func parseCheckpoint(envelope string) (*checkpoint, error) {
+	text, signatureBlock, ok := bytes.Cut([]byte(envelope), []byte("\n\n"))
+	if !ok {
+		return nil, fmt.Errorf("checkpoint is not a signed note")
+	}
+	text = append(text, '\n')
+
+	lines := bytes.Split(text, []byte("\n"))
+	if len(lines) < 4 {
+		return nil, fmt.Errorf("checkpoint is too short")
+	}
+	c := &checkpoint{origin: string(lines[0]), text: text}
+	if _, err := fmt.Sscanf(string(lines[1]), "%d", &c.size); err != nil {
+		return nil, fmt.Errorf("checkpoint has invalid tree size: %w", err)
+	}
+	rootHash, err := base64.StdEncoding.DecodeString(string(lines[2]))
+	if err != nil {
+		return nil, fmt.Errorf("checkpoint has invalid root hash: %w", err)
+	}
+	c.rootHash = rootHash
+
+	for _, line := range bytes.Split(bytes.TrimRight(signatureBlock, "\n"), []byte("\n")) {
+		// "— <name> <base64 of key hint and signature>"
+		fields := bytes.Fields(line)
+		if len(fields) != 3 || string(fields[0]) != "—" {
+			return nil, fmt.Errorf("checkpoint has malformed signature line")
+		}
+		signature, err := base64.StdEncoding.DecodeString(string(fields[2]))
+		if err != nil || len(signature) < 5 {
+			return nil, fmt.Errorf("checkpoint has malformed signature")
+		}
+		c.signatures = append(c.signatures, signature)
+	}
+	return c, nil
+}
@@ -0,0 +1,81 @@
+package verifier
+
+import (
+	"fmt"
+	"testing"
+)
+
+// merkleRoot and merklePath are the reference definitions of RFC 6962, section 2.1.
+func merkleRoot(leaves [][]byte) []byte {
+	if len(leaves) == 1 {
+		return merkleLeafHash(leaves[0])
+	}
+	k := merkleSplit(len(leaves))
+	return merkleNodeHash(merkleRoot(leaves[:k]), merkleRoot(leaves[k:]))
+}
+
+func merklePath(m int, leaves [][]byte) [][]byte {
+	if len(leaves) == 1 {
+		return nil
+	}
+	k := merkleSplit(len(leaves))
+	if m < k {
+		return append(merklePath(m, leaves[:k]), merkleRoot(leaves[k:]))
+	}
+	return append(merklePath(m-k, leaves[k:]), merkleRoot(leaves[:k]))
+}
+
+// merkleSplit returns the largest power of two smaller than n.
+func merkleSplit(n int) int {
+	k := 1
+	for k<<1 < n {
+		k <<= 1
+	}
+	return k
+}
+
+func TestVerifyInclusion(t *testing.T) {
+	for size := 1; size <= 9; size++ {
+		var leaves [][]byte
+		for i := range size {
+			leaves = append(leaves, []byte(fmt.Sprintf("leaf %d", i)))
+		}
+		root := merkleRoot(leaves)
+
+		for index := range size {
+			proof := merklePath(index, leaves)
+			if err := verifyInclusion(int64(index), int64(size), merkleLeafHash(leaves[index]), proof, root); err != nil {
+				t.Errorf("size %d, index %d: verifyInclusion() error = %v", size, index, err)
+			}
+			if err := verifyInclusion(int64(index), int64(size), merkleLeafHash([]byte("other")), proof, root); err == nil {
+				t.Errorf("size %d, index %d: verifyInclusion() accepted other leaf", size, index)
+			}
+			if size > 1 {
+				if err := verifyInclusion(int64((index+1)%size), int64(size), merkleLeafHash(leaves[index]), proof, root); err == nil {
+					t.Errorf("size %d, index %d: verifyInclusion() accepted wrong index", size, index)
+				}
+				if err := verifyInclusion(int64(index), int64(size), merkleLeafHash(leaves[index]), proof[1:], root); err == nil {
+					t.Errorf("size %d, index %d: verifyInclusion() accepted short proof", size, index)
+				}
+			}
+		}
+	}
+}
+
+func TestParseCheckpoint(t *testing.T) {
+	envelope := "rekor.sigstore.dev - 1193050959916656506\n42\nAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA=\n\n— rekor.sigstore.dev wNI9ajBFAiEA\n"
+	c, err := parseCheckpoint(envelope)
+	if err != nil {
+		t.Fatalf("parseCheckpoint() error = %v", err)
+	}
+	if c.origin != "rekor.sigstore.dev - 1193050959916656506" || c.size != 42 || len(c.rootHash) != 32 || len(c.signatures) != 1 {
+		t.Errorf("parseCheckpoint() = %+v", c)
+	}
+	if string(c.text) != "rekor.sigstore.dev - 1193050959916656506\n42\nAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA=\n" {
+		t.Errorf("parseCheckpoint() text = %q", c.text)
+	}
+
+	if _, err = parseCheckpoint("rekor.sigstore.dev\n42\n"); err == nil {
+		t.Error("parseCheckpoint() accepted unsigned checkpoint")
+	}
+}
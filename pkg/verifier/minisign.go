@@ -0,0 +1,141 @@
+package verifier
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+// Public keys and signatures in the text format of minisign and signify.
+//
+// Both consist of an untrusted comment line and a base64-encoded line holding
+// the algorithm, the key ID and the key or signature itself. Minisign signatures
+// additionally carry a trusted comment, which is signed together with the signature.
+
+const (
+	minisignUntrustedComment = "untrusted comment: "
+	minisignTrustedComment   = "trusted comment: "
+)
+
+// Minisign signature algorithms: Ed25519 of the message itself, and of its BLAKE2b-512 digest.
+var (
+	minisignAlgorithmPure   = [2]byte{'E', 'd'}
+	minisignAlgorithmHashed = [2]byte{'E', 'D'}
+)
+
+// ed25519Key is a trusted minisign or signify public key.
+type ed25519Key struct {
+	id  [8]byte
+	key ed25519.PublicKey
+}
+
+// keyID returns the ID of the key the way minisign prints it.
+func (k ed25519Key) keyID() string {
+	return fmt.Sprintf("%016X", binary.LittleEndian.Uint64(k.id[:]))
+}
+
+// minisignSignature is a decoded minisign or signify signature.
+type minisignSignature struct {
+	algorithm [2]byte
+	keyID     [8]byte
+	signature []byte
+	// trusted reports whether the signature has a trusted comment, i.e. it is a minisign signature.
+	trusted         bool
+	trustedComment  string
+	globalSignature []byte
+}
+
+// isMinisignData reports whether the key or signature is in the format of minisign or signify.
+func isMinisignData(data []byte) bool {
+	return bytes.HasPrefix(data, []byte(minisignUntrustedComment))
+}
+
+// minisignLines returns the lines of the data, without the trailing empty ones.
+func minisignLines(data []byte) []string {
+	return strings.Split(strings.TrimRight(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n"), "\n")
+}
+
+// parseMinisignPublicKey decodes a minisign or signify public key.
+func parseMinisignPublicKey(data []byte) (ed25519Key, error) {
+	lines := minisignLines(data)
+	if len(lines) != 2 || !strings.HasPrefix(lines[0], minisignUntrustedComment) {
+		return ed25519Key{}, VerificationError{ErrInvalidKey, "public key must consist of a comment and the key", nil}
+	}
+	raw, err := base64.StdEncoding.DecodeString(lines[1])
+	if err != nil {
+		return ed25519Key{}, VerificationError{ErrInvalidKey, "public key is not valid base64", err}
+	}
+	if len(raw) != 2+8+ed25519.PublicKeySize || [2]byte(raw[:2]) != minisignAlgorithmPure {
+		return ed25519Key{}, VerificationError{ErrInvalidKey, "public key is not an Ed25519 key", nil}
+	}
+	return ed25519Key{id: [8]byte(raw[2:10]), key: ed25519.PublicKey(raw[10:])}, nil
+}
+
+// parseMinisignSignature decodes a minisign or signify signature.
+func parseMinisignSignature(data []byte) (*minisignSignature, error) {
+	lines := minisignLines(data)
+	if (len(lines) != 2 && len(lines) != 4) || !strings.HasPrefix(lines[0], minisignUntrustedComment) {
+		return nil, VerificationError{ErrMalformedSignature, "signature must consist of a comment and the signature", nil}
+	}
+	raw, err := base64.StdEncoding.DecodeString(lines[1])
+	if err != nil {
+		return nil, VerificationError{ErrMalformedSignature, "signature is not valid base64", err}
+	}
+	if len(raw) != 2+8+ed25519.SignatureSize {
+		return nil, VerificationError{ErrMalformedSignature, "signature has invalid length", nil}
+	}
+	sig := &minisignSignature{algorithm: [2]byte(raw[:2]), keyID: [8]byte(raw[2:10]), signature: raw[10:]}
+	if sig.algorithm != minisignAlgorithmPure && sig.algorithm != minisignAlgorithmHashed {
+		return nil, VerificationError{ErrMalformedSignature, fmt.Sprintf("unsupported signature algorithm '%s'", sig.algorithm[:]), nil}
+	}
+
+	if len(lines) == 2 {
+		if sig.algorithm == minisignAlgorithmHashed {
+			return nil, VerificationError{ErrMalformedSignature, "prehashed signature has no trusted comment", nil}
+		}
+		return sig, nil
+	}
+	comment, ok := strings.CutPrefix(lines[2], minisignTrustedComment)
+	if !ok {
+		return nil, VerificationError{ErrMalformedSignature, "signature has no trusted comment", nil}
+	}
+	global, err := base64.StdEncoding.DecodeString(lines[3])
+	if err != nil || len(global) != ed25519.SignatureSize {
+		return nil, VerificationError{ErrMalformedSignature, "signature of the trusted comment is malformed", err}
+	}
+	sig.trusted, sig.trustedComment, sig.globalSignature = true, comment, global
+	return sig, nil
+}
+
+// scheme returns the name of the scheme the signature has been created with.
+func (s *minisignSignature) scheme() string {
+	if s.trusted {
+		return SchemeMinisign
+	}
+	return SchemeSignify
+}
+
+// keyIDString returns the ID of the key that claims to have created the signature.
+func (s *minisignSignature) keyIDString() string {
+	return ed25519Key{id: s.keyID}.keyID()
+}
+
+// verify checks the signature of the message and of the trusted comment.
+func (s *minisignSignature) verify(key ed25519Key, message []byte) error {
+	if s.algorithm == minisignAlgorithmHashed {
+		digest := blake2b.Sum512(message)
+		message = digest[:]
+	}
+	if !ed25519.Verify(key.key, message, s.signature) {
+		return VerificationError{ErrDigestMismatch, "signature does not match", nil}
+	}
+	if s.trusted && !ed25519.Verify(key.key, append(bytes.Clone(s.signature), s.trustedComment...), s.globalSignature) {
+		return VerificationError{ErrDigestMismatch, "signature of the trusted comment does not match", nil}
+	}
+	return nil
+}
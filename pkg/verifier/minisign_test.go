@@ -0,0 +1,105 @@
+package verifier
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func minisignKeyring(t *testing.T) *Keyring {
+	t.Helper()
+	keyring, err := NewKeyring(
+		readTestdata(t, filepath.Join("testdata", "keys", "test-public.asc")),
+		readTestdata(t, filepath.Join("testdata", "keys", "test-minisign.pub")),
+	)
+	if err != nil {
+		t.Fatalf("NewKeyring() error = %v", err)
+	}
+	return keyring
+}
+
+// The message, its signature and the public key come from the test suite of aead.dev/minisign,
+// so the format is checked against an independent implementation.
+func TestMinisignInterop(t *testing.T) {
+	keyring, err := NewKeyring(readTestdata(t, filepath.Join("testdata", "minisign", "minisign.pub")))
+	if err != nil {
+		t.Fatalf("NewKeyring() error = %v", err)
+	}
+	message := readTestdata(t, filepath.Join("testdata", "minisign", "message.txt"))
+	signature := readTestdata(t, filepath.Join("testdata", "minisign", "message.txt.minisig"))
+
+	keyID, err := keyring.Verify(message, signature)
+	if err != nil || keyID != "C373193807678450" {
+		t.Errorf("Verify() = %s, %v, want C373193807678450", keyID, err)
+	}
+
+	tampered := bytes.Replace(signature, []byte("file:message.txt"), []byte("file:other.txt"), 1)
+	if _, err = keyring.Verify(message, tampered); !errors.Is(err, ErrDigestMismatch) {
+		t.Errorf("Verify() with tampered trusted comment error = %v, want %v", err, ErrDigestMismatch)
+	}
+	if _, err = keyring.Verify([]byte("Hello World?\n"), signature); !errors.Is(err, ErrDigestMismatch) {
+		t.Errorf("Verify() of other message error = %v, want %v", err, ErrDigestMismatch)
+	}
+}
+
+func TestVerifyPlaybookMinisign(t *testing.T) {
+	playbook := readTestdata(t, filepath.Join("testdata", "minisign", "playbook.yml"))
+
+	report, err := VerifyPlaybook(playbook, minisignKeyring(t), Policy{})
+	if err != nil {
+		t.Fatalf("VerifyPlaybook() error = %v", err)
+	}
+	for _, play := range report.Plays {
+		if play.KeyID != "B86D14907EC2513A" {
+			t.Errorf("play %d: key ID = %s, want B86D14907EC2513A", play.Index, play.KeyID)
+		}
+	}
+
+	// OpenPGP playbooks are still verified by the same keyring.
+	if _, err = VerifyPlaybook(readTestdata(t, filepath.Join("testdata", "golden", "cve-update.yml")), minisignKeyring(t), Policy{}); err != nil {
+		t.Errorf("VerifyPlaybook() of OpenPGP playbook error = %v", err)
+	}
+
+	// The key is not trusted by the OpenPGP-only keyring.
+	if _, err = VerifyPlaybook(playbook, testKeyring(t), Policy{}); !errors.Is(err, ErrDigestMismatch) {
+		t.Errorf("VerifyPlaybook() with unknown key error = %v, want %v", err, ErrDigestMismatch)
+	}
+
+	keyring := minisignKeyring(t)
+	if err = keyring.Revoke("B86D14907EC2513A"); err != nil {
+		t.Fatalf("Revoke() error = %v", err)
+	}
+	if _, err = VerifyPlaybook(playbook, keyring, Policy{}); !errors.Is(err, ErrRevokedKey) {
+		t.Errorf("VerifyPlaybook() with revoked key error = %v, want %v", err, ErrRevokedKey)
+	}
+}
+
+func TestParseMinisignSignature(t *testing.T) {
+	signature := readTestdata(t, filepath.Join("testdata", "minisign", "message.txt.minisig"))
+	lines := bytes.Split(signature, []byte("\n"))
+
+	tests := []struct {
+		name      string
+		signature []byte
+		scheme    string
+		want      error
+	}{
+		{"minisign", signature, SchemeMinisign, nil},
+		{"without trusted comment", bytes.Join(lines[:2], []byte("\n")), SchemeSignify, nil},
+		{"without signature", lines[0], "", ErrMalformedSignature},
+		{"invalid base64", bytes.Join([][]byte{lines[0], []byte("not base64")}, []byte("\n")), "", ErrMalformedSignature},
+		{"missing trusted comment prefix", bytes.Replace(signature, []byte("trusted comment: "), nil, 1), "", ErrMalformedSignature},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, err := ParseSignature(tt.signature)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("ParseSignature() error = %v, want %v", err, tt.want)
+			}
+			if err == nil && (info.Scheme != tt.scheme || info.KeyID != "C373193807678450") {
+				t.Errorf("ParseSignature() = %+v", info)
+			}
+		})
+	}
+}
@@ -0,0 +1,137 @@
+package verifier
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// byteOrderMark is the UTF-8 encoding of U+FEFF, which some editors put at the beginning of files.
+var byteOrderMark = []byte("\xef\xbb\xbf")
+
+// NormalizePayload removes the artifacts that transport and editors commonly introduce
+// into payloads: a leading UTF-8 byte order mark, CRLF line endings and whitespace
+// at the end of lines.
+//
+// The content is signed without them, so a payload that only differs by them verifies
+// once normalized. Trailing whitespace is significant in YAML block scalars, so the
+// normalization is only applied when the policy asks for it.
+func NormalizePayload(payload []byte) []byte {
+	normalized, _ := io.ReadAll(newNormalizingReader(bytes.NewReader(payload)))
+	return normalized
+}
+
+// normalizingReader normalizes the payload it reads line by line, see NormalizePayload.
+type normalizingReader struct {
+	r       *bufio.Reader
+	started bool
+	pending []byte
+	err     error
+}
+
+func newNormalizingReader(r io.Reader) *normalizingReader {
+	return &normalizingReader{r: bufio.NewReader(r)}
+}
+
+func (n *normalizingReader) Read(p []byte) (int, error) {
+	for len(n.pending) == 0 {
+		if n.err != nil {
+			return 0, n.err
+		}
+		line, err := n.r.ReadBytes('\n')
+		n.err = err
+		if !n.started {
+			n.started = true
+			line = bytes.TrimPrefix(line, byteOrderMark)
+		}
+		if newline := bytes.HasSuffix(line, []byte("\n")); newline {
+			line = append(bytes.TrimRight(line, " \t\r\n"), '\n')
+		} else {
+			line = bytes.TrimRight(line, " \t\r")
+		}
+		n.pending = line
+	}
+	copied := copy(p, n.pending)
+	n.pending = n.pending[copied:]
+	return copied, nil
+}
+
+// artifactDetector records which transport artifacts the payload written to it contains,
+// see NormalizePayload.
+type artifactDetector struct {
+	// head are the first bytes of the payload, enough to recognize the byte order mark.
+	head []byte
+	// previous are the last two bytes written.
+	previous           [2]byte
+	byteOrderMark      bool
+	crlf               bool
+	trailingWhitespace bool
+}
+
+func (d *artifactDetector) Write(p []byte) (int, error) {
+	if len(d.head) < len(byteOrderMark) {
+		d.head = append(d.head, p[:min(len(p), len(byteOrderMark)-len(d.head))]...)
+		d.byteOrderMark = bytes.Equal(d.head, byteOrderMark)
+	}
+	for _, b := range p {
+		if b == '\n' {
+			last := d.previous[1]
+			if last == '\r' {
+				d.crlf = true
+				last = d.previous[0]
+			}
+			if last == ' ' || last == '\t' {
+				d.trailingWhitespace = true
+			}
+		}
+		d.previous[0], d.previous[1] = d.previous[1], b
+	}
+	return len(p), nil
+}
+
+// artifacts describes the artifacts found in the payload.
+func (d *artifactDetector) artifacts() []string {
+	var artifacts []string
+	if d.byteOrderMark {
+		artifacts = append(artifacts, "a UTF-8 byte order mark")
+	}
+	if d.crlf {
+		artifacts = append(artifacts, "CRLF line endings")
+	}
+	if d.trailingWhitespace {
+		artifacts = append(artifacts, "trailing whitespace")
+	}
+	return artifacts
+}
+
+// yamlArtifacts describes the artifacts found in the payload that change the content of YAML.
+//
+// YAML parsers skip the byte order mark and convert line endings themselves, but keep
+// trailing whitespace of block scalars.
+func (d *artifactDetector) yamlArtifacts() []string {
+	if d.trailingWhitespace {
+		return []string{"trailing whitespace"}
+	}
+	return nil
+}
+
+// payloadArtifacts describes the transport artifacts the payload contains.
+func payloadArtifacts(payload []byte) []string {
+	var d artifactDetector
+	_, _ = d.Write(payload)
+	return d.artifacts()
+}
+
+// withArtifactsHint explains a signature mismatch of a payload with transport artifacts,
+// which are a much more likely cause of it than tampering.
+func withArtifactsHint(err error, artifacts []string) error {
+	if len(artifacts) == 0 || !errors.Is(err, ErrDigestMismatch) {
+		return err
+	}
+	message := fmt.Sprintf("payload contains %s, which may have been introduced in transport; try normalizing the payload",
+		strings.Join(artifacts, " and "))
+	return VerificationError{ErrDigestMismatch, message, err}
+}
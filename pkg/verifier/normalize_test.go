@@ -0,0 +1,69 @@
+package verifier
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestNormalizePayload(t *testing.T) {
+	tests := []struct {
+		name      string
+		payload   string
+		want      string
+		artifacts []string
+	}{
+		{"clean", "a: 1\nb: |\n  text\n", "a: 1\nb: |\n  text\n", nil},
+		{"byte order mark", "\xef\xbb\xbfa: 1\n", "a: 1\n", []string{"a UTF-8 byte order mark"}},
+		{"CRLF", "a: 1\r\nb: 2\r\n", "a: 1\nb: 2\n", []string{"CRLF line endings"}},
+		{"trailing whitespace", "a: 1 \t\nb: |\n  text  \r\n", "a: 1\nb: |\n  text\n", []string{"CRLF line endings", "trailing whitespace"}},
+		{"no final newline", "a: 1  ", "a: 1", nil},
+		{"byte order mark in the middle", "a: 1\n\xef\xbb\xbf", "a: 1\n\xef\xbb\xbf", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NormalizePayload([]byte(tt.payload)); string(got) != tt.want {
+				t.Errorf("NormalizePayload() = %q, want %q", got, tt.want)
+			}
+			if got := payloadArtifacts([]byte(tt.payload)); !slices.Equal(got, tt.artifacts) {
+				t.Errorf("payloadArtifacts() = %v, want %v", got, tt.artifacts)
+			}
+		})
+	}
+}
+
+func TestVerifyPlaybookNormalizePayload(t *testing.T) {
+	playbook := "- name: play\n  vars:\n    insights_signature_exclude: /vars/insights_signature\n  tasks:\n" +
+		"    - shell: |\n        echo signed\n"
+	signed, err := SignPlaybook([]byte(playbook), testSigner(t), Policy{})
+	if err != nil {
+		t.Fatalf("SignPlaybook() error = %v", err)
+	}
+	// the editor left whitespace after the command, which is a part of the block scalar
+	transported := bytes.Replace(signed, []byte("echo signed\n"), []byte("echo signed  \r\n"), 1)
+
+	_, err = VerifyPlaybook(transported, testKeyring(t), Policy{})
+	if !errors.Is(err, ErrDigestMismatch) || !strings.Contains(err.Error(), "trailing whitespace") {
+		t.Errorf("VerifyPlaybook() error = %v, want %v with a hint", err, ErrDigestMismatch)
+	}
+	if _, err = VerifyPlaybook(transported, testKeyring(t), Policy{NormalizePayload: true}); err != nil {
+		t.Errorf("VerifyPlaybook() of normalized payload error = %v", err)
+	}
+}
+
+func TestVerifyDetachedNormalizePayload(t *testing.T) {
+	playbook := readTestdata(t, filepath.Join("testdata", "detached", "playbook.yml"))
+	signature := readTestdata(t, filepath.Join("testdata", "detached", "playbook.yml.asc"))
+	transported := append(bytes.Clone(byteOrderMark), playbook...)
+
+	_, err := VerifyDetachedPolicy(transported, signature, testKeyring(t), Policy{})
+	if !errors.Is(err, ErrDigestMismatch) || !strings.Contains(err.Error(), "byte order mark") {
+		t.Errorf("VerifyDetachedPolicy() error = %v, want %v with a hint", err, ErrDigestMismatch)
+	}
+	if _, err = VerifyDetachedPolicy(transported, signature, testKeyring(t), Policy{NormalizePayload: true}); err != nil {
+		t.Errorf("VerifyDetachedPolicy() of normalized payload error = %v", err)
+	}
+}
@@ -0,0 +1,245 @@
+package verifier
+
+import (
+	"context"
+	"log/slog"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Stages of the verification. They are named like their spans.
+const (
+	// StageVerify is the verification of a payload of any content type.
+	StageVerify = "verify"
+	// StagePlaybook is the verification of a playbook.
+	StagePlaybook = "verify playbook"
+	// StageParse is the parsing of a YAML document of the playbook.
+	StageParse = "parse"
+	// StagePlay is the verification of a play.
+	StagePlay = "verify play"
+	// StageClean is the removal of the elements of the play excluded from the hash.
+	StageClean = "clean"
+	// StageSerialize is the serialization of the cleaned play in a serialization profile.
+	StageSerialize = "serialize"
+	// StageHash is the hashing of the serialized play.
+	StageHash = "hash"
+	// StageSignature is the verification of the signature of the digest.
+	StageSignature = "gpg verify"
+)
+
+// Observer is notified of the progress of verifications, so applications embedding the verifier
+// (e.g. a web console) can show it without parsing the logs.
+//
+// The methods are called by the goroutine that verifies the play; when the policy verifies
+// plays in parallel (see Policy.Jobs), they are called concurrently and have to be safe for it.
+// They should return quickly, since the verification waits for them.
+type Observer interface {
+	// OnStageStart is called when a stage of the verification starts.
+	OnStageStart(ctx context.Context, stage Stage)
+	// OnStageEnd is called when the stage ends, with the error the stage failed with.
+	OnStageEnd(ctx context.Context, stage Stage, err error)
+	// OnExclusion is called for every element of a play that is excluded from the hash.
+	OnExclusion(ctx context.Context, exclusion Exclusion)
+	// OnVerdict is called when a play is decided, and when the whole payload is decided
+	// by Verify, Verifier.VerifyPayload or VerifyContext.
+	OnVerdict(ctx context.Context, verdict Verdict)
+}
+
+// Stage is a stage of the verification.
+type Stage struct {
+	// Name is the name of the stage, e.g. StageSerialize.
+	Name string
+	// Document is the index of the YAML document, or -1 for stages of the whole payload.
+	Document int
+	// Play is the index of the play in the playbook, or -1 for stages that are not of a play.
+	Play int
+}
+
+// Exclusion is an element of a play excluded from the hash.
+type Exclusion struct {
+	ExcludedPath
+	// Document is the index of the YAML document of the play, or -1 if it is not known.
+	Document int
+	// Play is the index of the play in the playbook, or -1 if it is not known.
+	Play int
+}
+
+// Verdict is the outcome of the verification of a play or of the whole payload.
+type Verdict struct {
+	// Document is the index of the YAML document of the play, or -1 for the whole payload.
+	Document int
+	// Play is the index of the play in the playbook, or -1 for the whole payload.
+	Play int
+	// Name is the name of the play.
+	Name     string
+	Verified bool
+	// Unsigned is set when the play has no signature, but is accepted without being verified,
+	// see Policy.Unsigned.
+	Unsigned bool
+	// KeyID is the ID of the key that created the signature of a verified play.
+	KeyID string
+	// Err is the reason why the play or the payload has not been verified.
+	Err error
+}
+
+// WithObserver sets the observer of the verification. The default is LogObserver.
+func WithObserver(observer Observer) Option {
+	return func(o *options) {
+		o.observer = observer
+	}
+}
+
+// LogObserver is the Observer of verifications that have none: it logs their progress
+// to the logger of the verification, see Logger.
+type LogObserver struct{}
+
+func (LogObserver) OnStageStart(ctx context.Context, stage Stage) {
+	Logger(ctx).Debug("stage started", stageAttributes(stage)...)
+}
+
+func (LogObserver) OnStageEnd(ctx context.Context, stage Stage, err error) {
+	if err != nil {
+		Logger(ctx).Debug("stage failed", append(stageAttributes(stage), slog.Any("error", err))...)
+		return
+	}
+	Logger(ctx).Debug("stage finished", stageAttributes(stage)...)
+}
+
+func (LogObserver) OnExclusion(ctx context.Context, exclusion Exclusion) {
+	Logger(ctx).Info("excluding", slog.String("path", exclusion.Path))
+}
+
+func (LogObserver) OnVerdict(ctx context.Context, verdict Verdict) {
+	switch {
+	case verdict.Play < 0:
+		Logger(ctx).Debug("payload decided", slog.Bool("verified", verdict.Verified))
+	case verdict.Err != nil:
+		// the caller of the verification logs the error it returns
+		Logger(ctx).Info("play not verified", slog.Int("play", verdict.Play), slog.Any("error", verdict.Err))
+	case verdict.Unsigned:
+		// the verification has logged it according to its policy
+	default:
+		Logger(ctx).Info("play verified", slog.Int("play", verdict.Play), slog.String("key", verdict.KeyID))
+	}
+}
+
+// stageAttributes returns the attributes of the stage's log messages.
+func stageAttributes(stage Stage) []any {
+	attributes := []any{slog.String("stage", stage.Name)}
+	if stage.Document >= 0 {
+		attributes = append(attributes, slog.Int("document", stage.Document))
+	}
+	if stage.Play >= 0 {
+		attributes = append(attributes, slog.Int("play", stage.Play))
+	}
+	return attributes
+}
+
+// JoinObservers returns the observer that notifies each of the observers in turn,
+// e.g. a LogObserver and an observer that shows the progress to the user.
+func JoinObservers(observers ...Observer) Observer {
+	return joinedObservers(observers)
+}
+
+// joinedObservers implements JoinObservers.
+type joinedObservers []Observer
+
+func (o joinedObservers) OnStageStart(ctx context.Context, stage Stage) {
+	for _, observer := range o {
+		observer.OnStageStart(ctx, stage)
+	}
+}
+
+func (o joinedObservers) OnStageEnd(ctx context.Context, stage Stage, err error) {
+	for _, observer := range o {
+		observer.OnStageEnd(ctx, stage, err)
+	}
+}
+
+func (o joinedObservers) OnExclusion(ctx context.Context, exclusion Exclusion) {
+	for _, observer := range o {
+		observer.OnExclusion(ctx, exclusion)
+	}
+}
+
+func (o joinedObservers) OnVerdict(ctx context.Context, verdict Verdict) {
+	for _, observer := range o {
+		observer.OnVerdict(ctx, verdict)
+	}
+}
+
+// observerKey is the key of the observer of a Verifier in the context of its verifications.
+type observerKey struct{}
+
+// positionKey is the key of the position of the document and play in the context of their verification.
+type positionKey struct{}
+
+// position is the document and the play that are verified.
+type position struct {
+	document, play int
+}
+
+// contextWithObserver returns the context of a verification that notifies the observer.
+func contextWithObserver(ctx context.Context, observer Observer) context.Context {
+	return context.WithValue(ctx, observerKey{}, observer)
+}
+
+// contextObserver returns the observer of the verification the context belongs to.
+func contextObserver(ctx context.Context) Observer {
+	if observer, ok := ctx.Value(observerKey{}).(Observer); ok {
+		return observer
+	}
+	return LogObserver{}
+}
+
+// contextWithPosition returns the context of the verification of the document's play.
+// Play is -1 for the context of the whole document.
+func contextWithPosition(ctx context.Context, document, play int) context.Context {
+	return context.WithValue(ctx, positionKey{}, position{document, play})
+}
+
+// contextPosition returns the document and play the context of the verification belongs to.
+func contextPosition(ctx context.Context) position {
+	if p, ok := ctx.Value(positionKey{}).(position); ok {
+		return p
+	}
+	return position{-1, -1}
+}
+
+// stage is a running stage of the verification: a span of the context's trace and the events
+// of the observer.
+type stage struct {
+	ctx   context.Context
+	span  trace.Span
+	stage Stage
+}
+
+// startStage starts the stage of the document or play the context belongs to.
+// The returned context is the context of the stage's span.
+func startStage(ctx context.Context, name string, attributes ...attribute.KeyValue) (context.Context, *stage) {
+	ctx, span := tracer.Start(ctx, name, trace.WithAttributes(attributes...))
+	p := contextPosition(ctx)
+	s := &stage{ctx: ctx, span: span, stage: Stage{Name: name, Document: p.document, Play: p.play}}
+	contextObserver(ctx).OnStageStart(ctx, s.stage)
+	return ctx, s
+}
+
+// end marks the stage as failed if there is an error, and ends it.
+func (s *stage) end(err error) {
+	endSpan(s.span, err)
+	contextObserver(s.ctx).OnStageEnd(s.ctx, s.stage, err)
+}
+
+// observeExclusions notifies the observer of the elements excluded from the play.
+func observeExclusions(ctx context.Context, excluded []ExcludedPath) {
+	observer, p := contextObserver(ctx), contextPosition(ctx)
+	for _, path := range excluded {
+		observer.OnExclusion(ctx, Exclusion{ExcludedPath: path, Document: p.document, Play: p.play})
+	}
+}
+
+// observeVerdict notifies the observer of the verdict of the whole payload.
+func observeVerdict(ctx context.Context, report Report, err error) {
+	contextObserver(ctx).OnVerdict(ctx, Verdict{Document: -1, Play: -1, Verified: report.Verified && err == nil, Err: err})
+}
@@ -0,0 +1,133 @@
+package verifier
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// recordingObserver records the events of the verification as lines of text.
+type recordingObserver struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (o *recordingObserver) record(format string, args ...any) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.events = append(o.events, fmt.Sprintf(format, args...))
+}
+
+func (o *recordingObserver) OnStageStart(_ context.Context, stage Stage) {
+	o.record("start %s %d/%d", stage.Name, stage.Document, stage.Play)
+}
+
+func (o *recordingObserver) OnStageEnd(_ context.Context, stage Stage, err error) {
+	o.record("end %s %d/%d %v", stage.Name, stage.Document, stage.Play, err != nil)
+}
+
+func (o *recordingObserver) OnExclusion(_ context.Context, exclusion Exclusion) {
+	o.record("exclude %s %d/%d", exclusion.Path, exclusion.Document, exclusion.Play)
+}
+
+func (o *recordingObserver) OnVerdict(_ context.Context, verdict Verdict) {
+	o.record("verdict %d/%d %v %v", verdict.Document, verdict.Play, verdict.Verified, errors.Is(verdict.Err, ErrNoSignature))
+}
+
+// playEvents returns the recorded events of the play at the position, e.g. '1/2'.
+func (o *recordingObserver) playEvents(position string) []string {
+	var events []string
+	for _, event := range o.events {
+		if strings.Contains(event, " "+position) {
+			events = append(events, event)
+		}
+	}
+	return events
+}
+
+func TestObserver(t *testing.T) {
+	signed := readTestdata(t, filepath.Join("testdata", "golden", "cve-update.yml"))
+	reboot := readTestdata(t, filepath.Join("testdata", "golden", "reboot.yml"))
+	playEvents := func(position string, verified bool) []string {
+		events := []string{"start verify play " + position}
+		if verified {
+			events = append(events,
+				"start clean "+position, "end clean "+position+" false",
+				"exclude /hosts "+position, "exclude /vars/insights_signature "+position,
+				"start serialize "+position, "end serialize "+position+" false",
+				"start hash "+position, "end hash "+position+" false",
+				"start gpg verify "+position, "end gpg verify "+position+" false",
+			)
+		}
+		return append(events, fmt.Sprintf("end verify play %s %v", position, !verified), fmt.Sprintf("verdict %s %v %v", position, verified, !verified))
+	}
+
+	tests := []struct {
+		name     string
+		playbook []byte
+		plays    map[string]bool
+		verified bool
+	}{
+		{"signed documents", bytes.Join([][]byte{signed, reboot}, []byte("---\n")), map[string]bool{"0/0": true, "1/1": true, "1/2": true, "1/3": true}, true},
+		{"unsigned play", append(slices.Clone(signed), []byte("- name: unsigned\n")...), map[string]bool{"0/0": true, "0/1": false}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			observer := &recordingObserver{}
+			v, err := NewVerifier(WithKeyring(testKeyring(t)), WithPolicy(Policy{Jobs: 2}), WithObserver(observer))
+			if err != nil {
+				t.Fatalf("NewVerifier() error = %v", err)
+			}
+			if _, err = v.Verify(context.Background(), bytes.NewReader(tt.playbook)); err != nil && !errors.Is(err, ErrNoSignature) {
+				t.Fatalf("Verify() error = %v", err)
+			}
+
+			for position, verified := range tt.plays {
+				if got, want := observer.playEvents(position), playEvents(position, verified); !slices.Equal(got, want) {
+					t.Errorf("events of play %s =\n%s\nwant\n%s", position, strings.Join(got, "\n"), strings.Join(want, "\n"))
+				}
+			}
+			events := observer.events
+			if events[0] != "start verify playbook -1/-1" || !strings.HasPrefix(events[1], "start parse 0/-1") {
+				t.Errorf("first events = %q, want the playbook and its first document", events[:2])
+			}
+			want := []string{fmt.Sprintf("end verify playbook -1/-1 %v", !tt.verified), fmt.Sprintf("verdict -1/-1 %v %v", tt.verified, !tt.verified)}
+			if last := events[len(events)-2:]; !slices.Equal(last, want) {
+				t.Errorf("last events = %q, want %q", last, want)
+			}
+		})
+	}
+}
+
+// LogObserver logs the exclusions and verdicts the way the verification always has.
+func TestLogObserver(t *testing.T) {
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&logs, nil))
+	recording := &recordingObserver{}
+	observer := JoinObservers(LogObserver{}, recording)
+
+	ctx := contextWithLogger(context.Background(), logger)
+	observer.OnExclusion(ctx, Exclusion{ExcludedPath: ExcludedPath{Path: "/hosts", Type: "str"}, Document: 0, Play: 0})
+	observer.OnVerdict(ctx, Verdict{Document: 0, Play: 0, Verified: true, KeyID: "08D82F6981A5FD2F"})
+	observer.OnVerdict(ctx, Verdict{Document: 0, Play: 1, Err: MissingSignatureError{ErrNoSignature, "no signature", nil}})
+	observer.OnStageStart(ctx, Stage{Name: StageParse, Document: 0, Play: -1})
+
+	for _, want := range []string{`msg=excluding path=/hosts`, `msg="play verified" play=0 key=08D82F6981A5FD2F`, `level=INFO msg="play not verified" play=1`} {
+		if !strings.Contains(logs.String(), want) {
+			t.Errorf("logs do not contain %q:\n%s", want, logs.String())
+		}
+	}
+	if strings.Contains(logs.String(), "stage started") {
+		t.Errorf("stages are logged at the info level:\n%s", logs.String())
+	}
+	if want := "exclude /hosts 0/0\nverdict 0/0 true false\nverdict 0/1 false true\nstart parse 0/-1"; strings.Join(recording.events, "\n") != want {
+		t.Errorf("joined observer recorded\n%s\nwant\n%s", strings.Join(recording.events, "\n"), want)
+	}
+}
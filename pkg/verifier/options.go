@@ -0,0 +1,62 @@
+package verifier
+
+import (
+	"context"
+	"io"
+	"log/slog"
+)
+
+// Option configures Verify and NewVerifier.
+type Option func(*options)
+
+// options are the settings of Verify and of a Verifier.
+type options struct {
+	contentType string
+	keyring     *Keyring
+	policy      Policy
+	logger      *slog.Logger
+	observer    Observer
+}
+
+// WithContentType selects the handler of the payload. The default is PlaybookContentType.
+func WithContentType(contentType string) Option {
+	return func(o *options) {
+		o.contentType = contentType
+	}
+}
+
+// WithKeyring sets the trusted keys. It is required.
+func WithKeyring(keyring *Keyring) Option {
+	return func(o *options) {
+		o.keyring = keyring
+	}
+}
+
+// WithPolicy sets the policy of the verification. The default is the zero Policy.
+func WithPolicy(policy Policy) Option {
+	return func(o *options) {
+		o.policy = policy
+	}
+}
+
+// WithLogger sets the logger of the verification. The default is the default logger of slog
+// at the time of the verification.
+func WithLogger(logger *slog.Logger) Option {
+	return func(o *options) {
+		o.logger = logger
+	}
+}
+
+// contextReader is a reader that fails once its context is done, so a payload streamed
+// from a slow source stops being read when the verification is cancelled.
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (r contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.r.Read(p)
+}
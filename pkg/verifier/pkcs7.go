@@ -0,0 +1,160 @@
+package verifier
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/smallstep/pkcs7"
+)
+
+// pkcs7SignedDataOID is the DER encoding of the OID of CMS signed data.
+var pkcs7SignedDataOID = []byte{0x06, 0x09, 0x2a, 0x86, 0x48, 0x86, 0xf7, 0x0d, 0x01, 0x07, 0x02}
+
+// TrustCertificateAuthorities makes the keyring accept CMS/PKCS#7 signatures created
+// with certificates issued by the certificate authorities, e.g. the CA of Satellite.
+func (k *Keyring) TrustCertificateAuthorities(certificates ...*x509.Certificate) error {
+	if len(certificates) == 0 {
+		return VerificationError{ErrInvalidKey, "no certificate authority to trust", nil}
+	}
+	roots := x509.NewCertPool()
+	for _, certificate := range certificates {
+		roots.AddCert(certificate)
+	}
+	k.authorities = &authorityTrust{roots: roots, certificates: certificates}
+	return nil
+}
+
+// authorityTrust holds the trusted certificate authorities.
+type authorityTrust struct {
+	roots        *x509.CertPool
+	certificates []*x509.Certificate
+}
+
+// fingerprints returns the SHA-256 fingerprints of the certificate authorities.
+func (t *authorityTrust) fingerprints() []string {
+	var fingerprints []string
+	for _, certificate := range t.certificates {
+		fingerprints = append(fingerprints, certificateFingerprint(certificate))
+	}
+	slices.Sort(fingerprints)
+	return fingerprints
+}
+
+// certificateFingerprint returns the SHA-256 fingerprint of the certificate in hexadecimal.
+func certificateFingerprint(certificate *x509.Certificate) string {
+	hash := sha256.Sum256(certificate.Raw)
+	return strings.ToUpper(hex.EncodeToString(hash[:]))
+}
+
+// pkcs7DER returns the DER (or BER) encoding of the PKCS#7 signature, which is either
+// PEM-encoded or binary.
+func pkcs7DER(signature []byte) ([]byte, bool) {
+	if bytes.HasPrefix(bytes.TrimSpace(signature), []byte("-----BEGIN ")) {
+		block, _ := pem.Decode(signature)
+		if block == nil || (block.Type != "PKCS7" && block.Type != "CMS") {
+			return nil, false
+		}
+		return block.Bytes, true
+	}
+	// The OID of the content type follows the tag and length of the outer sequence.
+	return signature, len(signature) > 0 && signature[0] == 0x30 && bytes.Contains(signature[:min(len(signature), 16)], pkcs7SignedDataOID)
+}
+
+// parsePKCS7 parses the PKCS#7 signature, which must have a single signer.
+func parsePKCS7(signature []byte) (*pkcs7.PKCS7, *x509.Certificate, error) {
+	der, ok := pkcs7DER(signature)
+	if !ok {
+		return nil, nil, VerificationError{ErrMalformedSignature, "signature is not a PKCS#7 signature", nil}
+	}
+	p7, err := pkcs7.Parse(der)
+	if err != nil {
+		return nil, nil, VerificationError{ErrMalformedSignature, "signature is not valid PKCS#7 signed data", err}
+	}
+	signer := p7.GetOnlySigner()
+	if signer == nil {
+		return nil, nil, VerificationError{ErrMalformedSignature, fmt.Sprintf("PKCS#7 signature must have a single signer with a certificate, has %d signers", len(p7.Signers)), nil}
+	}
+	return p7, signer, nil
+}
+
+// pkcs7Scheme verifies detached CMS/PKCS#7 signatures against the trusted certificate authorities.
+type pkcs7Scheme struct{}
+
+func (pkcs7Scheme) detect(signature []byte) bool {
+	_, ok := pkcs7DER(signature)
+	return ok
+}
+
+func (pkcs7Scheme) verify(_ *slog.Logger, k *Keyring, message []byte, signature []byte) (string, error) {
+	if k.authorities == nil {
+		return "", VerificationError{ErrDigestMismatch, "signature is a PKCS#7 signature, but no certificate authority is trusted", nil}
+	}
+	p7, signer, err := parsePKCS7(signature)
+	if err != nil {
+		return "", err
+	}
+	if len(p7.Content) > 0 {
+		return "", VerificationError{ErrMalformedSignature, "PKCS#7 signature is not detached", nil}
+	}
+	p7.Content = message
+
+	// The certificate chain is checked at the signing time, if the signature records it.
+	if err = p7.VerifyWithChain(k.authorities.roots); err != nil {
+		return "", VerificationError{ErrDigestMismatch, "signature does not match", err}
+	}
+	fingerprint := certificateFingerprint(signer)
+	if k.isRevokedKeyID(fingerprint) {
+		return "", VerificationError{ErrRevokedKey, fmt.Sprintf("signature was created by revoked certificate %s", fingerprint), nil}
+	}
+	return signer.Subject.String(), nil
+}
+
+// parsePKCS7SignatureInfo reads the metadata of a PKCS#7 signature.
+//
+// The key ID is the subject of the signing certificate. Created is left empty if the
+// signature does not record the signing time.
+func parsePKCS7SignatureInfo(signature []byte) (*SignatureInfo, error) {
+	p7, signer, err := parsePKCS7(signature)
+	if err != nil {
+		return nil, err
+	}
+	info := &SignatureInfo{
+		Scheme:             SchemePKCS7,
+		KeyID:              signer.Subject.String(),
+		Fingerprint:        certificateFingerprint(signer),
+		HashAlgorithm:      pkcs7HashAlgorithmName(p7),
+		PublicKeyAlgorithm: signer.PublicKeyAlgorithm.String(),
+		Version:            p7.Signers[0].Version,
+	}
+	var created time.Time
+	if err = p7.UnmarshalSignedAttribute(pkcs7.OIDAttributeSigningTime, &created); err == nil {
+		info.Created = created.UTC()
+	}
+	return info, nil
+}
+
+// pkcs7HashAlgorithmName returns the name of the digest algorithm of the signer.
+func pkcs7HashAlgorithmName(p7 *pkcs7.PKCS7) string {
+	algorithm := p7.Signers[0].DigestAlgorithm.Algorithm
+	switch {
+	case algorithm.Equal(pkcs7.OIDDigestAlgorithmSHA1):
+		return "SHA1"
+	case algorithm.Equal(pkcs7.OIDDigestAlgorithmSHA224):
+		return "SHA-224"
+	case algorithm.Equal(pkcs7.OIDDigestAlgorithmSHA256):
+		return "SHA-256"
+	case algorithm.Equal(pkcs7.OIDDigestAlgorithmSHA384):
+		return "SHA-384"
+	case algorithm.Equal(pkcs7.OIDDigestAlgorithmSHA512):
+		return "SHA-512"
+	}
+	return algorithm.String()
+}
@@ -0,0 +1,135 @@
+package verifier
+
+import (
+	"crypto/ecdsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/smallstep/pkcs7"
+)
+
+// testAuthority is a certificate authority, like the one of Satellite.
+type testAuthority struct {
+	certificate *x509.Certificate
+	key         *ecdsa.PrivateKey
+}
+
+func newTestAuthority(t *testing.T) *testAuthority {
+	t.Helper()
+	key := generateECDSAKey(t)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test Satellite CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	return &testAuthority{certificate: createCertificate(t, template, template, &key.PublicKey, key), key: key}
+}
+
+// sign returns the detached DER-encoded PKCS#7 signature of the message and the signing certificate.
+func (a *testAuthority) sign(t *testing.T, message []byte) ([]byte, *x509.Certificate) {
+	t.Helper()
+	key := generateECDSAKey(t)
+	certificate := createCertificate(t, &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "satellite.example.com"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}, a.certificate, &key.PublicKey, a.key)
+
+	signedData, err := pkcs7.NewSignedData(message)
+	if err != nil {
+		t.Fatalf("pkcs7.NewSignedData() error = %v", err)
+	}
+	signedData.SetDigestAlgorithm(pkcs7.OIDDigestAlgorithmSHA256)
+	if err = signedData.AddSigner(certificate, key, pkcs7.SignerInfoConfig{}); err != nil {
+		t.Fatalf("AddSigner() error = %v", err)
+	}
+	signedData.Detach()
+	signature, err := signedData.Finish()
+	if err != nil {
+		t.Fatalf("Finish() error = %v", err)
+	}
+	return signature, certificate
+}
+
+func TestPKCS7(t *testing.T) {
+	authority := newTestAuthority(t)
+	message := []byte("digest of the play")
+	signature, certificate := authority.sign(t, message)
+	armored := pem.EncodeToMemory(&pem.Block{Type: "PKCS7", Bytes: signature})
+
+	tests := []struct {
+		name        string
+		message     []byte
+		signature   []byte
+		authorities []*x509.Certificate
+		revoked     string
+		want        error
+	}{
+		{"valid", message, signature, []*x509.Certificate{authority.certificate}, "", nil},
+		{"valid PEM", message, armored, []*x509.Certificate{authority.certificate}, "", nil},
+		{"other message", []byte("other"), signature, []*x509.Certificate{authority.certificate}, "", ErrDigestMismatch},
+		{"untrusted authority", message, signature, []*x509.Certificate{newTestAuthority(t).certificate}, "", ErrDigestMismatch},
+		{"no authority", message, signature, nil, "", ErrDigestMismatch},
+		{"revoked certificate", message, signature, []*x509.Certificate{authority.certificate}, certificateFingerprint(certificate), ErrRevokedKey},
+		{"truncated", message, signature[:len(signature)/2], []*x509.Certificate{authority.certificate}, "", ErrMalformedSignature},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keyring := testKeyring(t)
+			if tt.authorities != nil {
+				if err := keyring.TrustCertificateAuthorities(tt.authorities...); err != nil {
+					t.Fatalf("TrustCertificateAuthorities() error = %v", err)
+				}
+			}
+			if tt.revoked != "" {
+				if err := keyring.Revoke(tt.revoked); err != nil {
+					t.Fatalf("Revoke() error = %v", err)
+				}
+			}
+
+			signer, err := keyring.Verify(tt.message, tt.signature)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("Verify() error = %v, want %v", err, tt.want)
+			}
+			if err == nil && signer != "CN=satellite.example.com" {
+				t.Errorf("Verify() = %s, want CN=satellite.example.com", signer)
+			}
+		})
+	}
+}
+
+func TestParsePKCS7Signature(t *testing.T) {
+	signature, certificate := newTestAuthority(t).sign(t, []byte("digest of the play"))
+
+	info, err := ParseSignature(signature)
+	if err != nil {
+		t.Fatalf("ParseSignature() error = %v", err)
+	}
+	want := SignatureInfo{
+		Scheme:             SchemePKCS7,
+		KeyID:              "CN=satellite.example.com",
+		Fingerprint:        certificateFingerprint(certificate),
+		HashAlgorithm:      "SHA-256",
+		PublicKeyAlgorithm: "ECDSA",
+		Version:            1,
+	}
+	created := info.Created
+	info.Created = time.Time{}
+	if *info != want {
+		t.Errorf("ParseSignature() = %+v, want %+v", *info, want)
+	}
+	if time.Since(created) > time.Minute {
+		t.Errorf("ParseSignature() created = %s", created)
+	}
+}
@@ -0,0 +1,440 @@
+package verifier
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+
+	yaml3 "gopkg.in/yaml.v3"
+)
+
+// dynamicLabels are the top-level keys of a play that may contain dynamic elements, see DynamicLabels.
+var dynamicLabels = map[string]any{"hosts": nil, "vars": nil}
+
+// DynamicLabels returns the sorted top-level keys of a play that may contain dynamic elements.
+//
+// Only these keys and the variables nested anywhere in the play can be excluded
+// from the hash; everything else (e.g. tasks) is always covered by the signature.
+// The keys are a part of the signing scheme and cannot be changed.
+func DynamicLabels() []string {
+	return sortedKeys(dynamicLabels)
+}
+
+// sortedKeys returns the sorted keys of the set.
+func sortedKeys(set map[string]any) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// ExclusionWildcard is a segment of an exclusion path that matches any key or index.
+const ExclusionWildcard = "*"
+
+// UnmarshalPlaybook parses the playbook into a list of plays.
+//
+// Plays of all YAML documents in the playbook are returned. Every play is a mapping node.
+func UnmarshalPlaybook(playbook []byte) ([]*yaml3.Node, error) {
+	var data []*yaml3.Node
+	decoder := NewPlaybookDecoder(bytes.NewReader(playbook))
+	for {
+		plays, err := decoder.Decode()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		data = append(data, plays...)
+	}
+
+	if len(data) == 0 {
+		return nil, PlaybookError{ErrMalformedYAML, "playbook contains no data", nil}
+	}
+	return data, nil
+}
+
+// PlaybookDecoder reads plays from a stream of YAML documents.
+//
+// Only a single document is held in memory at a time, which keeps the memory
+// usage low for playbooks with many plays split into documents. The position of each
+// document in the stream is known once it is decoded, see Range.
+type PlaybookDecoder struct {
+	// AllowDuplicateKeys accepts mappings with duplicate keys, see Policy.
+	AllowDuplicateKeys bool
+	// MemoryBudget limits the estimated size of all decoded documents, see Policy.
+	MemoryBudget int64
+
+	splitter *documentSplitter
+	// raw is the last document read from the stream.
+	raw rawDocument
+	// used is the estimated size of the documents decoded so far.
+	used int64
+}
+
+// NewPlaybookDecoder returns a decoder that reads from r.
+func NewPlaybookDecoder(r io.Reader) *PlaybookDecoder {
+	return &PlaybookDecoder{splitter: newDocumentSplitter(r)}
+}
+
+// Range returns the position in the stream of the document decoded last.
+func (d *PlaybookDecoder) Range() (offset, length int64) {
+	return d.raw.offset, int64(len(d.raw.content))
+}
+
+// Decode parses the next YAML document into a list of plays.
+//
+// Documents using ambiguous YAML features are refused, see lintDocument.
+// The aliases and merge keys of the document are resolved by canonicalize, so the plays
+// are trees of mapping, sequence and scalar nodes in the order of the document. Their scalars
+// are constructed by the YAML 1.1 rules of PyYAML (e.g. 'yes' is a boolean), see constructScalar.
+//
+// It returns io.EOF when there are no more documents.
+func (d *PlaybookDecoder) Decode() ([]*yaml3.Node, error) {
+	_, plays, err := d.decode()
+	return plays, err
+}
+
+// decode parses the next YAML document and returns both its node and its plays.
+//
+// The node is returned also when the document is valid YAML, but not a list of plays.
+func (d *PlaybookDecoder) decode() (*yaml3.Node, []*yaml3.Node, error) {
+	raw, err := d.splitter.next()
+	if err != nil {
+		if err == io.EOF {
+			return nil, nil, io.EOF
+		}
+		return nil, nil, PlaybookError{ErrMalformedYAML, "invalid YAML", err}
+	}
+	d.raw = raw
+	document, err := decodeDocument(raw)
+	if err != nil {
+		if err == io.EOF {
+			return nil, nil, io.EOF
+		}
+		return nil, nil, PlaybookError{ErrMalformedYAML, "invalid YAML", err}
+	}
+	if err := lintDocument(document, d.AllowDuplicateKeys); err != nil {
+		return nil, nil, err
+	}
+	c := &canonicalizer{size: d.used, budget: d.MemoryBudget}
+	canonical, err := c.resolve(document, false)
+	d.used = c.size
+	if err != nil {
+		return nil, nil, err
+	}
+	plays, err := documentPlays(canonical)
+	if err != nil {
+		return document, nil, err
+	}
+	return document, plays, nil
+}
+
+// notPlaysError means a YAML document is valid, but it is not a list of plays.
+type notPlaysError struct {
+	message string
+}
+
+func (e notPlaysError) Error() string {
+	return e.message
+}
+
+// documentPlays returns the plays of the canonical document, whose scalars are all
+// constructed once, so a play PyYAML would refuse to load is refused as well.
+//
+// An empty document has no plays; other documents that are not lists of mappings
+// fail with notPlaysError.
+func documentPlays(document *yaml3.Node) ([]*yaml3.Node, error) {
+	if len(document.Content) == 0 {
+		return nil, nil
+	}
+	root := document.Content[0]
+	switch {
+	case root.Kind == yaml3.ScalarNode && scalarTag(root) == "!!null":
+		return nil, nil
+	case root.Kind != yaml3.SequenceNode:
+		message := fmt.Sprintf("document at line %d is a %s, not a list of plays", root.Line, yamlTypeName(root))
+		return nil, PlaybookError{ErrMalformedYAML, "invalid YAML", notPlaysError{message}}
+	}
+	for _, play := range root.Content {
+		if play.Kind != yaml3.MappingNode {
+			message := fmt.Sprintf("play at line %d is a %s, not a mapping", play.Line, yamlTypeName(play))
+			return nil, PlaybookError{ErrMalformedYAML, "invalid YAML", notPlaysError{message}}
+		}
+	}
+	if err := constructScalars(root); err != nil {
+		return nil, err
+	}
+	return root.Content, nil
+}
+
+// GetPlaybookExclusions extracts dynamic keys that are meant to be excluded from the playbook hash.
+func GetPlaybookExclusions(p *yaml3.Node) ([][]string, error) {
+	value, _ := getPlaybookVariable(p, "insights_signature_exclude")
+	rawExclusions, ok := stringValue(value)
+	if !ok {
+		return nil, PlaybookError{ErrUnsupportedType, fmt.Sprintf("'insights_signature_exclude' must be a string, not %s", yamlTypeName(value)), nil}
+	}
+
+	if rawExclusions == "" {
+		return nil, MissingSignatureError{ErrNoSignature, "playbook doesn't contain key 'insights_signature_exclude'", nil}
+	}
+
+	var exclusions [][]string
+	for _, exclusion := range strings.Split(rawExclusions, ",") {
+		var exclusionBits []string
+		for _, bit := range strings.Split(exclusion, "/") {
+			if bit != "" {
+				exclusionBits = append(exclusionBits, bit)
+			}
+		}
+		if len(exclusionBits) == 0 {
+			continue
+		}
+		if !isExcludable(exclusionBits) {
+			return nil, VerificationError{ErrInvalidExclusion, fmt.Sprintf("path '%s' cannot be excluded", formatPath(exclusionBits)), nil}
+		}
+		exclusions = append(exclusions, exclusionBits)
+	}
+	return exclusions, nil
+}
+
+// getPlaybookVariable returns the value of the variable defined in 'vars' of the play.
+//
+// The variables are either a map, or a list of single-key maps; in the list form,
+// the last definition of the variable wins, as in Ansible.
+func getPlaybookVariable(p *yaml3.Node, name string) (value *yaml3.Node, found bool) {
+	vars, ok := mappingValue(p, "vars")
+	if !ok {
+		return nil, false
+	}
+	switch vars.Kind {
+	case yaml3.MappingNode:
+		return mappingValue(vars, name)
+	case yaml3.SequenceNode:
+		for _, variable := range vars.Content {
+			if variable.Kind == yaml3.MappingNode && len(variable.Content) == 2 && isKey(variable.Content[0], name) {
+				value, found = variable.Content[1], true
+			}
+		}
+	}
+	return value, found
+}
+
+// mappingValue returns the value of the string key of the mapping node.
+func mappingValue(m *yaml3.Node, key string) (*yaml3.Node, bool) {
+	if m.Kind != yaml3.MappingNode {
+		return nil, false
+	}
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if isKey(m.Content[i], key) {
+			return m.Content[i+1], true
+		}
+	}
+	return nil, false
+}
+
+// isKey reports whether the node is the string scalar name.
+func isKey(node *yaml3.Node, name string) bool {
+	return node.Kind == yaml3.ScalarNode && node.Value == name && scalarTag(node) == "!!str"
+}
+
+// stringValue returns the string of the scalar node. A missing or null node is an empty string;
+// ok is false for any other node.
+func stringValue(node *yaml3.Node) (value string, ok bool) {
+	if node == nil {
+		return "", true
+	}
+	switch v := scalarValue(node).(type) {
+	case string:
+		return v, true
+	case nil:
+		return "", node.Kind == yaml3.ScalarNode
+	}
+	return "", false
+}
+
+// CleanPlaybook removes the dynamic elements listed in 'insights_signature_exclude' from the play.
+//
+// Exclusion paths may be of any depth. Nested maps are addressed by their keys,
+// nested lists by the index of their items (e.g. '/tasks/0/vars/timestamp').
+// A path segment '*' matches any key or index (e.g. '/vars/*', '/tasks/*/vars/timestamp').
+// Variables given as a list of single-key maps are addressed by their names as well,
+// as if they were a map (e.g. '/vars/insights_signature').
+//
+// The play is not modified; the cleaned play shares the nodes that were not cleaned with it.
+func CleanPlaybook(p *yaml3.Node) (*yaml3.Node, error) {
+	clean, _, err := cleanPlaybook(p)
+	return clean, err
+}
+
+// cleanPlaybook removes the dynamic elements from the play and returns the elements
+// that have been removed. The verification notifies its observer of them, see Observer.OnExclusion.
+func cleanPlaybook(p *yaml3.Node) (*yaml3.Node, []ExcludedPath, error) {
+	return cleanPlaybookContext(context.Background(), p)
+}
+
+// cleanPlaybookContext is like cleanPlaybook, but logs to the logger of the context's verification.
+func cleanPlaybookContext(ctx context.Context, p *yaml3.Node) (*yaml3.Node, []ExcludedPath, error) {
+	exclusions, err := GetPlaybookExclusions(p)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	c := cleaner{exclusions: exclusions, logger: Logger(ctx)}
+	clean := c.cleanMap(p, []string{})
+
+	c.logger.Debug("playbook cleaned")
+	return clean, c.excluded, nil
+}
+
+// cleaner walks the play and copies everything but the excluded paths.
+type cleaner struct {
+	exclusions [][]string
+	logger     *slog.Logger
+	// excluded are the elements that have been removed.
+	excluded []ExcludedPath
+}
+
+// cleanItem descends into the item if any of the exclusions points below its path.
+func (c *cleaner) cleanItem(item *yaml3.Node, path []string) *yaml3.Node {
+	if !isExclusionBelow(path, c.exclusions) {
+		return item
+	}
+
+	switch item.Kind {
+	case yaml3.MappingNode:
+		return c.cleanMap(item, path)
+	case yaml3.SequenceNode:
+		return c.cleanList(item, path)
+	default:
+		// there is nothing below a scalar to exclude
+		return item
+	}
+}
+
+// cleanMap returns a copy of the map without the excluded keys.
+func (c *cleaner) cleanMap(m *yaml3.Node, path []string) *yaml3.Node {
+	clean := *m
+	clean.Content = []*yaml3.Node{}
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		key, value := m.Content[i], m.Content[i+1]
+		pairPath := append(path[:len(path):len(path)], pathSegment(key))
+		if isExcluded(pairPath, c.exclusions) {
+			c.exclude(pairPath, value)
+			continue
+		}
+
+		c.logger.Debug("including", slog.String("path", formatPath(pairPath)))
+		clean.Content = append(clean.Content, key, c.cleanItem(value, pairPath))
+	}
+	return &clean
+}
+
+// cleanList returns a copy of the list without the excluded items.
+func (c *cleaner) cleanList(l *yaml3.Node, path []string) *yaml3.Node {
+	clean := *l
+	clean.Content = []*yaml3.Node{}
+	for i, item := range l.Content {
+		itemPath := append(path[:len(path):len(path)], strconv.Itoa(i))
+		if isExcluded(itemPath, c.exclusions) {
+			c.exclude(itemPath, item)
+			continue
+		}
+
+		// A variable of the list form of 'vars' is addressed by its name, like in a map.
+		if isListVariable(path, item) {
+			if value := c.cleanMap(item, path); len(value.Content) > 0 {
+				clean.Content = append(clean.Content, value)
+			}
+			continue
+		}
+
+		c.logger.Debug("including", slog.String("path", formatPath(itemPath)))
+		clean.Content = append(clean.Content, c.cleanItem(item, itemPath))
+	}
+	return &clean
+}
+
+// isListVariable reports whether the item of the list at the path is a variable
+// of 'vars' given as a list of single-key maps.
+func isListVariable(path []string, item *yaml3.Node) bool {
+	if len(path) == 0 || path[len(path)-1] != "vars" {
+		return false
+	}
+	return item.Kind == yaml3.MappingNode && len(item.Content) == 2
+}
+
+// exclude records the removal of the value at the path.
+func (c *cleaner) exclude(path []string, value *yaml3.Node) {
+	c.excluded = append(c.excluded, ExcludedPath{Path: formatPath(path), Type: yamlTypeName(value)})
+}
+
+// isExcludable reports whether the exclusion is allowed to remove content from the hash.
+//
+// The path has to start with one of the DynamicLabels or lead through variables
+// (e.g. '/tasks/0/vars/timestamp').
+func isExcludable(exclusion []string) bool {
+	if _, ok := dynamicLabels[exclusion[0]]; ok {
+		return true
+	}
+	for _, segment := range exclusion[1:] {
+		if segment == "vars" {
+			return true
+		}
+	}
+	return false
+}
+
+// isExcluded reports whether the path is equal to one of the exclusions.
+func isExcluded(path []string, exclusions [][]string) bool {
+	for _, exclusion := range exclusions {
+		if len(exclusion) == len(path) && hasPathPrefix(exclusion, path) {
+			return true
+		}
+	}
+	return false
+}
+
+// isExclusionBelow reports whether one of the exclusions points to a descendant of the path.
+func isExclusionBelow(path []string, exclusions [][]string) bool {
+	for _, exclusion := range exclusions {
+		if len(exclusion) > len(path) && hasPathPrefix(exclusion, path) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasPathPrefix reports whether the exclusion starts with the path.
+func hasPathPrefix(exclusion []string, path []string) bool {
+	for i := range path {
+		if exclusion[i] != ExclusionWildcard && exclusion[i] != path[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// pathSegment converts the map key into a segment of an exclusion path.
+func pathSegment(key *yaml3.Node) string {
+	if key.Kind != yaml3.ScalarNode {
+		return key.Value
+	}
+	if segment, ok := scalarValue(key).(string); ok {
+		return segment
+	}
+	return fmt.Sprint(scalarValue(key))
+}
+
+// formatPath returns the path in the format used by 'insights_signature_exclude'.
+func formatPath(path []string) string {
+	return "/" + strings.Join(path, "/")
+}
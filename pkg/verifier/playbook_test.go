@@ -0,0 +1,225 @@
+package verifier
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestCleanPlaybook(t *testing.T) {
+	tests := []struct {
+		name     string
+		playbook string
+		want     string
+	}{
+		{
+			name: "direct and nested",
+			playbook: `- hosts: all
+  vars:
+    insights_signature_exclude: /hosts,/vars/insights_signature
+    insights_signature: c2lnbmF0dXJl
+`,
+			want: "ordereddict([('vars', ordereddict([('insights_signature_exclude', '/hosts,/vars/insights_signature')]))])",
+		},
+		{
+			name: "deep",
+			playbook: `- vars:
+    insights_signature_exclude: /vars/insights_signature/extra/level
+    insights_signature:
+      extra:
+        level: 1
+        kept: 2
+`,
+			want: "ordereddict([('vars', ordereddict([('insights_signature_exclude', '/vars/insights_signature/extra/level'), " +
+				"('insights_signature', ordereddict([('extra', ordereddict([('kept', 2)]))]))]))])",
+		},
+		{
+			name: "list index",
+			playbook: `- vars:
+    insights_signature_exclude: /tasks/1/vars/stamp
+  tasks:
+    - vars:
+        stamp: 1
+    - vars:
+        stamp: 2
+`,
+			want: "ordereddict([('vars', ordereddict([('insights_signature_exclude', '/tasks/1/vars/stamp')])), " +
+				"('tasks', [ordereddict([('vars', ordereddict([('stamp', 1)]))]), ordereddict([('vars', ordereddict())])])])",
+		},
+		{
+			name: "wildcard key",
+			playbook: `- hosts: all
+  vars:
+    insights_signature_exclude: /hosts,/vars/*
+    insights_signature: c2lnbmF0dXJl
+    insights_timestamp: 1
+`,
+			want: "ordereddict([('vars', ordereddict())])",
+		},
+		{
+			name: "wildcard index",
+			playbook: `- vars:
+    insights_signature_exclude: /tasks/*/vars/stamp
+  tasks:
+    - command: date
+      vars:
+        stamp: 1
+    - command: uptime
+      vars:
+        stamp: 2
+`,
+			want: "ordereddict([('vars', ordereddict([('insights_signature_exclude', '/tasks/*/vars/stamp')])), " +
+				"('tasks', [ordereddict([('command', 'date'), ('vars', ordereddict())]), " +
+				"ordereddict([('command', 'uptime'), ('vars', ordereddict())])])])",
+		},
+		{
+			name: "list items",
+			playbook: `- vars:
+    insights_signature_exclude: /vars/stamps/0,/vars/stamps/2
+    stamps: [1, 2, 3]
+`,
+			want: "ordereddict([('vars', ordereddict([('insights_signature_exclude', '/vars/stamps/0,/vars/stamps/2'), ('stamps', [2])]))])",
+		},
+		{
+			name: "nested lists",
+			playbook: `- vars:
+    insights_signature_exclude: /tasks/0/block/*/vars/stamp,/tasks/0/vars/matrix/1/0
+  tasks:
+    - vars:
+        matrix: [[1, 2], [3, 4]]
+      block:
+        - command: date
+          vars:
+            stamp: 1
+`,
+			want: "ordereddict([('vars', ordereddict([('insights_signature_exclude', '/tasks/0/block/*/vars/stamp,/tasks/0/vars/matrix/1/0')])), " +
+				"('tasks', [ordereddict([('vars', ordereddict([('matrix', [[1, 2], [4]])])), " +
+				"('block', [ordereddict([('command', 'date'), ('vars', ordereddict())])])])])])",
+		},
+		{
+			name: "list of variables",
+			playbook: `- vars:
+    insights_signature_exclude: /tasks/0/vars/stamp,/tasks/0/vars/host/name
+  tasks:
+    - vars:
+        - stamp: 1
+        - host:
+            name: localhost
+            port: 22
+        - kept: 2
+`,
+			want: "ordereddict([('vars', ordereddict([('insights_signature_exclude', '/tasks/0/vars/stamp,/tasks/0/vars/host/name')])), " +
+				"('tasks', [ordereddict([('vars', [ordereddict([('host', ordereddict([('port', 22)]))]), ordereddict([('kept', 2)])])])])])",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plays, err := UnmarshalPlaybook([]byte(tt.playbook))
+			if err != nil {
+				t.Fatalf("UnmarshalPlaybook() error = %v", err)
+			}
+			clean, err := CleanPlaybook(plays[0])
+			if err != nil {
+				t.Fatalf("CleanPlaybook() error = %v", err)
+			}
+			got, err := MarshallPlaybook(clean)
+			if err != nil {
+				t.Fatalf("MarshallPlaybook() error = %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("CleanPlaybook() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCleanPlaybookNotExcludable(t *testing.T) {
+	exclusions := []string{"/tasks", "/tasks/*/register", "/*", "/name", "/roles/0/role"}
+
+	for _, exclusion := range exclusions {
+		t.Run(exclusion, func(t *testing.T) {
+			playbook := "- vars:\n    insights_signature_exclude: /hosts," + exclusion + "\n"
+			plays, err := UnmarshalPlaybook([]byte(playbook))
+			if err != nil {
+				t.Fatalf("UnmarshalPlaybook() error = %v", err)
+			}
+			if _, err = CleanPlaybook(plays[0]); err == nil {
+				t.Errorf("CleanPlaybook() expected error for exclusion %s", exclusion)
+			}
+		})
+	}
+}
+
+func TestCleanPlaybookBelowScalar(t *testing.T) {
+	play := parsePlay(t, "{vars: {insights_signature_exclude: /vars/stamp/value, stamp: 1}}")
+	clean, err := CleanPlaybook(play)
+	if err != nil {
+		t.Fatalf("CleanPlaybook() error = %v", err)
+	}
+	want := "ordereddict([('vars', ordereddict([('insights_signature_exclude', '/vars/stamp/value'), ('stamp', 1)]))])"
+	if got, _ := MarshallPlaybook(clean); string(got) != want {
+		t.Errorf("MarshallPlaybook() = %s, want %s", got, want)
+	}
+}
+
+func TestVariablesList(t *testing.T) {
+	playbook := `- name: list of variables
+  vars:
+    - insights_signature_exclude: /vars/insights_signature
+    - insights_signature: c2lnbmF0dXJl
+    - timeout: 1
+    - timeout: 2
+`
+	plays, err := UnmarshalPlaybook([]byte(playbook))
+	if err != nil {
+		t.Fatalf("UnmarshalPlaybook() error = %v", err)
+	}
+
+	if signature, err := GetPlaybookSignature(plays[0]); err != nil || string(signature) != "signature" {
+		t.Errorf("GetPlaybookSignature() = %q, %v", signature, err)
+	}
+	if value, _ := getPlaybookVariable(plays[0], "timeout"); scalarValue(value) != int64(2) {
+		t.Errorf("getPlaybookVariable() = %v, want 2", scalarValue(value))
+	}
+	clean, err := CleanPlaybook(plays[0])
+	if err != nil {
+		t.Fatalf("CleanPlaybook() error = %v", err)
+	}
+	got, err := MarshallPlaybook(clean)
+	if err != nil {
+		t.Fatalf("MarshallPlaybook() error = %v", err)
+	}
+	want := "ordereddict([('name', 'list of variables'), ('vars', [ordereddict([('insights_signature_exclude', '/vars/insights_signature')]), " +
+		"ordereddict([('timeout', 1)]), ordereddict([('timeout', 2)])])])"
+	if string(got) != want {
+		t.Errorf("CleanPlaybook() = %s, want %s", got, want)
+	}
+}
+
+func TestCleanPlaybookExclusionTypes(t *testing.T) {
+	playbook := `- hosts: [a, b]
+  vars:
+    insights_signature_exclude: /hosts,/vars/*
+    insights_signature: c2lnbmF0dXJl
+    stamp: 1
+    ratio: 0.5
+    enabled: true
+    nothing:
+    extra: {a: 1}
+`
+	plays, err := UnmarshalPlaybook([]byte(playbook))
+	if err != nil {
+		t.Fatalf("UnmarshalPlaybook() error = %v", err)
+	}
+	_, excluded, err := cleanPlaybook(plays[0])
+	if err != nil {
+		t.Fatalf("cleanPlaybook() error = %v", err)
+	}
+	want := []ExcludedPath{
+		{"/hosts", "sequence"}, {"/vars/insights_signature_exclude", "string"}, {"/vars/insights_signature", "string"},
+		{"/vars/stamp", "integer"}, {"/vars/ratio", "float"}, {"/vars/enabled", "boolean"}, {"/vars/nothing", "null"}, {"/vars/extra", "mapping"},
+	}
+	if !slices.Equal(excluded, want) {
+		t.Errorf("cleanPlaybook() excluded = %v, want %v", excluded, want)
+	}
+}
@@ -0,0 +1,120 @@
+package verifier
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	yaml3 "gopkg.in/yaml.v3"
+)
+
+// Policy controls which payloads are accepted and what is reported about them.
+type Policy struct {
+	// RequireAllSigned makes every YAML document of the playbook carry a signature.
+	//
+	// By default, documents without any signed play (e.g. metadata concatenated
+	// to the playbook) are skipped, as long as at least one play has been verified.
+	RequireAllSigned bool
+	// Unsigned is what happens to plays and documents without a signature: UnsignedReject,
+	// the default, UnsignedWarn or UnsignedAllow. RequireAllSigned still refuses unsigned documents.
+	Unsigned string
+	// AllowDuplicateKeys accepts mappings with duplicate keys; the last value of a key wins,
+	// as in Python. By default they are refused, like other ambiguous YAML.
+	AllowDuplicateKeys bool
+	// NormalizePayload removes a UTF-8 byte order mark, CRLF line endings and trailing whitespace
+	// from the payload before it is verified, see NormalizePayload.
+	NormalizePayload bool
+	// Strict refuses plays with top-level keys that are not PlayKeywords.
+	Strict bool
+	// ReportDiff adds to each play of the report a diff of the play and its cleaned form.
+	ReportDiff bool
+	// Jobs is the maximal number of plays of a YAML document verified concurrently.
+	// Values lower than 2 verify the plays one by one.
+	Jobs int
+	// HashAlgorithm is the digest algorithm of plays that do not declare one
+	// in 'insights_signature_hash'. Empty means DefaultHashAlgorithm.
+	HashAlgorithm string
+	// MinimumHashAlgorithm is the weakest digest algorithm that is accepted.
+	// Empty means DefaultHashAlgorithm.
+	MinimumHashAlgorithm string
+	// SerializationProfiles are the serialization profiles a signature may match, tried in order.
+	// Empty means only DefaultSerializationProfile.
+	SerializationProfiles []string
+	// MaxSignatureAge is the longest time since the creation of a signature it is accepted for.
+	// Signatures created in the future are refused as well. Zero means signatures do not expire.
+	MaxSignatureAge time.Duration
+	// ClockSkew is how far in the future a signature may have been created, to tolerate
+	// clocks that are not synchronized. It only applies together with MaxSignatureAge.
+	ClockSkew time.Duration
+	// MemoryBudget is a soft limit of the memory the YAML documents of a payload may take
+	// once parsed, in bytes. Zero means no limit, except for the limit of aliases.
+	MemoryBudget int64
+	// Rules are evaluated once the signatures are verified, see ParseRules. A payload violating
+	// any of them is refused with a PolicyError and the violations are added to the report.
+	Rules []Rule
+	// ContentSource names where the payload comes from (e.g. 'insights' or 'satellite'),
+	// selecting the rules that apply to it. Empty means the source is unknown.
+	ContentSource string
+	// ReferenceSerializations are the serialized plays the signer hashed, by the index of the play.
+	// The diagnosis of a play whose digest does not match tells where its serializations differ.
+	ReferenceSerializations [][]byte
+}
+
+// Handling of content without a signature, see Policy.Unsigned.
+const (
+	// UnsignedReject refuses plays without a signature or its exclusions. Documents without any
+	// signed play are skipped, and payloads without a signed document are refused.
+	UnsignedReject = "reject"
+	// UnsignedWarn accepts plays and documents without a signature with a warning, e.g. while
+	// the signing of playbooks is rolled out. They are passed on, but they have not been verified,
+	// see Report.HasUnsigned.
+	UnsignedWarn = "warn"
+	// UnsignedAllow accepts plays and documents without a signature like UnsignedWarn, but only
+	// logs them at the debug level, like insights-client did before it verified playbooks.
+	UnsignedAllow = "allow"
+)
+
+// UnsignedPolicies returns the supported values of Policy.Unsigned.
+func UnsignedPolicies() []string {
+	return []string{UnsignedReject, UnsignedWarn, UnsignedAllow}
+}
+
+// acceptsUnsigned reports whether the policy accepts plays and documents without a signature.
+// Unknown values refuse them.
+func acceptsUnsigned(policy Policy) bool {
+	return policy.Unsigned == UnsignedWarn || policy.Unsigned == UnsignedAllow
+}
+
+// logUnsigned logs that the unsigned content is accepted, as a warning if the policy asks for it.
+func logUnsigned(ctx context.Context, policy Policy, msg string, args ...any) {
+	level := slog.LevelDebug
+	if policy.Unsigned == UnsignedWarn {
+		level = slog.LevelWarn
+	}
+	Logger(ctx).Log(ctx, level, msg, args...)
+}
+
+// diffReport returns the diff between the play and its cleaned form, or an empty string
+// if it could not be created.
+func diffReport(ctx context.Context, dirty *yaml3.Node, index int) string {
+	clean, _, err := cleanPlaybookContext(ctx, dirty)
+	if err != nil {
+		return ""
+	}
+	diff, err := DiffPlay(dirty, clean, index)
+	if err != nil {
+		Logger(ctx).Debug("could not create diff", slog.Int("play", index), slog.Any("error", err))
+		return ""
+	}
+	return diff
+}
+
+// isSignedDocument reports whether any play of the document carries a signature.
+func isSignedDocument(plays []*yaml3.Node) bool {
+	for i := range plays {
+		if _, ok := getPlaybookVariable(plays[i], "insights_signature"); ok {
+			return true
+		}
+	}
+	return false
+}
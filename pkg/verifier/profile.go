@@ -0,0 +1,126 @@
+package verifier
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Serialization profiles are the ways releases of insights-core serialized the plays.
+//
+// Plays signed by older releases only verify when their profile is enabled by the policy.
+const (
+	// ProfilePython3 is the serialization of insights-core running on Python 3.
+	ProfilePython3 = "python3"
+	// ProfilePython2 is the serialization of insights-core running on Python 2 (e.g. on RHEL 7).
+	// Strings with non-ASCII characters are unicode objects, whose repr() has the 'u' prefix
+	// and escapes every non-ASCII character; integers outside the range of 64 bits are longs,
+	// whose repr() has the 'L' suffix.
+	ProfilePython2 = "python2"
+)
+
+// DefaultSerializationProfile is the profile of plays when the policy does not enable any.
+const DefaultSerializationProfile = ProfilePython3
+
+// serializer marshals plays the way a serialization profile does.
+type serializer struct {
+	python2 bool
+}
+
+// serializers maps the names of the serialization profiles to their serializers.
+var serializers = map[string]serializer{
+	ProfilePython3: {},
+	ProfilePython2: {python2: true},
+}
+
+// SerializationProfiles returns the names of the serialization profiles.
+func SerializationProfiles() []string {
+	var names []string
+	for name := range serializers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// lookupSerializer returns the serializer of the profile.
+func lookupSerializer(profile string) (serializer, error) {
+	s, ok := serializers[profile]
+	if !ok {
+		return serializer{}, PlaybookError{ErrUnsupportedType, fmt.Sprintf("unsupported serialization profile '%s'", profile), nil}
+	}
+	return s, nil
+}
+
+// policyProfiles returns the serialization profiles enabled by the policy, the default one first.
+func policyProfiles(policy Policy) []string {
+	if len(policy.SerializationProfiles) == 0 {
+		return []string{DefaultSerializationProfile}
+	}
+	return policy.SerializationProfiles
+}
+
+// writeString quotes the string the same way repr() of the profile's Python does.
+func (s serializer) writeString(b serialWriter, str string) {
+	if s.python2 && !isASCII(str) {
+		b.WriteByte('u')
+		writeUnicodePython2(b, str)
+		return
+	}
+	writeString(b, str)
+}
+
+// formatBigInt formats the integer beyond the range of int64, which is a long on Python 2.
+func (s serializer) formatBigInt(n *big.Int) string {
+	if s.python2 {
+		return n.String() + "L"
+	}
+	return n.String()
+}
+
+// writeUnicodePython2 quotes the string the same way Python 2's repr() of unicode does.
+//
+// The quotes are selected like in Python 3, but every character outside of printable
+// ASCII is escaped.
+func writeUnicodePython2(b serialWriter, s string) {
+	quote := '\''
+	if strings.ContainsRune(s, '\'') && !strings.ContainsRune(s, '"') {
+		quote = '"'
+	}
+
+	b.WriteRune(quote)
+	for _, r := range s {
+		switch {
+		case r == quote || r == '\\':
+			b.WriteRune('\\')
+			b.WriteRune(r)
+		case r == '\t':
+			b.WriteString(`\t`)
+		case r == '\n':
+			b.WriteString(`\n`)
+		case r == '\r':
+			b.WriteString(`\r`)
+		case r >= ' ' && r < 0x7f:
+			b.WriteRune(r)
+		case r <= 0xff:
+			fmt.Fprintf(b, `\x%02x`, r)
+		case r <= 0xffff:
+			fmt.Fprintf(b, `\u%04x`, r)
+		default:
+			fmt.Fprintf(b, `\U%08x`, r)
+		}
+	}
+	b.WriteRune(quote)
+}
+
+// isASCII reports whether the string consists of ASCII characters only.
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
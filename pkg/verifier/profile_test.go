@@ -0,0 +1,77 @@
+package verifier
+
+import (
+	"encoding/base64"
+	"errors"
+	"math"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+// Expected values were produced by Python 2.7's repr().
+func TestMarshallPlaybookProfilePython2(t *testing.T) {
+	tests := []struct {
+		item any
+		want string
+	}{
+		{"ascii", "'ascii'"},
+		{"it's", `"it's"`},
+		{"café", `u'caf\xe9'`},
+		{"€ and 'x'", `u"\u20ac and 'x'"`},
+		{"\U0001f600\n", `u'\U0001f600\n'`},
+		{new(big.Int).SetUint64(math.MaxUint64), "18446744073709551615L"},
+		{new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(-1), 63), big.NewInt(1)), "-9223372036854775809L"},
+		{int64(math.MaxInt64), "9223372036854775807"},
+		{Binary("it's \xc3\xa9"), `"it's \xc3\xa9"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			got, err := serializers[ProfilePython2].marshallScalar(tt.item)
+			if err != nil {
+				t.Fatalf("marshallScalar(%v) error = %v", tt.item, err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("marshallScalar(%v) = %s, want %s", tt.item, got, tt.want)
+			}
+		})
+	}
+
+	want := `ordereddict([(u'\u043a\u043b\u044e\u0447', [1, u'\xe9'])])`
+	got, err := MarshallPlaybookProfile(parsePlay(t, "{ключ: [1, é]}"), ProfilePython2)
+	if err != nil || string(got) != want {
+		t.Errorf("MarshallPlaybookProfile() = %s, %v, want %s", got, err, want)
+	}
+}
+
+func TestVerifyPlaybookSerializationProfiles(t *testing.T) {
+	playbook := "- name: Vérifier\n  vars:\n    insights_signature_exclude: /vars/insights_signature\n    insights_signature: PLACEHOLDER\n"
+	plays, err := UnmarshalPlaybook([]byte(playbook))
+	if err != nil {
+		t.Fatalf("UnmarshalPlaybook() error = %v", err)
+	}
+	clean, err := CleanPlaybook(plays[0])
+	if err != nil {
+		t.Fatalf("CleanPlaybook() error = %v", err)
+	}
+	serialized, err := MarshallPlaybookProfile(clean, ProfilePython2)
+	if err != nil {
+		t.Fatalf("MarshallPlaybookProfile() error = %v", err)
+	}
+	signature, err := testSigner(t).Sign(Hash(serialized))
+	if err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+	signed := []byte(strings.Replace(playbook, "PLACEHOLDER", base64.StdEncoding.EncodeToString(signature), 1))
+
+	if _, err = VerifyPlaybook(signed, testKeyring(t), Policy{}); !errors.Is(err, ErrDigestMismatch) {
+		t.Errorf("VerifyPlaybook() error = %v, want %v", err, ErrDigestMismatch)
+	}
+	report, err := VerifyPlaybook(signed, testKeyring(t), Policy{SerializationProfiles: []string{ProfilePython3, ProfilePython2}})
+	if err != nil {
+		t.Fatalf("VerifyPlaybook() error = %v", err)
+	}
+	if profile := report.Plays[0].SerializationProfile; profile != ProfilePython2 {
+		t.Errorf("VerifyPlaybook() profile = %s, want %s", profile, ProfilePython2)
+	}
+}
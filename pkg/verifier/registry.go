@@ -0,0 +1,53 @@
+package verifier
+
+import (
+	"sort"
+	"sync"
+)
+
+// registry maps names to the extensions registered under them, e.g. the handlers of content types.
+//
+// It is safe for concurrent use, so extensions can be registered while payloads are verified.
+type registry[T any] struct {
+	mu      sync.RWMutex
+	entries map[string]T
+}
+
+// newRegistry returns a registry of the built-in extensions.
+func newRegistry[T any](entries map[string]T) *registry[T] {
+	return &registry[T]{entries: entries}
+}
+
+// register adds the extension under the name, replacing the previous one.
+func (r *registry[T]) register(name string, entry T) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries[name] = entry
+}
+
+// unregister removes the extension registered under the name.
+func (r *registry[T]) unregister(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.entries, name)
+}
+
+// lookup returns the extension registered under the name.
+func (r *registry[T]) lookup(name string) (T, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	entry, ok := r.entries[name]
+	return entry, ok
+}
+
+// names returns the sorted names of the registered extensions.
+func (r *registry[T]) names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.entries))
+	for name := range r.entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,158 @@
+package verifier
+
+import (
+	"fmt"
+	"math/big"
+	"slices"
+
+	yaml3 "gopkg.in/yaml.v3"
+)
+
+// Report describes the result of the playbook verification.
+type Report struct {
+	// Verified is true when every play of the playbook has been verified.
+	Verified bool         `json:"verified"`
+	Plays    []PlayReport `json:"plays"`
+	// SkippedDocuments are the indexes of YAML documents that have not been verified
+	// because they are not signed.
+	SkippedDocuments []int `json:"skipped_documents"`
+	// UnsignedDocuments are the indexes of YAML documents without a signature that have been
+	// accepted without being verified, see Policy.Unsigned.
+	UnsignedDocuments []int `json:"unsigned_documents,omitempty"`
+	// Documents are the positions of the YAML documents in the payload, so the verified ones
+	// can be passed on exactly as they were received, see VerifiedPayload.
+	Documents []DocumentRange `json:"documents,omitempty"`
+	// Digest is the hex-encoded digest of the payload verified by a detached signature.
+	Digest string `json:"digest,omitempty"`
+	// KeyID is the ID of the key that created the detached signature.
+	KeyID string `json:"key_id,omitempty"`
+	// TrustLevel is the trust level of the key that created the detached signature, e.g. TrustLevelWarnOnly.
+	TrustLevel string `json:"trust_level,omitempty"`
+	// Signature is the metadata of the detached signature.
+	Signature *SignatureInfo `json:"signature,omitempty"`
+	// Violations are the rules of the policy the payload does not comply with.
+	Violations []Violation `json:"violations,omitempty"`
+	// Error describes why the playbook could not be verified.
+	Error string `json:"error,omitempty"`
+}
+
+// PlayReport describes the result of a single play verification.
+type PlayReport struct {
+	// Index is the position of the play in the playbook.
+	Index int `json:"index"`
+	// Document is the index of the YAML document of the playbook the play is in.
+	Document int `json:"document"`
+	// Name is the value of the 'name' key of the play.
+	Name string `json:"name"`
+	// Tasks is the number of tasks and handlers of the play, including the tasks of blocks.
+	Tasks int `json:"tasks"`
+	// Digest is the hex-encoded digest of the serialized play.
+	Digest string `json:"digest,omitempty"`
+	// HashAlgorithm is the name of the algorithm the digest has been computed with.
+	HashAlgorithm string `json:"hash_algorithm,omitempty"`
+	// KeyID is the ID of the key that signed the play.
+	KeyID string `json:"key_id,omitempty"`
+	// TrustLevel is the trust level of the key that signed the play, e.g. TrustLevelWarnOnly.
+	TrustLevel string `json:"trust_level,omitempty"`
+	// SerializationVersion is the version of the serialization the play has been hashed in.
+	SerializationVersion string `json:"serialization_version,omitempty"`
+	// SerializationProfile is the serialization profile the signature matched.
+	SerializationProfile string `json:"serialization_profile,omitempty"`
+	// Signature is the metadata of the signature of the play, even if it does not match.
+	Signature *SignatureInfo `json:"signature,omitempty"`
+	// Excluded are the paths that have been removed from the play before hashing.
+	Excluded []string `json:"excluded"`
+	// Exclusions are the paths that have been removed along with the types of their values,
+	// so auditors can confirm that only dynamic elements were left out of the signature.
+	Exclusions []ExcludedPath `json:"exclusions"`
+	// Diff is a unified diff between the play and its cleaned form, if requested.
+	Diff     string `json:"diff,omitempty"`
+	Verified bool   `json:"verified"`
+	// Unsigned is set when the play has no signature, but has been accepted without being
+	// verified, see Policy.Unsigned.
+	Unsigned bool `json:"unsigned,omitempty"`
+	// Error describes why the play could not be verified.
+	Error string `json:"error,omitempty"`
+	// Diagnosis explains why the signature of the play could not be verified.
+	Diagnosis *Diagnosis `json:"diagnosis,omitempty"`
+}
+
+// WarnOnlyKeyIDs returns the IDs of the warn-only keys that created the signatures of the verified
+// payload, sorted. The payload is verified, but callers should warn about it.
+func (r Report) WarnOnlyKeyIDs() []string {
+	var keyIDs []string
+	if r.TrustLevel == TrustLevelWarnOnly {
+		keyIDs = append(keyIDs, r.KeyID)
+	}
+	for _, play := range r.Plays {
+		if play.Verified && play.TrustLevel == TrustLevelWarnOnly {
+			keyIDs = append(keyIDs, play.KeyID)
+		}
+	}
+	slices.Sort(keyIDs)
+	return slices.Compact(keyIDs)
+}
+
+// HasUnsigned reports whether the payload contains plays or documents without a signature that
+// have been accepted without being verified, see Policy.Unsigned.
+func (r Report) HasUnsigned() bool {
+	return len(r.UnsignedDocuments) > 0 || slices.ContainsFunc(r.Plays, func(play PlayReport) bool { return play.Unsigned })
+}
+
+// ExcludedPath is an element that has been removed from the play before hashing.
+type ExcludedPath struct {
+	// Path is the path of the element, e.g. '/vars/insights_signature'.
+	Path string `json:"path"`
+	// Type is the YAML type of the removed value: 'mapping', 'sequence', 'string',
+	// 'integer', 'float', 'boolean' or 'null'.
+	Type string `json:"type"`
+}
+
+// excludedPaths returns the paths of the excluded elements.
+func excludedPaths(exclusions []ExcludedPath) []string {
+	paths := []string{}
+	for _, exclusion := range exclusions {
+		paths = append(paths, exclusion.Path)
+	}
+	return paths
+}
+
+// yamlTypeName returns the YAML type of the parsed node.
+func yamlTypeName(node *yaml3.Node) string {
+	if node == nil {
+		return "null"
+	}
+	switch node.Kind {
+	case yaml3.MappingNode:
+		return "mapping"
+	case yaml3.SequenceNode:
+		return "sequence"
+	case yaml3.AliasNode:
+		return "alias"
+	}
+	switch value := scalarValue(node).(type) {
+	case string:
+		return "string"
+	case int64, *big.Int:
+		return "integer"
+	case float64:
+		return "float"
+	case bool:
+		return "boolean"
+	case Timestamp:
+		return "timestamp"
+	case Binary:
+		return "binary"
+	case nil:
+		return "null"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
+
+// getPlayName returns the name of the play, or an empty string if it has none.
+func getPlayName(p *yaml3.Node) string {
+	value, _ := mappingValue(p, "name")
+	name, _ := scalarValue(value).(string)
+	return name
+}
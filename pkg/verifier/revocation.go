@@ -0,0 +1,75 @@
+package verifier
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+)
+
+// ParseRevocationList returns the key fingerprints listed in the revocation list.
+//
+// The list contains one fingerprint or long key ID per line. Empty lines and lines
+// starting with '#' are ignored.
+func ParseRevocationList(content []byte) []string {
+	var fingerprints []string
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fingerprints = append(fingerprints, line)
+	}
+	return fingerprints
+}
+
+// Revoke makes the keyring refuse signatures created by the keys.
+//
+// The keys are identified by the fingerprint or the long key ID of their primary key,
+// or certificates by their SHA-256 fingerprint, in hexadecimal; spaces are ignored.
+// Revoked keys do not need to be part of the keyring.
+func (k *Keyring) Revoke(fingerprints ...string) error {
+	for _, fingerprint := range fingerprints {
+		normalized, err := normalizeFingerprint(fingerprint)
+		if err != nil {
+			return err
+		}
+		k.revoked = append(k.revoked, normalized)
+	}
+	return nil
+}
+
+// normalizeFingerprint returns the fingerprint or key ID in upper case without spaces.
+func normalizeFingerprint(fingerprint string) (string, error) {
+	normalized := strings.ToUpper(strings.ReplaceAll(fingerprint, " ", ""))
+	if _, err := hex.DecodeString(normalized); err != nil || (len(normalized) != 16 && len(normalized) != 40 && len(normalized) != 64) {
+		return "", VerificationError{ErrInvalidKey, fmt.Sprintf("invalid fingerprint '%s'", fingerprint), err}
+	}
+	return normalized, nil
+}
+
+// Revoked returns the fingerprints and key IDs of the revoked keys.
+func (k *Keyring) Revoked() []string {
+	return append([]string(nil), k.revoked...)
+}
+
+// revokedFingerprint returns the fingerprint of the entity if it has been revoked.
+func (k *Keyring) revokedFingerprint(entity *openpgp.Entity) (string, bool) {
+	fingerprint := strings.ToUpper(hex.EncodeToString(entity.PrimaryKey.Fingerprint))
+	for _, revoked := range k.revoked {
+		if strings.HasSuffix(fingerprint, revoked) {
+			return fingerprint, true
+		}
+	}
+	return "", false
+}
+
+// isRevokedKeyID reports whether the key of the ID has been revoked.
+func (k *Keyring) isRevokedKeyID(keyID string) bool {
+	return slices.Contains(k.revoked, keyID)
+}
@@ -0,0 +1,52 @@
+package verifier
+
+import (
+	"errors"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestParseRevocationList(t *testing.T) {
+	content := "# compromised in 2024\n\nF1384BE3CDF0D33E73A1F3F708D82F6981A5FD2F\n  08D82F6981A5FD2F  \n"
+	want := []string{"F1384BE3CDF0D33E73A1F3F708D82F6981A5FD2F", "08D82F6981A5FD2F"}
+	if got := ParseRevocationList([]byte(content)); !slices.Equal(got, want) {
+		t.Errorf("ParseRevocationList() = %v, want %v", got, want)
+	}
+}
+
+func TestKeyringRevoke(t *testing.T) {
+	playbook := readTestdata(t, filepath.Join("testdata", "golden", "cve-update.yml"))
+
+	tests := []struct {
+		name    string
+		revoked []string
+		want    error
+	}{
+		{"not revoked", []string{"E544FCAF4E2D23F9"}, nil},
+		{"fingerprint", []string{"F1384BE3CDF0D33E73A1F3F708D82F6981A5FD2F"}, ErrRevokedKey},
+		{"spaced fingerprint", []string{"F138 4BE3 CDF0 D33E 73A1  F3F7 08D8 2F69 81A5 FD2F"}, ErrRevokedKey},
+		{"key ID", []string{"08d82f6981a5fd2f"}, ErrRevokedKey},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keyring := testKeyring(t)
+			if err := keyring.Revoke(tt.revoked...); err != nil {
+				t.Fatalf("Revoke() error = %v", err)
+			}
+			_, err := VerifyPlaybook(playbook, keyring, Policy{})
+			if !errors.Is(err, tt.want) {
+				t.Errorf("VerifyPlaybook() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestKeyringRevokeInvalid(t *testing.T) {
+	for _, fingerprint := range []string{"nonsense", "08D82F69", "F1384BE3CDF0D33E73A1F3F708D82F6981A5FD2F00"} {
+		if err := testKeyring(t).Revoke(fingerprint); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("Revoke(%s) error = %v, want %v", fingerprint, err, ErrInvalidKey)
+		}
+	}
+}
@@ -0,0 +1,218 @@
+package verifier
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	yaml3 "gopkg.in/yaml.v3"
+)
+
+// Rule is an allow or deny rule of the policy file, evaluated once the signatures are verified.
+//
+// A payload violating any rule that applies to it is refused, even if it is signed.
+// Unset constraints are not checked.
+type Rule struct {
+	// Name identifies the rule in the report.
+	Name string `yaml:"name"`
+	// Sources are the content sources the rule applies to, see Policy.ContentSource.
+	// Empty means every source.
+	Sources []string `yaml:"sources"`
+	// RequireKeyIDs are the keys one of which has to sign the payload, by key ID or fingerprint.
+	RequireKeyIDs []string `yaml:"require_key_ids"`
+	// AllowHosts are the only host patterns the plays may target.
+	AllowHosts []string `yaml:"allow_hosts"`
+	// DenyHosts are the host patterns the plays may not target, e.g. 'all'.
+	DenyHosts []string `yaml:"deny_hosts"`
+	// MaxTasks is the maximal number of tasks and handlers of a play, including the tasks of blocks.
+	// Zero means no limit.
+	MaxTasks int `yaml:"max_tasks"`
+}
+
+// Violation describes a rule of the policy the payload does not comply with.
+type Violation struct {
+	// Rule is the name of the violated rule.
+	Rule string `json:"rule"`
+	// Play is the index of the play violating the rule, or -1 if it is the whole payload.
+	Play    int    `json:"play"`
+	Message string `json:"message"`
+}
+
+// rulesFile is the format of the policy file.
+type rulesFile struct {
+	Rules []Rule `yaml:"rules"`
+}
+
+// ParseRules parses the rules of the YAML policy file.
+//
+// The file is a mapping with the list 'rules', e.g.:
+//
+//	rules:
+//	  - name: signed-by-red-hat
+//	    sources: [insights]
+//	    require_key_ids: [199E2F91FD431D51]
+//	  - name: no-hosts-all
+//	    deny_hosts: [all, "*"]
+//	  - name: small-plays
+//	    max_tasks: 50
+//
+// Unknown keys are refused, so a misspelled constraint cannot silently allow everything.
+func ParseRules(content []byte) ([]Rule, error) {
+	decoder := yaml3.NewDecoder(bytes.NewReader(content))
+	decoder.KnownFields(true)
+	var file rulesFile
+	if err := decoder.Decode(&file); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("could not parse rules: %w", err)
+	}
+
+	names := map[string]bool{}
+	for i, rule := range file.Rules {
+		switch {
+		case rule.Name == "":
+			return nil, fmt.Errorf("rule %d has no name", i)
+		case names[rule.Name]:
+			return nil, fmt.Errorf("rule %s is defined twice", rule.Name)
+		case rule.MaxTasks < 0:
+			return nil, fmt.Errorf("rule %s has a negative task limit", rule.Name)
+		case len(rule.RequireKeyIDs) == 0 && len(rule.AllowHosts) == 0 && len(rule.DenyHosts) == 0 && rule.MaxTasks == 0:
+			return nil, fmt.Errorf("rule %s has no constraint", rule.Name)
+		}
+		names[rule.Name] = true
+	}
+	return file.Rules, nil
+}
+
+// applies reports whether the rule applies to payloads of the content source.
+func (r Rule) applies(source string) bool {
+	return len(r.Sources) == 0 || slices.Contains(r.Sources, source)
+}
+
+// checkKey returns the violations of the rules by the key that signed the payload or the play.
+func checkKey(policy Policy, play int, keyID string, signature *SignatureInfo) []Violation {
+	var violations []Violation
+	for _, rule := range policy.Rules {
+		if !rule.applies(policy.ContentSource) || len(rule.RequireKeyIDs) == 0 {
+			continue
+		}
+		matches := func(required string) bool {
+			required = strings.ToUpper(strings.ReplaceAll(required, " ", ""))
+			return required == strings.ToUpper(keyID) || (signature != nil && required == strings.ToUpper(signature.Fingerprint))
+		}
+		if !slices.ContainsFunc(rule.RequireKeyIDs, matches) {
+			violations = append(violations, Violation{rule.Name, play, fmt.Sprintf("signed by key %s, which is not required", keyID)})
+		}
+	}
+	return violations
+}
+
+// checkPlay returns the violations of the rules by the content of the play.
+//
+// The play is the one that has been parsed, before the excluded paths (e.g. 'hosts') were removed,
+// as that is what Ansible runs.
+func checkPlay(policy Policy, index int, play *yaml3.Node) []Violation {
+	var violations []Violation
+	hosts := playHosts(play)
+	tasks := countTasks(play)
+	for _, rule := range policy.Rules {
+		if !rule.applies(policy.ContentSource) {
+			continue
+		}
+		for _, host := range hosts {
+			if slices.Contains(rule.DenyHosts, host) {
+				violations = append(violations, Violation{rule.Name, index, fmt.Sprintf("targets denied hosts '%s'", host)})
+			}
+			if len(rule.AllowHosts) > 0 && !slices.Contains(rule.AllowHosts, host) {
+				violations = append(violations, Violation{rule.Name, index, fmt.Sprintf("targets hosts '%s', which are not allowed", host)})
+			}
+		}
+		if rule.MaxTasks > 0 && tasks > rule.MaxTasks {
+			violations = append(violations, Violation{rule.Name, index, fmt.Sprintf("has %d tasks, more than %d", tasks, rule.MaxTasks)})
+		}
+	}
+	return violations
+}
+
+// violationsError returns the error of the violations, or nil if there are none.
+func violationsError(violations []Violation) error {
+	if len(violations) == 0 {
+		return nil
+	}
+	var messages []string
+	for _, violation := range violations {
+		messages = append(messages, fmt.Sprintf("%s: %s", violation.Rule, violation.Message))
+	}
+	return PolicyError{ErrPolicyViolation, strings.Join(messages, "; "), nil}
+}
+
+// playHosts returns the host patterns the play targets. Patterns are separated by commas,
+// or by colons in the syntax older Ansible releases use, or listed in a sequence.
+func playHosts(play *yaml3.Node) []string {
+	value, ok := mappingValue(play, "hosts")
+	if !ok {
+		return nil
+	}
+
+	var patterns []string
+	switch {
+	case value.Kind == yaml3.SequenceNode:
+		for _, pattern := range value.Content {
+			patterns = append(patterns, pathSegment(pattern))
+		}
+	case value.Kind != yaml3.ScalarNode:
+		patterns = append(patterns, pathSegment(value))
+	default:
+		switch value := scalarValue(value).(type) {
+		case string:
+			patterns = strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == ':' })
+		case nil:
+		default:
+			patterns = append(patterns, fmt.Sprint(value))
+		}
+	}
+	for i := range patterns {
+		patterns[i] = strings.TrimSpace(patterns[i])
+	}
+	return slices.DeleteFunc(patterns, func(pattern string) bool { return pattern == "" })
+}
+
+// countTasks returns the number of tasks and handlers of the play. Blocks are not tasks
+// of their own, the tasks of their 'block', 'rescue' and 'always' sections are counted instead.
+func countTasks(play *yaml3.Node) int {
+	count := 0
+	for i := 0; i+1 < len(play.Content); i += 2 {
+		switch pathSegment(play.Content[i]) {
+		case "tasks", "pre_tasks", "post_tasks", "handlers":
+			count += countTaskList(play.Content[i+1])
+		}
+	}
+	return count
+}
+
+// countTaskList returns the number of tasks of the list, descending into blocks.
+func countTaskList(tasks *yaml3.Node) int {
+	if tasks.Kind != yaml3.SequenceNode {
+		return 0
+	}
+	count := 0
+	for _, task := range tasks.Content {
+		if task.Kind != yaml3.MappingNode {
+			count++
+			continue
+		}
+		block := false
+		for i := 0; i+1 < len(task.Content); i += 2 {
+			switch pathSegment(task.Content[i]) {
+			case "block", "rescue", "always":
+				block = true
+				count += countTaskList(task.Content[i+1])
+			}
+		}
+		if !block {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,101 @@
+package verifier
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseRules(t *testing.T) {
+	rules, err := ParseRules([]byte("rules:\n  - name: no-all\n    sources: [insights]\n    deny_hosts: [all]\n  - name: small\n    max_tasks: 5\n"))
+	if err != nil {
+		t.Fatalf("ParseRules() error = %v", err)
+	}
+	if len(rules) != 2 || rules[0].Name != "no-all" || rules[0].Sources[0] != "insights" || rules[1].MaxTasks != 5 {
+		t.Errorf("ParseRules() = %+v", rules)
+	}
+
+	for name, content := range map[string]string{
+		"unknown key":   "rules:\n  - name: typo\n    deny_host: [all]\n",
+		"no name":       "rules:\n  - deny_hosts: [all]\n",
+		"duplicate":     "rules:\n  - name: a\n    max_tasks: 1\n  - name: a\n    max_tasks: 2\n",
+		"no constraint": "rules:\n  - name: empty\n",
+		"negative":      "rules:\n  - name: negative\n    max_tasks: -1\n",
+	} {
+		if _, err = ParseRules([]byte(content)); err == nil {
+			t.Errorf("ParseRules(%s) error = nil, want error", name)
+		}
+	}
+}
+
+func TestVerifyPlaybookRules(t *testing.T) {
+	golden := readTestdata(t, filepath.Join("testdata", "golden", "cve-update.yml"))
+	// The hosts are excluded from the signature, the rules see the ones that are run.
+	allHosts := bytes.Replace(golden, []byte("@@HOSTS@@"), []byte("all"), 1)
+	someHosts := bytes.Replace(golden, []byte("@@HOSTS@@"), []byte("web1,web2"), 1)
+
+	tests := []struct {
+		name       string
+		playbook   []byte
+		rules      []Rule
+		source     string
+		violations int
+	}{
+		{"required key", golden, []Rule{{Name: "key", RequireKeyIDs: []string{"08d82f6981a5fd2f"}}}, "", 0},
+		{"required fingerprint", golden, []Rule{{Name: "key", RequireKeyIDs: []string{"F138 4BE3 CDF0 D33E 73A1 F3F7 08D8 2F69 81A5 FD2F"}}}, "", 0},
+		{"other key", golden, []Rule{{Name: "key", RequireKeyIDs: []string{"E544FCAF4E2D23F9"}}}, "", 1},
+		{"denied hosts", allHosts, []Rule{{Name: "hosts", DenyHosts: []string{"all"}}}, "", 1},
+		{"allowed hosts", someHosts, []Rule{{Name: "hosts", AllowHosts: []string{"web1", "web2"}}}, "", 0},
+		{"not allowed hosts", someHosts, []Rule{{Name: "hosts", AllowHosts: []string{"web1"}}}, "", 1},
+		{"tasks", golden, []Rule{{Name: "tasks", MaxTasks: 2}}, "", 0},
+		{"too many tasks", golden, []Rule{{Name: "tasks", MaxTasks: 1}}, "", 1},
+		{"other source", allHosts, []Rule{{Name: "hosts", Sources: []string{"satellite"}, DenyHosts: []string{"all"}}}, "insights", 0},
+		{"matching source", allHosts, []Rule{{Name: "hosts", Sources: []string{"insights"}, DenyHosts: []string{"all"}}}, "insights", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report, err := VerifyPlaybook(tt.playbook, testKeyring(t), Policy{Rules: tt.rules, ContentSource: tt.source})
+			if len(report.Violations) != tt.violations {
+				t.Fatalf("violations = %+v, want %d", report.Violations, tt.violations)
+			}
+			if tt.violations == 0 {
+				if err != nil || !report.Verified {
+					t.Errorf("VerifyPlaybook() = %v, %v, want verified", report.Verified, err)
+				}
+				return
+			}
+			var policyError PolicyError
+			if !errors.As(err, &policyError) || !errors.Is(err, ErrPolicyViolation) || report.Verified || report.Plays[0].Verified {
+				t.Errorf("VerifyPlaybook() = %v, %v, want policy error", report.Verified, err)
+			}
+			if report.Violations[0].Rule != tt.rules[0].Name || report.Violations[0].Play != 0 {
+				t.Errorf("violation = %+v", report.Violations[0])
+			}
+		})
+	}
+}
+
+func TestCountTasks(t *testing.T) {
+	plays, err := NewPlaybookDecoder(bytes.NewReader([]byte(`
+- hosts: all
+  pre_tasks:
+    - ping:
+  tasks:
+    - block:
+        - ping:
+        - ping:
+      rescue:
+        - ping:
+    - ping:
+  handlers:
+    - name: restart
+      service: {name: sshd, state: restarted}
+`))).Decode()
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if got := countTasks(plays[0]); got != 6 {
+		t.Errorf("countTasks() = %d, want 6", got)
+	}
+}
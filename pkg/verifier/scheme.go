@@ -0,0 +1,117 @@
+package verifier
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+)
+
+// Names of the signature schemes.
+const (
+	SchemeOpenPGP  = "openpgp"
+	SchemeMinisign = "minisign"
+	SchemeSignify  = "signify"
+	SchemeSigstore = "sigstore"
+	SchemePKCS7    = "pkcs7"
+)
+
+// signatureScheme verifies signatures of a single format.
+type signatureScheme interface {
+	// detect reports whether the signature is in the format of the scheme.
+	detect(signature []byte) bool
+	// verify checks that the signature of the message has been created by one of the keys
+	// of the keyring and returns the ID of that key. Details of the verification are logged to the logger.
+	verify(logger *slog.Logger, keyring *Keyring, message []byte, signature []byte) (string, error)
+}
+
+// signatureSchemes are detected in order. OpenPGP, the original scheme, comes last,
+// so signatures without a marker of another scheme are treated as OpenPGP signatures.
+var signatureSchemes = []signatureScheme{ed25519Scheme{}, sigstoreScheme{}, pkcs7Scheme{}, openpgpScheme{}}
+
+// detectScheme returns the scheme the signature has been created with.
+func detectScheme(signature []byte) signatureScheme {
+	for _, scheme := range signatureSchemes {
+		if scheme.detect(signature) {
+			return scheme
+		}
+	}
+	return openpgpScheme{}
+}
+
+// openpgpScheme verifies ASCII-armored or binary OpenPGP signatures.
+type openpgpScheme struct{}
+
+func (openpgpScheme) detect(signature []byte) bool {
+	return true
+}
+
+func (openpgpScheme) verify(logger *slog.Logger, k *Keyring, message []byte, signature []byte) (string, error) {
+	var lastErr error
+	for _, entity := range k.entities {
+		var err error
+		if k.openpgpBackend != nil {
+			err = checkWithBackend(k.openpgpBackend, entity, message, signature)
+		} else {
+			err = checkDetachedSignature(openpgp.EntityList{entity}, message, signature)
+		}
+		if err != nil {
+			logger.Debug("key did not verify signature", slog.String("key", entity.PrimaryKey.KeyIdString()), slog.Any("error", err))
+			lastErr = err
+			continue
+		}
+		if fingerprint, revoked := k.revokedFingerprint(entity); revoked {
+			return "", VerificationError{ErrRevokedKey, fmt.Sprintf("signature was created by revoked key %s", fingerprint), nil}
+		}
+		return entity.PrimaryKey.KeyIdString(), nil
+	}
+	return "", VerificationError{ErrDigestMismatch, "signature does not match", lastErr}
+}
+
+// checkDetachedSignature verifies either ASCII-armored or binary signature.
+func checkDetachedSignature(keyring openpgp.KeyRing, message []byte, signature []byte) error {
+	var err error
+	if bytes.HasPrefix(signature, []byte("-----BEGIN PGP SIGNATURE-----")) {
+		_, err = openpgp.CheckArmoredDetachedSignature(keyring, bytes.NewReader(message), bytes.NewReader(signature), nil)
+	} else {
+		_, err = openpgp.CheckDetachedSignature(keyring, bytes.NewReader(message), bytes.NewReader(signature), nil)
+	}
+	return err
+}
+
+// checkWithBackend verifies the signature with the external backend, against the entity alone.
+func checkWithBackend(backend OpenPGPBackend, entity *openpgp.Entity, message []byte, signature []byte) error {
+	var publicKey bytes.Buffer
+	if err := entity.Serialize(&publicKey); err != nil {
+		return fmt.Errorf("could not serialize public key: %w", err)
+	}
+	return backend(publicKey.Bytes(), message, signature)
+}
+
+// ed25519Scheme verifies Ed25519 signatures in the format of minisign or signify.
+type ed25519Scheme struct{}
+
+func (ed25519Scheme) detect(signature []byte) bool {
+	return isMinisignData(signature)
+}
+
+func (ed25519Scheme) verify(_ *slog.Logger, k *Keyring, message []byte, signature []byte) (string, error) {
+	sig, err := parseMinisignSignature(signature)
+	if err != nil {
+		return "", err
+	}
+	for _, key := range k.ed25519Keys {
+		if key.id != sig.keyID {
+			continue
+		}
+		if err = sig.verify(key, message); err != nil {
+			return "", err
+		}
+		if k.isRevokedKeyID(key.keyID()) {
+			return "", VerificationError{ErrRevokedKey, fmt.Sprintf("signature was created by revoked key %s", key.keyID()), nil}
+		}
+		return key.keyID(), nil
+	}
+	return "", VerificationError{ErrDigestMismatch, fmt.Sprintf("signature was created by unknown key %s", sig.keyIDString()), nil}
+}
@@ -0,0 +1,267 @@
+package verifier
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+	"unicode"
+
+	yaml3 "gopkg.in/yaml.v3"
+)
+
+// MarshallPlaybook takes in the playbook and marshals it into a string
+// as per the requirements of the hashing scheme.
+func MarshallPlaybook(p *yaml3.Node) ([]byte, error) {
+	return MarshallPlaybookProfile(p, DefaultSerializationProfile)
+}
+
+// MarshallPlaybookProfile is like MarshallPlaybook, but marshals the playbook the way
+// the serialization profile does.
+func MarshallPlaybookProfile(p *yaml3.Node, profile string) ([]byte, error) {
+	s, err := lookupSerializer(profile)
+	if err != nil {
+		return nil, err
+	}
+	return s.marshallItem(p)
+}
+
+// WritePlaybook writes the playbook marshalled as per MarshallPlaybook into the writer,
+// e.g. a hash.Hash, without holding the whole marshalled playbook in memory.
+func WritePlaybook(w io.Writer, p *yaml3.Node) error {
+	return WritePlaybookProfile(w, p, DefaultSerializationProfile)
+}
+
+// WritePlaybookProfile is like WritePlaybook, but marshals the playbook the way
+// the serialization profile does.
+func WritePlaybookProfile(w io.Writer, p *yaml3.Node, profile string) error {
+	s, err := lookupSerializer(profile)
+	if err != nil {
+		return err
+	}
+	b := bufio.NewWriter(w)
+	if err = s.writeItem(b, p); err != nil {
+		return err
+	}
+	return b.Flush()
+}
+
+// serialWriter is what the serializer writes to: a bytes.Buffer when the play is marshalled
+// in memory, or a bufio.Writer when it is streamed, whose errors are returned by Flush.
+type serialWriter interface {
+	io.Writer
+	io.ByteWriter
+	io.StringWriter
+	WriteRune(r rune) (int, error)
+	AvailableBuffer() []byte
+}
+
+// marshallPlaybookScalar marshals the scalar value the way the default profile does.
+func marshallPlaybookScalar(value any) ([]byte, error) {
+	return serializer{}.marshallScalar(value)
+}
+
+// marshallItem marshals the node into a new buffer.
+func (s serializer) marshallItem(item *yaml3.Node) ([]byte, error) {
+	var b bytes.Buffer
+	if err := s.writeItem(&b, item); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// marshallScalar marshals the value constructed from a scalar, see constructScalar.
+func (s serializer) marshallScalar(item any) ([]byte, error) {
+	var b bytes.Buffer
+	if err := s.writeScalar(&b, item); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// writeItem writes the node to the writer. The whole play is written to a single writer,
+// so large plays are not copied again on every level of nesting.
+func (s serializer) writeItem(b serialWriter, item *yaml3.Node) error {
+	switch item.Kind {
+	case yaml3.MappingNode:
+		return s.writeMap(b, item)
+	case yaml3.SequenceNode:
+		return s.writeList(b, item)
+	case yaml3.ScalarNode:
+		value, err := constructScalar(item)
+		if err != nil {
+			return err
+		}
+		return s.writeScalar(b, value)
+	default:
+		return PlaybookError{ErrUnsupportedType, fmt.Sprintf("cannot serialize node of kind %d at line %d, resolve its aliases first", item.Kind, item.Line), nil}
+	}
+}
+
+// writeScalar writes the value constructed from a scalar, see constructScalar.
+func (s serializer) writeScalar(b serialWriter, item any) error {
+	switch v := item.(type) {
+	case nil:
+		b.WriteString("None")
+	case bool:
+		if v {
+			b.WriteString("True")
+		} else {
+			b.WriteString("False")
+		}
+	case string:
+		s.writeString(b, v)
+	case int64:
+		b.Write(strconv.AppendInt(b.AvailableBuffer(), v, 10))
+	case *big.Int:
+		b.WriteString(s.formatBigInt(v))
+	case float64:
+		b.WriteString(formatFloat(v))
+	case Timestamp:
+		b.WriteString(s.formatTimestamp(v))
+	case Binary:
+		b.WriteString(s.formatBinary(v))
+	default:
+		return PlaybookError{ErrUnsupportedType, fmt.Sprintf("cannot serialize value of type %T", item), nil}
+	}
+	return nil
+}
+
+func (s serializer) writeMap(b serialWriter, m *yaml3.Node) error {
+	// repr() of an empty OrderedDict has no list of items
+	if len(m.Content) == 0 {
+		b.WriteString("ordereddict()")
+		return nil
+	}
+	b.WriteString("ordereddict([")
+
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Kind != yaml3.ScalarNode {
+			return PlaybookError{ErrUnsupportedType, fmt.Sprintf("cannot serialize key of type %s at line %d", yamlTypeName(m.Content[i]), m.Content[i].Line), nil}
+		}
+		if i > 0 {
+			b.WriteString(", ")
+		}
+
+		b.WriteByte('(')
+		if err := s.writeItem(b, m.Content[i]); err != nil {
+			return err
+		}
+		b.WriteString(", ")
+		if err := s.writeItem(b, m.Content[i+1]); err != nil {
+			return err
+		}
+		b.WriteByte(')')
+	}
+
+	b.WriteString("])")
+	return nil
+}
+
+func (s serializer) writeList(b serialWriter, l *yaml3.Node) error {
+	b.WriteByte('[')
+
+	for i, item := range l.Content {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		if err := s.writeItem(b, item); err != nil {
+			return err
+		}
+	}
+
+	b.WriteByte(']')
+	return nil
+}
+
+// formatFloat formats the number the same way Python's repr() does.
+//
+// Python uses the shortest representation that round-trips. It switches to scientific
+// notation when the decimal exponent is lower than -4 or at least 16, and always prints
+// the exponent with a sign and at least two digits.
+func formatFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "inf"
+	case math.IsInf(f, -1):
+		return "-inf"
+	case math.IsNaN(f):
+		return "nan"
+	}
+
+	// shortest representation, e.g. '-1.2345e+06'
+	formatted := strconv.FormatFloat(f, 'e', -1, 64)
+	sign := ""
+	if strings.HasPrefix(formatted, "-") {
+		sign = "-"
+		formatted = formatted[1:]
+	}
+	mantissa, rawExponent, _ := strings.Cut(formatted, "e")
+	digits := strings.Replace(mantissa, ".", "", 1)
+	exponent, _ := strconv.Atoi(rawExponent)
+
+	// position of the decimal point relative to the start of the digits
+	point := exponent + 1
+	if point <= -4 || point > 16 {
+		if len(digits) > 1 {
+			digits = digits[:1] + "." + digits[1:]
+		}
+		exponentSign := "+"
+		if exponent < 0 {
+			exponentSign = "-"
+			exponent = -exponent
+		}
+		return fmt.Sprintf("%s%se%s%02d", sign, digits, exponentSign, exponent)
+	}
+
+	switch {
+	case point <= 0:
+		return sign + "0." + strings.Repeat("0", -point) + digits
+	case point >= len(digits):
+		return sign + digits + strings.Repeat("0", point-len(digits)) + ".0"
+	default:
+		return sign + digits[:point] + "." + digits[point:]
+	}
+}
+
+// writeString quotes the string the same way Python 3's repr() does.
+//
+// Single quotes are preferred, double quotes are used when the string contains
+// a single quote but no double quote. Backslashes, the selected quote and
+// non-printable characters are escaped; printable non-ASCII characters are kept.
+func writeString(b serialWriter, s string) {
+	quote := '\''
+	if strings.ContainsRune(s, '\'') && !strings.ContainsRune(s, '"') {
+		quote = '"'
+	}
+
+	b.WriteRune(quote)
+	for _, r := range s {
+		switch {
+		case r == quote || r == '\\':
+			b.WriteRune('\\')
+			b.WriteRune(r)
+		case r == '\t':
+			b.WriteString(`\t`)
+		case r == '\n':
+			b.WriteString(`\n`)
+		case r == '\r':
+			b.WriteString(`\r`)
+		case r < ' ' || r == 0x7f:
+			fmt.Fprintf(b, `\x%02x`, r)
+		case r < 0x7f || unicode.IsPrint(r):
+			b.WriteRune(r)
+		case r <= 0xff:
+			fmt.Fprintf(b, `\x%02x`, r)
+		case r <= 0xffff:
+			fmt.Fprintf(b, `\u%04x`, r)
+		default:
+			fmt.Fprintf(b, `\U%08x`, r)
+		}
+	}
+	b.WriteRune(quote)
+}
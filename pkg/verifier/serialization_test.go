@@ -0,0 +1,318 @@
+package verifier
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"math/rand"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"testing/quick"
+
+	yaml3 "gopkg.in/yaml.v3"
+)
+
+// Expected values were produced by Python's repr().
+func TestMarshallPlaybookItemNumbers(t *testing.T) {
+	tests := []struct {
+		item any
+		want string
+	}{
+		{int64(30), "30"},
+		{int64(-5), "-5"},
+		{int64(0), "0"},
+		{int64(math.MaxInt64), "9223372036854775807"},
+		{new(big.Int).SetUint64(math.MaxUint64), "18446744073709551615"},
+		{1.5, "1.5"},
+		{-2.25, "-2.25"},
+		{30.0, "30.0"},
+		{0.0, "0.0"},
+		{math.Copysign(0, -1), "-0.0"},
+		{0.1, "0.1"},
+		{1.0 / 3, "0.3333333333333333"},
+		{1e15, "1000000000000000.0"},
+		{1e16, "1e+16"},
+		{123456789012345678.0, "1.2345678901234568e+17"},
+		{-1.23e+20, "-1.23e+20"},
+		{0.0001, "0.0001"},
+		{1e-5, "1e-05"},
+		{1.5e-7, "1.5e-07"},
+		{math.Inf(1), "inf"},
+		{math.Inf(-1), "-inf"},
+		{math.NaN(), "nan"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			got, err := marshallPlaybookScalar(tt.item)
+			if err != nil {
+				t.Fatalf("marshallPlaybookScalar(%v) error = %v", tt.item, err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("marshallPlaybookScalar(%v) = %s, want %s", tt.item, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMarshallPlaybookNumbers(t *testing.T) {
+	play := parsePlay(t, "{timeout: 30, retries: -3, delay: 0.5, ratio: 2.5e-06, big: 1.0e+20}")
+
+	want := "ordereddict([('timeout', 30), ('retries', -3), ('delay', 0.5), ('ratio', 2.5e-06), ('big', 1e+20)])"
+	got, err := MarshallPlaybook(play)
+	if err != nil {
+		t.Fatalf("MarshallPlaybook() error = %v", err)
+	}
+	if string(got) != want {
+		t.Errorf("MarshallPlaybook() = %s, want %s", got, want)
+	}
+}
+
+func TestMarshallPlaybookNone(t *testing.T) {
+	playbook := []byte(`- name: Restart insights-client
+  hosts: "@@HOSTS@@"
+  vars:
+    insights_signature_exclude: /hosts,/vars/insights_signature
+    insights_signature: c2lnbmF0dXJl
+    insights_remediation:
+  gather_facts:
+  tasks:
+    - ping:
+    - name: Restart service
+      service:
+        name: insights-client
+        state: restarted
+        enabled: null
+`)
+	want := "ordereddict([('name', 'Restart insights-client'), " +
+		"('vars', ordereddict([('insights_signature_exclude', '/hosts,/vars/insights_signature'), ('insights_remediation', None)])), " +
+		"('gather_facts', None), " +
+		"('tasks', [ordereddict([('ping', None)]), " +
+		"ordereddict([('name', 'Restart service'), ('service', ordereddict([('name', 'insights-client'), ('state', 'restarted'), ('enabled', None)]))])])])"
+
+	plays, err := UnmarshalPlaybook(playbook)
+	if err != nil {
+		t.Fatalf("UnmarshalPlaybook() error = %v", err)
+	}
+	clean, err := CleanPlaybook(plays[0])
+	if err != nil {
+		t.Fatalf("CleanPlaybook() error = %v", err)
+	}
+	got, err := MarshallPlaybook(clean)
+	if err != nil {
+		t.Fatalf("MarshallPlaybook() error = %v", err)
+	}
+	if string(got) != want {
+		t.Errorf("MarshallPlaybook() = %s, want %s", got, want)
+	}
+}
+
+func TestMarshallPlaybookEmptyVars(t *testing.T) {
+	plays, err := UnmarshalPlaybook([]byte("- name: empty\n  vars:\n"))
+	if err != nil {
+		t.Fatalf("UnmarshalPlaybook() error = %v", err)
+	}
+	if _, err = CleanPlaybook(plays[0]); err == nil {
+		t.Errorf("CleanPlaybook() expected error for play without exclusions")
+	}
+
+	want := "ordereddict([('name', 'empty'), ('vars', None)])"
+	got, err := MarshallPlaybook(plays[0])
+	if err != nil {
+		t.Fatalf("MarshallPlaybook() error = %v", err)
+	}
+	if string(got) != want {
+		t.Errorf("MarshallPlaybook() = %s, want %s", got, want)
+	}
+}
+
+// The corpus was generated by Python 3 from a list of inputs: {"input": s, "repr": repr(s)}.
+func TestMarshallPlaybookItemStrings(t *testing.T) {
+	raw, err := os.ReadFile("testdata/python_repr.json")
+	if err != nil {
+		t.Fatalf("could not read corpus: %v", err)
+	}
+	var corpus []struct {
+		Input string `json:"input"`
+		Repr  string `json:"repr"`
+	}
+	if err = json.Unmarshal(raw, &corpus); err != nil {
+		t.Fatalf("could not parse corpus: %v", err)
+	}
+
+	for _, tt := range corpus {
+		t.Run(tt.Repr, func(t *testing.T) {
+			got, err := marshallPlaybookScalar(tt.Input)
+			if err != nil {
+				t.Fatalf("marshallPlaybookScalar(%q) error = %v", tt.Input, err)
+			}
+			if string(got) != tt.Repr {
+				t.Errorf("marshallPlaybookScalar(%q) = %s, want %s", tt.Input, got, tt.Repr)
+			}
+		})
+	}
+}
+
+func TestMarshallPlaybookKeys(t *testing.T) {
+	want := `ordereddict([("it's", 'ok')])`
+	got, err := MarshallPlaybook(parsePlay(t, `{"it's": ok}`))
+	if err != nil {
+		t.Fatalf("MarshallPlaybook() error = %v", err)
+	}
+	if string(got) != want {
+		t.Errorf("MarshallPlaybook() = %s, want %s", got, want)
+	}
+}
+
+// randomPlay is a play of randomly nested mapping, sequence and scalar nodes.
+type randomPlay struct {
+	play *yaml3.Node
+}
+
+// randomStrings are fragments of the strings of randomPlay, which need quoting or escaping in YAML and Python.
+var randomStrings = []string{
+	"plain", "two words", "it's", `say "hi"`, `back\slash`, "tab\there", "line\nbreak", "padded ", " leading",
+	"yes", "null", "~", "1", "1.5", "-", "#", ": colon", "{{ item }}", "[x]", "&anchor", "*alias", "!tag",
+	"café", "日本", "\U0001F600", "",
+}
+
+func (randomPlay) Generate(r *rand.Rand, _ int) reflect.Value {
+	return reflect.ValueOf(randomPlay{randomMapping(r, 4)})
+}
+
+func randomMapping(r *rand.Rand, depth int) *yaml3.Node {
+	m := &yaml3.Node{Kind: yaml3.MappingNode}
+	for i := range r.Intn(4) {
+		// the keys are unique, so the playbook is not refused for duplicate keys
+		key := randomString(fmt.Sprintf("k%d%s", i, randomStrings[r.Intn(len(randomStrings))]))
+		if r.Intn(4) == 0 {
+			key = randomScalar(strconv.Itoa(i))
+		}
+		m.Content = append(m.Content, key, randomValue(r, depth-1))
+	}
+	return m
+}
+
+func randomValue(r *rand.Rand, depth int) *yaml3.Node {
+	kind := r.Intn(9)
+	if depth <= 0 {
+		kind = 2 + r.Intn(7)
+	}
+	switch kind {
+	case 0:
+		return randomMapping(r, depth)
+	case 1:
+		items := &yaml3.Node{Kind: yaml3.SequenceNode}
+		for range r.Intn(4) {
+			items.Content = append(items.Content, randomValue(r, depth-1))
+		}
+		return items
+	case 2:
+		return randomScalar([]string{"~", "null", ""}[r.Intn(3)])
+	case 3:
+		return randomScalar([]string{"yes", "no", "true", "off"}[r.Intn(4)])
+	case 4:
+		return randomScalar(strconv.Itoa(r.Intn(2001) - 1000))
+	case 5:
+		return randomScalar(fmt.Sprintf("%d%d", uint64(math.MaxInt64), r.Intn(1000)))
+	case 6:
+		return randomScalar(fmt.Sprintf("%d.25", r.Intn(2001)-1000))
+	default:
+		var b strings.Builder
+		for range 1 + r.Intn(3) {
+			b.WriteString(randomStrings[r.Intn(len(randomStrings))])
+		}
+		return randomString(b.String())
+	}
+}
+
+// randomScalar is a plain scalar, which is resolved by its value.
+func randomScalar(value string) *yaml3.Node {
+	return &yaml3.Node{Kind: yaml3.ScalarNode, Value: value}
+}
+
+// randomString is a double-quoted scalar, which is always a string.
+func randomString(value string) *yaml3.Node {
+	return &yaml3.Node{Kind: yaml3.ScalarNode, Style: yaml3.DoubleQuotedStyle, Value: value}
+}
+
+// Every shape of nodes, e.g. mappings nested in sequences nested in sequences,
+// is serialized the same way after a round trip through the playbook decoder.
+func TestMarshallPlaybookShapes(t *testing.T) {
+	roundTrip := func(p randomPlay) bool {
+		content, err := yaml3.Marshal(&yaml3.Node{Kind: yaml3.SequenceNode, Content: []*yaml3.Node{p.play}})
+		if err != nil {
+			t.Fatalf("yaml3.Marshal() error = %v", err)
+		}
+		plays, err := UnmarshalPlaybook(content)
+		if err != nil {
+			t.Logf("UnmarshalPlaybook() error = %v\n%s", err, content)
+			return false
+		}
+		for _, profile := range SerializationProfiles() {
+			want, err := MarshallPlaybookProfile(p.play, profile)
+			if err != nil {
+				t.Logf("MarshallPlaybookProfile(%s) error = %v\n%s", profile, err, content)
+				return false
+			}
+			got, err := MarshallPlaybookProfile(plays[0], profile)
+			if err != nil || !bytes.Equal(got, want) {
+				t.Logf("MarshallPlaybookProfile(%s) = %s, %v, want %s\n%s", profile, got, err, want, content)
+				return false
+			}
+		}
+		return true
+	}
+	if err := quick.Check(roundTrip, &quick.Config{MaxCount: 1000}); err != nil {
+		t.Error(err)
+	}
+}
+
+// failingWriter refuses every write.
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) { return 0, errors.New("disk full") }
+
+// The streamed serialization writes the same bytes as the serialization in memory,
+// also when the play is larger than the buffer of the stream.
+func TestWritePlaybook(t *testing.T) {
+	huge, err := UnmarshalPlaybook(hugePlaybook(200))
+	if err != nil {
+		t.Fatalf("UnmarshalPlaybook() error = %v", err)
+	}
+	compare := func(p randomPlay) bool {
+		for _, profile := range SerializationProfiles() {
+			want, err := MarshallPlaybookProfile(p.play, profile)
+			if err != nil {
+				t.Logf("MarshallPlaybookProfile(%s) error = %v", profile, err)
+				return false
+			}
+			var got bytes.Buffer
+			if err = WritePlaybookProfile(&got, p.play, profile); err != nil || !bytes.Equal(got.Bytes(), want) {
+				t.Logf("WritePlaybookProfile(%s) = %s, %v, want %s", profile, got.Bytes(), err, want)
+				return false
+			}
+		}
+		return true
+	}
+	if !compare(randomPlay{huge[0]}) {
+		t.Error("huge play is streamed differently")
+	}
+	if err := quick.Check(compare, &quick.Config{MaxCount: 200}); err != nil {
+		t.Error(err)
+	}
+
+	if err = WritePlaybook(failingWriter{}, huge[0]); err == nil {
+		t.Error("WritePlaybook() into a failing writer succeeded")
+	}
+	if err = WritePlaybookProfile(io.Discard, huge[0], "python4"); !errors.Is(err, ErrUnsupportedType) {
+		t.Errorf("WritePlaybookProfile(python4) error = %v, want %v", err, ErrUnsupportedType)
+	}
+}
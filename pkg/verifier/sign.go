@@ -0,0 +1,164 @@
+package verifier
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	yaml3 "gopkg.in/yaml.v3"
+)
+
+// Signer creates signatures of plays with an OpenPGP private key.
+//
+// It is meant for test environments, which need valid playbooks without access
+// to the production signing service.
+type Signer struct {
+	entity *openpgp.Entity
+}
+
+// NewSigner loads the ASCII-armored OpenPGP private key. The key must not be encrypted.
+func NewSigner(privateKey []byte) (*Signer, error) {
+	entities, err := openpgp.ReadArmoredKeyRing(bytes.NewReader(privateKey))
+	if err != nil {
+		return nil, VerificationError{ErrInvalidKey, "could not load private key", err}
+	}
+	if len(entities) != 1 || entities[0].PrivateKey == nil {
+		return nil, VerificationError{ErrInvalidKey, "key is not a single private key", nil}
+	}
+	if entities[0].PrivateKey.Encrypted {
+		return nil, VerificationError{ErrInvalidKey, "private key is encrypted", nil}
+	}
+	return &Signer{entity: entities[0]}, nil
+}
+
+// Sign returns the ASCII-armored detached signature of the digest, like 'gpg --armor --detach-sign'.
+func (s *Signer) Sign(digest []byte) ([]byte, error) {
+	var signature bytes.Buffer
+	if err := openpgp.ArmoredDetachSign(&signature, s.entity, bytes.NewReader(digest), &packet.Config{DefaultHash: crypto.SHA256}); err != nil {
+		return nil, fmt.Errorf("could not sign: %w", err)
+	}
+	return signature.Bytes(), nil
+}
+
+// SignPlaybook signs every play of the playbook and returns the playbook with
+// the signatures stored in 'insights_signature', replacing the existing ones.
+//
+// Each play has to exclude its signature already (see StripSignature). The plays
+// are hashed with the algorithm they declare, or the one of the policy. Documents
+// that are not lists of plays are kept as they are. The playbook is encoded again,
+// so its formatting may change, but not its content.
+func SignPlaybook(playbook []byte, signer *Signer, policy Policy) ([]byte, error) {
+	return SignPlaybookContext(context.Background(), playbook, signer, policy)
+}
+
+// SignPlaybookContext is like SignPlaybook, but logs the signed plays to the logger of the context.
+func SignPlaybookContext(ctx context.Context, playbook []byte, signer *Signer, policy Policy) ([]byte, error) {
+	decoder := NewPlaybookDecoder(bytes.NewReader(playbook))
+	decoder.AllowDuplicateKeys = policy.AllowDuplicateKeys
+	decoder.MemoryBudget = policy.MemoryBudget
+
+	var signed bytes.Buffer
+	encoder := yaml3.NewEncoder(&signed)
+	encoder.SetIndent(2)
+	for {
+		document, plays, err := decoder.decode()
+		if err == io.EOF {
+			break
+		}
+		var notPlays notPlaysError
+		if err != nil && !errors.As(err, &notPlays) {
+			return nil, err
+		}
+		if err == nil {
+			if err = signDocument(Logger(ctx), document, plays, signer, policy); err != nil {
+				return nil, err
+			}
+		}
+		if err = encoder.Encode(document); err != nil {
+			return nil, fmt.Errorf("could not encode playbook: %w", err)
+		}
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, fmt.Errorf("could not encode playbook: %w", err)
+	}
+	return signed.Bytes(), nil
+}
+
+// signDocument stores the signatures of the plays in the nodes of the document, logging them to the logger.
+func signDocument(logger *slog.Logger, document *yaml3.Node, plays []*yaml3.Node, signer *Signer, policy Policy) error {
+	if len(plays) == 0 {
+		return nil
+	}
+	nodes := document.Content[0]
+	if nodes.Kind != yaml3.SequenceNode || len(nodes.Content) != len(plays) {
+		return PlaybookError{ErrMalformedYAML, "could not locate the plays in the document", nil}
+	}
+
+	for i := range plays {
+		serialized, err := StripSignature(plays[i])
+		if err != nil {
+			return err
+		}
+		algorithm, err := playHashAlgorithm(plays[i], policy)
+		if err != nil {
+			return err
+		}
+		signature, err := signer.Sign(algorithm.Sum(serialized))
+		if err != nil {
+			return err
+		}
+		if err = setSignature(nodes.Content[i], base64.StdEncoding.EncodeToString(signature)); err != nil {
+			return err
+		}
+		logger.Debug("play signed", slog.Int("play", i), slog.String("hash", algorithm.Name()))
+	}
+	return nil
+}
+
+// setSignature sets 'insights_signature' in the variables of the play node.
+func setSignature(play *yaml3.Node, signature string) error {
+	value := &yaml3.Node{Kind: yaml3.ScalarNode, Tag: "!!str", Value: signature}
+	if play.Kind != yaml3.MappingNode {
+		return PlaybookError{ErrUnsupportedType, fmt.Sprintf("play at line %d is not a mapping", play.Line), nil}
+	}
+
+	for i := 0; i+1 < len(play.Content); i += 2 {
+		if play.Content[i].Value != "vars" {
+			continue
+		}
+		vars := play.Content[i+1]
+		switch vars.Kind {
+		case yaml3.MappingNode:
+			for j := 0; j+1 < len(vars.Content); j += 2 {
+				if vars.Content[j].Value == "insights_signature" {
+					vars.Content[j+1] = value
+					return nil
+				}
+			}
+			vars.Content = append(vars.Content, &yaml3.Node{Kind: yaml3.ScalarNode, Tag: "!!str", Value: "insights_signature"}, value)
+			return nil
+		case yaml3.SequenceNode:
+			// the last definition of the variable wins, see getPlaybookVariable
+			for j := len(vars.Content) - 1; j >= 0; j-- {
+				if variable := vars.Content[j]; variable.Kind == yaml3.MappingNode && len(variable.Content) == 2 && variable.Content[0].Value == "insights_signature" {
+					variable.Content[1] = value
+					return nil
+				}
+			}
+			vars.Content = append(vars.Content, &yaml3.Node{Kind: yaml3.MappingNode, Tag: "!!map", Content: []*yaml3.Node{
+				{Kind: yaml3.ScalarNode, Tag: "!!str", Value: "insights_signature"}, value,
+			}})
+			return nil
+		default:
+			return PlaybookError{ErrUnsupportedType, fmt.Sprintf("variables at line %d are neither a mapping nor a list", vars.Line), nil}
+		}
+	}
+	return PlaybookError{ErrUnsupportedType, fmt.Sprintf("play at line %d has no variables", play.Line), nil}
+}
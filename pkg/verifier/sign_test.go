@@ -0,0 +1,59 @@
+package verifier
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+// testSigner returns a signer with the private test key from testdata/keys.
+func testSigner(t testing.TB) *Signer {
+	t.Helper()
+	signer, err := NewSigner(readTestdata(t, filepath.Join("testdata", "keys", "test-private.asc")))
+	if err != nil {
+		t.Fatalf("NewSigner() error = %v", err)
+	}
+	return signer
+}
+
+func TestSignPlaybook(t *testing.T) {
+	tests := []struct {
+		name     string
+		playbook string
+		plays    int
+	}{
+		{"golden corpus", string(readTestdata(t, filepath.Join("testdata", "golden", "scalars.yml"))), 1},
+		{"new signature", "- name: play\n  vars:\n    insights_signature_exclude: /vars/insights_signature\n    enabled: yes\n", 1},
+		{"list of variables", "- name: play\n  vars:\n    - insights_signature_exclude: /vars/insights_signature\n    - insights_signature_hash: sha512\n", 1},
+		{"documents", "- name: one\n  vars: {insights_signature_exclude: /vars/insights_signature}\n---\ninsights: metadata\n---\n" +
+			"- name: two\n  vars: {insights_signature_exclude: /vars/insights_signature}\n", 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			signed, err := SignPlaybook([]byte(tt.playbook), testSigner(t), Policy{})
+			if err != nil {
+				t.Fatalf("SignPlaybook() error = %v", err)
+			}
+			report, err := VerifyPlaybook(signed, testKeyring(t), Policy{})
+			if err != nil {
+				t.Fatalf("VerifyPlaybook() error = %v\n%s", err, signed)
+			}
+			if len(report.Plays) != tt.plays {
+				t.Errorf("VerifyPlaybook() verified %d plays, want %d", len(report.Plays), tt.plays)
+			}
+		})
+	}
+}
+
+func TestSignPlaybookNotExcluded(t *testing.T) {
+	playbook := []byte("- name: play\n  vars:\n    insights_signature_exclude: /hosts\n")
+	if _, err := SignPlaybook(playbook, testSigner(t), Policy{}); !errors.Is(err, ErrInvalidExclusion) {
+		t.Errorf("SignPlaybook() error = %v, want %v", err, ErrInvalidExclusion)
+	}
+}
+
+func TestNewSignerPublicKey(t *testing.T) {
+	if _, err := NewSigner(readTestdata(t, filepath.Join("testdata", "keys", "test-public.asc"))); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("NewSigner() error = %v, want %v", err, ErrInvalidKey)
+	}
+}
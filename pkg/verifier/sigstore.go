@@ -0,0 +1,456 @@
+package verifier
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"com.github/m-horky/playbook-verifier/internal/hygiene"
+)
+
+// sigstoreMediaType is the prefix of the media type of Sigstore bundles.
+const sigstoreMediaType = "application/vnd.dev.sigstore.bundle"
+
+// OIDs of the certificate extensions holding the OIDC issuer of the signer.
+var (
+	oidFulcioIssuer   = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 1, 1}
+	oidFulcioIssuerV2 = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 1, 8}
+)
+
+// SigstoreTrustRoot is what Sigstore bundles are verified against.
+//
+// Bundles are signed by short-lived certificates issued by the certificate authority
+// (Fulcio) to an identity authenticated by an OIDC issuer, and the signature is recorded
+// in a transparency log (Rekor).
+type SigstoreTrustRoot struct {
+	// Roots are the root certificates of the certificate authority.
+	Roots []*x509.Certificate
+	// Intermediates are its intermediate certificates.
+	Intermediates []*x509.Certificate
+	// RekorKeys are the public keys of the trusted transparency logs.
+	RekorKeys []crypto.PublicKey
+	// Identities are the signers whose signatures are accepted. At least one is required.
+	Identities []SigstoreIdentity
+	// FetchEntry enables the online mode. The entry of the transparency log is fetched
+	// by its index, so its inclusion proof can be checked even if the bundle does not
+	// contain it. Without it, the signed promise of inclusion in the bundle is enough.
+	FetchEntry func(logIndex int64) (*RekorEntry, error)
+}
+
+// SigstoreIdentity is a signer authenticated by an OIDC issuer.
+type SigstoreIdentity struct {
+	// Issuer is the URL of the OIDC issuer, e.g. 'https://github.com/login/oauth'.
+	Issuer string
+	// SubjectAlternativeName is the e-mail address or URI of the signer.
+	SubjectAlternativeName string
+}
+
+// String returns the identity as 'issuer=subject'.
+func (i SigstoreIdentity) String() string {
+	return i.Issuer + "=" + i.SubjectAlternativeName
+}
+
+// RekorEntry is an entry of the transparency log and the proof of its inclusion in the log.
+type RekorEntry struct {
+	// Body is the canonicalized body of the entry.
+	Body     []byte
+	LogIndex int64
+	TreeSize int64
+	RootHash []byte
+	Hashes   [][]byte
+	// Checkpoint is the signed tree head the root hash belongs to.
+	Checkpoint string
+}
+
+// TrustSigstore makes the keyring accept Sigstore bundles verified against the trust root.
+func (k *Keyring) TrustSigstore(root SigstoreTrustRoot) error {
+	if len(root.Roots) == 0 {
+		return VerificationError{ErrInvalidKey, "Sigstore trust root has no root certificate", nil}
+	}
+	if len(root.RekorKeys) == 0 {
+		return VerificationError{ErrInvalidKey, "Sigstore trust root has no transparency log key", nil}
+	}
+	if len(root.Identities) == 0 {
+		return VerificationError{ErrInvalidKey, "Sigstore trust root has no identity", nil}
+	}
+	logs := map[string]crypto.PublicKey{}
+	for _, key := range root.RekorKeys {
+		logID, err := rekorLogID(key)
+		if err != nil {
+			return VerificationError{ErrInvalidKey, "could not load transparency log key", err}
+		}
+		logs[logID] = key
+	}
+	k.sigstore = &sigstoreTrust{SigstoreTrustRoot: root, logs: logs}
+	return nil
+}
+
+// sigstoreTrust is the trust root with the transparency log keys indexed by their log ID.
+type sigstoreTrust struct {
+	SigstoreTrustRoot
+	logs map[string]crypto.PublicKey
+}
+
+// fingerprints returns the fingerprints of the root certificates and the IDs of the logs.
+func (t *sigstoreTrust) fingerprints() []string {
+	var fingerprints []string
+	for _, root := range t.Roots {
+		hash := sha256.Sum256(root.Raw)
+		fingerprints = append(fingerprints, strings.ToUpper(hex.EncodeToString(hash[:])))
+	}
+	for logID := range t.logs {
+		fingerprints = append(fingerprints, strings.ToUpper(logID))
+	}
+	for _, identity := range t.Identities {
+		fingerprints = append(fingerprints, identity.String())
+	}
+	slices.Sort(fingerprints)
+	return fingerprints
+}
+
+// sigstoreBundle is a Sigstore bundle in its JSON encoding, versions 0.1 to 0.3.
+type sigstoreBundle struct {
+	MediaType            string `json:"mediaType"`
+	VerificationMaterial struct {
+		Certificate *struct {
+			RawBytes []byte `json:"rawBytes"`
+		} `json:"certificate"`
+		X509CertificateChain *struct {
+			Certificates []struct {
+				RawBytes []byte `json:"rawBytes"`
+			} `json:"certificates"`
+		} `json:"x509CertificateChain"`
+		TlogEntries []sigstoreTlogEntry `json:"tlogEntries"`
+	} `json:"verificationMaterial"`
+	MessageSignature *struct {
+		MessageDigest *struct {
+			Algorithm string `json:"algorithm"`
+			Digest    []byte `json:"digest"`
+		} `json:"messageDigest"`
+		Signature []byte `json:"signature"`
+	} `json:"messageSignature"`
+}
+
+// sigstoreTlogEntry is an entry of the transparency log in the bundle.
+type sigstoreTlogEntry struct {
+	LogIndex int64 `json:"logIndex,string"`
+	LogID    struct {
+		KeyID []byte `json:"keyId"`
+	} `json:"logId"`
+	KindVersion struct {
+		Kind    string `json:"kind"`
+		Version string `json:"version"`
+	} `json:"kindVersion"`
+	IntegratedTime   int64 `json:"integratedTime,string"`
+	InclusionPromise *struct {
+		SignedEntryTimestamp []byte `json:"signedEntryTimestamp"`
+	} `json:"inclusionPromise"`
+	InclusionProof *struct {
+		LogIndex   int64    `json:"logIndex,string"`
+		RootHash   []byte   `json:"rootHash"`
+		TreeSize   int64    `json:"treeSize,string"`
+		Hashes     [][]byte `json:"hashes"`
+		Checkpoint struct {
+			Envelope string `json:"envelope"`
+		} `json:"checkpoint"`
+	} `json:"inclusionProof"`
+	CanonicalizedBody []byte `json:"canonicalizedBody"`
+}
+
+// hashedRekord is the body of a 'hashedrekord' entry of the transparency log.
+type hashedRekord struct {
+	Kind string `json:"kind"`
+	Spec struct {
+		Data struct {
+			Hash struct {
+				Algorithm string `json:"algorithm"`
+				Value     string `json:"value"`
+			} `json:"hash"`
+		} `json:"data"`
+		Signature struct {
+			Content   []byte `json:"content"`
+			PublicKey struct {
+				Content []byte `json:"content"`
+			} `json:"publicKey"`
+		} `json:"signature"`
+	} `json:"spec"`
+}
+
+// isSigstoreBundle reports whether the signature is a Sigstore bundle.
+func isSigstoreBundle(signature []byte) bool {
+	trimmed := bytes.TrimSpace(signature)
+	return bytes.HasPrefix(trimmed, []byte("{")) && bytes.Contains(trimmed, []byte(sigstoreMediaType))
+}
+
+// sigstoreScheme verifies Sigstore bundles with a message signature.
+type sigstoreScheme struct{}
+
+func (sigstoreScheme) detect(signature []byte) bool {
+	return isSigstoreBundle(signature)
+}
+
+func (sigstoreScheme) verify(_ *slog.Logger, k *Keyring, message []byte, signature []byte) (string, error) {
+	if k.sigstore == nil {
+		return "", VerificationError{ErrDigestMismatch, "signature is a Sigstore bundle, but Sigstore is not trusted", nil}
+	}
+	var bundle sigstoreBundle
+	if err := json.Unmarshal(signature, &bundle); err != nil {
+		return "", VerificationError{ErrMalformedSignature, "Sigstore bundle is not valid JSON", err}
+	}
+	if !strings.HasPrefix(bundle.MediaType, sigstoreMediaType) {
+		return "", VerificationError{ErrMalformedSignature, fmt.Sprintf("unsupported bundle media type '%s'", bundle.MediaType), nil}
+	}
+	return k.sigstore.verify(&bundle, message)
+}
+
+// verify checks the bundle of the message and returns the identity of the signer.
+func (t *sigstoreTrust) verify(bundle *sigstoreBundle, message []byte) (string, error) {
+	fail := func(format string, args ...any) (string, error) {
+		return "", VerificationError{ErrDigestMismatch, fmt.Sprintf(format, args...), nil}
+	}
+
+	certificates, err := bundle.certificates()
+	if err != nil {
+		return "", err
+	}
+	if bundle.MessageSignature == nil {
+		return "", VerificationError{ErrMalformedSignature, "Sigstore bundle has no message signature", nil}
+	}
+	if len(bundle.VerificationMaterial.TlogEntries) == 0 {
+		return fail("Sigstore bundle has no transparency log entry")
+	}
+	leaf := certificates[0]
+	signature := bundle.MessageSignature.Signature
+	digest := sha256.Sum256(message)
+	if md := bundle.MessageSignature.MessageDigest; md != nil && (md.Algorithm != "SHA2_256" || !hygiene.Equal(md.Digest, digest[:])) {
+		return fail("Sigstore bundle was created for another message")
+	}
+	if !verifyWithKey(leaf.PublicKey, message, signature) {
+		return fail("signature does not match")
+	}
+
+	// The signature must have been logged while the certificate was valid.
+	entry := bundle.VerificationMaterial.TlogEntries[0]
+	if err = t.verifyEntry(&entry, leaf, signature, digest[:]); err != nil {
+		return "", err
+	}
+	intermediates := x509.NewCertPool()
+	for _, certificate := range slices.Concat(t.Intermediates, certificates[1:]) {
+		intermediates.AddCert(certificate)
+	}
+	roots := x509.NewCertPool()
+	for _, certificate := range t.Roots {
+		roots.AddCert(certificate)
+	}
+	_, err = leaf.Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		CurrentTime:   time.Unix(entry.IntegratedTime, 0),
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+	})
+	if err != nil {
+		return "", VerificationError{ErrDigestMismatch, "certificate is not trusted", err}
+	}
+
+	identity, err := certificateIdentity(leaf)
+	if err != nil {
+		return "", err
+	}
+	if !slices.Contains(t.Identities, identity) {
+		return fail("signer %s is not trusted", identity)
+	}
+	return identity.SubjectAlternativeName, nil
+}
+
+// verifyEntry checks that the entry records the signature and that it is included in a trusted log.
+func (t *sigstoreTrust) verifyEntry(entry *sigstoreTlogEntry, leaf *x509.Certificate, signature []byte, digest []byte) error {
+	fail := func(format string, args ...any) error {
+		return VerificationError{ErrDigestMismatch, fmt.Sprintf(format, args...), nil}
+	}
+
+	logID := hex.EncodeToString(entry.LogID.KeyID)
+	key, ok := t.logs[logID]
+	if !ok {
+		return fail("transparency log %s is not trusted", logID)
+	}
+
+	var body hashedRekord
+	if err := json.Unmarshal(entry.CanonicalizedBody, &body); err != nil {
+		return VerificationError{ErrMalformedSignature, "transparency log entry is not valid JSON", err}
+	}
+	publicKey, _ := pem.Decode(body.Spec.Signature.PublicKey.Content)
+	if body.Kind != "hashedrekord" ||
+		body.Spec.Data.Hash.Algorithm != "sha256" ||
+		!hygiene.EqualString(body.Spec.Data.Hash.Value, hex.EncodeToString(digest)) ||
+		!hygiene.Equal(body.Spec.Signature.Content, signature) ||
+		publicKey == nil || !hygiene.Equal(publicKey.Bytes, leaf.Raw) {
+		return fail("transparency log entry does not record the signature")
+	}
+
+	verified := false
+	if promise := entry.InclusionPromise; promise != nil {
+		// The signed entry timestamp covers the canonical JSON of the entry.
+		payload := fmt.Sprintf(`{"body":"%s","integratedTime":%d,"logID":"%s","logIndex":%d}`,
+			base64.StdEncoding.EncodeToString(entry.CanonicalizedBody), entry.IntegratedTime, logID, entry.LogIndex)
+		if !verifyWithKey(key, []byte(payload), promise.SignedEntryTimestamp) {
+			return fail("signed entry timestamp does not match")
+		}
+		verified = true
+	}
+
+	proof := entry.rekorEntry()
+	if proof == nil && t.FetchEntry != nil {
+		fetched, err := t.FetchEntry(entry.LogIndex)
+		if err != nil {
+			return fail("could not fetch transparency log entry: %v", err)
+		}
+		if !hygiene.Equal(fetched.Body, entry.CanonicalizedBody) {
+			return fail("transparency log entry differs from the bundle")
+		}
+		proof = fetched
+	}
+	if proof != nil {
+		if err := verifyRekorEntry(proof, logID, key); err != nil {
+			return fail("transparency log inclusion proof is invalid: %v", err)
+		}
+		verified = true
+	}
+
+	if !verified {
+		return fail("transparency log entry has no proof of inclusion")
+	}
+	return nil
+}
+
+// rekorEntry returns the inclusion proof of the bundle entry, if there is one.
+func (e *sigstoreTlogEntry) rekorEntry() *RekorEntry {
+	if e.InclusionProof == nil {
+		return nil
+	}
+	return &RekorEntry{
+		Body:       e.CanonicalizedBody,
+		LogIndex:   e.InclusionProof.LogIndex,
+		TreeSize:   e.InclusionProof.TreeSize,
+		RootHash:   e.InclusionProof.RootHash,
+		Hashes:     e.InclusionProof.Hashes,
+		Checkpoint: e.InclusionProof.Checkpoint.Envelope,
+	}
+}
+
+// verifyRekorEntry checks the inclusion proof of the entry and the checkpoint it leads to.
+func verifyRekorEntry(entry *RekorEntry, logID string, key crypto.PublicKey) error {
+	if err := verifyInclusion(entry.LogIndex, entry.TreeSize, merkleLeafHash(entry.Body), entry.Hashes, entry.RootHash); err != nil {
+		return err
+	}
+	c, err := parseCheckpoint(entry.Checkpoint)
+	if err != nil {
+		return err
+	}
+	if c.size != entry.TreeSize || !hygiene.Equal(c.rootHash, entry.RootHash) {
+		return fmt.Errorf("checkpoint is of another tree")
+	}
+	// The key hint of the signature is the beginning of the log ID.
+	hint, _ := hex.DecodeString(logID)
+	for _, signature := range c.signatures {
+		if hygiene.Equal(signature[:4], hint[:4]) && verifyWithKey(key, c.text, signature[4:]) {
+			return nil
+		}
+	}
+	return fmt.Errorf("checkpoint is not signed by the log")
+}
+
+// certificates returns the signing certificate of the bundle, followed by the rest of its chain.
+func (b *sigstoreBundle) certificates() ([]*x509.Certificate, error) {
+	var raw [][]byte
+	material := b.VerificationMaterial
+	switch {
+	case material.Certificate != nil:
+		raw = append(raw, material.Certificate.RawBytes)
+	case material.X509CertificateChain != nil:
+		for _, certificate := range material.X509CertificateChain.Certificates {
+			raw = append(raw, certificate.RawBytes)
+		}
+	}
+	if len(raw) == 0 {
+		return nil, VerificationError{ErrMalformedSignature, "Sigstore bundle has no certificate", nil}
+	}
+
+	var certificates []*x509.Certificate
+	for _, der := range raw {
+		certificate, err := x509.ParseCertificate(der)
+		if err != nil {
+			return nil, VerificationError{ErrMalformedSignature, "Sigstore bundle has invalid certificate", err}
+		}
+		certificates = append(certificates, certificate)
+	}
+	return certificates, nil
+}
+
+// certificateIdentity returns the identity the certificate has been issued to.
+func certificateIdentity(certificate *x509.Certificate) (SigstoreIdentity, error) {
+	var identity SigstoreIdentity
+	switch {
+	case len(certificate.EmailAddresses) > 0:
+		identity.SubjectAlternativeName = certificate.EmailAddresses[0]
+	case len(certificate.URIs) > 0:
+		identity.SubjectAlternativeName = certificate.URIs[0].String()
+	default:
+		return identity, VerificationError{ErrMalformedSignature, "certificate has no subject alternative name", nil}
+	}
+
+	for _, extension := range certificate.Extensions {
+		switch {
+		case extension.Id.Equal(oidFulcioIssuerV2):
+			if _, err := asn1.Unmarshal(extension.Value, &identity.Issuer); err != nil {
+				return identity, VerificationError{ErrMalformedSignature, "certificate has invalid issuer", err}
+			}
+			return identity, nil
+		case extension.Id.Equal(oidFulcioIssuer):
+			identity.Issuer = string(extension.Value)
+		}
+	}
+	if identity.Issuer == "" {
+		return identity, VerificationError{ErrMalformedSignature, "certificate has no issuer", nil}
+	}
+	return identity, nil
+}
+
+// rekorLogID returns the ID of the transparency log, the hex-encoded SHA-256 of its public key.
+func rekorLogID(key crypto.PublicKey) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		return "", err
+	}
+	hash := sha256.Sum256(der)
+	return hex.EncodeToString(hash[:]), nil
+}
+
+// verifyWithKey checks the signature of the message. ECDSA and RSA signatures are
+// over the SHA-256 digest of the message.
+func verifyWithKey(key crypto.PublicKey, message []byte, signature []byte) bool {
+	digest := sha256.Sum256(message)
+	switch key := key.(type) {
+	case *ecdsa.PublicKey:
+		return ecdsa.VerifyASN1(key, digest[:], signature)
+	case ed25519.PublicKey:
+		return ed25519.Verify(key, message, signature)
+	case *rsa.PublicKey:
+		return rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature) == nil
+	default:
+		return false
+	}
+}
@@ -0,0 +1,331 @@
+package verifier
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// testSigstore is a certificate authority and a transparency log that sign like Sigstore does.
+type testSigstore struct {
+	root      *x509.Certificate
+	rootKey   *ecdsa.PrivateKey
+	rekorKey  *ecdsa.PrivateKey
+	logID     []byte
+	identity  SigstoreIdentity
+	timestamp time.Time
+}
+
+func newTestSigstore(t *testing.T) *testSigstore {
+	t.Helper()
+	s := &testSigstore{
+		identity:  SigstoreIdentity{Issuer: "https://issuer.example.com", SubjectAlternativeName: "signer@example.com"},
+		timestamp: time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC),
+	}
+	s.rootKey = generateECDSAKey(t)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test Fulcio root"},
+		NotBefore:             s.timestamp.AddDate(-1, 0, 0),
+		NotAfter:              s.timestamp.AddDate(1, 0, 0),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	s.root = createCertificate(t, template, template, &s.rootKey.PublicKey, s.rootKey)
+
+	s.rekorKey = generateECDSAKey(t)
+	logID, err := rekorLogID(&s.rekorKey.PublicKey)
+	if err != nil {
+		t.Fatalf("rekorLogID() error = %v", err)
+	}
+	s.logID, _ = hex.DecodeString(logID)
+	return s
+}
+
+func (s *testSigstore) trustRoot() SigstoreTrustRoot {
+	return SigstoreTrustRoot{
+		Roots:      []*x509.Certificate{s.root},
+		RekorKeys:  []crypto.PublicKey{&s.rekorKey.PublicKey},
+		Identities: []SigstoreIdentity{s.identity},
+	}
+}
+
+// testBundle is a signed message, as it would be logged by the transparency log.
+type testBundle struct {
+	bundle map[string]any
+	entry  *RekorEntry
+}
+
+// sign creates the bundle of the message, logged at index 3 of the transparency log.
+func (s *testSigstore) sign(t *testing.T, message []byte) *testBundle {
+	t.Helper()
+	key := generateECDSAKey(t)
+	issuer, err := asn1.MarshalWithParams(s.identity.Issuer, "utf8")
+	if err != nil {
+		t.Fatalf("asn1.MarshalWithParams() error = %v", err)
+	}
+	leaf := createCertificate(t, &x509.Certificate{
+		SerialNumber:    big.NewInt(2),
+		NotBefore:       s.timestamp.Add(-time.Minute),
+		NotAfter:        s.timestamp.Add(10 * time.Minute),
+		KeyUsage:        x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:     []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+		EmailAddresses:  []string{s.identity.SubjectAlternativeName},
+		ExtraExtensions: []pkix.Extension{{Id: oidFulcioIssuerV2, Value: issuer}},
+	}, s.root, &key.PublicKey, s.rootKey)
+
+	digest := sha256.Sum256(message)
+	signature, err := ecdsa.SignASN1(rand.Reader, key, digest[:])
+	if err != nil {
+		t.Fatalf("ecdsa.SignASN1() error = %v", err)
+	}
+
+	body := map[string]any{
+		"apiVersion": "0.0.1",
+		"kind":       "hashedrekord",
+		"spec": map[string]any{
+			"data": map[string]any{"hash": map[string]any{"algorithm": "sha256", "value": hex.EncodeToString(digest[:])}},
+			"signature": map[string]any{
+				"content":   signature,
+				"publicKey": map[string]any{"content": pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: leaf.Raw})},
+			},
+		},
+	}
+	canonicalizedBody, err := json.Marshal(body)
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+
+	// the log contains other entries around ours
+	const logIndex = 3
+	leaves := [][]byte{[]byte("0"), []byte("1"), []byte("2"), canonicalizedBody, []byte("4")}
+	rootHash := merkleRoot(leaves)
+	text := fmt.Sprintf("rekor.test - 1\n%d\n%s\n", len(leaves), base64.StdEncoding.EncodeToString(rootHash))
+	checkpoint := text + "\n— rekor.test " + base64.StdEncoding.EncodeToString(append(bytes.Clone(s.logID[:4]), s.rekorSign(t, []byte(text))...)) + "\n"
+
+	payload := fmt.Sprintf(`{"body":"%s","integratedTime":%d,"logID":"%s","logIndex":%d}`,
+		base64.StdEncoding.EncodeToString(canonicalizedBody), s.timestamp.Unix(), hex.EncodeToString(s.logID), logIndex)
+
+	entry := &RekorEntry{
+		Body:       canonicalizedBody,
+		LogIndex:   logIndex,
+		TreeSize:   int64(len(leaves)),
+		RootHash:   rootHash,
+		Hashes:     merklePath(logIndex, leaves),
+		Checkpoint: checkpoint,
+	}
+	bundle := map[string]any{
+		"mediaType": "application/vnd.dev.sigstore.bundle.v0.3+json",
+		"verificationMaterial": map[string]any{
+			"certificate": map[string]any{"rawBytes": leaf.Raw},
+			"tlogEntries": []any{map[string]any{
+				"logIndex":          strconv.Itoa(logIndex),
+				"logId":             map[string]any{"keyId": s.logID},
+				"kindVersion":       map[string]any{"kind": "hashedrekord", "version": "0.0.1"},
+				"integratedTime":    strconv.FormatInt(s.timestamp.Unix(), 10),
+				"inclusionPromise":  map[string]any{"signedEntryTimestamp": s.rekorSign(t, []byte(payload))},
+				"canonicalizedBody": canonicalizedBody,
+				"inclusionProof": map[string]any{
+					"logIndex":   strconv.Itoa(logIndex),
+					"rootHash":   rootHash,
+					"treeSize":   strconv.Itoa(len(leaves)),
+					"hashes":     entry.Hashes,
+					"checkpoint": map[string]any{"envelope": checkpoint},
+				},
+			}},
+		},
+		"messageSignature": map[string]any{
+			"messageDigest": map[string]any{"algorithm": "SHA2_256", "digest": digest[:]},
+			"signature":     signature,
+		},
+	}
+	return &testBundle{bundle: bundle, entry: entry}
+}
+
+func (s *testSigstore) rekorSign(t *testing.T, message []byte) []byte {
+	t.Helper()
+	digest := sha256.Sum256(message)
+	signature, err := ecdsa.SignASN1(rand.Reader, s.rekorKey, digest[:])
+	if err != nil {
+		t.Fatalf("ecdsa.SignASN1() error = %v", err)
+	}
+	return signature
+}
+
+// tlogEntry returns the transparency log entry of the bundle, for modification.
+func (b *testBundle) tlogEntry() map[string]any {
+	return b.bundle["verificationMaterial"].(map[string]any)["tlogEntries"].([]any)[0].(map[string]any)
+}
+
+func (b *testBundle) marshal(t *testing.T) []byte {
+	t.Helper()
+	content, err := json.Marshal(b.bundle)
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	return content
+}
+
+func generateECDSAKey(t *testing.T) *ecdsa.PrivateKey {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("ecdsa.GenerateKey() error = %v", err)
+	}
+	return key
+}
+
+func createCertificate(t *testing.T, template, parent *x509.Certificate, publicKey any, parentKey any) *x509.Certificate {
+	t.Helper()
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, publicKey, parentKey)
+	if err != nil {
+		t.Fatalf("x509.CreateCertificate() error = %v", err)
+	}
+	certificate, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("x509.ParseCertificate() error = %v", err)
+	}
+	return certificate
+}
+
+func TestSigstore(t *testing.T) {
+	s := newTestSigstore(t)
+	message := []byte("digest of the play")
+
+	tests := []struct {
+		name    string
+		modify  func(b *testBundle, root *SigstoreTrustRoot)
+		message []byte
+		want    error
+	}{
+		{"valid", func(*testBundle, *SigstoreTrustRoot) {}, message, nil},
+		{"other message", func(*testBundle, *SigstoreTrustRoot) {}, []byte("other"), ErrDigestMismatch},
+		{"untrusted identity", func(_ *testBundle, root *SigstoreTrustRoot) {
+			root.Identities = []SigstoreIdentity{{Issuer: s.identity.Issuer, SubjectAlternativeName: "other@example.com"}}
+		}, message, ErrDigestMismatch},
+		{"untrusted log", func(_ *testBundle, root *SigstoreTrustRoot) {
+			root.RekorKeys = []crypto.PublicKey{&generateECDSAKey(t).PublicKey}
+		}, message, ErrDigestMismatch},
+		{"untrusted certificate authority", func(_ *testBundle, root *SigstoreTrustRoot) {
+			root.Roots = []*x509.Certificate{newTestSigstore(t).root}
+		}, message, ErrDigestMismatch},
+		{"logged after the certificate expired", func(b *testBundle, _ *SigstoreTrustRoot) {
+			b.tlogEntry()["integratedTime"] = strconv.FormatInt(s.timestamp.Add(time.Hour).Unix(), 10)
+			delete(b.tlogEntry(), "inclusionPromise")
+		}, message, ErrDigestMismatch},
+		{"tampered entry timestamp", func(b *testBundle, _ *SigstoreTrustRoot) {
+			b.tlogEntry()["inclusionPromise"] = map[string]any{"signedEntryTimestamp": []byte("forged")}
+		}, message, ErrDigestMismatch},
+		{"inclusion promise only", func(b *testBundle, _ *SigstoreTrustRoot) {
+			delete(b.tlogEntry(), "inclusionProof")
+		}, message, nil},
+		{"inclusion proof only", func(b *testBundle, _ *SigstoreTrustRoot) {
+			delete(b.tlogEntry(), "inclusionPromise")
+		}, message, nil},
+		{"no proof of inclusion", func(b *testBundle, _ *SigstoreTrustRoot) {
+			delete(b.tlogEntry(), "inclusionPromise")
+			delete(b.tlogEntry(), "inclusionProof")
+		}, message, ErrDigestMismatch},
+		{"online with fetched proof", func(b *testBundle, root *SigstoreTrustRoot) {
+			delete(b.tlogEntry(), "inclusionProof")
+			root.FetchEntry = func(int64) (*RekorEntry, error) { return b.entry, nil }
+		}, message, nil},
+		{"online with other entry", func(b *testBundle, root *SigstoreTrustRoot) {
+			delete(b.tlogEntry(), "inclusionProof")
+			root.FetchEntry = func(int64) (*RekorEntry, error) {
+				entry := *b.entry
+				entry.Body = []byte("{}")
+				return &entry, nil
+			}
+		}, message, ErrDigestMismatch},
+		{"online with log failure", func(b *testBundle, root *SigstoreTrustRoot) {
+			delete(b.tlogEntry(), "inclusionProof")
+			root.FetchEntry = func(int64) (*RekorEntry, error) { return nil, errors.New("connection refused") }
+		}, message, ErrDigestMismatch},
+		{"no certificate", func(b *testBundle, _ *SigstoreTrustRoot) {
+			delete(b.bundle["verificationMaterial"].(map[string]any), "certificate")
+		}, message, ErrMalformedSignature},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bundle, root := s.sign(t, message), s.trustRoot()
+			tt.modify(bundle, &root)
+			keyring := testKeyring(t)
+			if err := keyring.TrustSigstore(root); err != nil {
+				t.Fatalf("TrustSigstore() error = %v", err)
+			}
+
+			signer, err := keyring.Verify(tt.message, bundle.marshal(t))
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("Verify() error = %v, want %v", err, tt.want)
+			}
+			if err == nil && signer != s.identity.SubjectAlternativeName {
+				t.Errorf("Verify() = %s, want %s", signer, s.identity.SubjectAlternativeName)
+			}
+		})
+	}
+}
+
+func TestVerifyPlaybookSigstore(t *testing.T) {
+	s := newTestSigstore(t)
+	playbook := []byte(`- name: Insights remediation signed with Sigstore
+  hosts: "@@HOSTS@@"
+  vars:
+    insights_signature_exclude: /hosts,/vars/insights_signature
+    insights_signature: PLACEHOLDER
+  tasks:
+    - name: Reboot the system
+      reboot:
+`)
+	plays, err := UnmarshalPlaybook(playbook)
+	if err != nil {
+		t.Fatalf("UnmarshalPlaybook() error = %v", err)
+	}
+	clean, err := CleanPlaybook(plays[0])
+	if err != nil {
+		t.Fatalf("CleanPlaybook() error = %v", err)
+	}
+	serialized, err := MarshallPlaybook(clean)
+	if err != nil {
+		t.Fatalf("MarshallPlaybook() error = %v", err)
+	}
+	bundle := s.sign(t, Hash(serialized)).marshal(t)
+	playbook = bytes.Replace(playbook, []byte("PLACEHOLDER"), []byte(base64.StdEncoding.EncodeToString(bundle)), 1)
+
+	keyring := testKeyring(t)
+	if _, err = VerifyPlaybook(playbook, keyring, Policy{}); !errors.Is(err, ErrDigestMismatch) {
+		t.Errorf("VerifyPlaybook() without Sigstore trust error = %v, want %v", err, ErrDigestMismatch)
+	}
+	if err = keyring.TrustSigstore(s.trustRoot()); err != nil {
+		t.Fatalf("TrustSigstore() error = %v", err)
+	}
+	report, err := VerifyPlaybook(playbook, keyring, Policy{})
+	if err != nil {
+		t.Fatalf("VerifyPlaybook() error = %v", err)
+	}
+	if report.Plays[0].KeyID != "signer@example.com" {
+		t.Errorf("VerifyPlaybook() key ID = %s", report.Plays[0].KeyID)
+	}
+
+	info, err := ParseSignature(bundle)
+	if err != nil || info.Scheme != SchemeSigstore || info.KeyID != s.identity.String() || !info.Created.Equal(s.timestamp) {
+		t.Errorf("ParseSignature() = %+v, %v", info, err)
+	}
+}
@@ -0,0 +1,49 @@
+package verifier
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	yaml3 "gopkg.in/yaml.v3"
+)
+
+// playKeywords are the top-level keys of a play known to Ansible, see PlayKeywords.
+var playKeywords = map[string]any{
+	"any_errors_fatal": nil, "become": nil, "become_exe": nil, "become_flags": nil,
+	"become_method": nil, "become_user": nil, "check_mode": nil, "collections": nil,
+	"connection": nil, "debugger": nil, "diff": nil, "environment": nil,
+	"fact_path": nil, "force_handlers": nil, "gather_facts": nil, "gather_subset": nil,
+	"gather_timeout": nil, "handlers": nil, "hosts": nil, "ignore_errors": nil,
+	"ignore_unreachable": nil, "max_fail_percentage": nil, "module_defaults": nil, "name": nil,
+	"no_log": nil, "order": nil, "port": nil, "post_tasks": nil,
+	"pre_tasks": nil, "remote_user": nil, "roles": nil, "run_once": nil,
+	"serial": nil, "strategy": nil, "tags": nil, "tasks": nil,
+	"throttle": nil, "timeout": nil, "vars": nil, "vars_files": nil,
+	"vars_prompt": nil,
+}
+
+// PlayKeywords returns the sorted top-level keys of a play known to Ansible.
+//
+// The strict policy refuses plays with any other key, so executable content cannot be
+// smuggled in keys that are not expected to be there. The keys cannot be changed,
+// so plays are verified the same way by every goroutine.
+func PlayKeywords() []string {
+	return sortedKeys(playKeywords)
+}
+
+// checkPlayKeywords refuses plays with top-level keys that are not PlayKeywords.
+func checkPlayKeywords(p *yaml3.Node) error {
+	var unknown []string
+	for i := 0; i+1 < len(p.Content); i += 2 {
+		key := pathSegment(p.Content[i])
+		if _, ok := playKeywords[key]; !ok {
+			unknown = append(unknown, key)
+		}
+	}
+	if len(unknown) > 0 {
+		slices.Sort(unknown)
+		return PlaybookError{ErrUnknownKey, fmt.Sprintf("play contains unknown keys: %s", strings.Join(unknown, ", ")), nil}
+	}
+	return nil
+}
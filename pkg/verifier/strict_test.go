@@ -0,0 +1,38 @@
+package verifier
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheckPlayKeywords(t *testing.T) {
+	tests := []struct {
+		name    string
+		play    string
+		wantErr bool
+	}{
+		{"known keys", "- name: play\n  hosts: all\n  become: true\n  vars: {}\n  tasks: []\n", false},
+		{"unknown key", "- name: play\n  hosts: all\n  shell: rm -rf /\n", true},
+		{"non-string key", "- name: play\n  1: one\n", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plays, err := UnmarshalPlaybook([]byte(tt.play))
+			if err != nil {
+				t.Fatalf("UnmarshalPlaybook() error = %v", err)
+			}
+			err = checkPlayKeywords(plays[0])
+			if (err != nil) != tt.wantErr || (err != nil && !errors.Is(err, ErrUnknownKey)) {
+				t.Errorf("checkPlayKeywords() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestVerifyPlaybookStrict(t *testing.T) {
+	playbook := readTestdata(t, filepath.Join("testdata", "golden", "cve-update.yml"))
+	if _, err := VerifyPlaybook(playbook, testKeyring(t), Policy{Strict: true}); err != nil {
+		t.Errorf("VerifyPlaybook() error = %v", err)
+	}
+}
@@ -0,0 +1,100 @@
+package verifier
+
+import (
+	"bytes"
+	"errors"
+	"io"
+
+	yaml3 "gopkg.in/yaml.v3"
+)
+
+// StripSignatures returns the serialized form of each play of the playbook, without
+// its signature and the other excluded elements.
+//
+// These are the bytes the signer hashes (with the algorithm of the play) to create
+// the signatures; they are computed by the same code that verifies them.
+func StripSignatures(playbook []byte) ([][]byte, error) {
+	plays, err := UnmarshalPlaybook(playbook)
+	if err != nil {
+		return nil, err
+	}
+
+	var stripped [][]byte
+	for i := range plays {
+		serialized, err := StripSignature(plays[i])
+		if err != nil {
+			return nil, err
+		}
+		stripped = append(stripped, serialized)
+	}
+	return stripped, nil
+}
+
+// StripSignaturesPolicy is like StripSignatures, but parses the playbook as the verification
+// with the policy does, and serializes the plays in the first serialization profile of the policy.
+// YAML documents that are not lists of plays are skipped, as the verification skips them.
+func StripSignaturesPolicy(playbook []byte, policy Policy) ([][]byte, error) {
+	if policy.NormalizePayload {
+		playbook = NormalizePayload(playbook)
+	}
+	decoder := NewPlaybookDecoder(bytes.NewReader(playbook))
+	decoder.AllowDuplicateKeys = policy.AllowDuplicateKeys
+	decoder.MemoryBudget = policy.MemoryBudget
+	profile := policyProfiles(policy)[0]
+
+	var stripped [][]byte
+	for {
+		plays, err := decoder.Decode()
+		if err == io.EOF {
+			break
+		}
+		var notPlays notPlaysError
+		if errors.As(err, &notPlays) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		for i := range plays {
+			serialized, err := stripSignature(plays[i], profile)
+			if err != nil {
+				return nil, err
+			}
+			stripped = append(stripped, serialized)
+		}
+	}
+	if len(stripped) == 0 {
+		return nil, PlaybookError{ErrMalformedYAML, "playbook contains no plays", nil}
+	}
+	return stripped, nil
+}
+
+// StripSignature returns the serialized form of the play without its signature
+// and the other excluded elements.
+//
+// The play does not have to be signed yet, but it has to exclude its signature
+// ('/vars/insights_signature'), otherwise it could never be signed.
+func StripSignature(dirty *yaml3.Node) ([]byte, error) {
+	return stripSignature(dirty, DefaultSerializationProfile)
+}
+
+// stripSignature is StripSignature in the serialization profile.
+func stripSignature(dirty *yaml3.Node, profile string) ([]byte, error) {
+	exclusions, err := GetPlaybookExclusions(dirty)
+	if err != nil {
+		return nil, err
+	}
+	if !isExcluded([]string{"vars", "insights_signature"}, exclusions) {
+		return nil, VerificationError{ErrInvalidExclusion, "play does not exclude '/vars/insights_signature'", nil}
+	}
+
+	serialization, err := playSerialization(dirty)
+	if err != nil {
+		return nil, err
+	}
+	clean, _, err := cleanPlaybook(dirty)
+	if err != nil {
+		return nil, err
+	}
+	return serialization.Marshal(clean, profile)
+}
@@ -0,0 +1,66 @@
+package verifier
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestStripSignatures(t *testing.T) {
+	path := filepath.Join("testdata", "golden", "reboot.yml")
+	want := readTestdata(t, filepath.Join("testdata", "golden", "reboot.serialized"))
+
+	plays, err := StripSignatures(readTestdata(t, path))
+	if err != nil {
+		t.Fatalf("StripSignatures() error = %v", err)
+	}
+	if got := append(bytes.Join(plays, []byte("\n")), '\n'); !bytes.Equal(got, want) {
+		t.Errorf("StripSignatures() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestStripSignaturesNotExcluded(t *testing.T) {
+	tests := []struct {
+		name     string
+		playbook string
+		want     error
+	}{
+		{"no exclusions", "- name: play\n", ErrNoSignature},
+		{"signature not excluded", "- name: play\n  vars:\n    insights_signature_exclude: /hosts\n", ErrInvalidExclusion},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := StripSignatures([]byte(tt.playbook)); !errors.Is(err, tt.want) {
+				t.Errorf("StripSignatures() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestStripSignaturesPolicy(t *testing.T) {
+	reboot := readTestdata(t, filepath.Join("testdata", "golden", "reboot.yml"))
+	want := readTestdata(t, filepath.Join("testdata", "golden", "reboot.serialized"))
+	playbook := append([]byte("insights: metadata\n---\n"), bytes.ReplaceAll(reboot, []byte("\n"), []byte("\r\n"))...)
+
+	plays, err := StripSignaturesPolicy(playbook, Policy{NormalizePayload: true})
+	if err != nil {
+		t.Fatalf("StripSignaturesPolicy() error = %v", err)
+	}
+	if got := append(bytes.Join(plays, []byte("\n")), '\n'); !bytes.Equal(got, want) {
+		t.Errorf("StripSignaturesPolicy() =\n%s\nwant\n%s", got, want)
+	}
+
+	nonASCII := "- name: café\n  vars:\n    insights_signature_exclude: /vars/insights_signature\n"
+	plays, err = StripSignaturesPolicy([]byte(nonASCII), Policy{SerializationProfiles: []string{ProfilePython2, ProfilePython3}})
+	if err != nil {
+		t.Fatalf("StripSignaturesPolicy() error = %v", err)
+	}
+	if !bytes.Contains(plays[0], []byte(`u'caf\xe9'`)) {
+		t.Errorf("StripSignaturesPolicy() = %s, want the serialization of the python2 profile", plays[0])
+	}
+
+	if _, err = StripSignaturesPolicy([]byte("insights: metadata\n"), Policy{}); !errors.Is(err, ErrMalformedYAML) {
+		t.Errorf("StripSignaturesPolicy() error = %v, want %v", err, ErrMalformedYAML)
+	}
+}
@@ -0,0 +1,102 @@
+#!/usr/bin/env python3
+"""Generate the golden corpus with a second implementation of the serialization.
+
+The serialization and exclusion logic is written after the Python verifier of insights-core,
+but it is not insights-core: it runs on PyYAML's SafeLoader, with a stand-in 'ordereddict'
+class reproducing the repr of the ordered dictionaries of the Python verifier.
+Every play is loaded into ordered dictionaries, the excluded paths are deleted,
+and the digest is SHA-256 of str(play) encoded as UTF-8, unless the play declares
+another algorithm in 'insights_signature_hash'.
+
+Only exclusions of at most two segments ('/hosts', '/vars/NAME') are supported; playbooks
+with other exclusions are refused with ValueError. The scalars are constructed by the
+installed PyYAML, so the corpus only agrees with the verifier's YAML 1.1 construction
+as far as that version does.
+
+Usage:
+    GNUPGHOME=... ./generate.py [--sign] PLAYBOOK.yml...
+
+For each playbook, PLAYBOOK.serialized (one line per play) and PLAYBOOK.digest
+(one hex digest per play) are written. With --sign, signature placeholders
+(`insights_signature: PLACEHOLDER`) are replaced by signatures created by gpg
+with the default key of GNUPGHOME (see ../keys/test-private.asc).
+"""
+import base64
+import copy
+import hashlib
+import subprocess
+import sys
+from collections import OrderedDict
+
+import yaml
+
+EXCLUDABLE_VARIABLES = ["hosts", "vars"]
+
+
+class ordereddict(OrderedDict):
+    pass
+
+
+class Loader(yaml.SafeLoader):
+    pass
+
+
+def construct_mapping(loader, node):
+    loader.flatten_mapping(node)
+    return ordereddict(loader.construct_pairs(node))
+
+
+Loader.add_constructor(yaml.resolver.BaseResolver.DEFAULT_MAPPING_TAG, construct_mapping)
+
+
+def exclude_dynamic_elements(snippet):
+    exclusions = snippet["vars"]["insights_signature_exclude"].split(",")
+    for element in exclusions:
+        element = [string for string in element.split("/") if string != ""]
+        if len(element) == 1 and element[0] in EXCLUDABLE_VARIABLES and element[0] in snippet.keys():
+            del snippet[element[0]]
+        elif len(element) == 2 and element[0] in EXCLUDABLE_VARIABLES:
+            del snippet[element[0]][element[1]]
+        else:
+            raise ValueError("invalid exclusion: {0}".format(element))
+    return snippet
+
+
+def serialize(snippet):
+    return str(exclude_dynamic_elements(copy.deepcopy(snippet))).encode("UTF-8")
+
+
+def digest(snippet, serialized):
+    algorithm = snippet["vars"].get("insights_signature_hash", "sha256")
+    return hashlib.new(algorithm.replace("-", "_"), serialized)
+
+
+def sign(digest):
+    result = subprocess.run(["gpg", "--batch", "--armor", "--detach-sign"], input=digest, capture_output=True, check=True)
+    return base64.b64encode(result.stdout).decode()
+
+
+def main():
+    should_sign = "--sign" in sys.argv
+    for path in [arg for arg in sys.argv[1:] if not arg.startswith("--")]:
+        with open(path) as f:
+            raw = f.read()
+        plays = yaml.load(raw, Loader=Loader)
+
+        serialized = [serialize(play) for play in plays]
+        digests = [digest(play, serialized[i]) for i, play in enumerate(plays)]
+        if should_sign:
+            for play in digests:
+                raw = raw.replace("PLACEHOLDER", sign(play.digest()), 1)
+            with open(path, "w") as f:
+                f.write(raw)
+
+        base = path[: -len(".yml")]
+        with open(base + ".serialized", "wb") as f:
+            f.write(b"".join(play + b"\n" for play in serialized))
+        with open(base + ".digest", "w") as f:
+            f.write("".join(play.hexdigest() + "\n" for play in digests))
+
+
+if __name__ == "__main__":
+    main()
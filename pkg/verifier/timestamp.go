@@ -0,0 +1,146 @@
+package verifier
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Timestamp is a plain scalar PyYAML constructs a datetime.date or a datetime.datetime from,
+// e.g. '2024-01-01' or '2001-12-14 21:59:43.10 -5'.
+//
+// Its repr() is the one of the Python object, not of the string, so the play is hashed
+// the same way the signer hashed it.
+type Timestamp struct {
+	// Text is the scalar as written in the playbook.
+	Text string
+	// Date is set for scalars without a time, which are datetime.date objects.
+	Date bool
+
+	Year, Month, Day                  int
+	Hour, Minute, Second, Microsecond int
+	// Zoned is set for scalars with a time zone, including 'Z'.
+	Zoned bool
+	// Offset is the offset of the time zone from UTC.
+	Offset time.Duration
+}
+
+// timestampPattern matches the plain scalars PyYAML resolves as timestamps.
+var timestampPattern = regexp.MustCompile(`^(?:[0-9]{4}-[0-9]{2}-[0-9]{2}` +
+	`|[0-9]{4}-[0-9]{1,2}-[0-9]{1,2}(?:[Tt]|[ \t]+)[0-9]{1,2}:[0-9]{2}:[0-9]{2}(?:\.[0-9]*)?` +
+	`(?:[ \t]*(?:Z|[-+][0-9]{1,2}(?::[0-9]{2})?))?)$`)
+
+// timestampFields captures the fields of a timestamp the way PyYAML's constructor does.
+var timestampFields = regexp.MustCompile(`^([0-9]{4})-([0-9]{1,2})-([0-9]{1,2})` +
+	`(?:(?:[Tt]|[ \t]+)([0-9]{1,2}):([0-9]{2}):([0-9]{2})(?:\.([0-9]*))?` +
+	`(?:[ \t]*(Z|([-+])([0-9]{1,2})(?::([0-9]{2}))?))?)?$`)
+
+// ParseTimestamp parses the scalar the way PyYAML's timestamp constructor does.
+//
+// Dates that do not exist (e.g. '2024-02-30') are refused, since Python cannot load them either.
+func ParseTimestamp(text string) (Timestamp, error) {
+	fields := timestampFields.FindStringSubmatch(text)
+	if fields == nil {
+		return Timestamp{}, PlaybookError{ErrMalformedYAML, fmt.Sprintf("'%s' is not a timestamp", text), nil}
+	}
+	number := func(i int) int {
+		n, _ := strconv.Atoi(fields[i])
+		return n
+	}
+	t := Timestamp{Text: text, Year: number(1), Month: number(2), Day: number(3), Date: fields[4] == ""}
+	if !t.Date {
+		t.Hour, t.Minute, t.Second = number(4), number(5), number(6)
+		// Python keeps microseconds, further digits are dropped
+		fraction := fields[7]
+		if len(fraction) > 6 {
+			fraction = fraction[:6]
+		}
+		if fraction != "" {
+			t.Microsecond, _ = strconv.Atoi(fraction + strings.Repeat("0", 6-len(fraction)))
+		}
+		if fields[8] != "" {
+			t.Zoned = true
+			t.Offset = time.Duration(number(10))*time.Hour + time.Duration(number(11))*time.Minute
+			if fields[9] == "-" {
+				t.Offset = -t.Offset
+			}
+		}
+	}
+
+	date := time.Date(t.Year, time.Month(t.Month), t.Day, 0, 0, 0, 0, time.UTC)
+	switch {
+	case t.Year < 1, date.Month() != time.Month(t.Month), date.Day() != t.Day:
+		return Timestamp{}, PlaybookError{ErrMalformedYAML, fmt.Sprintf("timestamp '%s' is not a valid date", text), nil}
+	case t.Hour > 23 || t.Minute > 59 || t.Second > 59:
+		return Timestamp{}, PlaybookError{ErrMalformedYAML, fmt.Sprintf("timestamp '%s' is not a valid time", text), nil}
+	case t.Offset <= -24*time.Hour || t.Offset >= 24*time.Hour:
+		return Timestamp{}, PlaybookError{ErrMalformedYAML, fmt.Sprintf("timestamp '%s' is not a valid time zone", text), nil}
+	}
+	return t, nil
+}
+
+// Time returns the timestamp as time.Time. Timestamps without a time zone are in UTC.
+func (t Timestamp) Time() time.Time {
+	return time.Date(t.Year, time.Month(t.Month), t.Day, t.Hour, t.Minute, t.Second, t.Microsecond*1000, time.UTC).Add(-t.Offset)
+}
+
+// String returns the timestamp as it was written.
+func (t Timestamp) String() string {
+	return t.Text
+}
+
+// formatTimestamp formats the timestamp the same way repr() of the object PyYAML constructs does.
+//
+// PyYAML 3 of Python 2 converts timestamps with a time zone to naive datetimes in UTC,
+// while PyYAML 5.3 and later keep the time zone.
+func (s serializer) formatTimestamp(t Timestamp) string {
+	if t.Date {
+		return fmt.Sprintf("datetime.date(%d, %d, %d)", t.Year, t.Month, t.Day)
+	}
+	zone := ""
+	if s.python2 {
+		utc := t.Time()
+		t.Year, t.Month, t.Day = utc.Year(), int(utc.Month()), utc.Day()
+		t.Hour, t.Minute, t.Second = utc.Hour(), utc.Minute(), utc.Second()
+	} else if t.Zoned {
+		zone = ", tzinfo=datetime.timezone.utc"
+		if t.Offset != 0 {
+			zone = ", tzinfo=datetime.timezone(" + formatTimedelta(t.Offset) + ")"
+		}
+	}
+
+	// repr() omits the seconds and microseconds when they are zero
+	formatted := fmt.Sprintf("datetime.datetime(%d, %d, %d, %d, %d", t.Year, t.Month, t.Day, t.Hour, t.Minute)
+	if t.Second != 0 || t.Microsecond != 0 {
+		formatted += fmt.Sprintf(", %d", t.Second)
+	}
+	if t.Microsecond != 0 {
+		formatted += fmt.Sprintf(", %d", t.Microsecond)
+	}
+	return formatted + zone + ")"
+}
+
+// formatTimedelta formats the duration of whole minutes the same way repr() of a
+// datetime.timedelta does since Python 3.7: normalized to days and non-negative seconds.
+func formatTimedelta(d time.Duration) string {
+	seconds := int64(d / time.Second)
+	days := seconds / 86400
+	seconds %= 86400
+	if seconds < 0 {
+		days--
+		seconds += 86400
+	}
+	var fields []string
+	if days != 0 {
+		fields = append(fields, fmt.Sprintf("days=%d", days))
+	}
+	if seconds != 0 {
+		fields = append(fields, fmt.Sprintf("seconds=%d", seconds))
+	}
+	if len(fields) == 0 {
+		return "datetime.timedelta(0)"
+	}
+	return "datetime.timedelta(" + strings.Join(fields, ", ") + ")"
+}
@@ -0,0 +1,49 @@
+package verifier
+
+import (
+	"errors"
+	"testing"
+)
+
+// Expected values were produced by repr() of the objects PyYAML 3.10 of Python 2.7 constructs.
+func TestFormatTimestampPython2(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"2024-01-01", "datetime.date(2024, 1, 1)"},
+		{"2024-06-30T22:00:00Z", "datetime.datetime(2024, 6, 30, 22, 0)"},
+		{"2024-07-01 00:30:00.25 +02:00", "datetime.datetime(2024, 6, 30, 22, 30, 0, 250000)"},
+		{"2001-12-14t21:59:43.10-05:00", "datetime.datetime(2001, 12, 15, 2, 59, 43, 100000)"},
+		{"2002-12-14 3:04:05", "datetime.datetime(2002, 12, 14, 3, 4, 5)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			timestamp, err := ParseTimestamp(tt.text)
+			if err != nil {
+				t.Fatalf("ParseTimestamp() error = %v", err)
+			}
+			got, err := serializers[ProfilePython2].marshallScalar(timestamp)
+			if err != nil {
+				t.Fatalf("marshallScalar() error = %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("marshallScalar() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTimestampInvalid(t *testing.T) {
+	for _, text := range []string{"2024-13-01", "2023-02-29", "0000-01-01", "2024-01-01 24:00:00", "2024-01-01 10:00:60", "2024-01-01 10:00:00 +24"} {
+		t.Run(text, func(t *testing.T) {
+			if _, err := ParseTimestamp(text); !errors.Is(err, ErrMalformedYAML) {
+				t.Errorf("ParseTimestamp() error = %v, want %v", err, ErrMalformedYAML)
+			}
+			playbook := "- name: invalid\n  vars:\n    date: " + text + "\n"
+			if _, err := UnmarshalPlaybook([]byte(playbook)); !errors.Is(err, ErrMalformedYAML) {
+				t.Errorf("UnmarshalPlaybook() error = %v, want %v", err, ErrMalformedYAML)
+			}
+		})
+	}
+}
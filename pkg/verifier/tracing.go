@@ -0,0 +1,22 @@
+package verifier
+
+import (
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracer creates the spans of the verification stages.
+//
+// It uses the global tracer provider, so the spans are discarded unless the application
+// configures one.
+var tracer = otel.Tracer("com.github/m-horky/playbook-verifier/pkg/verifier")
+
+// endSpan marks the span as failed if there is an error, and ends it.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,54 @@
+package verifier
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestVerifyContextSpans(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	playbook := readTestdata(t, filepath.Join("testdata", "golden", "cve-update.yml"))
+	if _, err := VerifyContext(context.Background(), PlaybookContentType, playbook, testKeyring(t), Policy{}); err != nil {
+		t.Fatalf("VerifyContext() error = %v", err)
+	}
+
+	spans := map[string]sdktrace.ReadOnlySpan{}
+	for _, span := range recorder.Ended() {
+		spans[span.Name()] = span
+	}
+	for _, name := range []string{"verify", "verify playbook", "parse", "verify play", "clean", "serialize", "hash", "gpg verify"} {
+		if _, ok := spans[name]; !ok {
+			t.Errorf("span %q was not recorded", name)
+		}
+	}
+
+	attributes := func(span sdktrace.ReadOnlySpan) map[attribute.Key]attribute.Value {
+		values := map[attribute.Key]attribute.Value{}
+		for _, kv := range span.Attributes() {
+			values[kv.Key] = kv.Value
+		}
+		return values
+	}
+	if size := attributes(spans["verify"])["payload.size"].AsInt64(); size != int64(len(playbook)) {
+		t.Errorf("payload.size = %d, want %d", size, len(playbook))
+	}
+	if plays := attributes(spans["verify playbook"])["playbook.plays"].AsInt64(); plays != 1 {
+		t.Errorf("playbook.plays = %d, want 1", plays)
+	}
+	if keyID := attributes(spans["gpg verify"])["signature.key_id"].AsString(); keyID == "" {
+		t.Error("signature.key_id is empty")
+	}
+	if spans["gpg verify"].Parent().SpanID() != spans["verify play"].SpanContext().SpanID() {
+		t.Error("gpg verify is not a child of verify play")
+	}
+}
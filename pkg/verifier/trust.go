@@ -0,0 +1,62 @@
+package verifier
+
+import (
+	"encoding/hex"
+	"slices"
+	"strings"
+)
+
+// Trust levels of the keys that create signatures, see Keyring.WarnOnly.
+const (
+	// TrustLevelTrusted keys create signatures that are accepted.
+	TrustLevelTrusted = "trusted"
+	// TrustLevelWarnOnly keys create signatures that are accepted with a warning, e.g. the keys of
+	// a new signing infrastructure during its staged rollout.
+	TrustLevelWarnOnly = "warn-only"
+)
+
+// WarnOnly makes the keyring accept signatures created by the keys only with a warning:
+// the payload is verified, but the reports say the signature was created by a warn-only key.
+//
+// The keys are identified like by Revoke. Only OpenPGP and Ed25519 keys can be warn-only;
+// Sigstore identities and certificate authorities are always trusted.
+func (k *Keyring) WarnOnly(fingerprints ...string) error {
+	for _, fingerprint := range fingerprints {
+		normalized, err := normalizeFingerprint(fingerprint)
+		if err != nil {
+			return err
+		}
+		k.warnOnly = append(k.warnOnly, normalized)
+	}
+	return nil
+}
+
+// WarnOnlyKeys returns the fingerprints and key IDs of the warn-only keys.
+func (k *Keyring) WarnOnlyKeys() []string {
+	return append([]string(nil), k.warnOnly...)
+}
+
+// TrustLevel returns the trust level of the key of the ID returned by Verify.
+func (k *Keyring) TrustLevel(keyID string) string {
+	if len(k.warnOnly) == 0 {
+		return TrustLevelTrusted
+	}
+	if slices.Contains(k.warnOnly, keyID) {
+		return TrustLevelWarnOnly
+	}
+	for _, entity := range k.entities {
+		if entity.PrimaryKey.KeyIdString() != keyID {
+			continue
+		}
+		fingerprint := strings.ToUpper(hex.EncodeToString(entity.PrimaryKey.Fingerprint))
+		if slices.ContainsFunc(k.warnOnly, func(warnOnly string) bool { return strings.HasSuffix(fingerprint, warnOnly) }) {
+			return TrustLevelWarnOnly
+		}
+	}
+	for _, key := range k.ed25519Keys {
+		if key.keyID() == keyID && slices.Contains(k.warnOnly, strings.ToUpper(hex.EncodeToString(key.key))) {
+			return TrustLevelWarnOnly
+		}
+	}
+	return TrustLevelTrusted
+}
@@ -0,0 +1,90 @@
+package verifier
+
+import (
+	"errors"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestKeyringWarnOnly(t *testing.T) {
+	playbook := readTestdata(t, filepath.Join("testdata", "golden", "cve-update.yml"))
+
+	tests := []struct {
+		name     string
+		warnOnly []string
+		want     string
+	}{
+		{"none", nil, TrustLevelTrusted},
+		{"other key", []string{"E544FCAF4E2D23F9"}, TrustLevelTrusted},
+		{"fingerprint", []string{"F138 4BE3 CDF0 D33E 73A1  F3F7 08D8 2F69 81A5 FD2F"}, TrustLevelWarnOnly},
+		{"key ID", []string{"08d82f6981a5fd2f"}, TrustLevelWarnOnly},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keyring := testKeyring(t)
+			if err := keyring.WarnOnly(tt.warnOnly...); err != nil {
+				t.Fatalf("WarnOnly() error = %v", err)
+			}
+			report, err := VerifyPlaybook(playbook, keyring, Policy{})
+			if err != nil {
+				t.Fatalf("VerifyPlaybook() error = %v", err)
+			}
+			if got := report.Plays[0].TrustLevel; got != tt.want {
+				t.Errorf("VerifyPlaybook() trust level = %s, want %s", got, tt.want)
+			}
+			var want []string
+			if tt.want == TrustLevelWarnOnly {
+				want = []string{"08D82F6981A5FD2F"}
+			}
+			if got := report.WarnOnlyKeyIDs(); !slices.Equal(got, want) {
+				t.Errorf("WarnOnlyKeyIDs() = %v, want %v", got, want)
+			}
+		})
+	}
+
+	if err := testKeyring(t).WarnOnly("08D82F69"); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("WarnOnly(08D82F69) error = %v, want %v", err, ErrInvalidKey)
+	}
+}
+
+// Ed25519 keys are warn-only by their public key, the fingerprint the keyring reports for them.
+func TestKeyringWarnOnlyMinisign(t *testing.T) {
+	minisign, err := NewKeyring(readTestdata(t, filepath.Join("testdata", "keys", "test-minisign.pub")))
+	if err != nil {
+		t.Fatalf("NewKeyring() error = %v", err)
+	}
+	keyring := minisignKeyring(t)
+	if err = keyring.WarnOnly(minisign.Fingerprints()...); err != nil {
+		t.Fatalf("WarnOnly() error = %v", err)
+	}
+
+	report, err := VerifyPlaybook(readTestdata(t, filepath.Join("testdata", "minisign", "playbook.yml")), keyring, Policy{})
+	if err != nil {
+		t.Fatalf("VerifyPlaybook() error = %v", err)
+	}
+	if got := report.WarnOnlyKeyIDs(); !slices.Equal(got, []string{"B86D14907EC2513A"}) {
+		t.Errorf("WarnOnlyKeyIDs() = %v, want [B86D14907EC2513A]", got)
+	}
+	// the OpenPGP key of the same keyring is still trusted
+	report, err = VerifyPlaybook(readTestdata(t, filepath.Join("testdata", "golden", "cve-update.yml")), keyring, Policy{})
+	if err != nil || report.Plays[0].TrustLevel != TrustLevelTrusted {
+		t.Errorf("VerifyPlaybook() trust level = %s, %v, want %s", report.Plays[0].TrustLevel, err, TrustLevelTrusted)
+	}
+}
+
+func TestVerifyDetachedWarnOnly(t *testing.T) {
+	keyring := testKeyring(t)
+	if err := keyring.WarnOnly("08D82F6981A5FD2F"); err != nil {
+		t.Fatalf("WarnOnly() error = %v", err)
+	}
+	payload := readTestdata(t, filepath.Join("testdata", "detached", "playbook.yml"))
+	report, err := VerifyDetached(payload, readTestdata(t, filepath.Join("testdata", "detached", "playbook.yml.asc")), keyring)
+	if err != nil {
+		t.Fatalf("VerifyDetached() error = %v", err)
+	}
+	if !report.Verified || report.TrustLevel != TrustLevelWarnOnly {
+		t.Errorf("VerifyDetached() verified = %v, trust level = %s, want a verified warn-only payload", report.Verified, report.TrustLevel)
+	}
+}
@@ -0,0 +1,31 @@
+package verifier
+
+import (
+	"fmt"
+	"time"
+)
+
+// now returns the current time, tests replace it.
+var now = time.Now
+
+// checkSignatureAge refuses signatures created outside of the validity window of the policy.
+//
+// The signature has to be verified already, so its creation time can be trusted.
+// Signatures that do not record their creation time, like minisign ones, cannot be
+// checked and are refused when the policy has a validity window.
+func checkSignatureAge(info *SignatureInfo, policy Policy) error {
+	if policy.MaxSignatureAge <= 0 {
+		return nil
+	}
+	if info == nil || info.Created.IsZero() {
+		return VerificationError{ErrExpiredSignature, "signature does not record its creation time", nil}
+	}
+	current := now()
+	if age := current.Sub(info.Created); age > policy.MaxSignatureAge {
+		return VerificationError{ErrExpiredSignature, fmt.Sprintf("signature was created %s ago, at most %s is allowed", age.Round(time.Second), policy.MaxSignatureAge), nil}
+	}
+	if info.Created.After(current.Add(policy.ClockSkew)) {
+		return VerificationError{ErrExpiredSignature, fmt.Sprintf("signature was created in the future, at %s", info.Created.Format(time.RFC3339)), nil}
+	}
+	return nil
+}
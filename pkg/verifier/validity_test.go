@@ -0,0 +1,56 @@
+package verifier
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCheckSignatureAge(t *testing.T) {
+	created := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	t.Cleanup(func() { now = time.Now })
+	now = func() time.Time { return created.Add(24 * time.Hour) }
+	policy := Policy{MaxSignatureAge: 48 * time.Hour, ClockSkew: 5 * time.Minute}
+
+	tests := []struct {
+		name    string
+		info    *SignatureInfo
+		policy  Policy
+		wantErr bool
+	}{
+		{"no policy", &SignatureInfo{Created: created.Add(-time.Hour * 24 * 365)}, Policy{}, false},
+		{"fresh", &SignatureInfo{Created: created}, policy, false},
+		{"too old", &SignatureInfo{Created: created.Add(-48 * time.Hour)}, policy, true},
+		{"within clock skew", &SignatureInfo{Created: created.Add(24*time.Hour + time.Minute)}, policy, false},
+		{"in the future", &SignatureInfo{Created: created.Add(25 * time.Hour)}, policy, true},
+		{"no creation time", &SignatureInfo{Scheme: SchemeMinisign}, policy, true},
+		{"unknown signature", nil, policy, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkSignatureAge(tt.info, tt.policy)
+			if (err != nil) != tt.wantErr || (err != nil && !errors.Is(err, ErrExpiredSignature)) {
+				t.Errorf("checkSignatureAge() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestVerifyDetachedPolicy(t *testing.T) {
+	playbook := readTestdata(t, filepath.Join("testdata", "detached", "playbook.yml"))
+	signature := readTestdata(t, filepath.Join("testdata", "detached", "playbook.yml.asc"))
+	info, err := ParseSignature(signature)
+	if err != nil {
+		t.Fatalf("ParseSignature() error = %v", err)
+	}
+	t.Cleanup(func() { now = time.Now })
+	now = func() time.Time { return info.Created.Add(31 * 24 * time.Hour) }
+
+	if _, err = VerifyDetachedPolicy(playbook, signature, testKeyring(t), Policy{MaxSignatureAge: 30 * 24 * time.Hour}); !errors.Is(err, ErrExpiredSignature) {
+		t.Errorf("VerifyDetachedPolicy() error = %v, want %v", err, ErrExpiredSignature)
+	}
+	if _, err = VerifyDetachedPolicy(playbook, signature, testKeyring(t), Policy{MaxSignatureAge: 60 * 24 * time.Hour}); err != nil {
+		t.Errorf("VerifyDetachedPolicy() error = %v", err)
+	}
+}
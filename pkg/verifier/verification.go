@@ -0,0 +1,35 @@
+package verifier
+
+import (
+	"encoding/base64"
+	"fmt"
+
+	yaml3 "gopkg.in/yaml.v3"
+)
+
+// GetPlaybookSignature extracts the signature from the playbook variables and decodes it.
+//
+// The signature is stored base64-encoded in the key 'insights_signature'.
+func GetPlaybookSignature(p *yaml3.Node) ([]byte, error) {
+	value, _ := getPlaybookVariable(p, "insights_signature")
+	rawSignature, ok := stringValue(value)
+	if !ok {
+		return nil, VerificationError{ErrMalformedSignature, fmt.Sprintf("'insights_signature' must be a string, not %s", yamlTypeName(value)), nil}
+	}
+
+	if rawSignature == "" {
+		return nil, MissingSignatureError{ErrNoSignature, "playbook doesn't contain key 'insights_signature'", nil}
+	}
+
+	signature, err := base64.StdEncoding.DecodeString(rawSignature)
+	if err != nil {
+		return nil, VerificationError{ErrMalformedSignature, "signature is not valid base64", err}
+	}
+	return signature, nil
+}
+
+// VerifySignature checks that the detached GPG signature was created over the digest
+// by one of the keys in the keyring, and returns ID of that key.
+func VerifySignature(digest []byte, signature []byte, keyring *Keyring) (string, error) {
+	return keyring.Verify(digest, signature)
+}
@@ -0,0 +1,362 @@
+// Package verifier checks that Insights playbooks have been signed by a trusted key.
+//
+// Every play of the playbook is verified on its own: the signature is extracted from
+// its variables, the dynamic elements listed in 'insights_signature_exclude' are removed,
+// the rest is serialized the same way the Python implementation does it, and the digest
+// of the serialized play is checked against the signature.
+//
+// Signatures are verified in pure Go. The package never runs gpg, so it needs no
+// GnuPG home directory, agent socket or binaries at runtime.
+package verifier
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"runtime/debug"
+	"sync"
+
+	"go.opentelemetry.io/otel/attribute"
+	yaml3 "gopkg.in/yaml.v3"
+
+	"com.github/m-horky/playbook-verifier/internal/hygiene"
+)
+
+// VerifyPlaybook parses the raw playbook and verifies each of its plays.
+//
+// The plays must be signed with one of the keys in the keyring. All plays are verified
+// even if some of them fail, so the report is complete; the first error is returned.
+func VerifyPlaybook(playbook []byte, keyring *Keyring, policy Policy) (Report, error) {
+	return VerifyPlaybookContext(context.Background(), playbook, keyring, policy)
+}
+
+// VerifyPlaybookContext is like VerifyPlaybook, but records the stages of the verification
+// as spans of the context's trace.
+func VerifyPlaybookContext(ctx context.Context, playbook []byte, keyring *Keyring, policy Policy) (Report, error) {
+	return VerifyPlaybookReaderContext(ctx, bytes.NewReader(playbook), keyring, policy)
+}
+
+// VerifyPlaybookReader is like VerifyPlaybook, but decodes the playbook from the reader
+// one YAML document at a time.
+//
+// Documents that contain no signed play are skipped unless the policy requires all of them
+// to be signed.
+func VerifyPlaybookReader(r io.Reader, keyring *Keyring, policy Policy) (Report, error) {
+	return VerifyPlaybookReaderContext(context.Background(), r, keyring, policy)
+}
+
+// VerifyPlaybookReaderContext is like VerifyPlaybookReader, but records the stages
+// of the verification as spans of the context's trace.
+func VerifyPlaybookReaderContext(ctx context.Context, r io.Reader, keyring *Keyring, policy Policy) (report Report, err error) {
+	ctx, s := startStage(ctx, StagePlaybook)
+	defer func() {
+		s.span.SetAttributes(
+			attribute.Int("playbook.plays", len(report.Plays)),
+			attribute.Int("playbook.skipped_documents", len(report.SkippedDocuments)),
+		)
+		s.end(err)
+	}()
+
+	report = Report{Plays: []PlayReport{}, SkippedDocuments: []int{}}
+	fail := func(err error) (Report, error) {
+		report.Error = err.Error()
+		return report, err
+	}
+
+	var detector artifactDetector
+	if policy.NormalizePayload {
+		r = newNormalizingReader(r)
+	} else {
+		r = io.TeeReader(r, &detector)
+	}
+	decoder := NewPlaybookDecoder(r)
+	decoder.AllowDuplicateKeys = policy.AllowDuplicateKeys
+	decoder.MemoryBudget = policy.MemoryBudget
+	var firstErr error
+	// verified are the plays of the reports, so their signatures can be located
+	var verified []*yaml3.Node
+	for document := 0; ; document++ {
+		if err := ctx.Err(); err != nil {
+			return fail(err)
+		}
+		documentCtx := contextWithPosition(ctx, document, -1)
+		_, parse := startStage(documentCtx, StageParse, attribute.Int("playbook.document", document))
+		plays, err := decoder.Decode()
+		if err == io.EOF {
+			parse.end(nil)
+			break
+		}
+		parse.span.SetAttributes(attribute.Int("document.plays", len(plays)))
+		parse.end(err)
+		// The decoder does not keep the error of the reader, which fails once the context is done.
+		if err != nil && ctx.Err() != nil {
+			return fail(ctx.Err())
+		}
+
+		// Documents that are valid YAML, but not a list of plays, are treated as unsigned.
+		var notPlays notPlaysError
+		if err != nil && !errors.As(err, &notPlays) {
+			return fail(err)
+		}
+
+		offset, length := decoder.Range()
+		report.Documents = append(report.Documents, DocumentRange{Index: document, Offset: offset, Length: length})
+		if err != nil || !isSignedDocument(plays) {
+			if policy.RequireAllSigned {
+				return fail(MissingSignatureError{ErrNoSignature, fmt.Sprintf("document %d is not signed", document), err})
+			}
+			if acceptsUnsigned(policy) {
+				logUnsigned(ctx, policy, "accepting unsigned document", slog.Int("document", document))
+				report.UnsignedDocuments = append(report.UnsignedDocuments, document)
+				continue
+			}
+			Logger(ctx).Warn("skipping unsigned document", slog.Int("document", document))
+			report.SkippedDocuments = append(report.SkippedDocuments, document)
+			report.Documents[len(report.Documents)-1].Skipped = true
+			continue
+		}
+
+		playReports, errs := verifyPlays(documentCtx, plays, len(report.Plays), keyring, policy)
+		if err := ctx.Err(); err != nil {
+			return fail(err)
+		}
+		for i, playReport := range playReports {
+			index := len(report.Plays)
+			err := errs[i]
+			playReport.Index = index
+			playReport.Document = document
+			playReport.Tasks = countTasks(plays[i])
+			if errors.Is(err, ErrNoSignature) && acceptsUnsigned(policy) {
+				logUnsigned(ctx, policy, "accepting unsigned play", slog.Int("play", index), slog.Any("error", err))
+				playReport.Unsigned = true
+				err = nil
+			}
+			if err == nil && len(policy.Rules) > 0 {
+				// the rules of the content apply to unsigned plays too, the rules of the key cannot
+				violations := checkPlay(policy, index, plays[i])
+				if !playReport.Unsigned {
+					violations = append(checkKey(policy, index, playReport.KeyID, playReport.Signature), violations...)
+				}
+				report.Violations = append(report.Violations, violations...)
+				if err = violationsError(violations); err != nil {
+					playReport.Verified = false
+					playReport.Error = err.Error()
+				}
+			}
+			if policy.ReportDiff {
+				playReport.Diff = diffReport(ctx, plays[i], index)
+			}
+			if index < len(policy.ReferenceSerializations) {
+				compareReference(playReport.Diagnosis, plays[i], policy.ReferenceSerializations[index])
+			}
+			report.Plays = append(report.Plays, playReport)
+			verified = append(verified, plays[i])
+			contextObserver(ctx).OnVerdict(contextWithPosition(ctx, document, index), Verdict{
+				Document: document, Play: index, Name: playReport.Name, Verified: playReport.Verified, Unsigned: playReport.Unsigned, KeyID: playReport.KeyID, Err: err,
+			})
+			if err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+
+	if len(report.Plays) == 0 && len(report.UnsignedDocuments) == 0 {
+		if len(report.SkippedDocuments) > 0 {
+			return fail(MissingSignatureError{ErrNoSignature, "playbook contains no signed document", nil})
+		}
+		return fail(PlaybookError{ErrMalformedYAML, "playbook contains no data", nil})
+	}
+	if firstErr != nil {
+		locateSignatures(report.Plays, verified, keyring)
+		firstErr = withArtifactsHint(firstErr, detector.yamlArtifacts())
+		report.Error = firstErr.Error()
+		return report, firstErr
+	}
+	report.Verified = true
+	return report, nil
+}
+
+// verifyPlays verifies the plays of the document the context belongs to, using up to the given number
+// of goroutines. The first play has the index of first in the playbook.
+//
+// The reports and errors are returned in the order of the plays.
+func verifyPlays(ctx context.Context, plays []*yaml3.Node, first int, keyring *Keyring, policy Policy) ([]PlayReport, []error) {
+	document := contextPosition(ctx).document
+	jobs := policy.Jobs
+	reports := make([]PlayReport, len(plays))
+	errs := make([]error, len(plays))
+	if jobs <= 1 || len(plays) <= 1 {
+		for i := range plays {
+			reports[i], errs[i] = verifyPlay(contextWithPosition(ctx, document, first+i), plays[i], keyring, policy)
+		}
+		return reports, errs
+	}
+
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for range min(jobs, len(plays)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				reports[i], errs[i] = verifyPlay(contextWithPosition(ctx, document, first+i), plays[i], keyring, policy)
+			}
+		}()
+	}
+	for i := range plays {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+	return reports, errs
+}
+
+// VerifyPlay checks that the signature of a single play matches its content.
+func VerifyPlay(dirty *yaml3.Node, keyring *Keyring) (PlayReport, error) {
+	return verifyPlay(context.Background(), dirty, keyring, Policy{})
+}
+
+// verifyPlay verifies a single play, turning a panic of the verification into a VerificationError,
+// so a play that trips a bug cannot take the other plays or the whole service down.
+func verifyPlay(ctx context.Context, dirty *yaml3.Node, keyring *Keyring, policy Policy) (report PlayReport, err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			Logger(ctx).Debug("verification panicked", slog.Any("panic", recovered), slog.String("stack", string(debug.Stack())))
+			err = VerificationError{ErrInternal, fmt.Sprintf("verification panicked: %v", recovered), nil}
+			report = PlayReport{Excluded: []string{}, Exclusions: []ExcludedPath{}, Error: err.Error()}
+		}
+	}()
+	return verifyPlayStages(ctx, dirty, keyring, policy)
+}
+
+// verifyPlayStages verifies a single play, recording each stage as a span of the context's trace
+// and notifying the observer of the verification.
+func verifyPlayStages(ctx context.Context, dirty *yaml3.Node, keyring *Keyring, policy Policy) (report PlayReport, err error) {
+	report = PlayReport{Name: getPlayName(dirty), Excluded: []string{}, Exclusions: []ExcludedPath{}}
+	ctx, s := startStage(ctx, StagePlay, attribute.String("play.name", report.Name))
+	defer func() { s.end(err) }()
+	var attempts []SerializationAttempt
+	fail := func(err error) (PlayReport, error) {
+		report.Error = err.Error()
+		report.Diagnosis = diagnose(err, report.Signature, keyring)
+		if report.Diagnosis != nil && report.Diagnosis.Cause == CauseDigestMismatch {
+			report.Diagnosis.Attempts = attempts
+		}
+		return report, err
+	}
+
+	if err := ctx.Err(); err != nil {
+		return fail(err)
+	}
+
+	// Extract the signature
+	signature, err := GetPlaybookSignature(dirty)
+	if err != nil {
+		return fail(err)
+	}
+	defer hygiene.Wipe(signature)
+	report.Signature = parseSignatureMetadata(Logger(ctx), signature)
+
+	if policy.Strict {
+		if err = checkPlayKeywords(dirty); err != nil {
+			return fail(err)
+		}
+	}
+
+	algorithm, err := playHashAlgorithm(dirty, policy)
+	if err != nil {
+		return fail(err)
+	}
+	report.HashAlgorithm = algorithm.Name()
+
+	serialization, err := playSerialization(dirty)
+	if err != nil {
+		return fail(err)
+	}
+	report.SerializationVersion = serialization.Version()
+
+	// Delete dynamic elements
+	_, cleaning := startStage(ctx, StageClean)
+	clean, excluded, err := cleanPlaybookContext(ctx, dirty)
+	cleaning.span.SetAttributes(attribute.StringSlice("play.excluded", excludedPaths(excluded)))
+	cleaning.end(err)
+	if err != nil {
+		return fail(err)
+	}
+	observeExclusions(ctx, excluded)
+	report.Excluded = append(report.Excluded, excludedPaths(excluded)...)
+	report.Exclusions = append(report.Exclusions, excluded...)
+
+	// Verify it in each enabled serialization profile, until one matches
+	profiles := policyProfiles(policy)
+	var keyID string
+	for i, profile := range profiles {
+		var digest string
+		digest, keyID, err = verifyProfile(ctx, clean, serialization, profile, algorithm, signature, keyring)
+		if i == 0 || err == nil {
+			report.Digest = digest
+		}
+		if err == nil {
+			report.SerializationProfile = profile
+			break
+		}
+		if !errors.Is(err, ErrDigestMismatch) {
+			break
+		}
+		attempts = append(attempts, SerializationAttempt{Profile: profile, Digest: digest})
+		Logger(ctx).Debug("signature does not match the serialization profile", slog.String("profile", profile))
+	}
+	s.span.SetAttributes(attribute.String("signature.key_id", keyID))
+	if err == nil {
+		err = checkSignatureAge(report.Signature, policy)
+	}
+	if err != nil {
+		return fail(err)
+	}
+	report.KeyID = keyID
+	report.TrustLevel = keyring.TrustLevel(keyID)
+	if report.TrustLevel == TrustLevelWarnOnly {
+		Logger(ctx).Warn("play signed by a warn-only key", slog.String("key", keyID))
+	}
+	report.Verified = true
+	return report, nil
+}
+
+// verifyProfile serializes the cleaned play in the serialization profile, hashes it and verifies the signature
+// of the digest. It returns the hex-encoded digest and the ID of the key that created the signature.
+func verifyProfile(ctx context.Context, clean *yaml3.Node, serialization Serialization, profile string, algorithm HashAlgorithm, signature []byte, keyring *Keyring) (string, string, error) {
+	// Serialize it, straight into the hash if possible
+	_, stage := startStage(ctx, StageSerialize,
+		attribute.String("play.serialization_version", serialization.Version()),
+		attribute.String("play.serialization_profile", profile),
+	)
+	digest, size, err := sumPlay(ctx, clean, serialization, profile, algorithm)
+	stage.span.SetAttributes(attribute.Int("play.serialized_size", size))
+	stage.end(err)
+	if err != nil {
+		return "", "", err
+	}
+
+	// Report the hash
+	_, stage = startStage(ctx, StageHash, attribute.String("play.hash_algorithm", algorithm.Name()))
+	hexDigest := hex.EncodeToString(digest)
+	stage.span.SetAttributes(attribute.String("play.digest", hexDigest))
+	stage.end(nil)
+
+	// Verify the hash
+	if err = ctx.Err(); err != nil {
+		return hexDigest, "", err
+	}
+	_, stage = startStage(ctx, StageSignature)
+	keyID, err := keyring.verify(Logger(ctx), digest, signature)
+	if err == nil {
+		Logger(ctx).Debug("signature verified", slog.String("key", keyID))
+	}
+	stage.span.SetAttributes(attribute.String("signature.key_id", keyID))
+	stage.end(err)
+	return hexDigest, keyID, err
+}
@@ -0,0 +1,351 @@
+package verifier
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	yaml3 "gopkg.in/yaml.v3"
+)
+
+func TestVerifyPlaybookDocuments(t *testing.T) {
+	keyring := testKeyring(t)
+	signed := readTestdata(t, filepath.Join("testdata", "golden", "cve-update.yml"))
+	reboot := readTestdata(t, filepath.Join("testdata", "golden", "reboot.yml"))
+	join := func(documents ...[]byte) []byte {
+		return bytes.Join(documents, []byte("---\n"))
+	}
+
+	tests := []struct {
+		name     string
+		playbook []byte
+		policy   Policy
+		plays    int
+		skipped  []int
+		want     error
+	}{
+		{"single document", signed, Policy{}, 1, []int{}, nil},
+		{"signed documents", join(signed, reboot), Policy{}, 4, []int{}, nil},
+		{"unsigned play", join(signed, []byte("- name: unsigned\n")), Policy{}, 1, []int{1}, nil},
+		{"metadata document", join([]byte("insights: metadata\n"), signed), Policy{}, 1, []int{0}, nil},
+		{"unsigned play required", join(signed, []byte("- name: unsigned\n")), Policy{RequireAllSigned: true}, 1, []int{}, ErrNoSignature},
+		{"metadata document required", join(signed, []byte("insights: metadata\n")), Policy{RequireAllSigned: true}, 1, []int{}, ErrNoSignature},
+		{"partially signed document", append(signed, []byte("- name: unsigned\n")...), Policy{}, 2, []int{}, ErrNoSignature},
+		{"only unsigned documents", []byte("- name: unsigned\n"), Policy{}, 0, []int{0}, ErrNoSignature},
+		{"malformed document", join(signed, []byte("- [unclosed\n")), Policy{}, 1, []int{}, ErrMalformedYAML},
+		{"no documents", []byte(""), Policy{}, 0, []int{}, ErrMalformedYAML},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report, err := VerifyPlaybookReader(bytes.NewReader(tt.playbook), keyring, tt.policy)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("VerifyPlaybookReader() error = %v, want %v", err, tt.want)
+			}
+			if len(report.Plays) != tt.plays {
+				t.Errorf("VerifyPlaybookReader() verified %d plays, want %d", len(report.Plays), tt.plays)
+			}
+			if !slices.Equal(report.SkippedDocuments, tt.skipped) {
+				t.Errorf("VerifyPlaybookReader() skipped documents %v, want %v", report.SkippedDocuments, tt.skipped)
+			}
+			for i, play := range report.Plays {
+				if play.Index != i {
+					t.Errorf("play %d has index %d", i, play.Index)
+				}
+			}
+		})
+	}
+}
+
+func TestVerifyPlaybookUnsigned(t *testing.T) {
+	signed := readTestdata(t, filepath.Join("testdata", "golden", "cve-update.yml"))
+	tampered := bytes.Replace(signed, []byte("Insights remediation"), []byte("Tampered remediation"), 1)
+	unsigned := []byte("- name: unsigned\n  hosts: all\n")
+	join := func(documents ...[]byte) []byte {
+		return bytes.Join(documents, []byte("---\n"))
+	}
+
+	tests := []struct {
+		name          string
+		playbook      []byte
+		unsigned      string
+		unsignedPlays []int
+		unsignedDocs  []int
+		want          error
+	}{
+		{"unsigned play", append(slices.Clone(signed), unsigned...), UnsignedWarn, []int{1}, nil, nil},
+		{"unsigned document", join(signed, unsigned), UnsignedWarn, nil, []int{1}, nil},
+		{"only unsigned documents", join(unsigned, []byte("insights: metadata\n")), UnsignedAllow, nil, []int{0, 1}, nil},
+		{"tampered play", append(slices.Clone(tampered), unsigned...), UnsignedAllow, []int{1}, nil, ErrDigestMismatch},
+		{"unsigned play rejected", append(slices.Clone(signed), unsigned...), UnsignedReject, nil, nil, ErrNoSignature},
+		{"unknown handling", append(slices.Clone(signed), unsigned...), "yes", nil, nil, ErrNoSignature},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var logs bytes.Buffer
+			v, err := NewVerifier(WithKeyring(testKeyring(t)), WithPolicy(Policy{Unsigned: tt.unsigned}), WithLogger(slog.New(slog.NewTextHandler(&logs, nil))))
+			if err != nil {
+				t.Fatalf("NewVerifier() error = %v", err)
+			}
+			report, err := v.Verify(context.Background(), bytes.NewReader(tt.playbook))
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("Verify() error = %v, want %v", err, tt.want)
+			}
+			var unsignedPlays []int
+			for _, play := range report.Plays {
+				if play.Unsigned {
+					unsignedPlays = append(unsignedPlays, play.Index)
+					if play.Verified {
+						t.Errorf("unsigned play %d is verified", play.Index)
+					}
+				}
+			}
+			if !slices.Equal(unsignedPlays, tt.unsignedPlays) || !slices.Equal(report.UnsignedDocuments, tt.unsignedDocs) {
+				t.Errorf("Verify() unsigned plays %v and documents %v, want %v and %v", unsignedPlays, report.UnsignedDocuments, tt.unsignedPlays, tt.unsignedDocs)
+			}
+			if tt.want != nil {
+				return
+			}
+			if !report.Verified || !report.HasUnsigned() || len(report.SkippedDocuments) != 0 {
+				t.Errorf("Verify() report = %+v, want it verified with unsigned content", report)
+			}
+			// the unsigned content is passed on
+			if payload := VerifiedPayload(tt.playbook, report); !bytes.Equal(payload, tt.playbook) {
+				t.Errorf("VerifiedPayload() = %q, want the whole payload", payload)
+			}
+			if warned := strings.Contains(logs.String(), "level=WARN msg=\"accepting unsigned"); warned != (tt.unsigned == UnsignedWarn) {
+				t.Errorf("logs = %s, want a warning only for %s", logs.String(), UnsignedWarn)
+			}
+		})
+	}
+}
+
+func TestVerifyPlaybookJobs(t *testing.T) {
+	keyring := testKeyring(t)
+	reboot := readTestdata(t, filepath.Join("testdata", "golden", "reboot.yml"))
+	playbook := append(bytes.Clone(reboot), []byte("- name: unsigned\n")...)
+	playbook = append(playbook, reboot...)
+
+	serial, serialErr := VerifyPlaybook(playbook, keyring, Policy{})
+	parallel, parallelErr := VerifyPlaybook(playbook, keyring, Policy{Jobs: 4})
+	if !errors.Is(parallelErr, ErrNoSignature) || serialErr.Error() != parallelErr.Error() {
+		t.Errorf("VerifyPlaybook() error = %v, want %v", parallelErr, serialErr)
+	}
+	if len(parallel.Plays) != 7 {
+		t.Fatalf("VerifyPlaybook() verified %d plays, want 7", len(parallel.Plays))
+	}
+	for i := range serial.Plays {
+		if serial.Plays[i].Name != parallel.Plays[i].Name || serial.Plays[i].Digest != parallel.Plays[i].Digest {
+			t.Errorf("play %d = %+v, want %+v", i, parallel.Plays[i], serial.Plays[i])
+		}
+	}
+}
+
+func TestVerify(t *testing.T) {
+	keyring := testKeyring(t)
+	signed := readTestdata(t, filepath.Join("testdata", "golden", "cve-update.yml"))
+
+	report, err := Verify(context.Background(), bytes.NewReader(signed), WithKeyring(keyring), WithPolicy(Policy{Strict: true}))
+	if err != nil || !report.Verified {
+		t.Errorf("Verify() = %+v, %v", report, err)
+	}
+	if _, err = Verify(context.Background(), bytes.NewReader(signed)); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("Verify() without keyring error = %v, want %v", err, ErrInvalidKey)
+	}
+	_, err = Verify(context.Background(), bytes.NewReader(signed), WithKeyring(keyring), WithContentType("text/plain"))
+	if !errors.Is(err, ErrUnsupportedContentType) {
+		t.Errorf("Verify() of text error = %v, want %v", err, ErrUnsupportedContentType)
+	}
+}
+
+// A single Verifier verifies payloads from many goroutines while extensions are registered,
+// logging to its own logger only. Run with -race.
+func TestVerifierConcurrent(t *testing.T) {
+	var logs bytes.Buffer
+	v, err := NewVerifier(
+		WithKeyring(testKeyring(t)),
+		WithPolicy(Policy{Jobs: 2, ReportDiff: true}),
+		WithLogger(slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug}))),
+	)
+	if err != nil {
+		t.Fatalf("NewVerifier() error = %v", err)
+	}
+	var global bytes.Buffer
+	previous := slog.Default()
+	t.Cleanup(func() { slog.SetDefault(previous) })
+	slog.SetDefault(slog.New(slog.NewTextHandler(&global, nil)))
+
+	payloads := [][]byte{
+		readTestdata(t, filepath.Join("testdata", "golden", "cve-update.yml")),
+		readTestdata(t, filepath.Join("testdata", "golden", "reboot.yml")),
+		readTestdata(t, filepath.Join("testdata", "golden", "hash-algorithms.yml")),
+	}
+	const goroutines, rounds = 8, 5
+	var wg sync.WaitGroup
+	errs := make(chan error, goroutines*rounds*len(payloads))
+	for g := range goroutines {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range rounds {
+				for i, payload := range payloads {
+					var report Report
+					var err error
+					if g%2 == 0 {
+						report, err = v.Verify(context.Background(), bytes.NewReader(payload))
+					} else {
+						report, err = v.VerifyPayload(context.Background(), "", payload)
+					}
+					if err == nil && !report.Verified {
+						err = fmt.Errorf("payload %d is not verified", i)
+					}
+					if err != nil {
+						errs <- err
+					}
+				}
+			}
+		}()
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := range rounds {
+			RegisterHashAlgorithm(hashAlgorithm{fmt.Sprintf("test-%d", i), 128, sha256.New})
+			RegisterContextHandler(fmt.Sprintf("text/test-%d", i), VerifyPlaybookContext)
+			_ = ContentTypes()
+		}
+	}()
+	wg.Wait()
+	close(errs)
+	t.Cleanup(func() {
+		for i := range rounds {
+			hashAlgorithms.unregister(fmt.Sprintf("test-%d", i))
+			handlers.unregister(fmt.Sprintf("text/test-%d", i))
+		}
+	})
+
+	for err := range errs {
+		t.Errorf("Verify() error = %v", err)
+	}
+	if verified := strings.Count(logs.String(), "play verified"); verified < goroutines*rounds*len(payloads) {
+		t.Errorf("verifier logged %d verified plays, want at least %d", verified, goroutines*rounds*len(payloads))
+	}
+	if global.Len() > 0 {
+		t.Errorf("verifier logged to the default logger:\n%s", global.String())
+	}
+}
+
+// cancellingReader cancels the context once the first part has been read.
+type cancellingReader struct {
+	parts  [][]byte
+	cancel context.CancelFunc
+}
+
+func (r *cancellingReader) Read(p []byte) (int, error) {
+	if len(r.parts) == 0 {
+		return 0, io.EOF
+	}
+	n := copy(p, r.parts[0])
+	r.parts[0] = r.parts[0][n:]
+	if len(r.parts[0]) == 0 {
+		r.parts = r.parts[1:]
+		r.cancel()
+	}
+	return n, nil
+}
+
+func TestVerifyCancelled(t *testing.T) {
+	keyring := testKeyring(t)
+	signed := readTestdata(t, filepath.Join("testdata", "golden", "cve-update.yml"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := Verify(ctx, bytes.NewReader(signed), WithKeyring(keyring)); !errors.Is(err, context.Canceled) {
+		t.Errorf("Verify() error = %v, want %v", err, context.Canceled)
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	r := &cancellingReader{parts: [][]byte{append(bytes.Clone(signed), "---\n"...), signed}, cancel: cancel}
+	if _, err := Verify(ctx, r, WithKeyring(keyring)); !errors.Is(err, context.Canceled) {
+		t.Errorf("Verify() of stream error = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestVerifyPlaybookExclusions(t *testing.T) {
+	playbook := readTestdata(t, filepath.Join("testdata", "golden", "cve-update.yml"))
+	report, err := VerifyPlaybook(playbook, testKeyring(t), Policy{})
+	if err != nil {
+		t.Fatalf("VerifyPlaybook() error = %v", err)
+	}
+	want := []ExcludedPath{{Path: "/hosts", Type: "string"}, {Path: "/vars/insights_signature", Type: "string"}}
+	if !slices.Equal(report.Plays[0].Exclusions, want) {
+		t.Errorf("VerifyPlaybook() exclusions = %+v, want %+v", report.Plays[0].Exclusions, want)
+	}
+}
+
+// panickingSerialization fails the way a bug of a serialization would.
+type panickingSerialization struct{}
+
+func (panickingSerialization) Version() string { return "panic" }
+
+func (panickingSerialization) Marshal(play *yaml3.Node, _ string) ([]byte, error) {
+	return []byte(play.Alias.Value), nil
+}
+
+func TestVerifyPlaybookPanic(t *testing.T) {
+	RegisterSerialization(panickingSerialization{})
+	t.Cleanup(func() { serializations.unregister("panic") })
+	play := "- name: play\n  vars:\n    insights_signature_exclude: /vars/insights_signature\n" +
+		"    insights_signature_serialization: %s\n    insights_signature: c2lnbmF0dXJl\n"
+	playbook := fmt.Sprintf(play, "panic") + fmt.Sprintf(play, "v1")
+
+	for _, jobs := range []int{1, 2} {
+		report, err := VerifyPlaybook([]byte(playbook), testKeyring(t), Policy{Jobs: jobs})
+		if !errors.Is(err, ErrInternal) {
+			t.Fatalf("VerifyPlaybook(jobs=%d) error = %v, want %v", jobs, err, ErrInternal)
+		}
+		if len(report.Plays) != 2 || !strings.Contains(report.Plays[0].Error, "panicked") {
+			t.Fatalf("VerifyPlaybook(jobs=%d) = %+v, want both plays", jobs, report.Plays)
+		}
+		if report.Plays[1].Error == "" || strings.Contains(report.Plays[1].Error, "panicked") {
+			t.Errorf("play 1 error = %q, want a verification error", report.Plays[1].Error)
+		}
+	}
+}
+
+func TestVerifyPlaybookMemoryBudget(t *testing.T) {
+	keyring := testKeyring(t)
+	signed := readTestdata(t, filepath.Join("testdata", "golden", "cve-update.yml"))
+	multiple := append(append(bytes.Clone(signed), "---\n"...), signed...)
+
+	report, err := VerifyPlaybook(multiple, keyring, Policy{MemoryBudget: 16 << 20})
+	if err != nil || !report.Verified {
+		t.Errorf("VerifyPlaybook() = %+v, %v", report, err)
+	}
+
+	// A budget a single document fits in, but not both of them
+	decoder := NewPlaybookDecoder(bytes.NewReader(signed))
+	if _, err = decoder.Decode(); err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	_, err = VerifyPlaybook(multiple, keyring, Policy{MemoryBudget: decoder.used + decoder.used/2})
+	if !errors.Is(err, ErrMemoryBudget) {
+		t.Errorf("VerifyPlaybook() error = %v, want %v", err, ErrMemoryBudget)
+	}
+
+	laughs := "a: &a [x, x, x, x, x, x, x, x]\nb: &b [*a, *a, *a, *a, *a, *a, *a, *a]\nc: &c [*b, *b, *b, *b, *b, *b, *b, *b]\n"
+	if _, err = VerifyPlaybook([]byte(laughs), keyring, Policy{MemoryBudget: 4096}); !errors.Is(err, ErrMemoryBudget) {
+		t.Errorf("VerifyPlaybook() of aliases error = %v, want %v", err, ErrMemoryBudget)
+	}
+}
@@ -0,0 +1,97 @@
+package verifier
+
+import (
+	"fmt"
+	"io"
+
+	yaml3 "gopkg.in/yaml.v3"
+)
+
+// SerializationV1 is the serialization of the Python verifier: repr() of the play loaded
+// into ordered dictionaries. Its variants are the serialization profiles.
+const SerializationV1 = "v1"
+
+// SerializationV2 is the JSON Canonicalization Scheme (RFC 8785) of the play. It does not
+// depend on the quirks of Python's repr(), but no release of insights-core produces it yet.
+const SerializationV2 = "v2"
+
+// DefaultSerialization is the serialization of plays that do not declare one
+// in 'insights_signature_serialization'.
+const DefaultSerialization = SerializationV1
+
+// Serialization converts cleaned plays into the bytes that are hashed and signed.
+//
+// A play selects its serialization by its version in 'insights_signature_serialization',
+// so new serializations (e.g. a canonical JSON) can be introduced next to the existing
+// ones without changing the verification.
+type Serialization interface {
+	// Version is the value of 'insights_signature_serialization' that selects the serialization.
+	Version() string
+	// Marshal serializes the cleaned play in the serialization profile. Serializations
+	// that have no variants ignore the profile.
+	Marshal(play *yaml3.Node, profile string) ([]byte, error)
+}
+
+// StreamingSerialization is a Serialization that can write the play into a writer, e.g. the hash
+// of a HashAlgorithm that implements StreamingHashAlgorithm, so large plays are never held
+// in memory as a whole. The written bytes must be the same as those returned by Marshal.
+type StreamingSerialization interface {
+	Serialization
+	// Stream writes the cleaned play serialized in the serialization profile into the writer.
+	Stream(w io.Writer, play *yaml3.Node, profile string) error
+}
+
+// reprSerialization implements SerializationV1 and StreamingSerialization.
+type reprSerialization struct{}
+
+func (reprSerialization) Version() string { return SerializationV1 }
+
+func (reprSerialization) Marshal(play *yaml3.Node, profile string) ([]byte, error) {
+	return MarshallPlaybookProfile(play, profile)
+}
+
+func (reprSerialization) Stream(w io.Writer, play *yaml3.Node, profile string) error {
+	return WritePlaybookProfile(w, play, profile)
+}
+
+// serializations maps the versions to their serializations.
+var serializations = newRegistry(map[string]Serialization{
+	SerializationV1: reprSerialization{},
+	SerializationV2: canonicalJSONSerialization{},
+})
+
+// RegisterSerialization makes the serialization available to the plays that declare its version.
+//
+// It is safe for concurrent use with verification.
+func RegisterSerialization(serialization Serialization) {
+	serializations.register(serialization.Version(), serialization)
+}
+
+// SerializationVersions returns the versions of the available serializations.
+func SerializationVersions() []string {
+	return serializations.names()
+}
+
+// LookupSerialization returns the serialization of the version. Empty version means DefaultSerialization.
+func LookupSerialization(version string) (Serialization, error) {
+	if version == "" {
+		version = DefaultSerialization
+	}
+	serialization, ok := serializations.lookup(version)
+	if !ok {
+		return nil, VerificationError{ErrMalformedSignature, fmt.Sprintf("unsupported serialization '%s'", version), nil}
+	}
+	return serialization, nil
+}
+
+// playSerialization returns the serialization the play declares in 'insights_signature_serialization'.
+//
+// Like 'insights_signature_hash', the variable is a part of the signed content.
+func playSerialization(p *yaml3.Node) (Serialization, error) {
+	value, _ := getPlaybookVariable(p, "insights_signature_serialization")
+	version, ok := stringValue(value)
+	if !ok {
+		return nil, VerificationError{ErrMalformedSignature, fmt.Sprintf("'insights_signature_serialization' must be a string, not %s", yamlTypeName(value)), nil}
+	}
+	return LookupSerialization(version)
+}
@@ -0,0 +1,80 @@
+package verifier
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	yaml3 "gopkg.in/yaml.v3"
+)
+
+// namesSerialization serializes only the top-level keys of the play.
+type namesSerialization struct{}
+
+func (namesSerialization) Version() string { return "test" }
+
+func (namesSerialization) Marshal(play *yaml3.Node, _ string) ([]byte, error) {
+	var names []string
+	for i := 0; i < len(play.Content); i += 2 {
+		names = append(names, play.Content[i].Value)
+	}
+	return []byte(fmt.Sprint(names)), nil
+}
+
+func TestVerifyPlaybookSerializationVersion(t *testing.T) {
+	RegisterSerialization(namesSerialization{})
+	t.Cleanup(func() { serializations.unregister("test") })
+
+	tests := []struct {
+		name    string
+		version string
+	}{
+		{"default", ""},
+		{"v1", "\n    insights_signature_serialization: v1"},
+		{"v2", "\n    insights_signature_serialization: v2"},
+		{"registered", "\n    insights_signature_serialization: test"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			playbook := "- name: play\n  vars:\n    insights_signature_exclude: /vars/insights_signature" + tt.version + "\n"
+			signed, err := SignPlaybook([]byte(playbook), testSigner(t), Policy{})
+			if err != nil {
+				t.Fatalf("SignPlaybook() error = %v", err)
+			}
+			report, err := VerifyPlaybook(signed, testKeyring(t), Policy{})
+			if err != nil {
+				t.Fatalf("VerifyPlaybook() error = %v", err)
+			}
+			want := SerializationV1
+			switch tt.name {
+			case "v2":
+				want = SerializationV2
+			case "registered":
+				want = "test"
+			}
+			if got := report.Plays[0].SerializationVersion; got != want {
+				t.Errorf("SerializationVersion = %s, want %s", got, want)
+			}
+		})
+	}
+}
+
+func TestVerifyPlaybookSerializationVersionInvalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		version string
+	}{
+		{"unknown", "v9"},
+		{"not a string", "[v1]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			playbook := "- name: play\n  vars:\n    insights_signature_exclude: /vars/insights_signature\n" +
+				"    insights_signature_serialization: " + tt.version + "\n    insights_signature: c2lnbmF0dXJl\n"
+			_, err := VerifyPlaybook([]byte(playbook), testKeyring(t), Policy{})
+			if !errors.Is(err, ErrMalformedSignature) {
+				t.Errorf("VerifyPlaybook() error = %v, want %v", err, ErrMalformedSignature)
+			}
+		})
+	}
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"os"
+	"strings"
+)
+
+// Locations used by the verifier when it runs as a part of insights-client.
+const (
+	// DefaultConfigFile is the path of the configuration file read when `--config` is not set.
+	DefaultConfigFile = "/etc/insights-client/playbook-verifier.conf"
+	// DefaultCacheDir is the directory of cached verification reports.
+	DefaultCacheDir = "/var/cache/insights-playbook-verifier"
+	// DefaultSocket is the path of the unix socket the service listens on.
+	DefaultSocket = "/run/insights-playbook-verifier.sock"
+	// DefaultAuditLog is the path of the log of the verification decisions.
+	DefaultAuditLog = "/var/log/insights-client/playbook-verifier-audit.log"
+)
+
+// restrictSocket allows only the owner and the group of the service to connect to the socket.
+func restrictSocket(socket string) error {
+	return os.Chmod(socket, 0o660)
+}
+
+// fipsMode reports whether the kernel runs in FIPS mode.
+func fipsMode() (bool, error) {
+	content, err := os.ReadFile("/proc/sys/crypto/fips_enabled")
+	if err != nil {
+		return false, err
+	}
+	return strings.TrimSpace(string(content)) == "1", nil
+}
@@ -0,0 +1,50 @@
+//go:build !linux
+
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// Locations used by the verifier on the workstations of content developers (macOS, Windows).
+//
+// There is no insights-client there, so the files live in the directories of the user,
+// e.g. '~/Library/Application Support' on macOS and '%AppData%' on Windows.
+var (
+	// DefaultConfigFile is the path of the configuration file read when `--config` is not set.
+	DefaultConfigFile = userPath(os.UserConfigDir, "insights-client", "playbook-verifier.conf")
+	// DefaultCacheDir is the directory of cached verification reports.
+	DefaultCacheDir = userPath(os.UserCacheDir, "insights-playbook-verifier")
+	// DefaultSocket is the path of the unix socket the service listens on.
+	DefaultSocket = filepath.Join(os.TempDir(), "insights-playbook-verifier.sock")
+	// DefaultAuditLog is the path of the log of the verification decisions.
+	DefaultAuditLog = userPath(os.UserCacheDir, "insights-playbook-verifier", "audit.log")
+)
+
+// userPath joins the path elements to the user directory, or to the temporary directory
+// if the user directory is unknown.
+func userPath(directory func() (string, error), elem ...string) string {
+	base, err := directory()
+	if err != nil {
+		base = os.TempDir()
+	}
+	return filepath.Join(append([]string{base}, elem...)...)
+}
+
+// restrictSocket allows only the owner and the group of the service to connect to the socket.
+//
+// On Windows, the socket inherits the access control list of its directory instead.
+func restrictSocket(socket string) error {
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+	return os.Chmod(socket, 0o660)
+}
+
+// fipsMode reports whether the system runs in FIPS mode.
+func fipsMode() (bool, error) {
+	return false, errors.New("FIPS mode can only be detected on Linux")
+}
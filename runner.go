@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	"com.github/m-horky/playbook-verifier/pkg/verifier"
+)
+
+// RunnerVerdictFile is the name of the artifact the runner-hook command writes
+// into the artifacts directory of the ansible-runner private data directory.
+const RunnerVerdictFile = "playbook_verification.json"
+
+// RunnerVerdict is the machine-readable verdict of the runner-hook command.
+type RunnerVerdict struct {
+	// Verified is true if every selected playbook of the project is verified.
+	Verified bool `json:"verified"`
+	// Error is set if the playbooks could not be selected, or the files do not match --files-manifest.
+	Error string `json:"error,omitempty"`
+	// Ident is the identifier of the ansible-runner job, if known.
+	Ident string `json:"ident,omitempty"`
+	// Playbooks are the reports of the playbooks, whose paths are relative to the project directory.
+	Playbooks []FileReport `json:"playbooks"`
+}
+
+// runnerHook verifies the playbooks of the ansible-runner private data directory in place,
+// before ansible-runner runs them, and writes the verdict into its artifacts directory.
+//
+// The playbooks are the ones passed by --playbook, or the YAML files at the top of the project
+// directory; roles and variables in its subdirectories are not plays. The exit code is
+// the one of the first failed playbook, so the job can be refused on a non-zero code other
+// than ExitWarnOnlyKey.
+func runnerHook(ctx context.Context, arguments *Arguments, keyring *verifier.Keyring) int {
+	project := filepath.Join(arguments.PrivateDataDir, "project")
+	verdict := RunnerVerdict{Ident: arguments.RunnerIdent, Playbooks: []FileReport{}}
+
+	paths, err := runnerPlaybooks(project, arguments.RunnerPlaybooks)
+	if err != nil {
+		slog.Error("could not select playbooks", slog.Any("error", err))
+		verdict.Error = err.Error()
+		if err = writeRunnerVerdict(arguments.PrivateDataDir, verdict); err != nil {
+			slog.Error("could not write verdict", slog.Any("error", err))
+		}
+		return ExitIOError
+	}
+	slog.Debug("verifying project", slog.String("directory", project), slog.Int("playbooks", len(paths)))
+
+	verdict.Playbooks = verifyFiles(ctx, paths, arguments, keyring)
+	for i := range verdict.Playbooks {
+		verdict.Playbooks[i].Path, _ = filepath.Rel(project, verdict.Playbooks[i].Path)
+	}
+	code := fileReportsExitCode(verdict.Playbooks)
+	if (code == ExitOK || code == ExitWarnOnlyKey) && arguments.FilesManifest != "" {
+		if err = checkFilesManifest(arguments, keyring); err != nil {
+			slog.Error("could not verify files of the project", slog.Any("error", err))
+			verdict.Error = err.Error()
+			code = exitCode(err)
+		}
+	}
+	verdict.Verified = code == ExitOK || code == ExitWarnOnlyKey
+
+	if err = writeRunnerVerdict(arguments.PrivateDataDir, verdict); err != nil {
+		slog.Error("could not write verdict", slog.Any("error", err))
+		return ExitIOError
+	}
+	if err = printFileReports(os.Stdout, verdict.Playbooks, arguments); err != nil {
+		slog.Error("could not print report", slog.Any("error", err))
+		return ExitIOError
+	}
+	return code
+}
+
+// runnerPlaybooks returns the paths of the playbooks in the project directory.
+//
+// Named playbooks must stay inside the project directory. Without names, the YAML files
+// at the top of the directory are returned, in lexical order. At least one playbook is required,
+// an empty project is not a verified one.
+func runnerPlaybooks(project string, names []string) ([]string, error) {
+	var paths []string
+	if len(names) == 0 {
+		entries, err := os.ReadDir(project)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			if entry.Type().IsRegular() && (filepath.Ext(entry.Name()) == ".yml" || filepath.Ext(entry.Name()) == ".yaml") {
+				names = append(names, entry.Name())
+			}
+		}
+	}
+	for _, name := range names {
+		if !filepath.IsLocal(name) {
+			return nil, fmt.Errorf("playbook %s is outside of the project directory", name)
+		}
+		paths = append(paths, filepath.Join(project, name))
+	}
+	if len(paths) == 0 {
+		return nil, errors.New("no playbooks in the project directory " + project)
+	}
+	return paths, nil
+}
+
+// writeRunnerVerdict writes the verdict into the artifacts directory of the job, or of the private
+// data directory if the job identifier is unknown. The file is replaced atomically, so the runner
+// never reads a partial verdict.
+func writeRunnerVerdict(privateDataDir string, verdict RunnerVerdict) error {
+	directory := filepath.Join(privateDataDir, "artifacts", verdict.Ident)
+	if err := os.MkdirAll(directory, 0o700); err != nil {
+		return err
+	}
+	content, err := json.MarshalIndent(verdict, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	file, err := os.CreateTemp(directory, ".verdict-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+	if _, err = file.Write(append(content, '\n')); err != nil {
+		file.Close()
+		return err
+	}
+	if err = file.Close(); err != nil {
+		return err
+	}
+	path := filepath.Join(directory, RunnerVerdictFile)
+	slog.Debug("writing verdict", slog.String("path", path))
+	return os.Rename(file.Name(), path)
+}
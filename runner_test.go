@@ -0,0 +1,106 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// readRunnerVerdict reads the verdict the runner-hook command wrote for the job.
+func readRunnerVerdict(t *testing.T, privateDataDir, ident string) RunnerVerdict {
+	t.Helper()
+	content, err := os.ReadFile(filepath.Join(privateDataDir, "artifacts", ident, RunnerVerdictFile))
+	if err != nil {
+		t.Fatalf("could not read verdict: %v", err)
+	}
+	var verdict RunnerVerdict
+	if err = json.Unmarshal(content, &verdict); err != nil {
+		t.Fatalf("could not decode verdict %q: %v", content, err)
+	}
+	return verdict
+}
+
+func TestRunnerHook(t *testing.T) {
+	accepted := signedPlaybook(t, testPlaybook)
+	rejected := tamperedPlaybook(t)
+	tests := []struct {
+		name     string
+		files    map[string][]byte
+		args     []string
+		want     int
+		verified map[string]bool
+	}{
+		{
+			name:     "accepted and rejected",
+			files:    map[string][]byte{"accepted.yml": accepted, "rejected.yml": rejected},
+			want:     ExitSignatureMismatch,
+			verified: map[string]bool{"accepted.yml": true, "rejected.yml": false},
+		},
+		{
+			name:     "accepted",
+			files:    map[string][]byte{"accepted.yml": accepted, "roles/tasks/main.yml": rejected},
+			want:     ExitOK,
+			verified: map[string]bool{"accepted.yml": true},
+		},
+		{
+			name:     "selected playbook",
+			files:    map[string][]byte{"accepted.yml": accepted, "rejected.yml": rejected},
+			args:     []string{"--playbook", "accepted.yml"},
+			want:     ExitOK,
+			verified: map[string]bool{"accepted.yml": true},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			privateDataDir := t.TempDir()
+			for name, content := range test.files {
+				path := filepath.Join(privateDataDir, "project", name)
+				if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, content, 0o600); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			args := append([]string{"runner-hook", "--private-data-dir", privateDataDir, "--ident", "job"}, testArguments(t)...)
+			_, stderr, code := runMain(t, nil, nil, append(args, test.args...)...)
+			if code != test.want {
+				t.Errorf("exit code = %d, want %d; stderr:\n%s", code, test.want, stderr)
+			}
+
+			verdict := readRunnerVerdict(t, privateDataDir, "job")
+			if verdict.Verified != (test.want == ExitOK) {
+				t.Errorf("Verified = %t, want %t", verdict.Verified, test.want == ExitOK)
+			}
+			if verdict.Ident != "job" {
+				t.Errorf("Ident = %q, want job", verdict.Ident)
+			}
+			if len(verdict.Playbooks) != len(test.verified) {
+				t.Fatalf("Playbooks = %+v, want %d", verdict.Playbooks, len(test.verified))
+			}
+			for _, playbook := range verdict.Playbooks {
+				if verified, ok := test.verified[playbook.Path]; !ok || playbook.Report.Verified != verified {
+					t.Errorf("playbook %s verified %t, want %v", playbook.Path, playbook.Report.Verified, test.verified)
+				}
+			}
+		})
+	}
+}
+
+func TestRunnerHookOutsideProject(t *testing.T) {
+	privateDataDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(privateDataDir, "project"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+
+	args := append([]string{"runner-hook", "--private-data-dir", privateDataDir}, testArguments(t)...)
+	_, stderr, code := runMain(t, nil, nil, append(args, "--playbook", "../escape.yml")...)
+	if code != ExitIOError {
+		t.Errorf("exit code = %d, want %d; stderr:\n%s", code, ExitIOError, stderr)
+	}
+	if verdict := readRunnerVerdict(t, privateDataDir, ""); verdict.Verified || verdict.Error == "" {
+		t.Errorf("verdict = %+v, want an error", verdict)
+	}
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"runtime"
+	"slices"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"github.com/coreos/go-systemd/v22/journal"
+	"golang.org/x/sys/unix"
+)
+
+// sandboxSupported reports whether the process can sandbox itself.
+const sandboxSupported = true
+
+// sandboxSyscalls are denied in the sandbox: creating sockets, running programs, inspecting
+// other processes and changing the kernel state.
+var sandboxSyscalls = []uintptr{
+	unix.SYS_SOCKET, unix.SYS_SOCKETPAIR,
+	unix.SYS_EXECVE, unix.SYS_EXECVEAT,
+	unix.SYS_PTRACE, unix.SYS_PROCESS_VM_READV, unix.SYS_PROCESS_VM_WRITEV,
+	unix.SYS_MOUNT, unix.SYS_UMOUNT2, unix.SYS_PIVOT_ROOT, unix.SYS_CHROOT, unix.SYS_UNSHARE, unix.SYS_SETNS,
+	unix.SYS_BPF, unix.SYS_PERF_EVENT_OPEN, unix.SYS_USERFAULTFD,
+	unix.SYS_KEYCTL, unix.SYS_ADD_KEY, unix.SYS_REQUEST_KEY,
+	unix.SYS_INIT_MODULE, unix.SYS_FINIT_MODULE, unix.SYS_DELETE_MODULE, unix.SYS_KEXEC_LOAD,
+	unix.SYS_OPEN_BY_HANDLE_AT, unix.SYS_NAME_TO_HANDLE_AT,
+	// io_uring operations are not checked by seccomp
+	unix.SYS_IO_URING_SETUP, unix.SYS_IO_URING_ENTER, unix.SYS_IO_URING_REGISTER,
+}
+
+// sandboxPathSyscalls open or change paths. They are denied when Landlock is not available.
+var sandboxPathSyscalls = []uintptr{
+	unix.SYS_OPENAT, unix.SYS_OPENAT2,
+	unix.SYS_MKDIRAT, unix.SYS_MKNODAT, unix.SYS_UNLINKAT, unix.SYS_RENAMEAT2,
+	unix.SYS_LINKAT, unix.SYS_SYMLINKAT, unix.SYS_FCHMODAT, unix.SYS_FCHOWNAT, unix.SYS_TRUNCATE,
+}
+
+// Offsets of the fields of struct seccomp_data the filter reads.
+const (
+	seccompDataNr   = 0
+	seccompDataArch = 4
+)
+
+// applySandbox takes away the access to the file system and to the network from all threads
+// of the process, and denies running programs.
+//
+// The file system is restricted by Landlock. If the kernel does not support it, or the binary
+// has been built with cgo and the restriction cannot be applied to all threads, the syscalls
+// opening or changing paths are denied by seccomp instead. Files that are already open, such
+// as standard streams and the log file, stay usable.
+func applySandbox() error {
+	if sandboxArch == 0 {
+		return fmt.Errorf("sandbox is not supported on %s", runtime.GOARCH)
+	}
+
+	// Load what would otherwise be loaded from the disk later
+	_, _ = time.Now().Zone()
+	if _, ok := slog.Default().Handler().(*JournaldHandler); ok {
+		journal.Enabled()
+	}
+
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	if err := unix.Prctl(unix.PR_SET_NO_NEW_PRIVS, 1, 0, 0, 0); err != nil {
+		return fmt.Errorf("could not set no_new_privs: %w", err)
+	}
+
+	denied := slices.Concat(sandboxSyscalls, sandboxArchSyscalls)
+	if err := restrictFileSystem(); err != nil {
+		slog.Debug("denying file system syscalls instead of Landlock", slog.Any("error", err))
+		denied = slices.Concat(denied, sandboxPathSyscalls, sandboxArchPathSyscalls)
+	}
+	if err := filterSyscalls(denied); err != nil {
+		return fmt.Errorf("could not install seccomp filter: %w", err)
+	}
+	slog.Debug("sandbox applied")
+	return nil
+}
+
+// restrictFileSystem denies all threads any access to the file system and TCP with Landlock.
+func restrictFileSystem() error {
+	abi, _, errno := unix.Syscall(unix.SYS_LANDLOCK_CREATE_RULESET, 0, 0, unix.LANDLOCK_CREATE_RULESET_VERSION)
+	if errno != 0 {
+		return fmt.Errorf("Landlock is not available: %w", errno)
+	}
+
+	// Rights of the first ABI version, EXECUTE to MAKE_SYM
+	attr := unix.LandlockRulesetAttr{Access_fs: unix.LANDLOCK_ACCESS_FS_MAKE_SYM<<1 - 1}
+	if abi >= 2 {
+		attr.Access_fs |= unix.LANDLOCK_ACCESS_FS_REFER
+	}
+	if abi >= 3 {
+		attr.Access_fs |= unix.LANDLOCK_ACCESS_FS_TRUNCATE
+	}
+	if abi >= 4 {
+		attr.Access_net = unix.LANDLOCK_ACCESS_NET_BIND_TCP | unix.LANDLOCK_ACCESS_NET_CONNECT_TCP
+	}
+	if abi >= 5 {
+		attr.Access_fs |= unix.LANDLOCK_ACCESS_FS_IOCTL_DEV
+	}
+	if abi >= 6 {
+		attr.Scoped = unix.LANDLOCK_SCOPE_ABSTRACT_UNIX_SOCKET | unix.LANDLOCK_SCOPE_SIGNAL
+	}
+	ruleset, _, errno := unix.Syscall(unix.SYS_LANDLOCK_CREATE_RULESET, uintptr(unsafe.Pointer(&attr)), unsafe.Sizeof(attr), 0)
+	if errno != 0 {
+		return fmt.Errorf("could not create Landlock ruleset: %w", errno)
+	}
+	defer unix.Close(int(ruleset))
+
+	// Landlock only restricts the calling thread, the others have to do the same
+	if _, _, errno = syscall.AllThreadsSyscall(unix.SYS_PRCTL, unix.PR_SET_NO_NEW_PRIVS, 1, 0); errno != 0 {
+		return allThreadsError(errno)
+	}
+	if _, _, errno = syscall.AllThreadsSyscall(unix.SYS_LANDLOCK_RESTRICT_SELF, ruleset, 0, 0); errno != 0 {
+		return allThreadsError(errno)
+	}
+	return nil
+}
+
+// allThreadsError describes the failure of a syscall run on all threads.
+func allThreadsError(errno syscall.Errno) error {
+	if errors.Is(errno, syscall.ENOTSUP) {
+		return errors.New("threads cannot be restricted by a binary built with cgo")
+	}
+	return fmt.Errorf("could not restrict threads: %w", errno)
+}
+
+// filterSyscalls makes the syscalls fail with EPERM in all threads, and kills the process
+// if it makes syscalls of a different architecture.
+func filterSyscalls(denied []uintptr) error {
+	// Instructions after the syscall comparisons: allow, deny, kill
+	filter := []unix.SockFilter{
+		{Code: unix.BPF_LD | unix.BPF_W | unix.BPF_ABS, K: seccompDataArch},
+		{Code: unix.BPF_JMP | unix.BPF_JEQ | unix.BPF_K, Jt: 1, K: sandboxArch},
+		{Code: unix.BPF_RET | unix.BPF_K, K: unix.SECCOMP_RET_KILL_PROCESS},
+		{Code: unix.BPF_LD | unix.BPF_W | unix.BPF_ABS, K: seccompDataNr},
+		// The x32 ABI of amd64 has its own numbers
+		{Code: unix.BPF_JMP | unix.BPF_JGE | unix.BPF_K, Jt: uint8(len(denied) + 1), K: 0x40000000},
+	}
+	for i, number := range denied {
+		filter = append(filter, unix.SockFilter{Code: unix.BPF_JMP | unix.BPF_JEQ | unix.BPF_K, Jt: uint8(len(denied) - i), K: uint32(number)})
+	}
+	filter = append(filter,
+		unix.SockFilter{Code: unix.BPF_RET | unix.BPF_K, K: unix.SECCOMP_RET_ALLOW},
+		unix.SockFilter{Code: unix.BPF_RET | unix.BPF_K, K: unix.SECCOMP_RET_ERRNO | uint32(unix.EPERM)},
+	)
+
+	program := unix.SockFprog{Len: uint16(len(filter)), Filter: &filter[0]}
+	// TSYNC installs the filter in all threads, it returns the ID of a thread it failed for
+	thread, _, errno := unix.Syscall(unix.SYS_SECCOMP, unix.SECCOMP_SET_MODE_FILTER, unix.SECCOMP_FILTER_FLAG_TSYNC, uintptr(unsafe.Pointer(&program)))
+	if errno != 0 {
+		return errno
+	}
+	if thread != 0 {
+		return fmt.Errorf("could not synchronize thread %d", thread)
+	}
+	return nil
+}
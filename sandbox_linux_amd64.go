@@ -0,0 +1,15 @@
+package main
+
+import "golang.org/x/sys/unix"
+
+// sandboxArch is the audit architecture of the syscalls the sandbox allows.
+const sandboxArch = unix.AUDIT_ARCH_X86_64
+
+// sandboxArchSyscalls are denied in the sandbox in addition to sandboxSyscalls.
+var sandboxArchSyscalls []uintptr
+
+// sandboxArchPathSyscalls are the variants of sandboxPathSyscalls the architecture keeps for compatibility.
+var sandboxArchPathSyscalls = []uintptr{
+	unix.SYS_OPEN, unix.SYS_CREAT, unix.SYS_MKDIR, unix.SYS_RMDIR, unix.SYS_UNLINK, unix.SYS_RENAME, unix.SYS_RENAMEAT, unix.SYS_LINK, unix.SYS_SYMLINK,
+	unix.SYS_CHMOD, unix.SYS_CHOWN, unix.SYS_LCHOWN, unix.SYS_MKNOD,
+}
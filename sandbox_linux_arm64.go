@@ -0,0 +1,12 @@
+package main
+
+import "golang.org/x/sys/unix"
+
+// sandboxArch is the audit architecture of the syscalls the sandbox allows.
+const sandboxArch = unix.AUDIT_ARCH_AARCH64
+
+// sandboxArchSyscalls are denied in the sandbox in addition to sandboxSyscalls.
+var sandboxArchSyscalls []uintptr
+
+// sandboxArchPathSyscalls are the variants of sandboxPathSyscalls the architecture keeps for compatibility.
+var sandboxArchPathSyscalls = []uintptr{unix.SYS_RENAMEAT}
@@ -0,0 +1,11 @@
+//go:build linux && !amd64 && !arm64 && !ppc64le && !s390x
+
+package main
+
+// sandboxArch is zero, the seccomp filter is not written for the architecture.
+const sandboxArch = 0
+
+var (
+	sandboxArchSyscalls     []uintptr
+	sandboxArchPathSyscalls []uintptr
+)
@@ -0,0 +1,13 @@
+//go:build !linux
+
+package main
+
+import "errors"
+
+// sandboxSupported reports whether the process can sandbox itself.
+const sandboxSupported = false
+
+// applySandbox fails, the sandbox relies on Landlock and seccomp.
+func applySandbox() error {
+	return errors.New("sandbox is only supported on Linux")
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"time"
+
+	"com.github/m-horky/playbook-verifier/internal/keystore"
+	"com.github/m-horky/playbook-verifier/pkg/verifier"
+)
+
+// SelfTestCheck is the result of a single check of the self-test.
+type SelfTestCheck struct {
+	Name   string `json:"name"`
+	Passed bool   `json:"passed"`
+	Detail string `json:"detail"`
+	// exitCode is the exit code of the self-test if the check is the first one to fail.
+	exitCode int
+}
+
+// selfTest verifies the embedded sample playbook with the embedded keys, prints the results
+// of the checks and returns the exit code.
+//
+// The sample is known to be valid, so a failure points at the environment (the clock,
+// the keys, the cryptographic policy) rather than at the payload that failed to verify.
+func selfTest(ctx context.Context, w io.Writer, arguments *Arguments) int {
+	checks := runSelfTest(ctx, arguments)
+	if err := printSelfTest(w, checks, arguments.Format); err != nil {
+		slog.Error("could not print self-test", slog.Any("error", err))
+		return ExitIOError
+	}
+	for _, check := range checks {
+		if !check.Passed {
+			return check.exitCode
+		}
+	}
+	return ExitOK
+}
+
+// runSelfTest runs the checks of the self-test, stopping at the first one the others depend on.
+func runSelfTest(ctx context.Context, arguments *Arguments) []SelfTestCheck {
+	var checks []SelfTestCheck
+
+	// Embedded keys and the revocation lists
+	keys := SelfTestCheck{Name: "keys", exitCode: ExitIOError}
+	keyring, err := embeddedKeyring(arguments.RevokedKeys)
+	if err != nil {
+		keys.Detail = err.Error()
+		return append(checks, keys)
+	}
+	keys.Passed = true
+	keys.Detail = fmt.Sprintf("%d trusted keys of the %s environment, %d revoked", len(keyring.Fingerprints()), keystore.Environment(), len(keyring.Revoked()))
+	checks = append(checks, keys)
+
+	sample, err := keystore.SelfTestPlaybook()
+	if err != nil {
+		return append(checks, SelfTestCheck{Name: "sample", Detail: err.Error(), exitCode: ExitIOError})
+	}
+
+	// Cryptographic policy; it only explains failures of the verification
+	fips := SelfTestCheck{Name: "fips", Passed: true}
+	if enabled, err := fipsMode(); err != nil {
+		fips.Detail = fmt.Sprintf("unknown: %s", err)
+	} else if enabled {
+		fips.Detail = "enabled"
+	} else {
+		fips.Detail = "disabled"
+	}
+	checks = append(checks, fips)
+
+	// The sample has been signed in the past, so the signature must not come from the future
+	clock := SelfTestCheck{Name: "clock", exitCode: ExitSignatureMismatch}
+	now := time.Now()
+	inspections, err := verifier.InspectPlaybook(sample)
+	switch {
+	case err != nil:
+		clock.Detail = err.Error()
+	case len(inspections) == 0 || inspections[0].Signature == nil:
+		clock.Detail = "sample signature has no metadata"
+	case now.Add(arguments.ClockSkew).Before(inspections[0].Signature.Created):
+		clock.Detail = fmt.Sprintf("system time %s is before the creation of the sample signature %s",
+			now.UTC().Format(time.RFC3339), inspections[0].Signature.Created.UTC().Format(time.RFC3339))
+	default:
+		clock.Passed = true
+		clock.Detail = fmt.Sprintf("system time %s", now.UTC().Format(time.RFC3339))
+	}
+	checks = append(checks, clock)
+
+	// The whole pipeline; the age of the sample is fixed at build time, so it is not limited
+	verification := SelfTestCheck{Name: "verification"}
+	policy := arguments.Policy()
+	policy.MaxSignatureAge = 0
+	report, err := verifier.VerifyContext(ctx, verifier.PlaybookContentType, sample, keyring, policy)
+	if err != nil {
+		verification.Detail = err.Error()
+		verification.exitCode = exitCode(err)
+	} else {
+		verification.Passed = true
+		verification.Detail = fmt.Sprintf("sample playbook verified with key %s", report.Plays[0].KeyID)
+	}
+	return append(checks, verification)
+}
+
+// embeddedKeyring creates the keyring of the embedded keys, without the keys of the key directories.
+func embeddedKeyring(revokedKeys string) (*verifier.Keyring, error) {
+	publicKeys, err := keystore.PublicKeys()
+	if err != nil {
+		return nil, fmt.Errorf("could not load embedded keys: %w", err)
+	}
+	keyring, err := verifier.NewKeyring(publicKeys...)
+	if err != nil {
+		return nil, fmt.Errorf("could not create keyring: %w", err)
+	}
+	if err = revokeKeys(keyring, revokedKeys); err != nil {
+		return nil, fmt.Errorf("could not load revoked keys: %w", err)
+	}
+	return keyring, nil
+}
+
+// printSelfTest writes the results of the checks in the output format.
+func printSelfTest(w io.Writer, checks []SelfTestCheck, format string) error {
+	if format == FormatJSON {
+		return json.NewEncoder(w).Encode(checks)
+	}
+
+	for _, check := range checks {
+		result := "ok"
+		if !check.Passed {
+			result = "FAILED"
+		}
+		if _, err := fmt.Fprintf(w, "%-6s  %s: %s\n", result, check.Name, check.Detail); err != nil {
+			return err
+		}
+	}
+	return nil
+}
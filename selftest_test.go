@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"com.github/m-horky/playbook-verifier/internal/keystore"
+)
+
+// environmentKeyIDs returns the IDs of the keys embedded into the binaries of the environment,
+// which are the names of the key files of its directory.
+func environmentKeyIDs(t *testing.T, environment string) []string {
+	t.Helper()
+	files, err := filepath.Glob(filepath.Join("internal", "keystore", "keys", environment, "*.gpg"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var keyIDs []string
+	for _, file := range files {
+		keyIDs = append(keyIDs, strings.TrimSuffix(filepath.Base(file), ".gpg"))
+	}
+	return keyIDs
+}
+
+// TestSelfTest runs the self-test against the embedded keys of the environment of the build;
+// run it with `-tags staging` or `-tags development` for the other environments.
+func TestSelfTest(t *testing.T) {
+	arguments, err := parseArguments(append([]string{CommandSelfTest}, testArguments(t)...))
+	if err != nil {
+		t.Fatalf("parseArguments() error = %v", err)
+	}
+	keyIDs := environmentKeyIDs(t, keystore.Environment())
+
+	var output bytes.Buffer
+	code := selfTest(context.Background(), &output, arguments)
+	checks := runSelfTest(context.Background(), arguments)
+
+	if len(keyIDs) == 0 {
+		// the binaries of an environment without keys must not verify anything
+		if code != ExitIOError {
+			t.Errorf("selfTest() = %d, want %d without embedded keys; output:\n%s", code, ExitIOError, output.String())
+		}
+		if len(checks) != 1 || checks[0].Name != "keys" || checks[0].Passed {
+			t.Errorf("runSelfTest() = %+v, want the keys check failed", checks)
+		}
+		return
+	}
+
+	if code != ExitOK {
+		t.Fatalf("selfTest() = %d, want %d; output:\n%s", code, ExitOK, output.String())
+	}
+	verification := checks[len(checks)-1]
+	if verification.Name != "verification" || !verification.Passed {
+		t.Fatalf("runSelfTest() = %+v, want the sample verified", checks)
+	}
+	signedBy := strings.TrimPrefix(verification.Detail, "sample playbook verified with key ")
+	found := false
+	for _, keyID := range keyIDs {
+		found = found || keyID == signedBy
+	}
+	if !found {
+		t.Errorf("sample of the %s environment signed by %s, want one of its keys %v", keystore.Environment(), signedBy, keyIDs)
+	}
+}
@@ -0,0 +1,258 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/coreos/go-systemd/v22/activation"
+	"github.com/godbus/dbus/v5"
+
+	"com.github/m-horky/playbook-verifier/internal/metrics"
+	"com.github/m-horky/playbook-verifier/internal/service"
+	"com.github/m-horky/playbook-verifier/internal/webhook"
+	"com.github/m-horky/playbook-verifier/internal/yggdrasil"
+	"com.github/m-horky/playbook-verifier/pkg/verifier"
+)
+
+// serve runs the verification service until it receives SIGINT or SIGTERM.
+func serve(arguments *Arguments, keyring *verifier.Keyring) int {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	v := newServiceVerifier(arguments, keyring)
+	if arguments.MetricsListen != "" {
+		go serveMetrics(ctx, arguments.MetricsListen, v.Metrics)
+	}
+
+	if arguments.DBus {
+		return serveDBus(ctx, v)
+	}
+	if arguments.YggdrasilDirective != "" {
+		return serveYggdrasil(ctx, arguments.YggdrasilDirective, arguments.YggdrasilForward, v)
+	}
+
+	listener, err := activatedListener()
+	if err != nil {
+		slog.Error("could not use activated socket", slog.Any("error", err))
+		return ExitIOError
+	}
+	if arguments.Listen != "" {
+		return serveHTTP(ctx, arguments.Listen, listener, arguments.ReadTimeout, v)
+	}
+	if listener != nil {
+		return serveGRPCListener(ctx, listener, v)
+	}
+	return serveGRPC(ctx, arguments.Socket, v)
+}
+
+// newServiceVerifier returns the verifier of the long-running commands.
+func newServiceVerifier(arguments *Arguments, keyring *verifier.Keyring) *service.Verifier {
+	v := &service.Verifier{
+		Keyring: keyring,
+		Policy:  arguments.Policy(),
+		MaxSize: arguments.MaxSize,
+		Metrics: metrics.New(),
+		Audit:   openAuditLog(arguments),
+	}
+	v.Metrics.SetTrustedKeys(len(keyring.Fingerprints()))
+	return v
+}
+
+// serveWebhook runs the Kubernetes admission webhook until it receives SIGINT or SIGTERM.
+func serveWebhook(arguments *Arguments, keyring *verifier.Keyring) int {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	fields := arguments.WebhookFields
+	if fields == nil {
+		fields = webhook.DefaultFields
+	}
+	server := &http.Server{
+		Addr:              arguments.WebhookListen,
+		Handler:           webhook.NewHandler(newServiceVerifier(arguments, keyring), fields),
+		ReadHeaderTimeout: 10 * time.Second,
+		ReadTimeout:       arguments.ReadTimeout,
+		TLSConfig:         &tls.Config{MinVersion: tls.VersionTLS12},
+	}
+	go func() {
+		<-ctx.Done()
+		slog.Info("stopping service")
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		_ = server.Shutdown(shutdownCtx)
+	}()
+
+	slog.Info("serving", slog.String("address", arguments.WebhookListen))
+	err := server.ListenAndServeTLS(arguments.TLSCert, arguments.TLSKey)
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		slog.Error("could not serve", slog.Any("error", err))
+		return ExitIOError
+	}
+	return ExitOK
+}
+
+// activatedListener returns the socket passed by systemd socket activation, or nil if there is none.
+//
+// The socket is created, owned and restricted by systemd, so the service can be started
+// on the first connection.
+func activatedListener() (net.Listener, error) {
+	listeners, err := activation.Listeners()
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case len(listeners) == 0:
+		return nil, nil
+	case len(listeners) > 1:
+		return nil, fmt.Errorf("expected a single activated socket, got %d", len(listeners))
+	case listeners[0] == nil:
+		return nil, errors.New("activated socket is not a stream socket")
+	}
+	slog.Debug("using activated socket", slog.String("address", listeners[0].Addr().String()))
+	return listeners[0], nil
+}
+
+// serveGRPC serves the gRPC API on the unix socket.
+func serveGRPC(ctx context.Context, socket string, v *service.Verifier) int {
+	// A socket left behind by a previous instance would make Listen fail.
+	if err := os.Remove(socket); err != nil && !errors.Is(err, os.ErrNotExist) {
+		slog.Error("could not remove stale socket", slog.Any("error", err))
+		return ExitIOError
+	}
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		slog.Error("could not listen", slog.Any("error", err))
+		return ExitIOError
+	}
+	defer os.Remove(socket)
+	if err = restrictSocket(socket); err != nil {
+		slog.Error("could not set socket permissions", slog.Any("error", err))
+		return ExitIOError
+	}
+	return serveGRPCListener(ctx, listener, v)
+}
+
+// serveGRPCListener serves the gRPC API on the listener.
+func serveGRPCListener(ctx context.Context, listener net.Listener, v *service.Verifier) int {
+	server := service.NewGRPCServer(v)
+	go func() {
+		<-ctx.Done()
+		slog.Info("stopping service")
+		server.GracefulStop()
+	}()
+
+	slog.Info("serving", slog.String("socket", listener.Addr().String()))
+	if err := server.Serve(listener); err != nil {
+		slog.Error("could not serve", slog.Any("error", err))
+		return ExitIOError
+	}
+	return ExitOK
+}
+
+// serveDBus exports the verifier on the system bus.
+func serveDBus(ctx context.Context, v *service.Verifier) int {
+	conn, err := dbus.ConnectSystemBus()
+	if err != nil {
+		slog.Error("could not connect to system bus", slog.Any("error", err))
+		return ExitIOError
+	}
+	defer conn.Close()
+
+	if err = service.ExportDBus(conn, v); err != nil {
+		slog.Error("could not export D-Bus service", slog.Any("error", err))
+		return ExitIOError
+	}
+
+	slog.Info("serving", slog.String("bus_name", service.DBusName))
+	<-ctx.Done()
+	slog.Info("stopping service")
+	return ExitOK
+}
+
+// serveYggdrasil runs the verifier as the yggdrasil worker of the directive on the system bus.
+func serveYggdrasil(ctx context.Context, directive, forward string, v *service.Verifier) int {
+	conn, err := dbus.ConnectSystemBus()
+	if err != nil {
+		slog.Error("could not connect to system bus", slog.Any("error", err))
+		return ExitIOError
+	}
+	defer conn.Close()
+
+	worker, err := yggdrasil.NewWorker(conn, v, directive, forward)
+	if err != nil {
+		slog.Error("invalid yggdrasil worker", slog.Any("error", err))
+		return ExitUsageError
+	}
+	if err = worker.Export(); err != nil {
+		slog.Error("could not export yggdrasil worker", slog.Any("error", err))
+		return ExitIOError
+	}
+
+	slog.Info("serving", slog.String("bus_name", yggdrasil.WorkerName(directive)), slog.String("forward", forward))
+	<-ctx.Done()
+	slog.Info("stopping service")
+	worker.Wait()
+	return ExitOK
+}
+
+// serveHTTP serves the HTTP API on the address, or on the listener if it is not nil.
+func serveHTTP(ctx context.Context, address string, listener net.Listener, readTimeout time.Duration, v *service.Verifier) int {
+	server := &http.Server{
+		Addr:              address,
+		Handler:           service.NewHTTPHandler(v),
+		ReadHeaderTimeout: 10 * time.Second,
+		ReadTimeout:       readTimeout,
+	}
+	go func() {
+		<-ctx.Done()
+		slog.Info("stopping service")
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		_ = server.Shutdown(shutdownCtx)
+	}()
+
+	var err error
+	if listener != nil {
+		slog.Info("serving", slog.String("address", listener.Addr().String()))
+		err = server.Serve(listener)
+	} else {
+		slog.Info("serving", slog.String("address", address))
+		err = server.ListenAndServe()
+	}
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		slog.Error("could not serve", slog.Any("error", err))
+		return ExitIOError
+	}
+	return ExitOK
+}
+
+// serveMetrics serves the Prometheus metrics on the address.
+//
+// Failing to serve the metrics is logged, but does not stop the service.
+func serveMetrics(ctx context.Context, address string, m *metrics.Metrics) {
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", m.Handler())
+	server := &http.Server{
+		Addr:              address,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		<-ctx.Done()
+		_ = server.Close()
+	}()
+
+	slog.Info("serving metrics", slog.String("address", address))
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		slog.Error("could not serve metrics", slog.Any("error", err))
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"os"
+
+	"com.github/m-horky/playbook-verifier/internal/hygiene"
+	"com.github/m-horky/playbook-verifier/pkg/verifier"
+)
+
+// stripSignatures writes the serialized plays of the playbook the signer has to sign,
+// one play per line, and returns the exit code.
+func stripSignatures(w io.Writer, playbook []byte) int {
+	plays, err := verifier.StripSignatures(playbook)
+	if err != nil {
+		slog.Error("could not strip signatures", slog.Any("error", err))
+		return exitCode(err)
+	}
+	for _, play := range plays {
+		if _, err = w.Write(append(play, '\n')); err != nil {
+			slog.Error("could not print play", slog.Any("error", err))
+			return ExitIOError
+		}
+	}
+	return ExitOK
+}
+
+// emitCanonical writes the serialized plays of the payload to the path of the arguments,
+// and returns the exit code.
+//
+// Unlike stripSignatures, nothing is appended to the plays, so a signer written in another
+// language can sign the output as it is. Several plays are separated by NUL bytes, which
+// the serializations always escape.
+func emitCanonical(playbook []byte, arguments *Arguments) int {
+	w := os.Stdout
+	if arguments.EmitCanonical != "-" {
+		file, err := os.Create(arguments.EmitCanonical)
+		if err != nil {
+			slog.Error("could not create canonical form", slog.Any("error", err))
+			return ExitIOError
+		}
+		defer file.Close()
+		w = file
+	}
+	// the file is created before the sandbox forbids it
+	mustSandbox(arguments)
+
+	plays, err := verifier.StripSignaturesPolicy(playbook, arguments.Policy())
+	if err != nil {
+		slog.Error("could not serialize plays", slog.Any("error", err))
+		return exitCode(err)
+	}
+	if _, err = w.Write(bytes.Join(plays, []byte{0})); err != nil {
+		slog.Error("could not write canonical form", slog.Any("error", err))
+		return ExitIOError
+	}
+	if w != os.Stdout {
+		if err = w.Close(); err != nil {
+			slog.Error("could not write canonical form", slog.Any("error", err))
+			return ExitIOError
+		}
+	}
+	return ExitOK
+}
+
+// signPlaybook writes the playbook with its plays signed by the private key of the arguments,
+// and returns the exit code.
+func signPlaybook(w io.Writer, playbook []byte, arguments *Arguments) int {
+	privateKey, err := os.ReadFile(arguments.PrivateKey)
+	if err != nil {
+		slog.Error("could not read private key", slog.Any("error", err))
+		return ExitIOError
+	}
+	signer, err := verifier.NewSigner(privateKey)
+	hygiene.Wipe(privateKey)
+	if err != nil {
+		slog.Error("could not load private key", slog.Any("error", err))
+		return exitCode(err)
+	}
+
+	signed, err := verifier.SignPlaybook(playbook, signer, arguments.Policy())
+	if err != nil {
+		slog.Error("could not sign playbook", slog.Any("error", err))
+		return exitCode(err)
+	}
+	if _, err = w.Write(signed); err != nil {
+		slog.Error("could not print playbook", slog.Any("error", err))
+		return ExitIOError
+	}
+	return ExitOK
+}
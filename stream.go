@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+
+	"com.github/m-horky/playbook-verifier/internal/audit"
+	"com.github/m-horky/playbook-verifier/internal/framing"
+	"com.github/m-horky/playbook-verifier/pkg/verifier"
+)
+
+// StreamVerdict is the verification result of a single payload of the stream.
+type StreamVerdict struct {
+	// Index is the position of the payload in the stream, from 0.
+	Index int `json:"index"`
+	// ExitCode is the exit code the verification of the payload alone would have.
+	ExitCode int             `json:"exit_code"`
+	Report   verifier.Report `json:"report"`
+}
+
+// verifyStream verifies the payloads framed in the reader, one after another, so a parent process
+// can verify many payloads without starting the verifier for each of them.
+//
+// Every payload is a frame: its length as a 4-byte big-endian integer, followed by its bytes.
+// For every payload, two frames are written in the same order: the StreamVerdict encoded as JSON,
+// and the verified payload as it would be printed for it alone, which is empty if it is not verified.
+// Payloads larger than --max-size are refused, but the stream goes on.
+//
+// The exit code is ExitOK once the reader ends between two payloads, the verdicts carry
+// the exit codes of the payloads. A stream that cannot be read or written ends with ExitIOError.
+func verifyStream(ctx context.Context, r io.Reader, w io.Writer, arguments *Arguments, keyring *verifier.Keyring) int {
+	policy := arguments.Policy()
+	auditLog := openAuditLog(arguments)
+	mustSandbox(arguments)
+
+	reader := bufio.NewReader(r)
+	writer := bufio.NewWriter(w)
+	for index := 0; ; index++ {
+		payload, err := framing.ReadFrame(reader, arguments.MaxSize)
+		if err == io.EOF {
+			slog.Debug("stream ended", slog.Int("payloads", index))
+			return ExitOK
+		}
+		if err != nil && !errors.Is(err, framing.ErrFrameTooLarge) {
+			slog.Error("could not read payload", slog.Int("index", index), slog.Any("error", err))
+			return ExitIOError
+		}
+
+		verdict := StreamVerdict{Index: index}
+		var output []byte
+		if err != nil {
+			slog.Error("could not read payload", slog.Int("index", index), slog.Any("error", err))
+			verdict.ExitCode = ExitIOError
+			verdict.Report = verifier.Report{Plays: []verifier.PlayReport{}, SkippedDocuments: []int{}, Error: err.Error()}
+		} else {
+			verdict, output = verifyStreamPayload(ctx, index, payload, arguments, keyring, policy, auditLog)
+		}
+
+		encoded, err := json.Marshal(verdict)
+		if err == nil {
+			err = framing.WriteFrame(writer, encoded)
+		}
+		if err == nil {
+			err = framing.WriteFrame(writer, output)
+		}
+		if err == nil {
+			// the parent may wait for the verdict before it sends the next payload
+			err = writer.Flush()
+		}
+		if err != nil {
+			slog.Error("could not write verdict", slog.Int("index", index), slog.Any("error", err))
+			return ExitIOError
+		}
+	}
+}
+
+// verifyStreamPayload verifies a single payload of the stream, returning its verdict and
+// the verified payload.
+func verifyStreamPayload(ctx context.Context, index int, payload []byte, arguments *Arguments, keyring *verifier.Keyring, policy verifier.Policy, auditLog audit.Log) (StreamVerdict, []byte) {
+	logger := slog.With(slog.Int("index", index))
+	verdict := StreamVerdict{Index: index}
+
+	payload, err := unpackPlaybook(payload, arguments)
+	if err != nil {
+		logger.Error("could not unpack playbook", slog.Any("error", err))
+		verdict.ExitCode = exitCode(err)
+		verdict.Report = verifier.Report{Plays: []verifier.PlayReport{}, SkippedDocuments: []int{}, Error: err.Error()}
+		return verdict, nil
+	}
+
+	verdict.Report, err = verifier.VerifyContext(ctx, arguments.ContentType, payload, keyring, policy)
+	recordVerification(auditLog, arguments.ContentType, payload, verdict.Report, err)
+	if err != nil {
+		logger.Error("could not verify playbook", slog.Any("error", err))
+		verdict.ExitCode = exitCode(err)
+		return verdict, nil
+	}
+	logger.Info("playbook verified")
+	printWarnings(os.Stderr, fmt.Sprintf("payload %d: ", index), verdict.Report, arguments.Unsigned)
+	verdict.ExitCode = verdictCode(verdict.Report, err)
+	return verdict, verifiedPayload(payload, verdict.Report, arguments)
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+
+	"com.github/m-horky/playbook-verifier/internal/framing"
+)
+
+// readStreamVerdicts reads the verdict and the verified payload of every payload of the output of verifyStream.
+func readStreamVerdicts(t *testing.T, r io.Reader) ([]StreamVerdict, [][]byte) {
+	t.Helper()
+	var verdicts []StreamVerdict
+	var outputs [][]byte
+	for {
+		encoded, err := framing.ReadFrame(r, 0)
+		if err == io.EOF {
+			return verdicts, outputs
+		}
+		if err != nil {
+			t.Fatalf("could not read verdict: %v", err)
+		}
+		var verdict StreamVerdict
+		if err = json.Unmarshal(encoded, &verdict); err != nil {
+			t.Fatalf("could not decode verdict %q: %v", encoded, err)
+		}
+		output, err := framing.ReadFrame(r, 0)
+		if err != nil {
+			t.Fatalf("could not read payload of verdict %d: %v", verdict.Index, err)
+		}
+		verdicts = append(verdicts, verdict)
+		outputs = append(outputs, output)
+	}
+}
+
+func TestVerifyStream(t *testing.T) {
+	signed := signedPlaybook(t, testPlaybook)
+	arguments := parseTestArguments(t, "--batch-stdin", "--max-size", "4096")
+	keyring := mustLoadKeyring(arguments)
+
+	payloads := []struct {
+		name     string
+		payload  []byte
+		exitCode int
+		verified bool
+	}{
+		{name: "signed", payload: signed, exitCode: ExitOK, verified: true},
+		{name: "tampered", payload: tamperedPlaybook(t), exitCode: ExitSignatureMismatch},
+		{name: "unsigned", payload: []byte(testPlaybook), exitCode: ExitMissingSignature},
+		{name: "too large", payload: bytes.Repeat([]byte("#"), 4097), exitCode: ExitIOError},
+		{name: "not YAML", payload: []byte("- [unclosed\n"), exitCode: ExitParseError},
+		{name: "signed again", payload: signed, exitCode: ExitOK, verified: true},
+	}
+	var input bytes.Buffer
+	for _, payload := range payloads {
+		if err := framing.WriteFrame(&input, payload.payload); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var output bytes.Buffer
+	if code := verifyStream(context.Background(), &input, &output, arguments, keyring); code != ExitOK {
+		t.Errorf("verifyStream() = %d, want %d once the stream ends", code, ExitOK)
+	}
+	verdicts, outputs := readStreamVerdicts(t, &output)
+	if len(verdicts) != len(payloads) {
+		t.Fatalf("verifyStream() wrote %d verdicts, want %d", len(verdicts), len(payloads))
+	}
+	for i, payload := range payloads {
+		verdict := verdicts[i]
+		if verdict.Index != i {
+			t.Errorf("%s: Index = %d, want %d", payload.name, verdict.Index, i)
+		}
+		if verdict.ExitCode != payload.exitCode {
+			t.Errorf("%s: ExitCode = %d, want %d (%s)", payload.name, verdict.ExitCode, payload.exitCode, verdict.Report.Error)
+		}
+		if verdict.Report.Verified != payload.verified {
+			t.Errorf("%s: Verified = %t, want %t", payload.name, verdict.Report.Verified, payload.verified)
+		}
+		if want := map[bool][]byte{true: payload.payload, false: {}}[payload.verified]; !bytes.Equal(outputs[i], want) {
+			t.Errorf("%s: payload = %q, want %q", payload.name, outputs[i], want)
+		}
+	}
+}
+
+func TestVerifyStreamTruncated(t *testing.T) {
+	arguments := parseTestArguments(t, "--batch-stdin")
+	keyring := mustLoadKeyring(arguments)
+
+	var input bytes.Buffer
+	if err := framing.WriteFrame(&input, signedPlaybook(t, testPlaybook)); err != nil {
+		t.Fatal(err)
+	}
+	// the length of the second payload promises more than the stream has
+	input.Write([]byte{0, 0, 1, 0})
+	input.WriteString("- hosts")
+
+	var output bytes.Buffer
+	if code := verifyStream(context.Background(), &input, &output, arguments, keyring); code != ExitIOError {
+		t.Errorf("verifyStream() = %d, want %d", code, ExitIOError)
+	}
+	verdicts, _ := readStreamVerdicts(t, &output)
+	if len(verdicts) != 1 || !verdicts[0].Report.Verified {
+		t.Errorf("verifyStream() verdicts = %+v, want the first payload verified", verdicts)
+	}
+}
+
+func TestBatchStdin(t *testing.T) {
+	var input bytes.Buffer
+	for _, payload := range [][]byte{signedPlaybook(t, testPlaybook), tamperedPlaybook(t)} {
+		if err := framing.WriteFrame(&input, payload); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	stdout, stderr, code := runMain(t, &input, nil, append(testArguments(t), "--batch-stdin")...)
+	if code != ExitOK {
+		t.Fatalf("exit code = %d, want %d; stderr:\n%s", code, ExitOK, stderr)
+	}
+	verdicts, _ := readStreamVerdicts(t, strings.NewReader(stdout))
+	var codes []int
+	for _, verdict := range verdicts {
+		codes = append(codes, verdict.ExitCode)
+	}
+	if len(codes) != 2 || codes[0] != ExitOK || codes[1] != ExitSignatureMismatch {
+		t.Errorf("exit codes of the payloads = %v, want [%d %d]", codes, ExitOK, ExitSignatureMismatch)
+	}
+}
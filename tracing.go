@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// ServiceName is the name the spans of the verifier are exported under,
+// unless OTEL_SERVICE_NAME says otherwise.
+const ServiceName = "insights-playbook-verifier"
+
+// shutdownTracing exports the spans that have not been exported yet.
+var shutdownTracing = func() {}
+
+// setupTracing configures the export of spans over OTLP and returns the context
+// the verification should run in.
+//
+// Spans are only exported if the OTLP endpoint is configured via the standard
+// OTEL_EXPORTER_OTLP_ENDPOINT or OTEL_EXPORTER_OTLP_TRACES_ENDPOINT variables.
+// The parent span may be passed via TRACEPARENT and TRACESTATE variables, so the
+// verification is a part of the trace of the remediation that runs the verifier.
+func setupTracing() context.Context {
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	ctx := otel.GetTextMapPropagator().Extract(context.Background(), propagation.MapCarrier{
+		"traceparent": os.Getenv("TRACEPARENT"),
+		"tracestate":  os.Getenv("TRACESTATE"),
+	})
+
+	if os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") == "" && os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") == "" {
+		return ctx
+	}
+	exporter, err := otlptracehttp.New(ctx)
+	if err != nil {
+		slog.Warn("could not create trace exporter", slog.Any("error", err))
+		return ctx
+	}
+	res, err := resource.New(ctx,
+		resource.WithAttributes(attribute.String("service.name", ServiceName)),
+		resource.WithFromEnv(),
+	)
+	if err != nil {
+		slog.Warn("could not detect trace resource", slog.Any("error", err))
+	}
+	provider := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exporter), sdktrace.WithResource(res))
+	otel.SetTracerProvider(provider)
+
+	shutdownTracing = func() {
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := provider.Shutdown(shutdownCtx); err != nil {
+			slog.Warn("could not export spans", slog.Any("error", err))
+		}
+	}
+	slog.Debug("exporting spans over OTLP")
+	return ctx
+}
+
+// exit exports the remaining spans and exits with the code.
+func exit(code int) {
+	shutdownTracing()
+	os.Exit(code)
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"runtime"
+	"runtime/debug"
+	"strings"
+
+	"com.github/m-horky/playbook-verifier/internal/keystore"
+	"com.github/m-horky/playbook-verifier/pkg/verifier"
+)
+
+// VersionInfo describes the build of the verifier and what it supports.
+//
+// Support uses it to identify the binary; the server uses it to decide which
+// signatures the client is able to verify.
+type VersionInfo struct {
+	// Version is the version of the module, '(devel)' for builds from a working tree.
+	Version string `json:"version"`
+	// Commit is the git commit the binary has been built from, if known.
+	Commit string `json:"commit,omitempty"`
+	// Modified is true if the working tree had uncommitted changes.
+	Modified  bool   `json:"modified"`
+	GoVersion string `json:"go_version"`
+	Platform  string `json:"platform"`
+	// Environment is the signing environment of the embedded keys.
+	Environment string `json:"environment"`
+	// Keys are the fingerprints of the embedded trusted keys.
+	Keys []string `json:"keys"`
+	// RevokedKeys are the fingerprints of the embedded revoked keys.
+	RevokedKeys           []string `json:"revoked_keys"`
+	SerializationVersions []string `json:"serialization_versions"`
+	SerializationProfiles []string `json:"serialization_profiles"`
+	HashAlgorithms        []string `json:"hash_algorithms"`
+	ContentTypes          []string `json:"content_types"`
+}
+
+// versionInfo collects the information about the build.
+func versionInfo() (VersionInfo, error) {
+	info := VersionInfo{
+		Version:               "unknown",
+		GoVersion:             runtime.Version(),
+		Platform:              runtime.GOOS + "/" + runtime.GOARCH,
+		Environment:           keystore.Environment(),
+		SerializationVersions: verifier.SerializationVersions(),
+		SerializationProfiles: verifier.SerializationProfiles(),
+		HashAlgorithms:        verifier.HashAlgorithms(),
+		ContentTypes:          verifier.ContentTypes(),
+	}
+	if build, ok := debug.ReadBuildInfo(); ok {
+		info.Version = build.Main.Version
+		for _, setting := range build.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				info.Commit = setting.Value
+			case "vcs.modified":
+				info.Modified = setting.Value == "true"
+			}
+		}
+	}
+
+	// a build without the keys of its environment has to tell which one it is
+	publicKeys, err := keystore.PublicKeys()
+	if err != nil && !errors.Is(err, keystore.ErrNoEmbeddedKeys) {
+		return info, fmt.Errorf("could not load embedded keys: %w", err)
+	}
+	info.Keys = []string{}
+	if len(publicKeys) > 0 {
+		keyring, err := verifier.NewKeyring(publicKeys...)
+		if err != nil {
+			return info, fmt.Errorf("could not create keyring: %w", err)
+		}
+		info.Keys = keyring.Fingerprints()
+	}
+	revoked, err := keystore.RevokedKeys()
+	if err != nil {
+		return info, fmt.Errorf("could not load revoked keys: %w", err)
+	}
+	info.RevokedKeys = verifier.ParseRevocationList(revoked)
+	if info.RevokedKeys == nil {
+		info.RevokedKeys = []string{}
+	}
+	return info, nil
+}
+
+// printVersion writes the information about the build in the output format and returns the exit code.
+func printVersion(w io.Writer, format string) int {
+	info, err := versionInfo()
+	if err != nil {
+		slog.Error("could not collect version", slog.Any("error", err))
+		return ExitIOError
+	}
+
+	if format == FormatJSON {
+		if err = json.NewEncoder(w).Encode(info); err != nil {
+			slog.Error("could not print version", slog.Any("error", err))
+			return ExitIOError
+		}
+		return ExitOK
+	}
+
+	commit := info.Commit
+	if commit == "" {
+		commit = "unknown"
+	}
+	if info.Modified {
+		commit += " (modified)"
+	}
+	fmt.Fprintf(w, "playbook-verifier %s\n", info.Version)
+	fmt.Fprintf(w, "commit:         %s\n", commit)
+	fmt.Fprintf(w, "go:             %s %s\n", info.GoVersion, info.Platform)
+	fmt.Fprintf(w, "environment:    %s\n", info.Environment)
+	fmt.Fprintf(w, "serialization:  %s (profiles %s)\n", strings.Join(info.SerializationVersions, ", "), strings.Join(info.SerializationProfiles, ", "))
+	fmt.Fprintf(w, "hashes:         %s\n", strings.Join(info.HashAlgorithms, ", "))
+	fmt.Fprintf(w, "content types:  %s\n", strings.Join(info.ContentTypes, ", "))
+	for _, key := range info.Keys {
+		fmt.Fprintf(w, "key:            %s\n", key)
+	}
+	for _, key := range info.RevokedKeys {
+		fmt.Fprintf(w, "revoked key:    %s\n", key)
+	}
+	return ExitOK
+}